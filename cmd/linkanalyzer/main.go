@@ -7,6 +7,9 @@ import (
 	"time"
 
 	"github.com/ngonzalez/web-tools/internal/analyzer"
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 const (
@@ -23,15 +26,46 @@ func main() {
 	timeout := flag.Int("t", 10, "Request timeout in seconds")
 	flag.IntVar(timeout, "timeout", 10, "Request timeout in seconds")
 
-	maxDepth := flag.Int("d", 0, "Maximum crawl depth (0 = unlimited)")
-	flag.IntVar(maxDepth, "depth", 0, "Maximum crawl depth (0 = unlimited)")
+	maxDepth := flag.Int("d", 0, "Levels of pages inventoried (1 = start page only, 0 = unlimited)")
+	flag.IntVar(maxDepth, "depth", 0, "Levels of pages inventoried (1 = start page only, 0 = unlimited)")
 
-	verbose := flag.Bool("v", false, "Show all visited URLs")
-	flag.BoolVar(verbose, "verbose", false, "Show all visited URLs")
+	verbose := flag.Bool("v", false, "Show errors and notable events")
+	flag.BoolVar(verbose, "verbose", false, "Show errors and notable events")
+	vv := flag.Bool("vv", false, "Also show every visited URL")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
 
 	details := flag.Bool("details", true, "Show detailed breakdown of non-analyzable links")
 	flag.BoolVar(details, "D", true, "Show detailed breakdown of non-analyzable links")
 
+	sameScheme := flag.Bool("same-scheme", false, "Treat http:// and https:// URLs as the same page")
+	keepHTTP := flag.Bool("keep-http", false, "Crawl http:// URLs as is, even on a site enforcing HTTPS")
+
+	extraElements := flag.Bool("extra-elements", false, "Also inventory <area href>, <form action> and <link href> URLs")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	topDomains := flag.Int("top-domains", 10, "Number of most linked external domains to rank (0 = hide)")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	maxExamples := flag.Int("max-examples", 0, "Links kept per category to bound memory, counts stay exact (0 = all)")
+
+	maxRepeats := flag.Int("max-repeats", 2, "Report targets a page links to more than this many times (0 = off)")
+
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request (0 = none, the 3xx kept as is, -1 = up to 100)")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	maxURLLength := flag.Int("max-url-length", 115, "Report internal URLs longer than this many characters (0 = off)")
+
+	maxURLDepth := flag.Int("max-url-depth", 6, "Report internal URLs with more path segments than this (0 = off)")
+
+	maxURLParams := flag.Int("max-url-params", 4, "Report internal URLs with more query parameters than this (0 = off)")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sLinkAnalyzer%s - Detect non-analyzable links\n\n", colorBold, colorCyan, colorReset)
 		fmt.Fprintf(os.Stderr, "Usage: linkanalyzer [options] <url>\n\n")
@@ -42,12 +76,32 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 10)\n")
-		fmt.Fprintf(os.Stderr, "  -d, --depth int         Maximum crawl depth, 0 = unlimited (default 0)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show all visited URLs\n")
+		fmt.Fprintf(os.Stderr, "  -d, --depth int         Levels of pages inventoried, 1 = start page only, 0 = unlimited (default 0)\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show errors and notable events\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every visited URL\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
 		fmt.Fprintf(os.Stderr, "  -D, --details           Show detailed breakdown (default true)\n")
+		fmt.Fprintf(os.Stderr, "      --same-scheme       Treat http:// and https:// URLs as the same page\n")
+		fmt.Fprintf(os.Stderr, "      --keep-http         Crawl http:// URLs as is, even on a site enforcing HTTPS by redirect or HSTS\n")
+		fmt.Fprintf(os.Stderr, "      --extra-elements    Also inventory <area href>, <form action> and <link href> URLs\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --top-domains int   Number of most linked external domains to rank, 0 = hide (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --max-examples int  Links kept per category, 0 = all; counts stay exact (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --max-repeats int   Report targets a page links to more than this many times, 0 = off (default 2)\n")
+		fmt.Fprintf(os.Stderr, "      --max-redirects int Redirects followed per request, 0 = none (3xx kept as is), -1 = up to 100 (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --max-url-length int\n")
+		fmt.Fprintf(os.Stderr, "                          Report internal URLs longer than this many characters, 0 = off (default 115)\n")
+		fmt.Fprintf(os.Stderr, "      --max-url-depth int Report internal URLs with more path segments than this, 0 = off (default 6)\n")
+		fmt.Fprintf(os.Stderr, "      --max-url-params int\n")
+		fmt.Fprintf(os.Stderr, "                          Report internal URLs with more query parameters than this, 0 = off (default 4)\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  linkanalyzer https://example.com\n")
 		fmt.Fprintf(os.Stderr, "  linkanalyzer -c 20 -d 3 -v https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  linkanalyzer -d 1 https://example.com/article\n")
 	}
 
 	flag.Parse()
@@ -58,18 +112,49 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
 	startURL := args[0]
 
 	config := analyzer.Config{
-		Concurrency: *concurrency,
-		Timeout:     time.Duration(*timeout) * time.Second,
-		MaxDepth:    *maxDepth,
-		Verbose:     *verbose,
+		Concurrency:         *concurrency,
+		Timeout:             time.Duration(*timeout) * time.Second,
+		MaxDepth:            *maxDepth,
+		Verbosity:           verbosity.FromFlags(*verbose, *vv, *vvv),
+		TreatSchemesAsSame:  *sameScheme,
+		KeepHTTP:            *keepHTTP,
+		ExtraElements:       *extraElements,
+		Delay:               time.Duration(*delay) * time.Millisecond,
+		MaxIdleConnsPerHost: *idleConns,
+		MaxStoredExamples:   *maxExamples,
+		MaxRepeats:          *maxRepeats,
+		MaxRedirects:        *maxRedirects,
+		HTMLContentTypes:    htmlContentTypes,
+		FollowInternal:      true,
+		URLLimits: analyzer.URLLimits{
+			Length: *maxURLLength,
+			Depth:  *maxURLDepth,
+			Params: *maxURLParams,
+		},
 	}
 
 	fmt.Printf("%s%sLinkAnalyzer%s starting...\n", colorBold, colorCyan, colorReset)
 	fmt.Printf("Target: %s\n", startURL)
-	fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n\n", config.Concurrency, *timeout, config.MaxDepth)
+	if config.MaxDepth == 1 {
+		fmt.Printf("Timeout: %ds, start page only\n\n", *timeout)
+	} else {
+		fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n\n", config.Concurrency, *timeout, config.MaxDepth)
+	}
 
 	a := analyzer.New(config)
 	result, err := a.Analyze(startURL)
@@ -78,5 +163,9 @@ func main() {
 		os.Exit(1)
 	}
 
-	result.PrintSummary(*details)
+	result.PrintSummary(*details, *topDomains)
+
+	if *summaryLine {
+		fmt.Println(result.SummaryLine())
+	}
 }
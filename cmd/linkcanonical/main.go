@@ -7,6 +7,9 @@ import (
 	"time"
 
 	"github.com/ngonzalez/web-tools/internal/canonical"
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 const (
@@ -25,12 +28,43 @@ func main() {
 	maxDepth := flag.Int("d", 0, "Maximum crawl depth (0 = unlimited)")
 	flag.IntVar(maxDepth, "depth", 0, "Maximum crawl depth (0 = unlimited)")
 
-	verbose := flag.Bool("v", false, "Show all visited URLs")
-	flag.BoolVar(verbose, "verbose", false, "Show all visited URLs")
+	verbose := flag.Bool("v", false, "Show errors and notable events")
+	flag.BoolVar(verbose, "verbose", false, "Show errors and notable events")
+	vv := flag.Bool("vv", false, "Also show every visited URL")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
 
 	details := flag.Bool("details", true, "Show detailed breakdown")
 	flag.BoolVar(details, "D", true, "Show detailed breakdown")
 
+	ignoreWWW := flag.Bool("ignore-www", false, "Treat www and non-www URLs as equivalent")
+
+	ignoreScheme := flag.Bool("ignore-scheme", false, "Treat http and https URLs as equivalent")
+
+	sameScheme := flag.Bool("same-scheme", false, "Treat http:// and https:// URLs as the same page")
+	keepHTTP := flag.Bool("keep-http", false, "Crawl http:// URLs as is, even on a site enforcing HTTPS")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	ignoreParams := flag.String("ignore-query-params", "", "Comma-separated query parameters to ignore in canonical comparisons, utm_* or * for all")
+
+	keepParams := flag.String("keep-query-params", "", "Comma-separated query parameters always compared, e.g. page with --ignore-query-params *")
+
+	mapOutput := flag.Bool("map", false, "Output every crawled URL and its canonical as CSV")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	maxExamples := flag.Int("max-examples", 0, "Issues kept per type to bound memory, counts stay exact (0 = all)")
+
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request (0 = none, -1 = up to 100)")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	followCanonicals := flag.Bool("follow-canonicals", false, "Also crawl the canonical targets pages declare, as if linked")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sLinkCanonical%s - Verify canonical URLs\n\n", colorBold, colorCyan, colorReset)
 		fmt.Fprintf(os.Stderr, "Usage: linkcanonical [options] <url>\n\n")
@@ -39,18 +73,50 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Detects:\n")
 		fmt.Fprintf(os.Stderr, "  - Links pointing to non-canonical URLs\n")
 		fmt.Fprintf(os.Stderr, "  - Links causing redirects to canonical\n")
-		fmt.Fprintf(os.Stderr, "  - Pages with missing canonical tags\n")
+		fmt.Fprintf(os.Stderr, "  - Temporary redirects (302, 307) on internal links that look like permanent moves\n")
+		fmt.Fprintf(os.Stderr, "  - Pages with missing canonical tags, escalated when the same content\n")
+		fmt.Fprintf(os.Stderr, "    was reached through several URLs (parameters, slashes, http/https)\n")
+		fmt.Fprintf(os.Stderr, "  - Canonicals stripping query parameters (/p?page=2 → /p), listed apart for review\n")
 		fmt.Fprintf(os.Stderr, "  - Canonical URL mismatches\n")
-		fmt.Fprintf(os.Stderr, "  - Canonical chains (A→B→C)\n\n")
+		fmt.Fprintf(os.Stderr, "  - Canonical chains (A→B→C)\n")
+		fmt.Fprintf(os.Stderr, "  - Canonical loops (A→B→A)\n")
+		fmt.Fprintf(os.Stderr, "  - Mass canonicalization: most pages canonicalizing to one URL, such as the homepage\n")
+		fmt.Fprintf(os.Stderr, "  - Multiple canonical tags on one page\n")
+		fmt.Fprintf(os.Stderr, "  - Link header and <link> tag declaring different canonicals\n")
+		fmt.Fprintf(os.Stderr, "  - Canonical tags placed in <body>, which search engines ignore\n")
+		fmt.Fprintf(os.Stderr, "  - Canonicals pointing to a URL that answers an error or not at all\n")
+		fmt.Fprintf(os.Stderr, "  - Sections (/shop?page=2, /shop?color=red) whose pages mix canonical strategies\n")
+		fmt.Fprintf(os.Stderr, "  - Hreflang language versions canonicalizing to another URL\n")
+		fmt.Fprintf(os.Stderr, "  - Canonicals pointing to another domain\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -d, --depth int         Maximum crawl depth, 0 = unlimited (default 0)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show all visited URLs\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show errors and notable events\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every visited URL\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
 		fmt.Fprintf(os.Stderr, "  -D, --details           Show detailed breakdown (default true)\n")
+		fmt.Fprintf(os.Stderr, "      --ignore-www        Treat www and non-www URLs as equivalent\n")
+		fmt.Fprintf(os.Stderr, "      --ignore-scheme     Treat http and https URLs as equivalent\n")
+		fmt.Fprintf(os.Stderr, "      --same-scheme       Treat http:// and https:// URLs as the same page\n")
+		fmt.Fprintf(os.Stderr, "      --keep-http         Crawl http:// URLs as is, even on a site enforcing HTTPS by redirect or HSTS\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --ignore-query-params list\n")
+		fmt.Fprintf(os.Stderr, "                          Query parameters to ignore when comparing URLs (utm_*, * = all)\n")
+		fmt.Fprintf(os.Stderr, "      --keep-query-params list\n")
+		fmt.Fprintf(os.Stderr, "                          Query parameters still compared when ignoring the others\n")
+		fmt.Fprintf(os.Stderr, "      --map               Output every crawled URL and its canonical as CSV, for redirect rules\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --max-examples int  Issues kept per type, 0 = all; counts stay exact (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --max-redirects int Redirects followed per request, 0 = none, -1 = up to 100 (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --follow-canonicals Also crawl the canonical targets pages declare, as if linked\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  linkcanonical https://example.com\n")
 		fmt.Fprintf(os.Stderr, "  linkcanonical -c 20 -d 3 -v https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  linkcanonical --map https://example.com > canonicals.csv\n")
 	}
 
 	flag.Parse()
@@ -61,18 +127,44 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
 	startURL := args[0]
 
 	config := canonical.Config{
-		Concurrency: *concurrency,
-		Timeout:     time.Duration(*timeout) * time.Second,
-		MaxDepth:    *maxDepth,
-		Verbose:     *verbose,
+		Concurrency:         *concurrency,
+		Timeout:             time.Duration(*timeout) * time.Second,
+		MaxDepth:            *maxDepth,
+		Verbosity:           verbosity.FromFlags(*verbose, *vv, *vvv),
+		IgnoreWWW:           *ignoreWWW,
+		IgnoreScheme:        *ignoreScheme,
+		TreatSchemesAsSame:  *sameScheme,
+		KeepHTTP:            *keepHTTP,
+		Delay:               time.Duration(*delay) * time.Millisecond,
+		IgnoreParams:        canonical.ParseParamList(*ignoreParams),
+		KeepParams:          canonical.ParseParamList(*keepParams),
+		MaxIdleConnsPerHost: *idleConns,
+		MaxStoredExamples:   *maxExamples,
+		MaxRedirects:        *maxRedirects,
+		HTMLContentTypes:    htmlContentTypes,
+		FollowCanonicals:    *followCanonicals,
 	}
 
-	fmt.Printf("%s%sLinkCanonical%s starting...\n", colorBold, colorCyan, colorReset)
-	fmt.Printf("Target: %s\n", startURL)
-	fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n\n", config.Concurrency, *timeout, config.MaxDepth)
+	if !*mapOutput {
+		fmt.Printf("%s%sLinkCanonical%s starting...\n", colorBold, colorCyan, colorReset)
+		fmt.Printf("Target: %s\n", startURL)
+		fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n\n", config.Concurrency, *timeout, config.MaxDepth)
+	}
 
 	checker := canonical.New(config)
 	result, err := checker.Check(startURL)
@@ -81,7 +173,20 @@ func main() {
 		os.Exit(1)
 	}
 
-	result.PrintSummary(*details)
+	if *mapOutput {
+		fmt.Print(result.ExportMappingCSV())
+	} else {
+		result.PrintSummary(*details)
+	}
+
+	if *summaryLine {
+		// Kept off stdout when it carries structured output, so that stays parseable
+		out := os.Stdout
+		if *mapOutput {
+			out = os.Stderr
+		}
+		fmt.Fprintln(out, result.SummaryLine())
+	}
 
 	// Exit with error code if issues found
 	if len(result.Issues) > 0 {
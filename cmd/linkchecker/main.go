@@ -4,15 +4,27 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
 	"github.com/ngonzalez/web-tools/internal/crawler"
+	"github.com/ngonzalez/web-tools/internal/dashboard"
+	"github.com/ngonzalez/web-tools/internal/export"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/login"
+	"github.com/ngonzalez/web-tools/internal/metrics"
+	"github.com/ngonzalez/web-tools/internal/sitemap"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 const (
-	colorReset = "\033[0m"
-	colorCyan  = "\033[36m"
-	colorBold  = "\033[1m"
+	colorReset  = "\033[0m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+	colorBold   = "\033[1m"
 )
 
 func main() {
@@ -26,8 +38,64 @@ func main() {
 	maxDepth := flag.Int("d", 0, "Maximum crawl depth (0 = unlimited)")
 	flag.IntVar(maxDepth, "depth", 0, "Maximum crawl depth (0 = unlimited)")
 
-	verbose := flag.Bool("v", false, "Show all visited URLs")
-	flag.BoolVar(verbose, "verbose", false, "Show all visited URLs")
+	verbose := flag.Bool("v", false, "Show errors and notable events")
+	flag.BoolVar(verbose, "verbose", false, "Show errors and notable events")
+	vv := flag.Bool("vv", false, "Also show every visited URL")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
+
+	sameScheme := flag.Bool("same-scheme", false, "Treat http:// and https:// URLs as the same page")
+
+	extraElements := flag.Bool("extra-elements", false, "Also check <area href>, <form action> and <link href> URLs")
+
+	failFast := flag.Bool("fail-fast", false, "Stop at the first broken link")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	obeyNoFollow := flag.Bool("obey-nofollow", false, "Don't follow rel=nofollow links, like search engines")
+
+	probeOpenRedirects := flag.Bool("probe-open-redirects", false, "Probe url/redirect/next/return parameters for open redirects (heuristic)")
+
+	sitemaps := flag.String("sitemap", "", "Comma-separated sitemaps or RSS/Atom feeds, gzipped or not, whose URLs seed the crawl")
+
+	tui := flag.Bool("tui", false, "Show a live dashboard of the crawl instead of scrolling output")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	acceptStatus := flag.String("accept", "", "Comma-separated statuses or ranges reported as restricted, not broken (e.g. 401,403)")
+
+	loginURL := flag.String("login-url", "", "Login page or form URL to submit before crawling")
+	loginFields := login.Fields{}
+	flag.Var(loginFields, "login-field", "Login form field as name=value, repeatable")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	jsonOutput := flag.Bool("json", false, "Output results as JSON instead of the report")
+
+	csvOutput := flag.Bool("csv", false, "Output broken links as CSV, grouped by status")
+
+	acceptHeader := flag.String("accept-header", "", "Accept header sent with every request, e.g. text/html")
+
+	watch := flag.Duration("watch", 0, "Re-crawl at this interval and print only changes until Ctrl-C (e.g. 30s)")
+
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request (0 = none, -1 = up to 100)")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	prometheus := flag.Bool("prometheus", false, "Output metrics in Prometheus text format instead of the report")
+
+	metricsFile := flag.String("metrics-file", "", "Also write metrics in Prometheus text format to this file, after every run with --watch")
+
+	maxPages := flag.Int("max-pages", 0, "Stop visiting pages past this many (0 = 10000)")
+
+	order := flag.String("order", "bfs", "Crawl order: bfs, dfs or random")
+
+	baseline := flag.String("baseline", "", "File of known URLs, one per line; pages crawled outside it are reported as new")
+
+	keepHTTP := flag.Bool("keep-http", false, "Crawl http:// URLs as is, even on a site enforcing HTTPS")
+
+	tree := flag.Bool("tree", false, "Also print the site structure: pages per URL path section")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sLinkChecker%s - A broken link detector\n\n", colorBold, colorCyan, colorReset)
@@ -36,10 +104,44 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -d, --depth int         Maximum crawl depth, 0 = unlimited (default 0)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show all visited URLs\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show errors and notable events\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every visited URL\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
+		fmt.Fprintf(os.Stderr, "      --same-scheme       Treat http:// and https:// URLs as the same page\n")
+		fmt.Fprintf(os.Stderr, "      --extra-elements    Also check <area href>, <form action> and <link href> URLs\n")
+		fmt.Fprintf(os.Stderr, "      --fail-fast         Stop at the first broken link and exit with code 1\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --obey-nofollow     Don't follow rel=nofollow links, like search engines\n")
+		fmt.Fprintf(os.Stderr, "      --probe-open-redirects\n")
+		fmt.Fprintf(os.Stderr, "                          Probe url/redirect/next/return parameters for open redirects (heuristic)\n")
+		fmt.Fprintf(os.Stderr, "      --sitemap list      Comma-separated sitemaps or RSS/Atom feeds (.gz too) whose URLs seed the crawl\n")
+		fmt.Fprintf(os.Stderr, "      --tui               Show a live dashboard of the crawl, replacing -v output (terminals only)\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --accept list       Statuses or ranges reported as intentionally restricted, not broken (e.g. 401,403)\n")
+		fmt.Fprintf(os.Stderr, "      --login-url url     Login page or form URL to submit before crawling, with a session cookie\n")
+		fmt.Fprintf(os.Stderr, "      --login-field name=value\n")
+		fmt.Fprintf(os.Stderr, "                          Login form field, repeatable (hidden fields of the form are sent too)\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --json              Output results as JSON instead of the report\n")
+		fmt.Fprintf(os.Stderr, "      --csv               Output broken links as CSV, grouped by status\n")
+		fmt.Fprintf(os.Stderr, "      --accept-header str Accept header sent with every request, e.g. text/html\n")
+		fmt.Fprintf(os.Stderr, "      --watch duration    Re-crawl at this interval, printing newly broken and fixed links until Ctrl-C\n")
+		fmt.Fprintf(os.Stderr, "      --max-redirects int Redirects followed per request, 0 = none, -1 = up to 100 (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --prometheus        Output metrics in Prometheus text format instead of the report\n")
+		fmt.Fprintf(os.Stderr, "      --metrics-file path Also write metrics in Prometheus text format to this file, after every run with --watch\n")
+		fmt.Fprintf(os.Stderr, "      --max-pages int     Stop visiting pages past this many, 0 = 10000 (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --order str         Crawl order: bfs (shallow pages first), dfs or random (default bfs)\n")
+		fmt.Fprintf(os.Stderr, "      --baseline path     File of known URLs, one per line; pages crawled outside it are reported as new\n")
+		fmt.Fprintf(os.Stderr, "      --keep-http         Crawl http:// URLs as is, even on a site enforcing HTTPS by redirect or HSTS\n")
+		fmt.Fprintf(os.Stderr, "      --tree              Also print the site structure: pages per URL path section\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  linkchecker https://example.com\n")
 		fmt.Fprintf(os.Stderr, "  linkchecker -c 20 -t 5 -d 3 -v https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  linkchecker --sitemap /sitemap.xml.gz,/feed.xml https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  linkchecker --csv https://example.com > broken-links.csv\n")
+		fmt.Fprintf(os.Stderr, "  linkchecker --watch 30s http://localhost:8080\n")
 	}
 
 	flag.Parse()
@@ -51,33 +153,206 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
 	startURL := args[0]
 
+	crawlOrder, err := crawl.ParseOrder(*order)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --order: %v\n", err)
+		os.Exit(1)
+	}
+
+	accepted, err := crawler.ParseStatusList(*acceptStatus)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --accept: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Configure crawler
 	config := crawler.Config{
-		Concurrency: *concurrency,
-		Timeout:     time.Duration(*timeout) * time.Second,
-		MaxDepth:    *maxDepth,
-		Verbose:     *verbose,
+		Concurrency:         *concurrency,
+		Timeout:             time.Duration(*timeout) * time.Second,
+		MaxDepth:            *maxDepth,
+		Verbosity:           verbosity.FromFlags(*verbose, *vv, *vvv),
+		TreatSchemesAsSame:  *sameScheme,
+		ExtraElements:       *extraElements,
+		FailFast:            *failFast,
+		Delay:               time.Duration(*delay) * time.Millisecond,
+		ObeyNoFollow:        *obeyNoFollow,
+		ProbeOpenRedirects:  *probeOpenRedirects,
+		Sitemaps:            sitemap.ParseList(*sitemaps),
+		MaxIdleConnsPerHost: *idleConns,
+		AcceptStatus:        accepted,
+		Accept:              *acceptHeader,
+		MaxRedirects:        *maxRedirects,
+		HTMLContentTypes:    htmlContentTypes,
+		KeepHTTP:            *keepHTTP,
+		MaxPages:            *maxPages,
+		Order:               crawlOrder,
+	}
+	if *baseline != "" {
+		if config.Baseline, err = crawler.LoadBaseline(*baseline); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --baseline: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *loginURL != "" {
+		config.Login = &login.Flow{URL: *loginURL, Fields: loginFields}
 	}
 
-	fmt.Printf("%s%sLinkChecker%s starting...\n", colorBold, colorCyan, colorReset)
-	fmt.Printf("Target: %s\n", startURL)
-	fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n\n", config.Concurrency, *timeout, config.MaxDepth)
+	// The dashboard replaces the scrolling -v output, and only on a terminal
+	showDashboard := *tui && dashboard.IsTerminal(os.Stderr)
+	if showDashboard {
+		config.Verbosity = verbosity.Quiet
+	}
 
-	// Create and run crawler
-	c := crawler.New(config)
-	result, err := c.Crawl(startURL)
+	if *watch > 0 && (*jsonOutput || *csvOutput || *prometheus || *failFast) {
+		fmt.Fprintf(os.Stderr, "Error: --watch can't be combined with --json, --csv, --prometheus or --fail-fast\n")
+		os.Exit(1)
+	}
+
+	if !*jsonOutput && !*csvOutput && !*prometheus {
+		fmt.Printf("%s%sLinkChecker%s starting...\n", colorBold, colorCyan, colorReset)
+		fmt.Printf("Target: %s\n", startURL)
+		fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n\n", config.Concurrency, *timeout, config.MaxDepth)
+	}
+
+	// Create and run crawler, with a fresh crawler for every watch run
+	crawlOnce := func() (*crawler.CrawlResult, error) {
+		c := crawler.New(config)
+		var board *dashboard.Dashboard
+		if showDashboard {
+			board = dashboard.Start(os.Stderr, func() dashboard.Stats {
+				status := c.Progress()
+				return dashboard.Stats{Crawl: status.Crawl, Totals: status.Totals, Broken: status.Broken}
+			})
+		}
+		result, err := c.Crawl(startURL)
+		if board != nil {
+			board.Stop()
+		}
+		// Written after every run, so a watch keeps the file current
+		if err == nil && *metricsFile != "" {
+			if err := metrics.LinkCheck(result).WriteFile(*metricsFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --metrics-file: %v\n", err)
+			}
+		}
+		return result, err
+	}
+	result, err := crawlOnce()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	if *watch > 0 {
+		result = watchChanges(crawlOnce, result, *watch)
+	}
+
 	// Print results
-	result.PrintSummary()
+	switch {
+	case *jsonOutput:
+		if err := export.Write(os.Stdout, export.NewLinkCheck(result)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case *csvOutput:
+		fmt.Print(result.ExportCSVByStatus())
+	case *prometheus:
+		if err := metrics.LinkCheck(result).Write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		result.PrintSummary()
+		if *tree {
+			result.PrintTree()
+		}
+	}
+
+	if *summaryLine {
+		// Kept off stdout when it carries structured output, so that stays parseable
+		out := os.Stdout
+		if *jsonOutput || *csvOutput || *prometheus {
+			out = os.Stderr
+		}
+		fmt.Fprintln(out, result.SummaryLine())
+	}
 
 	// Exit with error code if broken links found
 	if len(result.BrokenLinks) > 0 {
 		os.Exit(1)
 	}
 }
+
+// watchChanges re-crawls every interval and prints how the broken links
+// changed since the previous run, until Ctrl-C. It returns the last
+// complete result; a crawl interrupted by Ctrl-C is dropped.
+func watchChanges(crawlOnce func() (*crawler.CrawlResult, error), result *crawler.CrawlResult, interval time.Duration) *crawler.CrawlResult {
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+
+	result.PrintSummary()
+	fmt.Println()
+	fmt.Printf("%s[%s] Run 1: %d broken link(s)%s\n", colorBold, time.Now().Format("15:04:05"), len(result.BrokenLinks), colorReset)
+	fmt.Printf("Watching: re-crawling every %s, press Ctrl-C to stop\n", interval)
+
+	type crawlDone struct {
+		result *crawler.CrawlResult
+		err    error
+	}
+	for run := 2; ; run++ {
+		select {
+		case <-interrupted:
+			return stopWatching(result, run-1)
+		case <-time.After(interval):
+		}
+
+		done := make(chan crawlDone, 1)
+		go func() {
+			next, err := crawlOnce()
+			done <- crawlDone{next, err}
+		}()
+
+		var next crawlDone
+		select {
+		case <-interrupted:
+			return stopWatching(result, run-1)
+		case next = <-done:
+		}
+
+		stamp := time.Now().Format("15:04:05")
+		if next.err != nil {
+			fmt.Printf("%s[%s] Run %d failed: %v%s\n", colorYellow, stamp, run, next.err, colorReset)
+			continue
+		}
+
+		delta := crawler.Diff(result, next.result)
+		fmt.Printf("%s[%s] Run %d: %d broken link(s), %d newly broken, %d fixed%s\n",
+			colorBold, stamp, run, len(next.result.BrokenLinks), len(delta.NewlyBroken), len(delta.Fixed), colorReset)
+		if delta.Empty() {
+			fmt.Printf("  No change\n")
+		}
+		delta.Print()
+		result = next.result
+	}
+}
+
+// stopWatching announces the end of a watch; the final state is printed by
+// the caller like a single run's
+func stopWatching(result *crawler.CrawlResult, runs int) *crawler.CrawlResult {
+	fmt.Printf("\nStopped after %d run(s). Final state:\n", runs)
+	return result
+}
@@ -6,7 +6,11 @@ import (
 	"os"
 	"time"
 
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/httppool"
 	"github.com/ngonzalez/web-tools/internal/indexer"
+	"github.com/ngonzalez/web-tools/internal/robots"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 const (
@@ -25,14 +29,35 @@ func main() {
 	maxDepth := flag.Int("d", 0, "Maximum crawl depth (0 = unlimited)")
 	flag.IntVar(maxDepth, "depth", 0, "Maximum crawl depth (0 = unlimited)")
 
-	verbose := flag.Bool("v", false, "Show all visited URLs")
-	flag.BoolVar(verbose, "verbose", false, "Show all visited URLs")
+	verbose := flag.Bool("v", false, "Show errors and notable events")
+	flag.BoolVar(verbose, "verbose", false, "Show errors and notable events")
+	vv := flag.Bool("vv", false, "Also show every visited URL")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
 
 	details := flag.Bool("details", true, "Show detailed breakdown")
 	flag.BoolVar(details, "D", true, "Show detailed breakdown")
 
 	noRobots := flag.Bool("no-robots", false, "Skip robots.txt checking")
 
+	sameScheme := flag.Bool("same-scheme", false, "Treat http:// and https:// URLs as the same page")
+	keepHTTP := flag.Bool("keep-http", false, "Crawl http:// URLs as is, even on a site enforcing HTTPS")
+
+	robotsTries := flag.Int("robots-tries", 3, "Attempts at fetching robots.txt")
+
+	ignoreCrawlDelay := flag.Bool("ignore-crawl-delay", false, "Keep --concurrency and --delay whatever robots.txt's Crawl-delay")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request (0 = none, the 3xx kept as is, -1 = up to 100)")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sLinkIndexer%s - Detect non-indexable links\n\n", colorBold, colorCyan, colorReset)
 		fmt.Fprintf(os.Stderr, "Usage: linkindexer [options] <url>\n\n")
@@ -41,14 +66,29 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  - Links with rel=\"sponsored\" or rel=\"ugc\"\n")
 		fmt.Fprintf(os.Stderr, "  - Pages with <meta name=\"robots\" content=\"noindex\">\n")
 		fmt.Fprintf(os.Stderr, "  - Pages with X-Robots-Tag: noindex header\n")
-		fmt.Fprintf(os.Stderr, "  - URLs blocked by robots.txt\n\n")
+		fmt.Fprintf(os.Stderr, "  - Pages whose robots meta tag and X-Robots-Tag header disagree\n")
+		fmt.Fprintf(os.Stderr, "  - URLs blocked by robots.txt\n")
+		fmt.Fprintf(os.Stderr, "  - Internally linked pages blocked by robots.txt, with the linking pages\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -d, --depth int         Maximum crawl depth, 0 = unlimited (default 0)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show all visited URLs\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show errors and notable events\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every visited URL\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
 		fmt.Fprintf(os.Stderr, "  -D, --details           Show detailed breakdown (default true)\n")
 		fmt.Fprintf(os.Stderr, "      --no-robots         Skip robots.txt checking\n")
+		fmt.Fprintf(os.Stderr, "      --robots-tries int  Attempts at fetching robots.txt (default 3)\n")
+		fmt.Fprintf(os.Stderr, "      --ignore-crawl-delay\n")
+		fmt.Fprintf(os.Stderr, "                          Keep --concurrency and --delay whatever robots.txt's Crawl-delay\n")
+		fmt.Fprintf(os.Stderr, "      --same-scheme       Treat http:// and https:// URLs as the same page\n")
+		fmt.Fprintf(os.Stderr, "      --keep-http         Crawl http:// URLs as is, even on a site enforcing HTTPS by redirect or HSTS\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --max-redirects int Redirects followed per request, 0 = none (3xx kept as is), -1 = up to 100 (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  linkindexer https://example.com\n")
 		fmt.Fprintf(os.Stderr, "  linkindexer -c 20 -d 3 -v https://example.com\n")
@@ -62,14 +102,34 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
 	startURL := args[0]
 
 	config := indexer.Config{
-		Concurrency:    *concurrency,
-		Timeout:        time.Duration(*timeout) * time.Second,
-		MaxDepth:       *maxDepth,
-		Verbose:        *verbose,
-		CheckRobotsTxt: !*noRobots,
+		Concurrency:         *concurrency,
+		Timeout:             time.Duration(*timeout) * time.Second,
+		MaxDepth:            *maxDepth,
+		Verbosity:           verbosity.FromFlags(*verbose, *vv, *vvv),
+		CheckRobotsTxt:      !*noRobots,
+		TreatSchemesAsSame:  *sameScheme,
+		KeepHTTP:            *keepHTTP,
+		RobotsRetry:         robots.RetryConfig{Attempts: *robotsTries, Backoff: robots.DefaultRetryConfig().Backoff},
+		Delay:               time.Duration(*delay) * time.Millisecond,
+		MaxIdleConnsPerHost: *idleConns,
+		MaxRedirects:        *maxRedirects,
+		HTMLContentTypes:    htmlContentTypes,
+		IgnoreCrawlDelay:    *ignoreCrawlDelay,
 	}
 
 	fmt.Printf("%s%sLinkIndexer%s starting...\n", colorBold, colorCyan, colorReset)
@@ -88,4 +148,8 @@ func main() {
 	}
 
 	result.PrintSummary(*details)
+
+	if *summaryLine {
+		fmt.Println(result.SummaryLine())
+	}
 }
@@ -6,7 +6,10 @@ import (
 	"os"
 	"time"
 
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/httppool"
 	"github.com/ngonzalez/web-tools/internal/latency"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 const (
@@ -15,6 +18,10 @@ const (
 	colorBold  = "\033[1m"
 )
 
+// exitOverBudget is the exit code of a run with pages over --budget, apart
+// from 1 for a run that failed
+const exitOverBudget = 3
+
 func main() {
 	concurrency := flag.Int("c", 10, "Number of concurrent requests")
 	flag.IntVar(concurrency, "concurrency", 10, "Number of concurrent requests")
@@ -25,8 +32,10 @@ func main() {
 	maxDepth := flag.Int("d", 0, "Maximum crawl depth (0 = unlimited)")
 	flag.IntVar(maxDepth, "depth", 0, "Maximum crawl depth (0 = unlimited)")
 
-	verbose := flag.Bool("v", false, "Show progress while crawling")
-	flag.BoolVar(verbose, "verbose", false, "Show progress while crawling")
+	verbose := flag.Bool("v", false, "Show errors and notable events")
+	flag.BoolVar(verbose, "verbose", false, "Show errors and notable events")
+	vv := flag.Bool("vv", false, "Also show every visited URL")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
 
 	barWidth := flag.Int("w", 30, "Width of the bar graph")
 	flag.IntVar(barWidth, "width", 30, "Width of the bar graph")
@@ -34,6 +43,27 @@ func main() {
 	showSize := flag.Bool("s", false, "Show page sizes")
 	flag.BoolVar(showSize, "size", false, "Show page sizes")
 
+	sameScheme := flag.Bool("same-scheme", false, "Treat http:// and https:// URLs as the same page")
+	keepHTTP := flag.Bool("keep-http", false, "Crawl http:// URLs as is, even on a site enforcing HTTPS")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	ascii := flag.Bool("ascii", false, "Draw bar graphs with # and - instead of block characters")
+
+	budget := flag.Duration("budget", 0, "Exit with code 3 when a page loads slower than this, e.g. 800ms")
+
+	minSize := flag.Int64("min-size", latency.DefaultMinSize, "Flag HTML pages answering 200 with a body under this many bytes (0 = off)")
+
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request (0 = none, the 3xx kept as is, -1 = up to 100)")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sLinkLatency%s - Measure page load times\n\n", colorBold, colorCyan, colorReset)
 		fmt.Fprintf(os.Stderr, "Usage: linklatency [options] <url>\n\n")
@@ -43,12 +73,26 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 30)\n")
 		fmt.Fprintf(os.Stderr, "  -d, --depth int         Maximum crawl depth, 0 = unlimited (default 0)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show progress while crawling\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show errors and notable events\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every visited URL\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
 		fmt.Fprintf(os.Stderr, "  -w, --width int         Width of the bar graph (default 30)\n")
 		fmt.Fprintf(os.Stderr, "  -s, --size              Show page sizes\n")
+		fmt.Fprintf(os.Stderr, "      --same-scheme       Treat http:// and https:// URLs as the same page\n")
+		fmt.Fprintf(os.Stderr, "      --keep-http         Crawl http:// URLs as is, even on a site enforcing HTTPS by redirect or HSTS\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --ascii             Draw bar graphs with # and - instead of block characters\n")
+		fmt.Fprintf(os.Stderr, "      --budget duration   Exit with code 3 when a page loads slower than this, e.g. 800ms\n")
+		fmt.Fprintf(os.Stderr, "      --min-size int      Flag HTML pages answering 200 with a body under this many bytes, 0 = off (default 512)\n")
+		fmt.Fprintf(os.Stderr, "      --max-redirects int Redirects followed per request, 0 = none (3xx kept as is), -1 = up to 100 (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  linklatency https://example.com\n")
 		fmt.Fprintf(os.Stderr, "  linklatency -c 5 -d 2 -s https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  linklatency --budget 800ms https://example.com\n")
 	}
 
 	flag.Parse()
@@ -59,13 +103,34 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
 	startURL := args[0]
 
 	config := latency.Config{
-		Concurrency: *concurrency,
-		Timeout:     time.Duration(*timeout) * time.Second,
-		MaxDepth:    *maxDepth,
-		Verbose:     *verbose,
+		Concurrency:         *concurrency,
+		Timeout:             time.Duration(*timeout) * time.Second,
+		MaxDepth:            *maxDepth,
+		Verbosity:           verbosity.FromFlags(*verbose, *vv, *vvv),
+		TreatSchemesAsSame:  *sameScheme,
+		KeepHTTP:            *keepHTTP,
+		Delay:               time.Duration(*delay) * time.Millisecond,
+		MaxIdleConnsPerHost: *idleConns,
+		ASCII:               *ascii,
+		Budget:              *budget,
+		MinSize:             *minSize,
+		MaxRedirects:        *maxRedirects,
+		HTMLContentTypes:    htmlContentTypes,
 	}
 
 	fmt.Printf("%s%sLinkLatency%s starting...\n", colorBold, colorCyan, colorReset)
@@ -80,4 +145,13 @@ func main() {
 	}
 
 	result.PrintSummary(*barWidth, *showSize)
+
+	if *summaryLine {
+		fmt.Println(result.SummaryLine())
+	}
+
+	// Exit with error code if pages are over budget; failed pages don't count
+	if len(result.OverBudgetPages()) > 0 {
+		os.Exit(exitOverBudget)
+	}
 }
@@ -6,7 +6,10 @@ import (
 	"os"
 	"time"
 
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/httppool"
 	"github.com/ngonzalez/web-tools/internal/migration"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 const (
@@ -26,14 +29,35 @@ func main() {
 	maxDepth := flag.Int("d", 0, "Maximum crawl depth (0 = unlimited)")
 	flag.IntVar(maxDepth, "depth", 0, "Maximum crawl depth (0 = unlimited)")
 
-	verbose := flag.Bool("v", false, "Show progress for each URL checked")
-	flag.BoolVar(verbose, "verbose", false, "Show progress for each URL checked")
+	verbose := flag.Bool("v", false, "Show errors and notable events")
+	flag.BoolVar(verbose, "verbose", false, "Show errors and notable events")
+	vv := flag.Bool("vv", false, "Also show every visited URL")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
 
 	useGET := flag.Bool("g", false, "Use GET requests instead of HEAD for checking")
 	flag.BoolVar(useGET, "get", false, "Use GET requests instead of HEAD for checking")
 
 	csvOutput := flag.Bool("csv", false, "Output lost links as CSV")
 
+	sameScheme := flag.Bool("same-scheme", false, "Treat http:// and https:// URLs as the same page")
+	keepHTTP := flag.Bool("keep-http", false, "Crawl http:// URLs as is, even on a site enforcing HTTPS")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	crawlConcurrency := flag.Int("crawl-concurrency", 0, "Concurrent requests crawling the old site (0 = --concurrency)")
+
+	checkConcurrency := flag.Int("check-concurrency", 0, "Concurrent requests checking the new site (0 = --concurrency)")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request (0 = none, the 3xx kept as is, -1 = up to 100)")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sLinkMigration%s - Detect lost links after site migration\n\n", colorBold, colorCyan, colorReset)
 		fmt.Fprintf(os.Stderr, "Usage: linkmigration [options] <old-site-url> <new-site-url>\n\n")
@@ -43,12 +67,27 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -d, --depth int         Maximum crawl depth, 0 = unlimited (default 0)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show progress for each URL checked\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show errors and notable events\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every visited URL\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
 		fmt.Fprintf(os.Stderr, "  -g, --get               Use GET requests instead of HEAD for checking\n")
 		fmt.Fprintf(os.Stderr, "      --csv               Output lost links as CSV format\n")
+		fmt.Fprintf(os.Stderr, "      --same-scheme       Treat http:// and https:// URLs as the same page\n")
+		fmt.Fprintf(os.Stderr, "      --keep-http         Crawl http:// URLs as is, even on a site enforcing HTTPS by redirect or HSTS\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --crawl-concurrency int\n")
+		fmt.Fprintf(os.Stderr, "                          Concurrent requests crawling the old site, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --check-concurrency int\n")
+		fmt.Fprintf(os.Stderr, "                          Concurrent requests checking the new site, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --max-redirects int Redirects followed per request, 0 = none (3xx kept as is), -1 = up to 100 (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  linkmigration https://old-site.com https://new-site.com\n")
 		fmt.Fprintf(os.Stderr, "  linkmigration -c 20 -d 3 -v https://old.example.com https://new.example.com\n")
+		fmt.Fprintf(os.Stderr, "  linkmigration --crawl-concurrency 2 --check-concurrency 50 https://old-site.com https://new-site.com\n")
 		fmt.Fprintf(os.Stderr, "  linkmigration --csv https://old-site.com https://new-site.com > lost-links.csv\n")
 	}
 
@@ -61,16 +100,36 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
 	oldSiteURL := args[0]
 	newSiteURL := args[1]
 
 	// Configure migrator
 	config := migration.Config{
-		Concurrency: *concurrency,
-		Timeout:     time.Duration(*timeout) * time.Second,
-		MaxDepth:    *maxDepth,
-		Verbose:     *verbose,
-		UseHEAD:     !*useGET,
+		Concurrency:         *concurrency,
+		Timeout:             time.Duration(*timeout) * time.Second,
+		MaxDepth:            *maxDepth,
+		Verbosity:           verbosity.FromFlags(*verbose, *vv, *vvv),
+		UseHEAD:             !*useGET,
+		TreatSchemesAsSame:  *sameScheme,
+		KeepHTTP:            *keepHTTP,
+		Delay:               time.Duration(*delay) * time.Millisecond,
+		CrawlConcurrency:    *crawlConcurrency,
+		CheckConcurrency:    *checkConcurrency,
+		MaxIdleConnsPerHost: *idleConns,
+		MaxRedirects:        *maxRedirects,
+		HTMLContentTypes:    htmlContentTypes,
 	}
 
 	if !*csvOutput {
@@ -95,6 +154,15 @@ func main() {
 		result.PrintSummary()
 	}
 
+	if *summaryLine {
+		// Kept off stdout when it carries structured output, so that stays parseable
+		out := os.Stdout
+		if *csvOutput {
+			out = os.Stderr
+		}
+		fmt.Fprintln(out, result.SummaryLine())
+	}
+
 	// Exit with error code if lost links found
 	if len(result.LostLinks) > 0 {
 		os.Exit(1)
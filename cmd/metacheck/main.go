@@ -4,9 +4,15 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/export"
+	"github.com/ngonzalez/web-tools/internal/httppool"
 	"github.com/ngonzalez/web-tools/internal/metacheck"
+	"github.com/ngonzalez/web-tools/internal/sitemap"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 const (
@@ -25,8 +31,10 @@ func main() {
 	maxDepth := flag.Int("d", 0, "Maximum crawl depth (0 = unlimited)")
 	flag.IntVar(maxDepth, "depth", 0, "Maximum crawl depth (0 = unlimited)")
 
-	verbose := flag.Bool("v", false, "Show crawl progress")
-	flag.BoolVar(verbose, "verbose", false, "Show crawl progress")
+	verbose := flag.Bool("v", false, "Show errors and notable events")
+	flag.BoolVar(verbose, "verbose", false, "Show errors and notable events")
+	vv := flag.Bool("vv", false, "Also show every visited URL")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
 
 	showAll := flag.Bool("a", false, "Show all issues (including short and duplicates)")
 	flag.BoolVar(showAll, "all", false, "Show all issues (including short and duplicates)")
@@ -34,6 +42,35 @@ func main() {
 	limit := flag.Int("n", 20, "Maximum number of pages to display per category")
 	flag.IntVar(limit, "limit", 20, "Maximum number of pages to display per category")
 
+	sameScheme := flag.Bool("same-scheme", false, "Treat http:// and https:// URLs as the same page")
+	keepHTTP := flag.Bool("keep-http", false, "Crawl http:// URLs as is, even on a site enforcing HTTPS")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	checkHTML := flag.Bool("check-html", false, "Also report duplicate ids and malformed HTML")
+
+	freshness := flag.Bool("freshness", false, "Report page ages and the pages not modified in over a year")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	autoSnippet := flag.Bool("auto-snippet", false, "Preview the body text Google likely shows for pages without description")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	ascii := flag.Bool("ascii", false, "Draw bar graphs with # and - instead of block characters")
+
+	jsonOutput := flag.Bool("json", false, "Output results as JSON instead of the report")
+
+	failOn := flag.String("fail-on", metacheck.DefaultFailOn, "Comma-separated category>count criteria that make the exit code 1")
+
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request (0 = none, the 3xx kept as is, -1 = up to 100)")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	sitemaps := flag.String("sitemap", "", "Comma-separated sitemaps or RSS/Atom feeds whose URLs internal links should reach")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sMetaCheck%s - Meta description length checker\n\n", colorBold, colorCyan, colorReset)
 		fmt.Fprintf(os.Stderr, "Usage: metacheck [options] <url>\n\n")
@@ -46,12 +83,33 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -d, --depth int         Maximum crawl depth, 0 = unlimited (default 0)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show crawl progress\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show errors and notable events\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every visited URL\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
 		fmt.Fprintf(os.Stderr, "  -a, --all               Show all issues (short, duplicates)\n")
 		fmt.Fprintf(os.Stderr, "  -n, --limit int         Max pages per category (default 20)\n")
+		fmt.Fprintf(os.Stderr, "      --same-scheme       Treat http:// and https:// URLs as the same page\n")
+		fmt.Fprintf(os.Stderr, "      --keep-http         Crawl http:// URLs as is, even on a site enforcing HTTPS by redirect or HSTS\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --check-html        Also report duplicate ids and malformed HTML\n")
+		fmt.Fprintf(os.Stderr, "      --freshness         Report page ages and the stalest pages\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --auto-snippet      Preview the body text Google likely shows for pages without description\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --ascii             Draw bar graphs with # and - instead of block characters\n")
+		fmt.Fprintf(os.Stderr, "      --json              Output results as JSON instead of the report\n")
+		fmt.Fprintf(os.Stderr, "      --fail-on list      Exit 1 when a category>count (or >=) criterion is met (default %s)\n", metacheck.DefaultFailOn)
+		fmt.Fprintf(os.Stderr, "                          Categories: ok, too_long, too_short, missing, duplicate, html_issues, stale,\n")
+		fmt.Fprintf(os.Stderr, "                          client_rendered, not_linked\n")
+		fmt.Fprintf(os.Stderr, "      --max-redirects int Redirects followed per request, 0 = none (3xx kept as is), -1 = up to 100 (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --sitemap list      Comma-separated sitemaps or RSS/Atom feeds (.gz too); lists their URLs no internal link reaches\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  metacheck https://example.com\n")
 		fmt.Fprintf(os.Stderr, "  metacheck -a -d 3 https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  metacheck --fail-on \"missing>0,duplicate>5\" https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  metacheck --sitemap /sitemap.xml https://example.com\n")
 	}
 
 	flag.Parse()
@@ -62,18 +120,49 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
+	criteria, err := metacheck.ParseCriteria(*failOn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --fail-on: %v\n", err)
+		os.Exit(1)
+	}
+
 	startURL := args[0]
 
 	config := metacheck.Config{
-		Concurrency: *concurrency,
-		Timeout:     time.Duration(*timeout) * time.Second,
-		MaxDepth:    *maxDepth,
-		Verbose:     *verbose,
+		Concurrency:         *concurrency,
+		Timeout:             time.Duration(*timeout) * time.Second,
+		MaxDepth:            *maxDepth,
+		Verbosity:           verbosity.FromFlags(*verbose, *vv, *vvv),
+		TreatSchemesAsSame:  *sameScheme,
+		KeepHTTP:            *keepHTTP,
+		Delay:               time.Duration(*delay) * time.Millisecond,
+		CheckHTML:           *checkHTML,
+		Freshness:           *freshness,
+		MaxIdleConnsPerHost: *idleConns,
+		AutoSnippet:         *autoSnippet,
+		ASCII:               *ascii,
+		MaxRedirects:        *maxRedirects,
+		HTMLContentTypes:    htmlContentTypes,
+		Sitemaps:            sitemap.ParseList(*sitemaps),
 	}
 
-	fmt.Printf("%s%sMetaCheck%s starting...\n", colorBold, colorCyan, colorReset)
-	fmt.Printf("Target: %s\n", startURL)
-	fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n\n", config.Concurrency, *timeout, config.MaxDepth)
+	if !*jsonOutput {
+		fmt.Printf("%s%sMetaCheck%s starting...\n", colorBold, colorCyan, colorReset)
+		fmt.Printf("Target: %s\n", startURL)
+		fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n\n", config.Concurrency, *timeout, config.MaxDepth)
+	}
 
 	checker := metacheck.New(config)
 	result, err := checker.Check(startURL)
@@ -82,10 +171,27 @@ func main() {
 		os.Exit(1)
 	}
 
-	result.PrintSummary(*showAll, *limit)
+	if *jsonOutput {
+		if err := export.Write(os.Stdout, export.NewMetaCheck(result)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		result.PrintSummary(*showAll, *limit)
+	}
+
+	if *summaryLine {
+		// Kept off stdout when it carries structured output, so that stays parseable
+		out := os.Stdout
+		if *jsonOutput {
+			out = os.Stderr
+		}
+		fmt.Fprintln(out, result.SummaryLine())
+	}
 
-	// Exit code based on issues
-	if result.TooLongCount > 0 || result.MissingCount > 0 {
+	// Exit code based on the --fail-on criteria
+	if failures := result.Failures(criteria); len(failures) > 0 {
+		fmt.Fprintf(os.Stderr, "Failed: %s\n", strings.Join(failures, ", "))
 		os.Exit(1)
 	}
 }
@@ -6,7 +6,12 @@ import (
 	"os"
 	"time"
 
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/export"
+	"github.com/ngonzalez/web-tools/internal/httppool"
 	"github.com/ngonzalez/web-tools/internal/pagerank"
+	"github.com/ngonzalez/web-tools/internal/sitemap"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 const (
@@ -25,8 +30,10 @@ func main() {
 	maxDepth := flag.Int("d", 0, "Maximum crawl depth (0 = unlimited)")
 	flag.IntVar(maxDepth, "depth", 0, "Maximum crawl depth (0 = unlimited)")
 
-	verbose := flag.Bool("v", false, "Show crawl progress")
-	flag.BoolVar(verbose, "verbose", false, "Show crawl progress")
+	verbose := flag.Bool("v", false, "Show errors and notable events")
+	flag.BoolVar(verbose, "verbose", false, "Show errors and notable events")
+	vv := flag.Bool("vv", false, "Also show every visited URL")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
 
 	topN := flag.Int("n", 20, "Number of top pages to display")
 	flag.IntVar(topN, "top", 20, "Number of top pages to display")
@@ -35,9 +42,43 @@ func main() {
 
 	maxIter := flag.Int("iter", 100, "Maximum PageRank iterations")
 
+	minScore := flag.Float64("min-score", 0, "Hide pages scoring below this value")
+
+	bottomN := flag.Int("bottom", 0, "Also show the N lowest-ranked pages")
+
 	barWidth := flag.Int("w", 20, "Width of bar graph")
 	flag.IntVar(barWidth, "width", 20, "Width of bar graph")
 
+	sameScheme := flag.Bool("same-scheme", false, "Treat http:// and https:// URLs as the same page")
+	keepHTTP := flag.Bool("keep-http", false, "Crawl http:// URLs as is, even on a site enforcing HTTPS")
+
+	csvOutput := flag.Bool("csv", false, "Output scores as CSV")
+
+	excludeNoIndex := flag.Bool("exclude-noindex", false, "Leave noindex pages out of the graph")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	obeyNoFollow := flag.Bool("obey-nofollow", false, "Don't follow rel=nofollow links, like search engines")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	ascii := flag.Bool("ascii", false, "Draw bar graphs with # and - instead of block characters")
+
+	jsonOutput := flag.Bool("json", false, "Output results as JSON instead of the report")
+
+	sitemaps := flag.String("sitemap", "", "Comma-separated sitemaps or RSS/Atom feeds whose URLs seed the crawl and are checked for orphans")
+
+	minOutLinks := flag.Int("min-outlinks", 3, "Flag pages linking to fewer internal pages (0 = off)")
+	maxOutLinks := flag.Int("max-outlinks", 100, "Flag pages linking to more internal pages (0 = off)")
+
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request (0 = none, the 3xx kept as is, -1 = up to 100)")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sPageRank%s - Calculate page importance\n\n", colorBold, colorCyan, colorReset)
 		fmt.Fprintf(os.Stderr, "Usage: pagerank [options] <url>\n\n")
@@ -50,14 +91,36 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -d, --depth int         Maximum crawl depth, 0 = unlimited (default 0)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show crawl progress\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show errors and notable events\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every visited URL\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
 		fmt.Fprintf(os.Stderr, "  -n, --top int           Number of top pages to display (default 20)\n")
 		fmt.Fprintf(os.Stderr, "      --damping float     Damping factor 0-1 (default 0.85)\n")
 		fmt.Fprintf(os.Stderr, "      --iter int          Maximum iterations (default 100)\n")
+		fmt.Fprintf(os.Stderr, "      --min-score float   Hide pages scoring below this value (chart and CSV)\n")
+		fmt.Fprintf(os.Stderr, "      --bottom int        Also show the N lowest-ranked pages\n")
 		fmt.Fprintf(os.Stderr, "  -w, --width int         Bar graph width (default 20)\n")
+		fmt.Fprintf(os.Stderr, "      --same-scheme       Treat http:// and https:// URLs as the same page\n")
+		fmt.Fprintf(os.Stderr, "      --keep-http         Crawl http:// URLs as is, even on a site enforcing HTTPS by redirect or HSTS\n")
+		fmt.Fprintf(os.Stderr, "      --csv               Output all scores as CSV instead of the chart\n")
+		fmt.Fprintf(os.Stderr, "      --exclude-noindex   Leave noindex pages out of the graph\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --obey-nofollow     Don't follow rel=nofollow links, like search engines\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --ascii             Draw bar graphs with # and - instead of block characters\n")
+		fmt.Fprintf(os.Stderr, "      --json              Output results as JSON instead of the report\n")
+		fmt.Fprintf(os.Stderr, "      --sitemap list      Comma-separated sitemaps or feeds (.gz too) to seed from and check for orphans\n")
+		fmt.Fprintf(os.Stderr, "      --min-outlinks int  Flag pages linking to fewer internal pages, 0 = off (default 3)\n")
+		fmt.Fprintf(os.Stderr, "      --max-outlinks int  Flag pages linking to more internal pages, 0 = off (default 100)\n")
+		fmt.Fprintf(os.Stderr, "      --max-redirects int Redirects followed per request, 0 = none (3xx kept as is), -1 = up to 100 (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  pagerank https://example.com\n")
 		fmt.Fprintf(os.Stderr, "  pagerank -n 50 -d 3 https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  pagerank --csv https://example.com > pagerank.csv\n")
+		fmt.Fprintf(os.Stderr, "  pagerank --sitemap /sitemap.xml https://example.com\n")
 	}
 
 	flag.Parse()
@@ -68,21 +131,47 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
 	startURL := args[0]
 
 	config := pagerank.Config{
-		Concurrency:   *concurrency,
-		Timeout:       time.Duration(*timeout) * time.Second,
-		MaxDepth:      *maxDepth,
-		Verbose:       *verbose,
-		DampingFactor: *damping,
-		MaxIterations: *maxIter,
+		Concurrency:         *concurrency,
+		Timeout:             time.Duration(*timeout) * time.Second,
+		MaxDepth:            *maxDepth,
+		Verbosity:           verbosity.FromFlags(*verbose, *vv, *vvv),
+		DampingFactor:       *damping,
+		MaxIterations:       *maxIter,
+		TreatSchemesAsSame:  *sameScheme,
+		KeepHTTP:            *keepHTTP,
+		ExcludeNoIndex:      *excludeNoIndex,
+		Delay:               time.Duration(*delay) * time.Millisecond,
+		ObeyNoFollow:        *obeyNoFollow,
+		MaxIdleConnsPerHost: *idleConns,
+		ASCII:               *ascii,
+		Sitemaps:            sitemap.ParseList(*sitemaps),
+		MinOutLinks:         *minOutLinks,
+		MaxOutLinks:         *maxOutLinks,
+		MaxRedirects:        *maxRedirects,
+		HTMLContentTypes:    htmlContentTypes,
 	}
 
-	fmt.Printf("%s%sPageRank%s starting...\n", colorBold, colorCyan, colorReset)
-	fmt.Printf("Target: %s\n", startURL)
-	fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n", config.Concurrency, *timeout, config.MaxDepth)
-	fmt.Printf("Damping: %.2f, Max Iterations: %d\n\n", config.DampingFactor, config.MaxIterations)
+	if !*csvOutput && !*jsonOutput {
+		fmt.Printf("%s%sPageRank%s starting...\n", colorBold, colorCyan, colorReset)
+		fmt.Printf("Target: %s\n", startURL)
+		fmt.Printf("Concurrency: %d, Timeout: %ds, Max Depth: %d\n", config.Concurrency, *timeout, config.MaxDepth)
+		fmt.Printf("Damping: %.2f, Max Iterations: %d\n\n", config.DampingFactor, config.MaxIterations)
+	}
 
 	crawler := pagerank.New(config)
 	result, err := crawler.Crawl(startURL)
@@ -91,5 +180,24 @@ func main() {
 		os.Exit(1)
 	}
 
-	result.PrintSummary(*topN, *barWidth)
+	switch {
+	case *jsonOutput:
+		if err := export.Write(os.Stdout, export.NewPageRank(result)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case *csvOutput:
+		fmt.Print(result.ExportCSV(*minScore))
+	default:
+		result.PrintSummary(*topN, *barWidth, *minScore, *bottomN)
+	}
+
+	if *summaryLine {
+		// Kept off stdout when it carries structured output, so that stays parseable
+		out := os.Stdout
+		if *jsonOutput || *csvOutput {
+			out = os.Stderr
+		}
+		fmt.Fprintln(out, result.SummaryLine())
+	}
 }
@@ -6,6 +6,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/httppool"
 	"github.com/ngonzalez/web-tools/internal/serp"
 )
 
@@ -28,23 +30,55 @@ func main() {
 	previewOnly := flag.Bool("p", false, "Show preview only (no analysis)")
 	flag.BoolVar(previewOnly, "preview", false, "Show preview only (no analysis)")
 
+	mobileDiff := flag.Bool("m", false, "Compare the metadata served to desktop and mobile user agents")
+	flag.BoolVar(mobileDiff, "mobile-diff", false, "Compare the metadata served to desktop and mobile user agents")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	titleRange := flag.String("title-range", "", "Recommended title length in characters, min-max (default by page language)")
+
+	descRange := flag.String("desc-range", "", "Recommended description length in characters, min-max (default by page language)")
+
+	pixels := flag.Bool("pixels", false, "Judge too long titles and descriptions by estimated pixel width")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	headOnly := flag.Bool("head-only", false, "Stop reading the page at the end of <head>, skipping the H1 and body checks")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sSERPreview%s - See how your page appears on Google\n\n", colorBold, colorCyan, colorReset)
 		fmt.Fprintf(os.Stderr, "Usage: serpreview [options] <url>\n\n")
 		fmt.Fprintf(os.Stderr, "Analyzes a page's SEO metadata and shows:\n")
 		fmt.Fprintf(os.Stderr, "  - Google search result preview (SERP snippet)\n")
 		fmt.Fprintf(os.Stderr, "  - Title and meta description analysis\n")
-		fmt.Fprintf(os.Stderr, "  - Open Graph and Twitter Card tags\n")
+		fmt.Fprintf(os.Stderr, "  - Open Graph (every og:image and og:locale) and Twitter Card tags\n")
 		fmt.Fprintf(os.Stderr, "  - Canonical URL and robots directives\n")
-		fmt.Fprintf(os.Stderr, "  - Schema.org structured data\n\n")
+		fmt.Fprintf(os.Stderr, "  - Separate mobile URL (rel=alternate media), checked for reachability and canonical\n")
+		fmt.Fprintf(os.Stderr, "  - Schema.org structured data, with BreadcrumbList trails validated\n")
+		fmt.Fprintf(os.Stderr, "  - FAQPage, HowTo and Article rich-result eligibility (required properties)\n")
+		fmt.Fprintf(os.Stderr, "  - <html lang> checked against the page's own hreflang entry\n")
+		fmt.Fprintf(os.Stderr, "  - Meta keywords and deprecated tags (<font>, <center>, revisit-after...)\n")
+		fmt.Fprintf(os.Stderr, "  - Last modification date (article metadata or Last-Modified header)\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
-		fmt.Fprintf(os.Stderr, "  -t, --timeout int   Request timeout in seconds (default 30)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose       Verbose output\n")
-		fmt.Fprintf(os.Stderr, "  -a, --analysis      Show analysis only (no preview)\n")
-		fmt.Fprintf(os.Stderr, "  -p, --preview       Show preview only (no analysis)\n")
+		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 30)\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Verbose output\n")
+		fmt.Fprintf(os.Stderr, "  -a, --analysis          Show analysis only (no preview)\n")
+		fmt.Fprintf(os.Stderr, "  -p, --preview           Show preview only (no analysis)\n")
+		fmt.Fprintf(os.Stderr, "  -m, --mobile-diff       Compare the metadata served to desktop and mobile user agents\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --title-range range Recommended title length in characters (default 30-60, 15-30 in ja, zh, ko)\n")
+		fmt.Fprintf(os.Stderr, "      --desc-range range  Recommended description length in characters (default 70-155, 35-80 in ja, zh, ko)\n")
+		fmt.Fprintf(os.Stderr, "      --pixels            Judge too long by estimated pixel width (600px title, 920px description, scaled by the ranges)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --head-only         Stop reading the page at the end of <head>, skipping the H1 and body checks\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  serpreview https://example.com\n")
 		fmt.Fprintf(os.Stderr, "  serpreview -a example.com\n")
+		fmt.Fprintf(os.Stderr, "  serpreview -m https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  serpreview --title-range 15-30 --pixels https://example.jp\n")
 	}
 
 	flag.Parse()
@@ -55,19 +89,67 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
 	targetURL := args[0]
 
+	lengths := serp.Lengths{Pixels: *pixels}
+	// Unset ranges follow the page language
+	if *titleRange != "" {
+		if lengths.Title, err = serp.ParseRange(*titleRange); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --title-range: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *descRange != "" {
+		if lengths.Description, err = serp.ParseRange(*descRange); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --desc-range: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	config := serp.Config{
-		Timeout: time.Duration(*timeout) * time.Second,
-		Verbose: *verbose,
+		Timeout:          time.Duration(*timeout) * time.Second,
+		Verbose:          *verbose,
+		Lengths:          lengths,
+		HTMLContentTypes: htmlContentTypes,
+		HeadOnly:         *headOnly,
 	}
 
 	fetcher := serp.New(config)
+
+	if *mobileDiff {
+		comparison, err := fetcher.CompareDevices(targetURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		comparison.PrintComparison()
+		if *summaryLine {
+			fmt.Println(comparison.SummaryLine())
+		}
+		return
+	}
+
 	meta, err := fetcher.Analyze(targetURL)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	var mobileCheck *serp.MobileAlternateCheck
+	if !*previewOnly {
+		mobileCheck = fetcher.CheckResources(meta)
+	}
 
 	// Show preview unless analysis-only mode
 	if !*analysisOnly {
@@ -78,5 +160,12 @@ func main() {
 	// Show analysis unless preview-only mode
 	if !*previewOnly {
 		meta.PrintMetaAnalysis()
+		if mobileCheck != nil {
+			mobileCheck.Print()
+		}
+	}
+
+	if *summaryLine {
+		fmt.Println(meta.SummaryLine())
 	}
 }
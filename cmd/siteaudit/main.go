@@ -7,6 +7,12 @@ import (
 	"time"
 
 	"github.com/ngonzalez/web-tools/internal/audit"
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/export"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/latency"
+	"github.com/ngonzalez/web-tools/internal/metrics"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 const (
@@ -25,28 +31,98 @@ func main() {
 	maxDepth := flag.Int("d", 0, "Maximum crawl depth (0 = unlimited)")
 	flag.IntVar(maxDepth, "depth", 0, "Maximum crawl depth (0 = unlimited)")
 
-	verbose := flag.Bool("v", false, "Show detailed progress")
-	flag.BoolVar(verbose, "verbose", false, "Show detailed progress")
+	verbose := flag.Bool("v", false, "Show audit progress")
+	flag.BoolVar(verbose, "verbose", false, "Show audit progress")
+	vv := flag.Bool("vv", false, "Also show every URL visited by the checks")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
+
+	cacheMB := flag.Int("cache-mb", 64, "Size of the shared response cache in MB (0 = disabled)")
+
+	sameScheme := flag.Bool("same-scheme", false, "Treat http:// and https:// URLs as the same page")
+	keepHTTP := flag.Bool("keep-http", false, "Crawl http:// URLs as is, even on a site enforcing HTTPS")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	brokenHigh := flag.Int("broken-high", 10, "Broken links above which the issue is high severity")
+	brokenCritical := flag.Int("broken-critical", 50, "Broken links above which the issue is critical, at least --broken-high")
+	slowRatio := flag.Float64("slow-ratio", 0.25, "Share of slow pages above which the issue is medium severity")
+	verySlowHigh := flag.Int("very-slow-high", 0, "Very slow pages above which the issue is high severity")
+
+	minSize := flag.Int64("min-size", latency.DefaultMinSize, "Flag HTML pages answering 200 with a body under this many bytes (0 = off)")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	ascii := flag.Bool("ascii", false, "Draw bar graphs with # and - instead of block characters")
+
+	jsonOutput := flag.Bool("json", false, "Output results as JSON instead of the report")
+
+	maxRedirects := flag.Int("max-redirects", 10, "Redirects followed per request (0 = none, -1 = up to 100)")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	prometheus := flag.Bool("prometheus", false, "Output metrics in Prometheus text format instead of the report")
+
+	metricsFile := flag.String("metrics-file", "", "Also write metrics in Prometheus text format to this file")
+
+	issuesCSV := flag.Bool("issues-csv", false, "Output the issue list as CSV instead of the report")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
+	only := flag.String("only", "", "Comma-separated phases to run: broken, links, indexability, canonical, performance, seo")
+	skip := flag.String("skip", "", "Comma-separated phases not to run")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s%sSiteAudit%s - Complete SEO audit tool\n\n", colorBold, colorCyan, colorReset)
 		fmt.Fprintf(os.Stderr, "Usage: siteaudit [options] <url>\n\n")
 		fmt.Fprintf(os.Stderr, "Performs a comprehensive audit of your website including:\n")
 		fmt.Fprintf(os.Stderr, "  • Broken links detection (404 errors)\n")
+		fmt.Fprintf(os.Stderr, "  • Insecure forms (http:// actions on HTTPS pages, password fields on http:// pages)\n")
+		fmt.Fprintf(os.Stderr, "  • Security and caching response headers (HSTS, CSP, X-Frame-Options, X-Content-Type-Options, Cache-Control)\n")
 		fmt.Fprintf(os.Stderr, "  • Non-analyzable links (external, files, mailto, etc.)\n")
 		fmt.Fprintf(os.Stderr, "  • Indexability issues (nofollow, noindex, robots.txt)\n")
-		fmt.Fprintf(os.Stderr, "  • Canonical URL verification\n")
+		fmt.Fprintf(os.Stderr, "  • Canonical URL verification, including canonicals that return errors\n")
 		fmt.Fprintf(os.Stderr, "  • Performance measurement (page latency)\n")
-		fmt.Fprintf(os.Stderr, "  • SEO analysis (title, description, OG tags, schema)\n")
-		fmt.Fprintf(os.Stderr, "  • PageRank calculation (internal link structure)\n\n")
+		fmt.Fprintf(os.Stderr, "  • SEO analysis (title, description, OG tags, schema; missing or multiple H1 on every page)\n")
+		fmt.Fprintf(os.Stderr, "  • PageRank calculation (internal link structure, canonicalized pages still linked)\n")
+		fmt.Fprintf(os.Stderr, "  • Crawl budget estimate (indexable pages, full crawl time, budget wasters)\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
 		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 15)\n")
 		fmt.Fprintf(os.Stderr, "  -d, --depth int         Maximum crawl depth, 0 = unlimited (default 0)\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show detailed progress\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show audit progress\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every URL visited by the checks\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
+		fmt.Fprintf(os.Stderr, "      --cache-mb int      Shared response cache size in MB, 0 = disabled (default 64)\n")
+		fmt.Fprintf(os.Stderr, "      --same-scheme       Treat http:// and https:// URLs as the same page\n")
+		fmt.Fprintf(os.Stderr, "      --keep-http         Crawl http:// URLs as is, even on a site enforcing HTTPS by redirect or HSTS\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --broken-high int   Broken links above which the issue is high severity (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --broken-critical int\n")
+		fmt.Fprintf(os.Stderr, "                          Broken links above which the issue is critical, at least --broken-high (default 50)\n")
+		fmt.Fprintf(os.Stderr, "      --slow-ratio float  Share of slow (>1s) pages above which the issue is medium severity (default 0.25)\n")
+		fmt.Fprintf(os.Stderr, "      --very-slow-high int\n")
+		fmt.Fprintf(os.Stderr, "                          Very slow (>3s) pages above which the issue is high severity (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --min-size int      Flag HTML pages answering 200 with a body under this many bytes, 0 = off (default 512)\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --ascii             Draw bar graphs with # and - instead of block characters\n")
+		fmt.Fprintf(os.Stderr, "      --json              Output results as JSON instead of the report\n")
+		fmt.Fprintf(os.Stderr, "      --max-redirects int Redirects followed per request, 0 = none, -1 = up to 100 (default 10)\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --prometheus        Output metrics in Prometheus text format instead of the report\n")
+		fmt.Fprintf(os.Stderr, "      --metrics-file path Also write metrics in Prometheus text format to this file\n")
+		fmt.Fprintf(os.Stderr, "      --issues-csv        Output the issue list as CSV instead of the report\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
+		fmt.Fprintf(os.Stderr, "      --only list         Comma-separated phases to run: broken, links, indexability,\n")
+		fmt.Fprintf(os.Stderr, "                          canonical, performance, seo (default all)\n")
+		fmt.Fprintf(os.Stderr, "      --skip list         Comma-separated phases not to run\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  siteaudit https://example.com\n")
 		fmt.Fprintf(os.Stderr, "  siteaudit -d 3 -v https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  siteaudit --broken-high 1 --slow-ratio 0.1 https://example.com\n")
+		fmt.Fprintf(os.Stderr, "  siteaudit --only broken,performance https://example.com\n")
 	}
 
 	flag.Parse()
@@ -57,20 +133,57 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
+	phases, err := audit.ParsePhases(*only, *skip)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	targetURL := args[0]
 
 	config := audit.Config{
-		Concurrency: *concurrency,
-		Timeout:     time.Duration(*timeout) * time.Second,
-		MaxDepth:    *maxDepth,
-		Verbose:     *verbose,
+		Concurrency:        *concurrency,
+		Timeout:            time.Duration(*timeout) * time.Second,
+		MaxDepth:           *maxDepth,
+		Verbosity:          verbosity.FromFlags(*verbose, *vv, *vvv),
+		CacheBytes:         int64(*cacheMB) << 20,
+		TreatSchemesAsSame: *sameScheme,
+		KeepHTTP:           *keepHTTP,
+		Delay:              time.Duration(*delay) * time.Millisecond,
+		Thresholds: audit.Thresholds{
+			BrokenHigh:     *brokenHigh,
+			BrokenCritical: *brokenCritical,
+			SlowRatio:      *slowRatio,
+			VerySlowHigh:   *verySlowHigh,
+		},
+		MinSize:             *minSize,
+		MaxIdleConnsPerHost: *idleConns,
+		ASCII:               *ascii,
+		Quiet:               *jsonOutput || *prometheus || *issuesCSV,
+		MaxRedirects:        *maxRedirects,
+		HTMLContentTypes:    htmlContentTypes,
+		Phases:              phases,
 	}
 
-	fmt.Printf("\n%s%s╔══════════════════════════════════════════════════════════════════════════════╗%s\n", colorBold, colorCyan, colorReset)
-	fmt.Printf("%s%s║                              SITE AUDIT                                       ║%s\n", colorBold, colorCyan, colorReset)
-	fmt.Printf("%s%s╚══════════════════════════════════════════════════════════════════════════════╝%s\n", colorBold, colorCyan, colorReset)
-	fmt.Printf("\nTarget: %s\n", targetURL)
-	fmt.Printf("Config: concurrency=%d, timeout=%ds, depth=%d\n", config.Concurrency, *timeout, config.MaxDepth)
+	if !*jsonOutput && !*prometheus && !*issuesCSV {
+		fmt.Printf("\n%s%s╔══════════════════════════════════════════════════════════════════════════════╗%s\n", colorBold, colorCyan, colorReset)
+		fmt.Printf("%s%s║                              SITE AUDIT                                       ║%s\n", colorBold, colorCyan, colorReset)
+		fmt.Printf("%s%s╚══════════════════════════════════════════════════════════════════════════════╝%s\n", colorBold, colorCyan, colorReset)
+		fmt.Printf("\nTarget: %s\n", targetURL)
+		fmt.Printf("Config: concurrency=%d, timeout=%ds, depth=%d\n", config.Concurrency, *timeout, config.MaxDepth)
+	}
 
 	auditor := audit.New(config)
 	result, err := auditor.Run(targetURL)
@@ -79,9 +192,43 @@ func main() {
 		os.Exit(1)
 	}
 
-	result.PrintReport()
+	switch {
+	case *jsonOutput:
+		if err := export.Write(os.Stdout, export.NewAudit(result)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case *prometheus:
+		if err := metrics.Audit(result).Write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case *issuesCSV:
+		fmt.Print(result.ExportIssuesCSV())
+	default:
+		result.PrintReport()
+	}
+
+	if *metricsFile != "" {
+		if err := metrics.Audit(result).WriteFile(*metricsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --metrics-file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *summaryLine {
+		// Kept off stdout when it carries structured output, so that stays parseable
+		out := os.Stdout
+		if *jsonOutput || *prometheus || *issuesCSV {
+			out = os.Stderr
+		}
+		fmt.Fprintln(out, result.SummaryLine())
+	}
 
-	// Exit code based on score
+	// Exit code based on score, when there is one
+	if !result.HasScore() {
+		return
+	}
 	if result.OverallScore < 50 {
 		os.Exit(2)
 	}
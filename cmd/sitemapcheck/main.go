@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/sitemapcheck"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
+)
+
+const (
+	colorReset = "\033[0m"
+	colorCyan  = "\033[36m"
+	colorBold  = "\033[1m"
+)
+
+func main() {
+	concurrency := flag.Int("c", 10, "Number of concurrent requests")
+	flag.IntVar(concurrency, "concurrency", 10, "Number of concurrent requests")
+
+	timeout := flag.Int("t", 10, "Request timeout in seconds")
+	flag.IntVar(timeout, "timeout", 10, "Request timeout in seconds")
+
+	verbose := flag.Bool("v", false, "Show errors and notable events")
+	flag.BoolVar(verbose, "verbose", false, "Show errors and notable events")
+	vv := flag.Bool("vv", false, "Also show every checked URL")
+	vvv := flag.Bool("vvv", false, "Also show timing details")
+
+	limit := flag.Int("l", 20, "Limit URLs shown per problem (0 = all)")
+	flag.IntVar(limit, "limit", 20, "Limit URLs shown per problem (0 = all)")
+
+	delay := flag.Int("delay", 0, "Milliseconds to wait between requests")
+
+	idleConns := flag.Int("idle-conns", 0, "Idle connections kept per host (0 = --concurrency)")
+
+	resolve := flag.String("resolve", "", "Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve")
+
+	htmlTypes := flag.String("html-types", "", "Comma-separated extra content types parsed as HTML, e.g. application/xml")
+
+	summaryLine := flag.Bool("summary-line", false, "Print a machine-readable summary line")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s%sSitemapCheck%s - Validate sitemaps and the URLs they list\n\n", colorBold, colorCyan, colorReset)
+		fmt.Fprintf(os.Stderr, "Usage: sitemapcheck [options] <sitemap-url>\n\n")
+		fmt.Fprintf(os.Stderr, "Fetches a sitemap or sitemap index (.gz too), follows every\n")
+		fmt.Fprintf(os.Stderr, "sitemap it lists and requests every listed URL.\n\n")
+		fmt.Fprintf(os.Stderr, "Detects:\n")
+		fmt.Fprintf(os.Stderr, "  - Sitemaps that cannot be fetched or are not well-formed XML\n")
+		fmt.Fprintf(os.Stderr, "  - Sitemaps over 50,000 URLs or 50MB uncompressed\n")
+		fmt.Fprintf(os.Stderr, "  - Relative URLs and URLs listed twice\n")
+		fmt.Fprintf(os.Stderr, "  - URLs that redirect or are broken instead of answering 200\n")
+		fmt.Fprintf(os.Stderr, "  - URLs that are noindex or canonicalized to another page\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fmt.Fprintf(os.Stderr, "  -c, --concurrency int   Number of concurrent requests (default 10)\n")
+		fmt.Fprintf(os.Stderr, "  -t, --timeout int       Request timeout in seconds (default 10)\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose           Show errors and notable events\n")
+		fmt.Fprintf(os.Stderr, "  -vv                     Also show every checked URL\n")
+		fmt.Fprintf(os.Stderr, "  -vvv                    Also show timing details\n")
+		fmt.Fprintf(os.Stderr, "  -l, --limit int         Limit URLs shown per problem, 0 = all (default 20)\n")
+		fmt.Fprintf(os.Stderr, "      --delay int         Milliseconds to wait between requests (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --idle-conns int    Idle connections kept per host, 0 = --concurrency (default 0)\n")
+		fmt.Fprintf(os.Stderr, "      --resolve list      Comma-separated host:ip or host:port:ip DNS overrides, like curl --resolve\n")
+		fmt.Fprintf(os.Stderr, "      --html-types list   Comma-separated extra content types parsed as HTML, e.g. application/xml\n")
+		fmt.Fprintf(os.Stderr, "      --summary-line      Print a machine-readable summary line\n")
+		fmt.Fprintf(os.Stderr, "\nExit status is 1 when any problem is found.\n")
+		fmt.Fprintf(os.Stderr, "\nExample:\n")
+		fmt.Fprintf(os.Stderr, "  sitemapcheck https://example.com/sitemap.xml\n")
+		fmt.Fprintf(os.Stderr, "  sitemapcheck -c 5 --delay 200 https://example.com/sitemap_index.xml\n")
+	}
+
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Installed before any transport is created, so every request uses it
+	if err := httppool.Resolve(*resolve); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --resolve: %v\n", err)
+		os.Exit(1)
+	}
+
+	htmlContentTypes, err := contenttype.ParseList(*htmlTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --html-types: %v\n", err)
+		os.Exit(1)
+	}
+
+	sitemapURL := args[0]
+
+	config := sitemapcheck.Config{
+		Concurrency:         *concurrency,
+		Timeout:             time.Duration(*timeout) * time.Second,
+		Verbosity:           verbosity.FromFlags(*verbose, *vv, *vvv),
+		Delay:               time.Duration(*delay) * time.Millisecond,
+		MaxIdleConnsPerHost: *idleConns,
+		HTMLContentTypes:    htmlContentTypes,
+	}
+
+	fmt.Printf("%s%sSitemapCheck%s starting...\n", colorBold, colorCyan, colorReset)
+	fmt.Printf("Target: %s\n", sitemapURL)
+	fmt.Printf("Concurrency: %d, Timeout: %ds\n\n", config.Concurrency, *timeout)
+
+	checker := sitemapcheck.New(config)
+	result, err := checker.Check(sitemapURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	result.PrintSummary(*limit)
+
+	if *summaryLine {
+		fmt.Println(result.SummaryLine())
+	}
+
+	if result.HasProblems() {
+		os.Exit(1)
+	}
+}
@@ -5,65 +5,80 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 // Config holds the analyzer configuration
 type Config struct {
-	Concurrency int
-	Timeout     time.Duration
-	MaxDepth    int
-	Verbose     bool
+	Concurrency         int
+	Timeout             time.Duration
+	MaxDepth            int // Levels of pages whose links are inventoried: 1 = the start page only, 0 = unlimited
+	Verbose             bool
+	Verbosity           int               // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Info
+	Transport           http.RoundTripper // Optional custom transport, e.g. a shared response cache
+	TreatSchemesAsSame  bool              // Collapse http:// and https:// URLs of the same page
+	KeepHTTP            bool              // Crawl http:// URLs as is even on a site enforcing HTTPS
+	ExtraElements       bool              // Also inventory <area href>, <form action> and <link href>
+	Delay               time.Duration     // Minimum pause between two requests
+	MaxIdleConnsPerHost int               // Idle connections kept per host, 0 matches Concurrency
+	MaxRedirects        int               // Redirects followed per request, 0 = none (the 3xx is kept as the page, its target not crawled), -1 = up to httppool.MaxRedirectsCap
+	HTMLContentTypes    []string          // Extra media types parsed as HTML, besides contenttype.HTML
+	MaxStoredExamples   int               // Links kept per type, 0 keeps them all; counts stay exact
+	MaxRepeats          int               // Links to one target a page may have before it is reported, 0 disables
+	URLLimits           URLLimits         // Bounds past which an internal URL is reported as long
+	FollowInternal      bool              // Crawl the internal links found; false only inventories the start page's links
 }
 
 // DefaultConfig returns a default configuration
 func DefaultConfig() Config {
 	return Config{
-		Concurrency: 10,
-		Timeout:     10 * time.Second,
-		MaxDepth:    0,
-		Verbose:     false,
+		Concurrency:    10,
+		Timeout:        10 * time.Second,
+		MaxDepth:       0,
+		Verbose:        false,
+		MaxRedirects:   httppool.DefaultMaxRedirects,
+		URLLimits:      DefaultURLLimits,
+		FollowInternal: true,
 	}
 }
 
 // Analyzer crawls a website and categorizes all links
 type Analyzer struct {
-	config    Config
-	baseURL   *url.URL
-	visited   map[string]bool
-	visitedMu sync.RWMutex
-	result    *AnalysisResult
-	resultMu  sync.Mutex
-	client    *http.Client
-	semaphore chan struct{}
+	config   Config
+	baseURL  *url.URL
+	result   *AnalysisResult
+	resultMu sync.Mutex
+	client   *http.Client
+	stats    crawlstats.Counter
 }
 
 // New creates a new Analyzer instance
 func New(config Config) *Analyzer {
+	config.Verbosity = verbosity.Effective(config.Verbosity, config.Verbose)
+	transport := config.Transport
+	if transport == nil {
+		transport = httppool.New(config.Concurrency, config.MaxIdleConnsPerHost)
+	}
 	return &Analyzer{
-		config:    config,
-		visited:   make(map[string]bool),
-		semaphore: make(chan struct{}, config.Concurrency),
+		config: config,
 		client: &http.Client{
-			Timeout: config.Timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
+			Timeout:       config.Timeout,
+			Transport:     transport,
+			CheckRedirect: httppool.CheckRedirect(config.MaxRedirects, httppool.ErrTooManyRedirects),
 		},
 	}
 }
 
-type urlTask struct {
-	url       string
-	sourceURL string
-	depth     int
-}
-
 // Analyze starts analyzing from the given URL
 func (a *Analyzer) Analyze(startURL string) (*AnalysisResult, error) {
 	parsed, err := url.Parse(startURL)
@@ -76,154 +91,93 @@ func (a *Analyzer) Analyze(startURL string) (*AnalysisResult, error) {
 	}
 
 	a.baseURL = parsed
+	a.stats.Start()
 	a.result = NewAnalysisResult(startURL)
-
-	tasks := make(chan urlTask, 1000)
-
-	a.markVisited(startURL)
-	tasks <- urlTask{url: startURL, sourceURL: "", depth: 0}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Start workers
-	for i := 0; i < a.config.Concurrency; i++ {
-		go a.worker(ctx, tasks)
-	}
-
-	// Monitor completion
-	done := make(chan struct{})
-	go func() {
-		for {
-			time.Sleep(100 * time.Millisecond)
-			a.visitedMu.RLock()
-			visitedCount := len(a.visited)
-			a.visitedMu.RUnlock()
-
-			if len(tasks) == 0 && len(a.semaphore) == 0 {
-				time.Sleep(500 * time.Millisecond)
-				if len(tasks) == 0 && len(a.semaphore) == 0 {
-					close(done)
-					return
-				}
-			}
-
-			if visitedCount > 10000 {
-				close(done)
-				return
-			}
-		}
-	}()
-
-	<-done
-	cancel()
-	close(tasks)
-
-	a.visitedMu.RLock()
-	a.result.TotalPages = len(a.visited)
-	a.visitedMu.RUnlock()
+	a.result.MaxStoredExamples = a.config.MaxStoredExamples
+	a.result.MaxRepeats = a.config.MaxRepeats
+	a.result.URLLimits = a.config.URLLimits
+
+	engine := crawl.New(crawl.Config{
+		Concurrency:        a.config.Concurrency,
+		MaxDepth:           a.config.MaxDepth,
+		Delay:              a.config.Delay,
+		TreatSchemesAsSame: a.config.TreatSchemesAsSame,
+		Rewrite:            httppool.HTTPSRewrite(a.config.KeepHTTP, a.client.Transport, a.config.Timeout, startURL),
+	}, a.processURL)
+	a.result.TotalPages = engine.Run(context.Background(), crawl.Task{URL: startURL})
+
+	a.result.sortLongURLs()
+	sort.SliceStable(a.result.RepeatedLinks, func(i, j int) bool {
+		return a.result.RepeatedLinks[i].SourceURL < a.result.RepeatedLinks[j].SourceURL
+	})
+	a.result.CrawlStats = a.stats.Snapshot()
 
 	return a.result, nil
 }
 
-func (a *Analyzer) worker(ctx context.Context, tasks chan urlTask) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case task, ok := <-tasks:
-			if !ok {
-				return
-			}
-			a.processURL(ctx, task, tasks)
-		}
-	}
-}
-
-func (a *Analyzer) processURL(ctx context.Context, task urlTask, tasks chan urlTask) {
-	select {
-	case a.semaphore <- struct{}{}:
-		defer func() { <-a.semaphore }()
-	case <-ctx.Done():
-		return
-	}
-
-	if a.config.MaxDepth > 0 && task.depth > a.config.MaxDepth {
-		return
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", task.url, nil)
+func (a *Analyzer) processURL(ctx context.Context, task crawl.Task) []crawl.Task {
+	req, err := http.NewRequestWithContext(ctx, "GET", task.URL, nil)
 	if err != nil {
-		return
+		return nil
 	}
 
 	req.Header.Set("User-Agent", "LinkAnalyzer/1.0")
 
+	start := time.Now()
 	resp, err := a.client.Do(req)
+	a.stats.AddRequest()
 	if err != nil {
 		if ctx.Err() != nil {
-			return
+			return nil
 		}
-		if a.config.Verbose {
-			printError(task.url, err.Error(), task.depth)
+		if a.config.Verbosity >= verbosity.Warn {
+			printError(task.URL, err.Error(), task.Depth)
 		}
-		return
+		return nil
 	}
 	defer resp.Body.Close()
 
-	if a.config.Verbose {
-		printProgress(task.url, resp.StatusCode, task.depth)
+	if verbosity.ShowStatus(a.config.Verbosity, resp.StatusCode) {
+		printProgress(task.URL, resp.StatusCode, task.Depth)
+	}
+	if a.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(task.URL, time.Since(start))
 	}
 
 	if resp.StatusCode >= 400 {
-		return
+		return nil
 	}
 
 	contentType := resp.Header.Get("Content-Type")
-	if !isHTML(contentType) {
-		return
+	if !contenttype.IsHTML(contentType, a.config.HTMLContentTypes) {
+		return nil
 	}
 
 	// Extract and classify all links
-	links := ExtractAllLinks(resp.Body, a.baseURL, task.url)
+	links, targets, malformed := extractPage(a.stats.Body(resp.Body), a.baseURL, task.URL, a.config.ExtraElements)
+
+	a.resultMu.Lock()
+	a.result.MalformedLinks = append(a.result.MalformedLinks, malformed...)
+	a.result.RepeatedLinks = append(a.result.RepeatedLinks, repeatedLinks(links, a.config.MaxRepeats)...)
+	a.resultMu.Unlock()
 
+	follow := a.config.FollowInternal && (a.config.MaxDepth == 0 || task.Depth+1 < a.config.MaxDepth)
+	var next []crawl.Task
 	for _, link := range links {
 		a.resultMu.Lock()
 		a.result.AddLink(link)
+		if link.Type == LinkTypeAnchor && !AnchorExists(link.URL, targets) {
+			a.result.DanglingAnchors = append(a.result.DanglingAnchors, link)
+		}
 		a.resultMu.Unlock()
 
-		// Only queue internal HTML links for further crawling
-		if link.Type == LinkTypeInternal && a.shouldVisit(link.URL) {
-			a.markVisited(link.URL)
-			select {
-			case tasks <- urlTask{url: link.URL, sourceURL: task.url, depth: task.depth + 1}:
-			default:
-			}
+		// Only follow internal HTML links, and none from the last level
+		// of pages inventoried
+		if follow && link.Type == LinkTypeInternal && IsSameDomain(link.URL, a.baseURL) {
+			next = append(next, crawl.Task{URL: link.URL})
 		}
 	}
-}
-
-func (a *Analyzer) markVisited(url string) {
-	a.visitedMu.Lock()
-	a.visited[url] = true
-	a.visitedMu.Unlock()
-}
-
-func (a *Analyzer) shouldVisit(targetURL string) bool {
-	if !IsSameDomain(targetURL, a.baseURL) {
-		return false
-	}
-
-	a.visitedMu.RLock()
-	visited := a.visited[targetURL]
-	a.visitedMu.RUnlock()
-
-	return !visited
-}
-
-func isHTML(contentType string) bool {
-	return strings.Contains(contentType, "text/html") ||
-		strings.Contains(contentType, "application/xhtml+xml")
+	return next
 }
 
 func printProgress(url string, statusCode int, depth int) {
@@ -240,10 +194,10 @@ func printProgress(url string, statusCode int, depth int) {
 	}
 
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[%d]%s %s\n", indent, statusColor, statusCode, colorReset, url)
+	fmt.Fprintf(os.Stderr, "%s%s[%d]%s %s\n", indent, statusColor, statusCode, colorReset, url)
 }
 
 func printError(url string, err string, depth int) {
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, err)
+	fmt.Fprintf(os.Stderr, "%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, err)
 }
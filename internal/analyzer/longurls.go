@@ -0,0 +1,123 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// URLLimits are the bounds past which an internal URL is reported as messy:
+// long URLs, deep paths and piled-up parameters hint at encoded junk or
+// crawl traps. 0 disables a bound.
+type URLLimits struct {
+	Length int // Characters of the whole URL
+	Depth  int // Path segments
+	Params int // Query parameters, repeated names included
+}
+
+// DefaultURLLimits are the limits used unless configured otherwise
+var DefaultURLLimits = URLLimits{Length: 115, Depth: 6, Params: 4}
+
+// LongURL is an internal URL past one of the URLLimits
+type LongURL struct {
+	URL       string
+	SourceURL string // First page found linking to it
+	Length    int
+	Depth     int
+	Params    int
+	Reasons   []string // One per limit exceeded, e.g. "142 chars"
+}
+
+// checkURL reports an internal URL, the first time it is seen, when it
+// exceeds a limit. Every URL is checked even when links are not all stored.
+func (r *AnalysisResult) checkURL(link Link) {
+	if r.checkedURLs[link.URL] {
+		return
+	}
+	r.checkedURLs[link.URL] = true
+
+	if long, ok := measureURL(link.URL, r.URLLimits); ok {
+		long.SourceURL = link.SourceURL
+		r.LongURLs = append(r.LongURLs, long)
+	}
+}
+
+// measureURL returns the measures of a URL, and whether it exceeds a limit
+func measureURL(rawURL string, limits URLLimits) (LongURL, bool) {
+	long := LongURL{URL: rawURL, Length: utf8.RuneCountInString(rawURL)}
+	if parsed, err := url.Parse(rawURL); err == nil {
+		for _, segment := range strings.Split(parsed.Path, "/") {
+			if segment != "" {
+				long.Depth++
+			}
+		}
+		for _, param := range strings.Split(parsed.RawQuery, "&") {
+			if param != "" {
+				long.Params++
+			}
+		}
+	}
+
+	if limits.Length > 0 && long.Length > limits.Length {
+		long.Reasons = append(long.Reasons, fmt.Sprintf("%d chars", long.Length))
+	}
+	if limits.Depth > 0 && long.Depth > limits.Depth {
+		long.Reasons = append(long.Reasons, fmt.Sprintf("%d levels deep", long.Depth))
+	}
+	if limits.Params > 0 && long.Params > limits.Params {
+		long.Reasons = append(long.Reasons, fmt.Sprintf("%d parameters", long.Params))
+	}
+	return long, len(long.Reasons) > 0
+}
+
+// sortLongURLs puts the longest URLs first
+func (r *AnalysisResult) sortLongURLs() {
+	sort.Slice(r.LongURLs, func(i, j int) bool {
+		if r.LongURLs[i].Length != r.LongURLs[j].Length {
+			return r.LongURLs[i].Length > r.LongURLs[j].Length
+		}
+		return r.LongURLs[i].URL < r.LongURLs[j].URL
+	})
+}
+
+// printLongURLs lists the internal URLs past the limits
+func (r *AnalysisResult) printLongURLs() {
+	if len(r.LongURLs) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sLong or complex internal URLs (%d):%s\n", colorBold, colorYellow, len(r.LongURLs), colorReset)
+	fmt.Printf("  %sOver %s; they hint at encoded junk or crawl traps.%s\n", colorGray, r.URLLimits.describe(), colorReset)
+	for i, long := range r.LongURLs {
+		if i >= 10 {
+			fmt.Printf("  %s... and %d more%s\n", colorGray, len(r.LongURLs)-10, colorReset)
+			break
+		}
+		fmt.Printf("  %s %s(%s)%s\n", long.URL, colorYellow, strings.Join(long.Reasons, ", "), colorReset)
+		fmt.Printf("    %s← %s%s\n", colorGray, long.SourceURL, colorReset)
+	}
+}
+
+// describe lists the enabled limits, e.g. "115 chars, 6 levels or 4 parameters"
+func (l URLLimits) describe() string {
+	var parts []string
+	if l.Length > 0 {
+		parts = append(parts, fmt.Sprintf("%d chars", l.Length))
+	}
+	if l.Depth > 0 {
+		parts = append(parts, fmt.Sprintf("%d levels", l.Depth))
+	}
+	if l.Params > 0 {
+		parts = append(parts, fmt.Sprintf("%d parameters", l.Params))
+	}
+	switch len(parts) {
+	case 0:
+		return "no limit"
+	case 1:
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " or " + parts[len(parts)-1]
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
 )
 
 // File extensions considered as non-HTML files
@@ -24,7 +26,27 @@ var fileExtensions = map[string]bool{
 
 // ExtractAllLinks parses HTML and extracts all links with their types
 func ExtractAllLinks(body io.Reader, baseURL *url.URL, sourceURL string) []Link {
+	return ExtractAllLinksWith(body, baseURL, sourceURL, false)
+}
+
+// ExtractAllLinksWith parses HTML and extracts all <a href> links with their
+// types. With extraElements it also reads <area href>, <form action> and
+// <link href>, tagging each link with its element.
+func ExtractAllLinksWith(body io.Reader, baseURL *url.URL, sourceURL string, extraElements bool) []Link {
+	links, _, _ := extractPage(body, baseURL, sourceURL, extraElements)
+	return links
+}
+
+// extractPage extracts the links of a page like ExtractAllLinksWith, along
+// with the in-page anchor targets it defines (every id, and <a name>) and
+// the <a> elements without a usable href
+func extractPage(body io.Reader, baseURL *url.URL, sourceURL string, extraElements bool) ([]Link, map[string]bool, []MalformedLink) {
 	var links []Link
+	var malformed []MalformedLink
+	var text *strings.Builder   // Anchor text of the last malformed link, until its </a>
+	var anchor *strings.Builder // Anchor text of links[anchorLink], an <a>, until its </a>
+	anchorLink := 0
+	targets := make(map[string]bool)
 	tokenizer := html.NewTokenizer(body)
 
 	for {
@@ -32,19 +54,61 @@ func ExtractAllLinks(body io.Reader, baseURL *url.URL, sourceURL string) []Link
 
 		switch tokenType {
 		case html.ErrorToken:
-			return links
+			return links, targets, malformed
+
+		case html.TextToken:
+			if text != nil {
+				text.Write(tokenizer.Text())
+			}
+			if anchor != nil {
+				anchor.Write(tokenizer.Text())
+			}
+
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "a" {
+				if text != nil {
+					malformed[len(malformed)-1].Text = strings.Join(strings.Fields(text.String()), " ")
+					text = nil
+				}
+				if anchor != nil {
+					links[anchorLink].Text = strings.Join(strings.Fields(anchor.String()), " ")
+					anchor = nil
+				}
+			}
 
 		case html.StartTagToken, html.SelfClosingTagToken:
 			token := tokenizer.Token()
 
+			if id := htmlattr.Get(token, "id"); id != "" {
+				targets[id] = true
+			}
+			if name := htmlattr.Get(token, "name"); name != "" && token.Data == "a" {
+				targets[name] = true
+			}
+
 			if token.Data == "a" {
-				for _, attr := range token.Attr {
-					if attr.Key == "href" {
-						link := classifyLink(attr.Val, baseURL, sourceURL)
-						if link != nil {
-							links = append(links, *link)
-						}
-						break
+				anchor = nil
+				if problem := hrefProblem(token); problem != "" {
+					malformed = append(malformed, MalformedLink{SourceURL: sourceURL, Problem: problem})
+					if tokenType == html.StartTagToken {
+						text = &strings.Builder{}
+					}
+					continue
+				}
+			}
+
+			if token.Data != "a" && !extraElements {
+				continue
+			}
+
+			if href, ok := htmlattr.LinkTarget(token); ok {
+				link := classifyLink(href, baseURL, sourceURL)
+				if link != nil {
+					link.Element = token.Data
+					links = append(links, *link)
+					if token.Data == "a" && tokenType == html.StartTagToken {
+						anchor = &strings.Builder{}
+						anchorLink = len(links) - 1
 					}
 				}
 			}
@@ -52,6 +116,29 @@ func ExtractAllLinks(body io.Reader, baseURL *url.URL, sourceURL string) []Link
 	}
 }
 
+// hrefProblem describes why an <a> has no usable href, or returns "" when
+// it has one. An <a> without href but with an id or name is an anchor
+// target, not a link.
+func hrefProblem(token html.Token) string {
+	for _, attr := range token.Attr {
+		if attr.Key != "href" {
+			continue
+		}
+		switch {
+		case attr.Val == "":
+			return ProblemEmptyHref
+		case strings.TrimSpace(attr.Val) == "":
+			return ProblemBlankHref
+		default:
+			return ""
+		}
+	}
+	if htmlattr.Get(token, "id") != "" || htmlattr.Get(token, "name") != "" {
+		return ""
+	}
+	return ProblemMissingHref
+}
+
 // classifyLink determines the type of a link
 func classifyLink(href string, baseURL *url.URL, sourceURL string) *Link {
 	href = strings.TrimSpace(href)
@@ -116,6 +203,22 @@ func classifyLink(href string, baseURL *url.URL, sourceURL string) *Link {
 	return &Link{URL: fullURL, SourceURL: sourceURL, Type: LinkTypeExternal}
 }
 
+// AnchorExists reports whether an in-page link such as #section points to a
+// target of its page. An empty fragment and #top scroll to the top of the
+// page and always exist.
+func AnchorExists(href string, targets map[string]bool) bool {
+	fragment := strings.TrimPrefix(href, "#")
+	if fragment == "" || strings.EqualFold(fragment, "top") {
+		return true
+	}
+	if targets[fragment] {
+		return true
+	}
+	// Fragments may be percent-encoded while ids are not
+	decoded, err := url.PathUnescape(fragment)
+	return err == nil && targets[decoded]
+}
+
 // getFileExtension extracts the lowercase file extension from a path
 func getFileExtension(urlPath string) string {
 	ext := path.Ext(urlPath)
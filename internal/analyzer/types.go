@@ -2,7 +2,12 @@ package analyzer
 
 import (
 	"fmt"
+	"net/url"
+	"slices"
 	"sort"
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
 )
 
 // LinkType categorizes the type of link
@@ -51,15 +56,60 @@ type Link struct {
 	SourceURL string
 	Type      LinkType
 	FileType  string // For LinkTypeFile: pdf, jpg, etc.
+	Element   string // Element the link was found in: a, area, form or link
+	Text      string // Anchor text of <a> links, whitespace collapsed
+}
+
+// RepeatedLink is a target one page links to more than
+// Config.MaxRepeats times
+type RepeatedLink struct {
+	SourceURL string
+	URL       string
+	Count     int
+	Texts     []string // Distinct anchor texts, in page order
+}
+
+// Problems of a MalformedLink
+const (
+	ProblemEmptyHref   = "empty href"
+	ProblemBlankHref   = "whitespace-only href"
+	ProblemMissingHref = "missing href"
+)
+
+// MalformedLink is an <a> element without a usable href: clickable, but
+// leading nowhere
+type MalformedLink struct {
+	SourceURL string
+	Problem   string
+	Text      string // Anchor text, whitespace collapsed
 }
 
 // AnalysisResult holds the complete analysis results
 type AnalysisResult struct {
-	StartURL       string
-	TotalPages     int
-	TotalLinks     int
-	LinksByType    map[LinkType][]Link
-	ExternalByHost map[string][]Link
+	StartURL        string
+	TotalPages      int
+	TotalLinks      int
+	LinksByType     map[LinkType][]Link
+	ExternalByHost  map[string][]Link
+	DanglingAnchors []Link          // In-page #anchor links with no matching id or name on their page
+	MalformedLinks  []MalformedLink // <a> elements with an empty, blank or missing href
+	RepeatedLinks   []RepeatedLink  // Targets linked more than MaxRepeats times from one page, sorted by page
+	MaxRepeats      int             // Links to one target a page may have, 0 disables RepeatedLinks
+	LongURLs        []LongURL       // Internal URLs past URLLimits, longest first
+	URLLimits       URLLimits
+	CrawlStats      crawlstats.Stats
+
+	// LinksByType keeps at most MaxStoredExamples links of each type, 0
+	// keeps them all; the counts include every link found
+	MaxStoredExamples int
+	CountByType       map[LinkType]int
+	CountByElement    map[string]int
+
+	// External links and linking pages per domain, counted as links come
+	// in so the ranking stays exact when links are not all stored
+	domainLinks map[string]int
+	domainPages map[string]map[string]bool
+	checkedURLs map[string]bool // Internal URLs measured against URLLimits
 }
 
 // NewAnalysisResult creates a new AnalysisResult
@@ -68,15 +118,199 @@ func NewAnalysisResult(startURL string) *AnalysisResult {
 		StartURL:       startURL,
 		LinksByType:    make(map[LinkType][]Link),
 		ExternalByHost: make(map[string][]Link),
+		CountByType:    make(map[LinkType]int),
+		CountByElement: make(map[string]int),
+		domainLinks:    make(map[string]int),
+		domainPages:    make(map[string]map[string]bool),
+		checkedURLs:    make(map[string]bool),
 	}
 }
 
-// AddLink adds a link to the results
+// AddLink counts a link and stores it while its type is below
+// MaxStoredExamples
 func (r *AnalysisResult) AddLink(link Link) {
 	r.TotalLinks++
+	r.CountByType[link.Type]++
+	r.CountByElement[link.Element]++
+
+	if link.Type == LinkTypeExternal {
+		host := linkDomain(link.URL)
+		r.domainLinks[host]++
+		if r.domainPages[host] == nil {
+			r.domainPages[host] = make(map[string]bool)
+		}
+		r.domainPages[host][link.SourceURL] = true
+	}
+	if link.Type == LinkTypeInternal {
+		r.checkURL(link)
+	}
+
+	if r.MaxStoredExamples > 0 && len(r.LinksByType[link.Type]) >= r.MaxStoredExamples {
+		return
+	}
 	r.LinksByType[link.Type] = append(r.LinksByType[link.Type], link)
 }
 
+// repeatedLinks returns the targets of a page's links that appear more than
+// max times, most repeated first. In-page anchors, mailto, tel and the like
+// are left out: only links to pages and files dilute signals.
+func repeatedLinks(links []Link, max int) []RepeatedLink {
+	if max <= 0 {
+		return nil
+	}
+
+	var order []string
+	byURL := make(map[string]*RepeatedLink)
+	for _, link := range links {
+		if link.Type != LinkTypeInternal && link.Type != LinkTypeExternal && link.Type != LinkTypeFile {
+			continue
+		}
+		repeated := byURL[link.URL]
+		if repeated == nil {
+			repeated = &RepeatedLink{SourceURL: link.SourceURL, URL: link.URL}
+			byURL[link.URL] = repeated
+			order = append(order, link.URL)
+		}
+		repeated.Count++
+		if !slices.Contains(repeated.Texts, link.Text) {
+			repeated.Texts = append(repeated.Texts, link.Text)
+		}
+	}
+
+	var repeated []RepeatedLink
+	for _, target := range order {
+		if byURL[target].Count > max {
+			repeated = append(repeated, *byURL[target])
+		}
+	}
+	sort.SliceStable(repeated, func(i, j int) bool {
+		return repeated[i].Count > repeated[j].Count
+	})
+	return repeated
+}
+
+// SlashInconsistency is an internal page linked both with and without a
+// trailing slash
+type SlashInconsistency struct {
+	WithSlash      string
+	WithoutSlash   string
+	WithSources    []string // Pages linking to the form with the slash
+	WithoutSources []string // Pages linking to the form without it
+}
+
+// TrailingSlashInconsistencies returns the internal URLs linked in both
+// forms, e.g. /page and /page/, sorted by URL. Only stored links are
+// compared, see MaxStoredExamples.
+func (r *AnalysisResult) TrailingSlashInconsistencies() []SlashInconsistency {
+	type forms struct {
+		with, without       string
+		withSrc, withoutSrc map[string]bool
+	}
+	byKey := make(map[string]*forms)
+
+	for _, link := range r.LinksByType[LinkTypeInternal] {
+		parsed, err := url.Parse(link.URL)
+		if err != nil || parsed.Path == "" || parsed.Path == "/" {
+			continue
+		}
+		trimmed := *parsed
+		trimmed.Path = strings.TrimSuffix(parsed.Path, "/")
+		key := trimmed.String()
+
+		f, ok := byKey[key]
+		if !ok {
+			f = &forms{withSrc: make(map[string]bool), withoutSrc: make(map[string]bool)}
+			byKey[key] = f
+		}
+		if strings.HasSuffix(parsed.Path, "/") {
+			f.with = link.URL
+			f.withSrc[link.SourceURL] = true
+		} else {
+			f.without = link.URL
+			f.withoutSrc[link.SourceURL] = true
+		}
+	}
+
+	var result []SlashInconsistency
+	for _, f := range byKey {
+		if f.with == "" || f.without == "" {
+			continue
+		}
+		result = append(result, SlashInconsistency{
+			WithSlash:      f.with,
+			WithoutSlash:   f.without,
+			WithSources:    sortedKeys(f.withSrc),
+			WithoutSources: sortedKeys(f.withoutSrc),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].WithoutSlash < result[j].WithoutSlash
+	})
+	return result
+}
+
+// DomainCount is an external domain and how often the site links to it
+type DomainCount struct {
+	Host  string
+	Links int // External links pointing to the domain
+	Pages int // Distinct pages carrying those links
+}
+
+// TopExternalDomains ranks the external domains by number of links, most
+// linked first. n <= 0 returns them all.
+func (r *AnalysisResult) TopExternalDomains(n int) []DomainCount {
+	domains := make([]DomainCount, 0, len(r.domainLinks))
+	for host, count := range r.domainLinks {
+		domains = append(domains, DomainCount{Host: host, Links: count, Pages: len(r.domainPages[host])})
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if domains[i].Links != domains[j].Links {
+			return domains[i].Links > domains[j].Links
+		}
+		return domains[i].Host < domains[j].Host
+	})
+	if n > 0 && len(domains) > n {
+		domains = domains[:n]
+	}
+	return domains
+}
+
+// linkDomain returns the lowercase host name of an external link
+func linkDomain(link string) string {
+	host := extractHost(link)
+	if parsed, err := url.Parse(link); err == nil && parsed.Hostname() != "" {
+		host = parsed.Hostname()
+	}
+	return strings.ToLower(host)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *AnalysisResult) SummaryLine() string {
+	return fmt.Sprintf("SUMMARY tool=linkanalyzer pages=%d links=%d internal=%d external=%d files=%d mailto=%d tel=%d javascript=%d slash_inconsistent=%d dangling_anchors=%d malformed=%d external_domains=%d repeated_links=%d long_urls=%d",
+		r.TotalPages, r.TotalLinks,
+		r.CountByType[LinkTypeInternal],
+		r.CountByType[LinkTypeExternal],
+		r.CountByType[LinkTypeFile],
+		r.CountByType[LinkTypeMailto],
+		r.CountByType[LinkTypeTel],
+		r.CountByType[LinkTypeJavaScript],
+		len(r.TrailingSlashInconsistencies()),
+		len(r.DanglingAnchors),
+		len(r.MalformedLinks),
+		len(r.TopExternalDomains(0)),
+		len(r.RepeatedLinks),
+		len(r.LongURLs))
+}
+
 // ANSI color codes
 const (
 	colorReset  = "\033[0m"
@@ -90,8 +324,9 @@ const (
 	colorBold   = "\033[1m"
 )
 
-// PrintSummary displays the analysis results
-func (r *AnalysisResult) PrintSummary(showDetails bool) {
+// PrintSummary displays the analysis results, with the topDomains most
+// linked external domains (0 hides them)
+func (r *AnalysisResult) PrintSummary(showDetails bool, topDomains int) {
 	fmt.Println()
 	fmt.Printf("%s%s=== Link Analysis Summary ===%s\n", colorBold, colorCyan, colorReset)
 	fmt.Printf("Start URL: %s%s%s\n", colorBlue, r.StartURL, colorReset)
@@ -116,8 +351,8 @@ func (r *AnalysisResult) PrintSummary(showDetails bool) {
 	}
 
 	for _, t := range typeOrder {
-		links := r.LinksByType[t]
-		if len(links) == 0 {
+		count := r.CountByType[t]
+		if count == 0 {
 			continue
 		}
 
@@ -126,13 +361,212 @@ func (r *AnalysisResult) PrintSummary(showDetails bool) {
 			color = colorYellow
 		}
 
-		fmt.Printf("  %s%-20s%s %d\n", color, t.String()+":", colorReset, len(links))
+		fmt.Printf("  %s%-20s%s %d\n", color, t.String()+":", colorReset, count)
 	}
 
+	r.printByElement()
+
+	r.printTopDomains(topDomains)
+
+	r.printTrailingSlash()
+
+	r.printDanglingAnchors()
+
+	r.printMalformedLinks()
+
+	r.printRepeatedLinks()
+
+	r.printLongURLs()
+
 	// Non-analyzable links details
 	if showDetails {
 		r.printNonAnalyzableDetails()
 	}
+
+	r.CrawlStats.Print()
+}
+
+// printByElement shows where links were found, only when links other than
+// <a href> were extracted
+func (r *AnalysisResult) printByElement() {
+	counts := r.CountByElement
+	if counts["area"]+counts["form"]+counts["link"] == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sLinks by Element:%s\n", colorBold, colorYellow, colorReset)
+	fmt.Println()
+
+	for _, element := range []string{"a", "area", "form", "link"} {
+		if counts[element] == 0 {
+			continue
+		}
+		fmt.Printf("  %s%-20s%s %d\n", colorGreen, "<"+element+">:", colorReset, counts[element])
+	}
+}
+
+// printTopDomains ranks the external domains the site links to most
+func (r *AnalysisResult) printTopDomains(n int) {
+	if n <= 0 {
+		return
+	}
+	all := r.TopExternalDomains(0)
+	if len(all) == 0 {
+		return
+	}
+	top := all
+	if len(top) > n {
+		top = top[:n]
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sTop external domains (%d of %d):%s\n", colorBold, colorYellow, len(top), len(all), colorReset)
+	fmt.Println()
+
+	for i, domain := range top {
+		fmt.Printf("  %s%2d.%s %-35s %s%d link(s)%s from %d page(s)\n", colorGray, i+1, colorReset, domain.Host, colorCyan, domain.Links, colorReset, domain.Pages)
+	}
+}
+
+// printTrailingSlash lists internal pages linked both with and without a
+// trailing slash, with one linking page for each form
+func (r *AnalysisResult) printTrailingSlash() {
+	pairs := r.TrailingSlashInconsistencies()
+	if len(pairs) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sTrailing-slash inconsistencies (%d):%s\n", colorBold, colorYellow, len(pairs), colorReset)
+	fmt.Printf("  %sThe same page is linked with and without a trailing slash%s\n", colorGray, colorReset)
+
+	for i, pair := range pairs {
+		if i >= 10 {
+			fmt.Printf("\n  %s... and %d more%s\n", colorGray, len(pairs)-10, colorReset)
+			break
+		}
+		fmt.Printf("\n  %s%s%s\n", colorCyan, pair.WithoutSlash, colorReset)
+		fmt.Printf("    %swithout slash from: %s (%d page(s))%s\n", colorGray, pair.WithoutSources[0], len(pair.WithoutSources), colorReset)
+		fmt.Printf("    %swith slash from:    %s (%d page(s))%s\n", colorGray, pair.WithSources[0], len(pair.WithSources), colorReset)
+	}
+}
+
+// printDanglingAnchors lists in-page links whose target does not exist on
+// their page, grouped by page
+func (r *AnalysisResult) printDanglingAnchors() {
+	if len(r.DanglingAnchors) == 0 {
+		return
+	}
+
+	bySource := make(map[string][]string)
+	for _, link := range r.DanglingAnchors {
+		bySource[link.SourceURL] = append(bySource[link.SourceURL], link.URL)
+	}
+	sources := make([]string, 0, len(bySource))
+	for source := range bySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	fmt.Println()
+	fmt.Printf("%s%sDangling in-page anchors (%d):%s\n", colorBold, colorRed, len(r.DanglingAnchors), colorReset)
+	fmt.Printf("  %sThese #anchor links match no id or <a name> on their page%s\n", colorGray, colorReset)
+
+	for i, source := range sources {
+		if i >= 10 {
+			fmt.Printf("\n  %s... and %d more pages%s\n", colorGray, len(sources)-10, colorReset)
+			break
+		}
+		fmt.Printf("\n  %s%s%s\n", colorCyan, source, colorReset)
+		fmt.Printf("    %s%s%s\n", colorRed, strings.Join(bySource[source], " "), colorReset)
+	}
+}
+
+// printMalformedLinks lists the <a> elements without a usable href, grouped
+// by page, with their text so they can be found in the template
+func (r *AnalysisResult) printMalformedLinks() {
+	if len(r.MalformedLinks) == 0 {
+		return
+	}
+
+	bySource := make(map[string][]MalformedLink)
+	for _, link := range r.MalformedLinks {
+		bySource[link.SourceURL] = append(bySource[link.SourceURL], link)
+	}
+	sources := make([]string, 0, len(bySource))
+	for source := range bySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	fmt.Println()
+	fmt.Printf("%s%sMalformed links (%d):%s\n", colorBold, colorRed, len(r.MalformedLinks), colorReset)
+	fmt.Printf("  %sThese <a> elements have an empty, blank or missing href: clickable but leading nowhere%s\n", colorGray, colorReset)
+
+	for i, source := range sources {
+		if i >= 10 {
+			fmt.Printf("\n  %s... and %d more pages%s\n", colorGray, len(sources)-10, colorReset)
+			break
+		}
+		fmt.Printf("\n  %s%s%s\n", colorCyan, source, colorReset)
+		for j, link := range bySource[source] {
+			if j >= 5 {
+				fmt.Printf("    %s... and %d more%s\n", colorGray, len(bySource[source])-5, colorReset)
+				break
+			}
+			text := link.Text
+			if runes := []rune(text); len(runes) > 60 {
+				text = string(runes[:57]) + "..."
+			}
+			if text == "" {
+				text = "(no text)"
+			} else {
+				text = fmt.Sprintf("%q", text)
+			}
+			fmt.Printf("    %s%s%s %s(%s)%s\n", colorRed, text, colorReset, colorGray, link.Problem, colorReset)
+		}
+	}
+}
+
+// printRepeatedLinks lists the targets each page links to more than
+// MaxRepeats times, with how many distinct anchor texts they use
+func (r *AnalysisResult) printRepeatedLinks() {
+	if len(r.RepeatedLinks) == 0 {
+		return
+	}
+
+	var sources []string
+	bySource := make(map[string][]RepeatedLink)
+	for _, link := range r.RepeatedLinks {
+		if bySource[link.SourceURL] == nil {
+			sources = append(sources, link.SourceURL)
+		}
+		bySource[link.SourceURL] = append(bySource[link.SourceURL], link)
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sRepeated links (%d on %d page(s)):%s\n", colorBold, colorYellow, len(r.RepeatedLinks), len(sources), colorReset)
+	fmt.Printf("  %sThese targets are linked more than %d time(s) from the same page, e.g. a menu duplicated in header and footer%s\n", colorGray, r.MaxRepeats, colorReset)
+
+	for i, source := range sources {
+		if i >= 10 {
+			fmt.Printf("\n  %s... and %d more pages%s\n", colorGray, len(sources)-10, colorReset)
+			break
+		}
+		fmt.Printf("\n  %s%s%s\n", colorCyan, source, colorReset)
+		for j, link := range bySource[source] {
+			if j >= 5 {
+				fmt.Printf("    %s... and %d more%s\n", colorGray, len(bySource[source])-5, colorReset)
+				break
+			}
+			texts := "identical anchor text"
+			if len(link.Texts) > 1 {
+				texts = fmt.Sprintf("%d different anchor texts", len(link.Texts))
+			}
+			fmt.Printf("    %s%d×%s %s %s(%s)%s\n", colorYellow, link.Count, colorReset, link.URL, colorGray, texts, colorReset)
+		}
+	}
 }
 
 func (r *AnalysisResult) printNonAnalyzableDetails() {
@@ -142,7 +576,8 @@ func (r *AnalysisResult) printNonAnalyzableDetails() {
 	// External links grouped by host
 	if links := r.LinksByType[LinkTypeExternal]; len(links) > 0 {
 		fmt.Println()
-		fmt.Printf("%s%sExternal Links (%d):%s\n", colorBold, colorYellow, len(links), colorReset)
+		fmt.Printf("%s%sExternal Links (%d):%s\n", colorBold, colorYellow, r.CountByType[LinkTypeExternal], colorReset)
+		r.printStoredNote(LinkTypeExternal)
 
 		// Group by host
 		byHost := make(map[string][]Link)
@@ -176,7 +611,8 @@ func (r *AnalysisResult) printNonAnalyzableDetails() {
 	// File links grouped by type
 	if links := r.LinksByType[LinkTypeFile]; len(links) > 0 {
 		fmt.Println()
-		fmt.Printf("%s%sFile/Document Links (%d):%s\n", colorBold, colorYellow, len(links), colorReset)
+		fmt.Printf("%s%sFile/Document Links (%d):%s\n", colorBold, colorYellow, r.CountByType[LinkTypeFile], colorReset)
+		r.printStoredNote(LinkTypeFile)
 
 		// Group by file type
 		byType := make(map[string][]Link)
@@ -207,7 +643,8 @@ func (r *AnalysisResult) printNonAnalyzableDetails() {
 	// Email links
 	if links := r.LinksByType[LinkTypeMailto]; len(links) > 0 {
 		fmt.Println()
-		fmt.Printf("%s%sEmail Links (%d):%s\n", colorBold, colorYellow, len(links), colorReset)
+		fmt.Printf("%s%sEmail Links (%d):%s\n", colorBold, colorYellow, r.CountByType[LinkTypeMailto], colorReset)
+		r.printStoredNote(LinkTypeMailto)
 		seen := make(map[string]bool)
 		for _, link := range links {
 			email := extractEmail(link.URL)
@@ -221,7 +658,8 @@ func (r *AnalysisResult) printNonAnalyzableDetails() {
 	// Phone links
 	if links := r.LinksByType[LinkTypeTel]; len(links) > 0 {
 		fmt.Println()
-		fmt.Printf("%s%sPhone Links (%d):%s\n", colorBold, colorYellow, len(links), colorReset)
+		fmt.Printf("%s%sPhone Links (%d):%s\n", colorBold, colorYellow, r.CountByType[LinkTypeTel], colorReset)
+		r.printStoredNote(LinkTypeTel)
 		seen := make(map[string]bool)
 		for _, link := range links {
 			phone := extractPhone(link.URL)
@@ -235,15 +673,15 @@ func (r *AnalysisResult) printNonAnalyzableDetails() {
 	// JavaScript links
 	if links := r.LinksByType[LinkTypeJavaScript]; len(links) > 0 {
 		fmt.Println()
-		fmt.Printf("%s%sJavaScript Links (%d):%s\n", colorBold, colorYellow, len(links), colorReset)
+		fmt.Printf("%s%sJavaScript Links (%d):%s\n", colorBold, colorYellow, r.CountByType[LinkTypeJavaScript], colorReset)
 		fmt.Printf("  %sThese links use JavaScript and cannot be statically analyzed%s\n", colorGray, colorReset)
 	}
 
 	// Anchor links
 	if links := r.LinksByType[LinkTypeAnchor]; len(links) > 0 {
 		fmt.Println()
-		fmt.Printf("%s%sAnchor Links (%d):%s\n", colorBold, colorYellow, len(links), colorReset)
-		fmt.Printf("  %sThese are in-page navigation links%s\n", colorGray, colorReset)
+		fmt.Printf("%s%sAnchor Links (%d):%s\n", colorBold, colorYellow, r.CountByType[LinkTypeAnchor], colorReset)
+		fmt.Printf("  %sThese are in-page navigation links, checked against the ids of their page%s\n", colorGray, colorReset)
 	}
 
 	fmt.Println()
@@ -286,3 +724,11 @@ func extractPhone(tel string) string {
 	}
 	return tel
 }
+
+// printStoredNote tells when the listed links of a type are only the
+// first MaxStoredExamples
+func (r *AnalysisResult) printStoredNote(t LinkType) {
+	if stored := len(r.LinksByType[t]); stored < r.CountByType[t] {
+		fmt.Printf("  %sOnly the first %d were kept%s\n", colorGray, stored, colorReset)
+	}
+}
@@ -1,36 +1,63 @@
 package audit
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
+	"os"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
 	"github.com/ngonzalez/web-tools/internal/analyzer"
 	"github.com/ngonzalez/web-tools/internal/canonical"
+	"github.com/ngonzalez/web-tools/internal/crawl"
 	"github.com/ngonzalez/web-tools/internal/crawler"
+	"github.com/ngonzalez/web-tools/internal/httpcache"
+	"github.com/ngonzalez/web-tools/internal/httppool"
 	"github.com/ngonzalez/web-tools/internal/indexer"
 	"github.com/ngonzalez/web-tools/internal/latency"
 	"github.com/ngonzalez/web-tools/internal/pagerank"
+	"github.com/ngonzalez/web-tools/internal/robots"
 	"github.com/ngonzalez/web-tools/internal/serp"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 // Config holds auditor configuration
 type Config struct {
-	Concurrency int
-	Timeout     time.Duration
-	MaxDepth    int
-	Verbose     bool
+	Concurrency         int
+	Timeout             time.Duration
+	MaxDepth            int
+	Verbose             bool
+	Verbosity           int           // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Warn
+	CacheBytes          int64         // Max size of the shared response cache, 0 disables it
+	TreatSchemesAsSame  bool          // Collapse http:// and https:// URLs of the same page
+	KeepHTTP            bool          // Crawl http:// URLs as is even on a site enforcing HTTPS
+	Delay               time.Duration // Minimum pause between two requests of a sub-check
+	Thresholds          Thresholds    // Issue severity escalation, negative fields use the defaults
+	MinSize             int64         // Body size under which a 200 HTML page is suspiciously empty, 0 disables the check
+	MaxIdleConnsPerHost int           // Idle connections kept per host, 0 matches Concurrency
+	MaxRedirects        int           // Redirects followed per request, 0 = none, -1 = up to httppool.MaxRedirectsCap
+	HTMLContentTypes    []string      // Extra media types parsed as HTML, besides contenttype.HTML
+	ASCII               bool          // Draw bar graphs with # and - instead of block characters
+	Quiet               bool          // No step headers, e.g. when stdout carries JSON
+	Phases              []Phase       // Phases to run, in AllPhases order; nil runs them all
 }
 
 // DefaultConfig returns default configuration
 func DefaultConfig() Config {
 	return Config{
-		Concurrency: 10,
-		Timeout:     15 * time.Second,
-		MaxDepth:    0,
-		Verbose:     false,
+		Concurrency:  10,
+		Timeout:      15 * time.Second,
+		MaxDepth:     0,
+		Verbose:      false,
+		CacheBytes:   64 << 20,
+		Thresholds:   DefaultThresholds(),
+		MinSize:      latency.DefaultMinSize,
+		MaxRedirects: httppool.DefaultMaxRedirects,
 	}
 }
 
@@ -38,13 +65,56 @@ func DefaultConfig() Config {
 type Auditor struct {
 	config Config
 	result *AuditResult
+	cache  *httpcache.Transport
+	robots *robots.Cache // One robots.txt fetch shared by every sub-check
+
+	// Status of every URL the link checker saw fail, for cross-checks
+	brokenStatus map[string]int
+
+	// Declared canonical of every page the canonical checker crawled
+	canonicals map[string]string
+
+	// HTTPS policy the link checker detected, to match http:// and https://
+	// forms of the same URL
+	https httppool.HTTPSPolicy
+
+	// Pages the latency crawl fetched successfully, or else the pages the
+	// PageRank crawl ranked, for site-wide checks
+	pages []string
 }
 
 // New creates a new Auditor
 func New(config Config) *Auditor {
-	return &Auditor{
-		config: config,
+	// The audit's own step summaries were what Verbose used to show
+	if config.Verbosity == verbosity.Quiet && config.Verbose {
+		config.Verbosity = verbosity.Warn
 	}
+	a := &Auditor{
+		config:       config,
+		robots:       robots.NewCache(),
+		brokenStatus: make(map[string]int),
+	}
+	if config.CacheBytes > 0 {
+		a.cache = httpcache.New(httppool.New(config.Concurrency, config.MaxIdleConnsPerHost), config.CacheBytes)
+	}
+	return a
+}
+
+// subVerbosity returns the level passed to the sub-checks: they stay quiet
+// unless every URL was asked for (-vv and above)
+func (a *Auditor) subVerbosity() int {
+	if a.config.Verbosity < verbosity.Info {
+		return verbosity.Quiet
+	}
+	return a.config.Verbosity
+}
+
+// transport returns the shared caching transport, or nil when caching is disabled
+func (a *Auditor) transport() http.RoundTripper {
+	if a.cache == nil {
+		return nil
+	}
+	return a.cache
 }
 
 // Run executes the full audit
@@ -59,66 +129,131 @@ func (a *Auditor) Run(targetURL string) (*AuditResult, error) {
 		return nil, fmt.Errorf("URL must use http or https scheme")
 	}
 
+	if err := a.config.Thresholds.Validate(); err != nil {
+		return nil, err
+	}
+
 	a.result = &AuditResult{
 		URL:       targetURL,
 		StartTime: time.Now(),
+		ASCII:     a.config.ASCII,
+		Phases:    a.config.Phases,
+	}
+
+	// Run the selected checks, numbered among those only
+	phases := a.config.Phases
+	if len(phases) == 0 {
+		phases = AllPhases
+	}
+	for i, phase := range phases {
+		switch phase {
+		case PhaseBroken:
+			a.step(i+1, len(phases), "Analyzing broken links...")
+			a.runBrokenLinksCheck(targetURL)
+		case PhaseLinks:
+			a.step(i+1, len(phases), "Analyzing non-analyzable links...")
+			a.runAnalyzerCheck(targetURL)
+		case PhaseIndexability:
+			a.step(i+1, len(phases), "Analyzing indexability...")
+			a.runIndexerCheck(targetURL)
+		case PhaseCanonical:
+			a.step(i+1, len(phases), "Checking canonicals...")
+			a.runCanonicalCheck(targetURL)
+		case PhasePerformance:
+			a.step(i+1, len(phases), "Measuring performance...")
+			a.runLatencyCheck(targetURL)
+		case PhaseSEO:
+			a.step(i+1, len(phases), "Analyzing SEO and PageRank...")
+			a.runSEOCheck(targetURL)
+			a.runPageRankCheck(targetURL)
+			a.runH1Check()
+		}
 	}
 
-	// Run all checks
-	fmt.Printf("\n%s%s[1/6]%s Analyzing broken links...\n", colorBold, colorCyan, colorReset)
-	a.runBrokenLinksCheck(targetURL)
-
-	fmt.Printf("%s%s[2/6]%s Analyzing non-analyzable links...\n", colorBold, colorCyan, colorReset)
-	a.runAnalyzerCheck(targetURL)
-
-	fmt.Printf("%s%s[3/6]%s Analyzing indexability...\n", colorBold, colorCyan, colorReset)
-	a.runIndexerCheck(targetURL)
-
-	fmt.Printf("%s%s[4/6]%s Checking canonicals...\n", colorBold, colorCyan, colorReset)
-	a.runCanonicalCheck(targetURL)
-
-	fmt.Printf("%s%s[5/6]%s Measuring performance...\n", colorBold, colorCyan, colorReset)
-	a.runLatencyCheck(targetURL)
-
-	fmt.Printf("%s%s[6/6]%s Analyzing SEO and PageRank...\n", colorBold, colorCyan, colorReset)
-	a.runSEOCheck(targetURL)
-	a.runPageRankCheck(targetURL)
-
 	a.result.EndTime = time.Now()
 	a.result.Duration = a.result.EndTime.Sub(a.result.StartTime)
+	a.result.CrawlStats.Duration = a.result.Duration
+
+	if a.cache != nil && a.config.Verbosity >= verbosity.Warn {
+		hits, misses := a.cache.Stats()
+		fmt.Fprintf(os.Stderr, "  %sResponse cache: %d hits, %d misses%s\n", colorGray, hits, misses, colorReset)
+	}
 
 	// Calculate scores and build issues
 	a.result.CalculateScores()
-	a.result.BuildIssues()
+	a.result.BuildIssues(a.config.Thresholds)
+	a.result.EstimateCrawlBudget(a.config.Concurrency)
 
 	return a.result, nil
 }
 
+// step announces the next of the checks run
+func (a *Auditor) step(n, total int, title string) {
+	if a.config.Quiet {
+		return
+	}
+	if n == 1 {
+		fmt.Println()
+	}
+	fmt.Printf("%s%s[%d/%d]%s %s\n", colorBold, colorCyan, n, total, colorReset, title)
+}
+
 func (a *Auditor) runBrokenLinksCheck(targetURL string) {
 	config := crawler.Config{
-		Concurrency: a.config.Concurrency,
-		Timeout:     a.config.Timeout,
-		MaxDepth:    a.config.MaxDepth,
-		Verbose:     false,
+		Concurrency:         a.config.Concurrency,
+		Timeout:             a.config.Timeout,
+		MaxDepth:            a.config.MaxDepth,
+		Verbosity:           a.subVerbosity(),
+		Transport:           a.transport(),
+		TreatSchemesAsSame:  a.config.TreatSchemesAsSame,
+		KeepHTTP:            a.config.KeepHTTP,
+		Delay:               a.config.Delay,
+		MaxIdleConnsPerHost: a.config.MaxIdleConnsPerHost,
+		MaxRedirects:        a.config.MaxRedirects,
+		HTMLContentTypes:    a.config.HTMLContentTypes,
 	}
 
 	c := crawler.New(config)
 	result, err := c.Crawl(targetURL)
 	if err != nil {
-		if a.config.Verbose {
-			fmt.Printf("  %sError: %v%s\n", colorRed, err, colorReset)
+		if a.config.Verbosity >= verbosity.Warn {
+			fmt.Fprintf(os.Stderr, "  %sError: %v%s\n", colorRed, err, colorReset)
 		}
 		return
 	}
 
+	a.result.CrawlStats = a.result.CrawlStats.Add(result.CrawlStats)
 	a.result.BrokenLinks = len(result.BrokenLinks)
 	for _, bl := range result.BrokenLinks {
 		a.result.BrokenURLs = append(a.result.BrokenURLs, bl.BrokenURL)
+		a.brokenStatus[result.HTTPS.Upgrade(bl.BrokenURL)] = bl.StatusCode
+	}
+	for _, er := range result.ExternalRedirects {
+		a.result.ExternalRedirects = append(a.result.ExternalRedirects, er.LinkURL+" → "+er.FinalURL)
+	}
+	for _, form := range result.InsecureForms {
+		a.result.InsecureForms = append(a.result.InsecureForms, fmt.Sprintf("%s (%s)", form.PageURL, form.Reason))
+	}
+	for _, h := range result.Headers {
+		switch {
+		case len(h.Missing) == 0:
+		case !h.Security:
+			a.result.NoCacheControl = h.Missing
+		case h.Present() == 0:
+			a.result.MissingHeaders = append(a.result.MissingHeaders, h.Header+" (not set)")
+		default:
+			a.result.MissingHeaders = append(a.result.MissingHeaders, fmt.Sprintf("%s (%d%% of pages lack it)", h.Header, h.MissingPercent()))
+		}
+	}
+	a.https = result.HTTPS
+	if result.HTTPS.Enforced() {
+		a.result.EnforcesHTTPS = result.HTTPS.String()
 	}
 	a.result.TotalVisited(result.TotalVisited)
+	a.result.LinksChecked = result.TotalVisited
 
-	if a.config.Verbose {
-		fmt.Printf("  %s✓ %d broken links found%s\n", colorGray, a.result.BrokenLinks, colorReset)
+	if a.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "  %s✓ %d broken links found%s\n", colorGray, a.result.BrokenLinks, colorReset)
 	}
 }
 
@@ -129,65 +264,113 @@ func (r *AuditResult) TotalVisited(count int) {
 }
 
 func (a *Auditor) runAnalyzerCheck(targetURL string) {
+	// The analyzer counts levels of pages, the start page being the first,
+	// where the audit's depth counts the links followed from it
+	depth := a.config.MaxDepth
+	if depth > 0 {
+		depth++
+	}
+
 	config := analyzer.Config{
-		Concurrency: a.config.Concurrency,
-		Timeout:     a.config.Timeout,
-		MaxDepth:    a.config.MaxDepth,
-		Verbose:     false,
+		Concurrency:         a.config.Concurrency,
+		Timeout:             a.config.Timeout,
+		MaxDepth:            depth,
+		Verbosity:           a.subVerbosity(),
+		Transport:           a.transport(),
+		TreatSchemesAsSame:  a.config.TreatSchemesAsSame,
+		KeepHTTP:            a.config.KeepHTTP,
+		Delay:               a.config.Delay,
+		MaxIdleConnsPerHost: a.config.MaxIdleConnsPerHost,
+		MaxRedirects:        a.config.MaxRedirects,
+		HTMLContentTypes:    a.config.HTMLContentTypes,
+		URLLimits:           analyzer.DefaultURLLimits,
+		FollowInternal:      true,
 	}
 
 	az := analyzer.New(config)
 	result, err := az.Analyze(targetURL)
 	if err != nil {
-		if a.config.Verbose {
-			fmt.Printf("  %sError: %v%s\n", colorRed, err, colorReset)
+		if a.config.Verbosity >= verbosity.Warn {
+			fmt.Fprintf(os.Stderr, "  %sError: %v%s\n", colorRed, err, colorReset)
 		}
 		return
 	}
 
+	a.result.CrawlStats = a.result.CrawlStats.Add(result.CrawlStats)
 	a.result.TotalVisited(result.TotalPages)
 	a.result.TotalLinks = result.TotalLinks
 
 	// Count by type
-	for linkType, links := range result.LinksByType {
-		switch linkType {
-		case analyzer.LinkTypeExternal:
-			a.result.ExternalLinks = len(links)
-		case analyzer.LinkTypeFile:
-			a.result.FileLinks = len(links)
-		case analyzer.LinkTypeMailto:
-			a.result.MailtoLinks = len(links)
-		case analyzer.LinkTypeJavaScript:
-			a.result.JSLinks = len(links)
+	a.result.ExternalLinks = result.CountByType[analyzer.LinkTypeExternal]
+	a.result.FileLinks = result.CountByType[analyzer.LinkTypeFile]
+	a.result.MailtoLinks = result.CountByType[analyzer.LinkTypeMailto]
+	a.result.JSLinks = result.CountByType[analyzer.LinkTypeJavaScript]
+	a.result.TrailingSlashPairs = len(result.TrailingSlashInconsistencies())
+	a.result.MalformedLinks = len(result.MalformedLinks)
+
+	// Query string variants, for the crawl budget
+	seen := make(map[string]bool)
+	variants := make(map[string]int)
+	for _, link := range result.LinksByType[analyzer.LinkTypeInternal] {
+		if seen[link.URL] {
+			continue
+		}
+		seen[link.URL] = true
+		a.result.InternalURLs++
+		if parsed, err := url.Parse(link.URL); err == nil && parsed.RawQuery != "" {
+			a.result.ParamURLs++
+			variants[parsed.Path]++
+		}
+	}
+	for path, count := range variants {
+		if count > ParamVariantsLimit {
+			a.result.ParamHeavyPaths = append(a.result.ParamHeavyPaths, fmt.Sprintf("%s (%d variants)", path, count))
 		}
 	}
+	sort.Strings(a.result.ParamHeavyPaths)
+	for _, long := range result.LongURLs {
+		a.result.LongURLs = append(a.result.LongURLs, fmt.Sprintf("%s (%s)", long.URL, strings.Join(long.Reasons, ", ")))
+	}
 
-	if a.config.Verbose {
-		fmt.Printf("  %s✓ %d external links, %d files%s\n", colorGray, a.result.ExternalLinks, a.result.FileLinks, colorReset)
+	if a.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "  %s✓ %d external links, %d files%s\n", colorGray, a.result.ExternalLinks, a.result.FileLinks, colorReset)
 	}
 }
 
 func (a *Auditor) runIndexerCheck(targetURL string) {
 	config := indexer.Config{
-		Concurrency:    a.config.Concurrency,
-		Timeout:        a.config.Timeout,
-		MaxDepth:       a.config.MaxDepth,
-		Verbose:        false,
-		CheckRobotsTxt: true,
+		Concurrency:         a.config.Concurrency,
+		Timeout:             a.config.Timeout,
+		MaxDepth:            a.config.MaxDepth,
+		Verbosity:           a.subVerbosity(),
+		CheckRobotsTxt:      true,
+		Transport:           a.transport(),
+		TreatSchemesAsSame:  a.config.TreatSchemesAsSame,
+		KeepHTTP:            a.config.KeepHTTP,
+		RobotsCache:         a.robots,
+		Delay:               a.config.Delay,
+		MaxIdleConnsPerHost: a.config.MaxIdleConnsPerHost,
+		MaxRedirects:        a.config.MaxRedirects,
+		HTMLContentTypes:    a.config.HTMLContentTypes,
 	}
 
 	idx := indexer.New(config)
 	result, err := idx.Analyze(targetURL)
 	if err != nil {
-		if a.config.Verbose {
-			fmt.Printf("  %sError: %v%s\n", colorRed, err, colorReset)
+		if a.config.Verbosity >= verbosity.Warn {
+			fmt.Fprintf(os.Stderr, "  %sError: %v%s\n", colorRed, err, colorReset)
 		}
 		return
 	}
 
+	a.result.CrawlStats = a.result.CrawlStats.Add(result.CrawlStats)
 	a.result.TotalVisited(result.TotalPages)
 	a.result.NoIndexPages = len(result.PagesWithNoIndex)
 
+	a.result.InternalNoFollowLinks = len(result.InternalNoFollow())
+	a.result.InternalRobotsBlocked = len(result.InternalRobotsBlocked())
+	a.result.RobotsConflicts = len(result.RobotsConflicts)
+
 	// Count nofollow links
 	for reason, issues := range result.ByReason {
 		switch reason {
@@ -198,65 +381,145 @@ func (a *Auditor) runIndexerCheck(targetURL string) {
 		}
 	}
 
-	if a.config.Verbose {
-		fmt.Printf("  %s✓ %d noindex pages, %d nofollow links%s\n", colorGray, a.result.NoIndexPages, a.result.NoFollowLinks, colorReset)
+	if a.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "  %s✓ %d noindex pages, %d nofollow links%s\n", colorGray, a.result.NoIndexPages, a.result.NoFollowLinks, colorReset)
 	}
 }
 
 func (a *Auditor) runCanonicalCheck(targetURL string) {
 	config := canonical.Config{
-		Concurrency: a.config.Concurrency,
-		Timeout:     a.config.Timeout,
-		MaxDepth:    a.config.MaxDepth,
-		Verbose:     false,
+		Concurrency:         a.config.Concurrency,
+		Timeout:             a.config.Timeout,
+		MaxDepth:            a.config.MaxDepth,
+		Verbosity:           a.subVerbosity(),
+		Transport:           a.transport(),
+		TreatSchemesAsSame:  a.config.TreatSchemesAsSame,
+		KeepHTTP:            a.config.KeepHTTP,
+		Delay:               a.config.Delay,
+		MaxIdleConnsPerHost: a.config.MaxIdleConnsPerHost,
+		MaxRedirects:        a.config.MaxRedirects,
+		HTMLContentTypes:    a.config.HTMLContentTypes,
 	}
 
 	checker := canonical.New(config)
 	result, err := checker.Check(targetURL)
 	if err != nil {
-		if a.config.Verbose {
-			fmt.Printf("  %sError: %v%s\n", colorRed, err, colorReset)
+		if a.config.Verbosity >= verbosity.Warn {
+			fmt.Fprintf(os.Stderr, "  %sError: %v%s\n", colorRed, err, colorReset)
 		}
 		return
 	}
 
+	a.result.CrawlStats = a.result.CrawlStats.Add(result.CrawlStats)
 	a.result.TotalVisited(result.TotalPages)
-	a.result.MissingCanonical = len(result.ByType[canonical.IssueMissingCanonical])
-	a.result.MismatchCanonical = len(result.ByType[canonical.IssueCanonicalMismatch]) + len(result.ByType[canonical.IssueNonCanonicalLink])
-	a.result.RedirectToCanonical = len(result.ByType[canonical.IssueRedirectToCanonical])
+	a.result.CanonicalPages = result.TotalPages
+	a.result.DuplicateNoCanonical = result.CountByType[canonical.IssueDuplicateNoCanonical]
+	a.result.MissingCanonical = result.CountByType[canonical.IssueMissingCanonical] + a.result.DuplicateNoCanonical
+	a.result.MismatchCanonical = result.CountByType[canonical.IssueCanonicalMismatch] + result.CountByType[canonical.IssueNonCanonicalLink]
+	a.result.StrippedParamsCanonical = result.CountByType[canonical.IssueCanonicalStripsParams]
+	a.result.RedirectToCanonical = result.CountByType[canonical.IssueRedirectToCanonical]
+	a.result.TemporaryRedirects = result.CountByType[canonical.IssueTemporaryRedirect]
+	a.result.HreflangCanonical = result.CountByType[canonical.IssueHreflangCanonical]
+	for _, issue := range result.ByType[canonical.IssueCanonicalLoop] {
+		a.result.CanonicalLoops = append(a.result.CanonicalLoops, strings.Join(issue.Loop, " → ")+" → "+issue.Loop[0])
+	}
+	for _, issue := range result.ByType[canonical.IssueMassCanonical] {
+		a.result.MassCanonicals = append(a.result.MassCanonicals, fmt.Sprintf("%s (%d pages)", issue.LinkedURL, len(issue.Pages)))
+	}
+	a.result.MultipleCanonical = result.CountByType[canonical.IssueMultipleCanonicals]
+	a.result.BodyCanonical = result.CountByType[canonical.IssueCanonicalInBody]
+	a.result.CrossDomainCanonical = result.CountByType[canonical.IssueCrossDomainCanonical]
+
+	seenHosts := make(map[string]bool)
+	for _, issue := range result.ByType[canonical.IssueCrossDomainCanonical] {
+		pair := issue.PageHost + " → " + issue.CanonicalHost
+		if !seenHosts[pair] {
+			seenHosts[pair] = true
+			a.result.CrossDomainHosts = append(a.result.CrossDomainHosts, pair)
+		}
+	}
+
+	a.canonicals = result.Canonicals
 
-	if a.config.Verbose {
-		fmt.Printf("  %s✓ %d missing canonical, %d incorrect%s\n", colorGray, a.result.MissingCanonical, a.result.MismatchCanonical, colorReset)
+	// The canonical checker only sees the declared URL; the link checker knows
+	// whether it actually loads
+	for page, target := range result.Canonicals {
+		if status, broken := a.brokenStatus[a.https.Upgrade(target)]; broken {
+			a.result.BrokenCanonicals = append(a.result.BrokenCanonicals, BrokenCanonical{
+				PageURL:      page,
+				CanonicalURL: target,
+				StatusCode:   status,
+			})
+		}
+	}
+	sort.Slice(a.result.BrokenCanonicals, func(i, j int) bool {
+		return a.result.BrokenCanonicals[i].PageURL < a.result.BrokenCanonicals[j].PageURL
+	})
+
+	a.result.RedirectedURLs = len(result.RedirectHops)
+	for u, hops := range result.RedirectHops {
+		if hops >= 2 {
+			a.result.RedirectChains++
+			a.result.RedirectChainURLs = append(a.result.RedirectChainURLs, fmt.Sprintf("%s (%d hops)", u, hops))
+		}
+	}
+	sort.Strings(a.result.RedirectChainURLs)
+
+	if a.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "  %s✓ %d missing canonical, %d incorrect%s\n", colorGray, a.result.MissingCanonical, a.result.MismatchCanonical, colorReset)
 	}
 }
 
 func (a *Auditor) runLatencyCheck(targetURL string) {
+	// Latency is measured against the network, never through the response cache
 	config := latency.Config{
-		Concurrency: a.config.Concurrency,
-		Timeout:     a.config.Timeout,
-		MaxDepth:    a.config.MaxDepth,
-		Verbose:     false,
+		Concurrency:         a.config.Concurrency,
+		Timeout:             a.config.Timeout,
+		MaxDepth:            a.config.MaxDepth,
+		Verbosity:           a.subVerbosity(),
+		TreatSchemesAsSame:  a.config.TreatSchemesAsSame,
+		KeepHTTP:            a.config.KeepHTTP,
+		Delay:               a.config.Delay,
+		MaxIdleConnsPerHost: a.config.MaxIdleConnsPerHost,
+		MaxRedirects:        a.config.MaxRedirects,
+		HTMLContentTypes:    a.config.HTMLContentTypes,
+		MinSize:             a.config.MinSize,
 	}
 
 	m := latency.New(config)
 	result, err := m.Measure(targetURL)
 	if err != nil {
-		if a.config.Verbose {
-			fmt.Printf("  %sError: %v%s\n", colorRed, err, colorReset)
+		if a.config.Verbosity >= verbosity.Warn {
+			fmt.Fprintf(os.Stderr, "  %sError: %v%s\n", colorRed, err, colorReset)
 		}
 		return
 	}
 
+	a.result.CrawlStats = a.result.CrawlStats.Add(result.CrawlStats)
 	a.result.TotalVisited(len(result.Pages))
+	a.result.MeasuredPages = len(result.Pages)
+
+	for _, page := range result.Pages {
+		if page.Error == "" && page.StatusCode >= 200 && page.StatusCode < 300 {
+			a.pages = append(a.pages, page.URL)
+		}
+	}
 
 	// Calculate stats
 	var totalDuration time.Duration
+	var totalSize int64
 	for _, page := range result.Pages {
 		if page.Error != "" {
 			continue
 		}
 
 		totalDuration += page.Duration
+		totalSize += page.Size
+
+		if page.Size > LargePageBytes {
+			a.result.LargePages++
+			a.result.LargePageURLs = append(a.result.LargePageURLs, page.URL)
+		}
 
 		if page.Duration > a.result.MaxLatency {
 			a.result.MaxLatency = page.Duration
@@ -272,24 +535,33 @@ func (a *Auditor) runLatencyCheck(targetURL string) {
 
 	if len(result.Pages) > 0 {
 		a.result.AvgLatency = totalDuration / time.Duration(len(result.Pages))
+		a.result.AvgPageSize = totalSize / int64(len(result.Pages))
+	}
+
+	a.result.RenderBlockingPages = len(result.HeavyRenderBlockingPages())
+	a.result.EmptyPageBytes = a.config.MinSize
+	for _, page := range result.EmptyPages() {
+		a.result.EmptyPages = append(a.result.EmptyPages, fmt.Sprintf("%s (%d bytes)", page.URL, page.Size))
 	}
+	sort.Strings(a.result.EmptyPages)
 
-	if a.config.Verbose {
-		fmt.Printf("  %s✓ Average latency: %v, %d slow pages%s\n", colorGray, a.result.AvgLatency.Round(time.Millisecond), a.result.SlowPages, colorReset)
+	if a.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "  %s✓ Average latency: %v, %d slow pages%s\n", colorGray, a.result.AvgLatency.Round(time.Millisecond), a.result.SlowPages, colorReset)
 	}
 }
 
 func (a *Auditor) runSEOCheck(targetURL string) {
 	config := serp.Config{
-		Timeout: a.config.Timeout,
-		Verbose: false,
+		Timeout:   a.config.Timeout,
+		Verbose:   a.subVerbosity() >= verbosity.Info,
+		Transport: a.transport(),
 	}
 
 	fetcher := serp.New(config)
 	meta, err := fetcher.Analyze(targetURL)
 	if err != nil {
-		if a.config.Verbose {
-			fmt.Printf("  %sError: %v%s\n", colorRed, err, colorReset)
+		if a.config.Verbosity >= verbosity.Warn {
+			fmt.Fprintf(os.Stderr, "  %sError: %v%s\n", colorRed, err, colorReset)
 		}
 		return
 	}
@@ -303,9 +575,18 @@ func (a *Auditor) runSEOCheck(targetURL string) {
 	a.result.HasCanonical = meta.Canonical != ""
 	a.result.HasH1 = meta.H1 != ""
 	a.result.SchemaTypes = meta.SchemaTypes
+	if meta.Keywords != "" {
+		a.result.LegacyMarkup = append(a.result.LegacyMarkup, fmt.Sprintf("meta keywords (%d)", meta.KeywordCount()))
+	}
+	a.result.LegacyMarkup = append(a.result.LegacyMarkup, meta.Deprecated...)
+	for _, rich := range meta.RichResults {
+		if !rich.Eligible() {
+			a.result.IneligibleRichResults = append(a.result.IneligibleRichResults, fmt.Sprintf("%s (missing %s)", rich.Type, strings.Join(rich.Missing, ", ")))
+		}
+	}
 
-	if a.config.Verbose {
-		fmt.Printf("  %s✓ Title: %v, Description: %v, OG: %v%s\n",
+	if a.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "  %s✓ Title: %v, Description: %v, OG: %v%s\n",
 			colorGray,
 			a.result.HasTitle,
 			a.result.HasMetaDescription,
@@ -314,29 +595,137 @@ func (a *Auditor) runSEOCheck(targetURL string) {
 	}
 }
 
+// runH1Check counts the H1 tags of every page the latency or PageRank crawl
+// found, so the H1 verdict covers the site rather than the homepage. Those
+// crawls bypass the response cache: only pages an earlier check fetched come
+// from it, the others are requested again, paced by Config.Delay.
+func (a *Auditor) runH1Check() {
+	if len(a.pages) == 0 {
+		return
+	}
+
+	fetcher := serp.New(serp.Config{
+		Timeout:   a.config.Timeout,
+		Verbose:   a.subVerbosity() >= verbosity.Info,
+		Transport: a.transport(),
+	})
+
+	seeds := make([]crawl.Task, 0, len(a.pages))
+	for _, page := range a.pages {
+		seeds = append(seeds, crawl.Task{URL: page})
+	}
+
+	// The pages are only checked, never crawled further
+	var mu sync.Mutex
+	engine := crawl.New(crawl.Config{
+		Concurrency: a.config.Concurrency,
+		MaxPages:    len(seeds),
+		Delay:       a.config.Delay,
+	}, func(ctx context.Context, task crawl.Task) []crawl.Task {
+		page := task.URL
+		meta, err := fetcher.Analyze(page)
+		if err != nil {
+			return nil // Not HTML, or gone since the crawl
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		a.result.H1PagesChecked++
+		switch {
+		case meta.H1Count == 0:
+			a.result.NoH1Pages = append(a.result.NoH1Pages, page)
+		case meta.H1Count > 1:
+			a.result.MultipleH1Pages = append(a.result.MultipleH1Pages, fmt.Sprintf("%s (%d H1)", page, meta.H1Count))
+		}
+		if meta.ClientRendered != "" {
+			a.result.ClientRendered = append(a.result.ClientRendered, fmt.Sprintf("%s (%s)", page, meta.ClientRendered))
+		}
+		return nil
+	})
+	engine.Run(context.Background(), seeds...)
+
+	sort.Strings(a.result.NoH1Pages)
+	sort.Strings(a.result.MultipleH1Pages)
+	sort.Strings(a.result.ClientRendered)
+
+	if a.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "  %s✓ H1 on %d pages: %d without, %d with several%s\n",
+			colorGray, a.result.H1PagesChecked, len(a.result.NoH1Pages), len(a.result.MultipleH1Pages), colorReset)
+	}
+}
+
+// linkedNonCanonicalMinInLinks is the number of linking pages from which a
+// canonicalized page counts as part of the site's navigation
+const linkedNonCanonicalMinInLinks = 3
+
+// findLinkedNonCanonicals crosses the canonical checker's data with the
+// link graph: a page canonicalizing to another URL, yet linked from many
+// pages, spends internal link equity on a URL the site itself disowns.
+// Neither check can see it alone.
+func (a *Auditor) findLinkedNonCanonicals(scores []pagerank.PageScore) {
+	opts := canonical.EquivalenceOptions{IgnoreScheme: a.config.TreatSchemesAsSame}
+	canonicalOf := make(map[string]string, len(a.canonicals))
+	for page, target := range a.canonicals {
+		canonicalOf[canonical.EquivalenceKey(page, opts)] = target
+	}
+
+	for _, page := range scores {
+		if page.InLinks < linkedNonCanonicalMinInLinks {
+			continue
+		}
+		target, ok := canonicalOf[canonical.EquivalenceKey(page.URL, opts)]
+		if !ok || canonical.URLsEquivalentWith(page.URL, target, opts) {
+			continue
+		}
+		a.result.LinkedNonCanonicals = append(a.result.LinkedNonCanonicals, LinkedNonCanonical{
+			URL:          page.URL,
+			CanonicalURL: target,
+			InLinks:      page.InLinks,
+			Score:        page.Score,
+		})
+	}
+
+	sort.Slice(a.result.LinkedNonCanonicals, func(i, j int) bool {
+		x, y := a.result.LinkedNonCanonicals[i], a.result.LinkedNonCanonicals[j]
+		if x.InLinks != y.InLinks {
+			return x.InLinks > y.InLinks
+		}
+		return x.URL < y.URL
+	})
+}
+
 func (a *Auditor) runPageRankCheck(targetURL string) {
 	config := pagerank.Config{
-		Concurrency:   a.config.Concurrency,
-		Timeout:       a.config.Timeout,
-		MaxDepth:      a.config.MaxDepth,
-		Verbose:       false,
-		DampingFactor: 0.85,
-		MaxIterations: 50,
+		Concurrency:         a.config.Concurrency,
+		Timeout:             a.config.Timeout,
+		MaxDepth:            a.config.MaxDepth,
+		Verbosity:           a.subVerbosity(),
+		DampingFactor:       0.85,
+		MaxIterations:       50,
+		Transport:           a.transport(),
+		TreatSchemesAsSame:  a.config.TreatSchemesAsSame,
+		KeepHTTP:            a.config.KeepHTTP,
+		Delay:               a.config.Delay,
+		MaxIdleConnsPerHost: a.config.MaxIdleConnsPerHost,
+		MaxRedirects:        a.config.MaxRedirects,
+		HTMLContentTypes:    a.config.HTMLContentTypes,
 	}
 
 	pr := pagerank.New(config)
 	result, err := pr.Crawl(targetURL)
 	if err != nil {
-		if a.config.Verbose {
-			fmt.Printf("  %sError: %v%s\n", colorRed, err, colorReset)
+		if a.config.Verbosity >= verbosity.Warn {
+			fmt.Fprintf(os.Stderr, "  %sError: %v%s\n", colorRed, err, colorReset)
 		}
 		return
 	}
 
+	a.result.CrawlStats = a.result.CrawlStats.Add(result.CrawlStats)
 	a.result.TotalVisited(result.TotalPages)
 	a.result.TotalLinks = result.TotalLinks
 
 	// Count orphan and dead-end pages
+	a.result.RankedPages = len(result.Scores)
 	for _, page := range result.Scores {
 		if page.InLinks == 0 {
 			a.result.OrphanPages++
@@ -346,6 +735,15 @@ func (a *Auditor) runPageRankCheck(targetURL string) {
 		}
 	}
 
+	a.findLinkedNonCanonicals(result.Scores)
+
+	// Without the performance phase, the H1 check covers the ranked pages
+	if len(a.pages) == 0 {
+		for _, page := range result.Scores {
+			a.pages = append(a.pages, page.URL)
+		}
+	}
+
 	// Get top pages
 	sorted := make([]pagerank.PageScore, len(result.Scores))
 	copy(sorted, result.Scores)
@@ -366,7 +764,7 @@ func (a *Auditor) runPageRankCheck(targetURL string) {
 		})
 	}
 
-	if a.config.Verbose {
-		fmt.Printf("  %s✓ %d orphan pages, %d dead-ends%s\n", colorGray, a.result.OrphanPages, a.result.DeadEndPages, colorReset)
+	if a.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "  %s✓ %d orphan pages, %d dead-ends%s\n", colorGray, a.result.OrphanPages, a.result.DeadEndPages, colorReset)
 	}
 }
@@ -0,0 +1,189 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Crawl budget heuristics. A search engine crawls a site at a rate bound by
+// the server's speed and spends that rate on every URL it finds, indexable
+// or not; these thresholds flag where the budget leaks.
+const (
+	ParamURLRatio      = 0.25    // Share of parameterized internal URLs above which parameters explode
+	ParamVariantsLimit = 10      // Query variants of one path above which the path is a facet trap
+	LargePageBytes     = 1 << 20 // HTML size above which a page is large
+	NoIndexRatio       = 0.10    // Share of crawled noindex pages above which crawling them wastes budget
+	SlowCrawlLatency   = time.Second
+)
+
+// BudgetWaster is a pattern that spends crawl budget on URLs that add
+// nothing to the index
+type BudgetWaster struct {
+	Name     string
+	Count    int
+	Detail   string
+	Signal   string // Check the numbers come from
+	Examples []string
+}
+
+// CrawlBudget estimates how a search engine would spend its crawl on the site
+type CrawlBudget struct {
+	Pages          int
+	IndexablePages int // Pages without noindex
+	AvgPageSize    int64
+	AvgLatency     time.Duration
+	SequentialTime time.Duration // One request at a time, as a cautious crawler: pages × average latency
+	ParallelTime   time.Duration // At the audit's concurrency
+	Concurrency    int
+	Wasters        []BudgetWaster
+}
+
+// EstimateCrawlBudget combines the page count, sizes, latency, indexability,
+// parameter and redirect data gathered by the sub-checks into r.CrawlBudget
+func (r *AuditResult) EstimateCrawlBudget(concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	b := CrawlBudget{
+		Pages:          r.TotalPages,
+		IndexablePages: max(r.TotalPages-r.NoIndexPages, 0),
+		AvgPageSize:    r.AvgPageSize,
+		AvgLatency:     r.AvgLatency,
+		SequentialTime: time.Duration(r.TotalPages) * r.AvgLatency,
+		Concurrency:    concurrency,
+	}
+	b.ParallelTime = b.SequentialTime / time.Duration(concurrency)
+
+	if r.InternalURLs > 0 {
+		ratio := float64(r.ParamURLs) / float64(r.InternalURLs)
+		if ratio > ParamURLRatio || len(r.ParamHeavyPaths) > 0 {
+			detail := fmt.Sprintf("%.0f%% of internal URLs carry query parameters", ratio*100)
+			if len(r.ParamHeavyPaths) > 0 {
+				detail += fmt.Sprintf(", %d path(s) with more than %d variants", len(r.ParamHeavyPaths), ParamVariantsLimit)
+			}
+			b.Wasters = append(b.Wasters, BudgetWaster{
+				Name:     "Parameter explosion",
+				Count:    r.ParamURLs,
+				Detail:   detail,
+				Signal:   "analyzer: internal link URLs",
+				Examples: r.ParamHeavyPaths,
+			})
+		}
+	}
+
+	if r.RedirectChains > 0 {
+		b.Wasters = append(b.Wasters, BudgetWaster{
+			Name:     "Redirect chains",
+			Count:    r.RedirectChains,
+			Detail:   fmt.Sprintf("URLs needing 2+ redirects, %d redirected URLs in total", r.RedirectedURLs),
+			Signal:   "canonical checker: redirects followed",
+			Examples: r.RedirectChainURLs,
+		})
+	}
+
+	if r.LargePages > 0 {
+		b.Wasters = append(b.Wasters, BudgetWaster{
+			Name:     "Large pages",
+			Count:    r.LargePages,
+			Detail:   fmt.Sprintf("HTML over %s", formatSize(LargePageBytes)),
+			Signal:   "latency: response sizes",
+			Examples: r.LargePageURLs,
+		})
+	}
+
+	if r.TotalPages > 0 && float64(r.NoIndexPages)/float64(r.TotalPages) > NoIndexRatio {
+		b.Wasters = append(b.Wasters, BudgetWaster{
+			Name:   "Crawled noindex pages",
+			Count:  r.NoIndexPages,
+			Detail: fmt.Sprintf("%.0f%% of crawled pages can't be indexed", float64(r.NoIndexPages)/float64(r.TotalPages)*100),
+			Signal: "indexer: noindex pages",
+		})
+	}
+
+	if r.DuplicateNoCanonical > 0 {
+		b.Wasters = append(b.Wasters, BudgetWaster{
+			Name:   "Duplicates without canonical",
+			Count:  r.DuplicateNoCanonical,
+			Detail: "Same content reached through several URLs",
+			Signal: "canonical checker: content hashes",
+		})
+	}
+
+	if r.AvgLatency > SlowCrawlLatency {
+		b.Wasters = append(b.Wasters, BudgetWaster{
+			Name:   "Slow responses",
+			Count:  r.SlowPages,
+			Detail: fmt.Sprintf("Average latency %v, crawlers slow down on slow servers", r.AvgLatency.Round(time.Millisecond)),
+			Signal: "latency: response times",
+		})
+	}
+
+	r.CrawlBudget = b
+}
+
+func (r *AuditResult) printCrawlBudget() {
+	b := r.CrawlBudget
+	if b.Pages == 0 {
+		return
+	}
+
+	fmt.Println(strings.Repeat("─", 80))
+	fmt.Printf("%s%s  CRAWL BUDGET%s\n", colorBold, colorCyan, colorReset)
+	fmt.Println(strings.Repeat("─", 80))
+	fmt.Println()
+
+	fmt.Printf("  %sPages crawled:%s         %d\n", colorGray, colorReset, b.Pages)
+	fmt.Printf("  %sIndexable pages:%s       %d\n", colorGray, colorReset, b.IndexablePages)
+	fmt.Printf("  %sAverage page size:%s     %s\n", colorGray, colorReset, formatSize(b.AvgPageSize))
+	fmt.Printf("  %sFull crawl, 1 request:%s %v\n", colorGray, colorReset, roundEstimate(b.SequentialTime))
+	fmt.Printf("  %sFull crawl, %d at once:%s %v\n", colorGray, b.Concurrency, colorReset, roundEstimate(b.ParallelTime))
+	fmt.Printf("  %sEstimates: pages × average latency, divided by parallel requests%s\n", colorGray, colorReset)
+	fmt.Println()
+
+	if len(b.Wasters) == 0 {
+		fmt.Printf("  %s✓ No crawl budget wasters detected%s\n\n", colorGreen, colorReset)
+		return
+	}
+
+	for _, w := range b.Wasters {
+		fmt.Printf("    %s• %s%s (%d)\n", colorYellow, w.Name, colorReset, w.Count)
+		fmt.Printf("      %s%s%s\n", colorGray, w.Detail, colorReset)
+		fmt.Printf("      %sSignal: %s%s\n", colorGray, w.Signal, colorReset)
+		for i, ex := range w.Examples {
+			if i >= 3 {
+				fmt.Printf("        %s... and %d more%s\n", colorGray, len(w.Examples)-3, colorReset)
+				break
+			}
+			if len(ex) > 60 {
+				ex = ex[:57] + "..."
+			}
+			fmt.Printf("        %s→ %s%s\n", colorGray, ex, colorReset)
+		}
+		fmt.Println()
+	}
+}
+
+// roundEstimate rounds a crawl time to seconds, or milliseconds for tiny sites
+func roundEstimate(d time.Duration) time.Duration {
+	if d < 10*time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
+func formatSize(bytes int64) string {
+	const (
+		KB = 1024
+		MB = KB * 1024
+	)
+
+	switch {
+	case bytes >= MB:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/MB)
+	case bytes >= KB:
+		return fmt.Sprintf("%.1fKB", float64(bytes)/KB)
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
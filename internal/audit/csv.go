@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"encoding/csv"
+	"strconv"
+	"strings"
+)
+
+// ExportIssuesCSV exports the issues to CSV, most severe first, for triage
+// in a spreadsheet or issue tracker. Examples are joined with "; ".
+func (r *AuditResult) ExportIssuesCSV() string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Write([]string{"category", "severity", "title", "description", "count", "suggestion", "examples"})
+	for _, issue := range r.Issues {
+		w.Write([]string{
+			string(issue.Category),
+			issue.Severity.String(),
+			issue.Title,
+			issue.Description,
+			strconv.Itoa(issue.Count),
+			issue.Suggestion,
+			strings.Join(issue.Examples, "; "),
+		})
+	}
+	w.Flush()
+	return sb.String()
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Phase is one of the six checks of an audit, named as on the command line
+type Phase string
+
+const (
+	PhaseBroken       Phase = "broken"       // Broken links
+	PhaseLinks        Phase = "links"        // Non-analyzable links
+	PhaseIndexability Phase = "indexability" // Noindex, nofollow, robots.txt
+	PhaseCanonical    Phase = "canonical"    // Canonicals and redirects
+	PhasePerformance  Phase = "performance"  // Page latency
+	PhaseSEO          Phase = "seo"          // Start page SEO, H1 and PageRank
+)
+
+// AllPhases lists every phase in the order an audit runs them
+var AllPhases = []Phase{PhaseBroken, PhaseLinks, PhaseIndexability, PhaseCanonical, PhasePerformance, PhaseSEO}
+
+// ParsePhases returns the phases to run from comma-separated --only and
+// --skip lists: those of only, or all of them when only is empty, minus
+// those of skip. Phases keep the audit order whatever the list order.
+func ParsePhases(only, skip string) ([]Phase, error) {
+	onlySet, err := parsePhaseList(only)
+	if err != nil {
+		return nil, fmt.Errorf("--only: %w", err)
+	}
+	skipSet, err := parsePhaseList(skip)
+	if err != nil {
+		return nil, fmt.Errorf("--skip: %w", err)
+	}
+
+	var phases []Phase
+	for _, p := range AllPhases {
+		if (len(onlySet) == 0 || onlySet[p]) && !skipSet[p] {
+			phases = append(phases, p)
+		}
+	}
+	if len(phases) == 0 {
+		return nil, fmt.Errorf("no phase left to run")
+	}
+	return phases, nil
+}
+
+// parsePhaseList parses a comma-separated list of phase names
+func parsePhaseList(s string) (map[Phase]bool, error) {
+	set := make(map[Phase]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, p := range AllPhases {
+			if Phase(name) == p {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown phase %q (want broken, links, indexability, canonical, performance or seo)", name)
+		}
+		set[Phase(name)] = true
+	}
+	return set, nil
+}
+
+// Ran reports whether the audit ran a phase. A result without phases
+// recorded comes from a full audit.
+func (r *AuditResult) Ran(p Phase) bool {
+	if len(r.Phases) == 0 {
+		return true
+	}
+	for _, ran := range r.Phases {
+		if ran == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Scored reports whether a score category had a phase to measure it.
+// Architecture draws on the PageRank of the SEO phase and on the canonical
+// phase, so either is enough.
+func (r *AuditResult) Scored(c Category) bool {
+	switch c {
+	case CategoryBrokenLinks:
+		return r.Ran(PhaseBroken)
+	case CategorySEO:
+		return r.Ran(PhaseSEO)
+	case CategoryPerformance:
+		return r.Ran(PhasePerformance)
+	case CategoryArchitecture:
+		return r.Ran(PhaseSEO) || r.Ran(PhaseCanonical)
+	}
+	return false
+}
+
+// HasScore reports whether the audit measured any score category. Without
+// one, as with --only links, the overall score means nothing and is not
+// shown.
+func (r *AuditResult) HasScore() bool {
+	for _, c := range []Category{CategoryBrokenLinks, CategorySEO, CategoryPerformance, CategoryArchitecture} {
+		if r.Scored(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScoreValue returns a score for key=value output, "skipped" when its
+// category was not measured
+func (r *AuditResult) ScoreValue(c Category, score int) string {
+	if !r.Scored(c) {
+		return "skipped"
+	}
+	return fmt.Sprint(score)
+}
+
+// SkippedPhases lists the phases the audit did not run
+func (r *AuditResult) SkippedPhases() []Phase {
+	var skipped []Phase
+	for _, p := range AllPhases {
+		if !r.Ran(p) {
+			skipped = append(skipped, p)
+		}
+	}
+	return skipped
+}
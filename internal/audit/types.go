@@ -5,6 +5,9 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/ngonzalez/web-tools/internal/barchart"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
 )
 
 // Severity levels for issues
@@ -62,6 +65,7 @@ const (
 	CategoryPerformance   Category = "Performance"
 	CategorySEO           Category = "SEO"
 	CategoryArchitecture  Category = "Architecture"
+	CategorySecurity      Category = "Security"
 )
 
 // Issue represents a single audit issue
@@ -85,32 +89,74 @@ type AuditResult struct {
 	// Summary stats
 	TotalPages    int
 	TotalLinks    int
+	CrawlStats    crawlstats.Stats // Summed over all sub-crawls
+
+	// Denominators of the scores, each counted by the phase its score comes
+	// from rather than by whichever phase crawled the most pages
+	LinksChecked   int // URLs the broken links crawl checked
+	MeasuredPages  int // Pages the performance phase timed
+	RankedPages    int // Pages the PageRank crawl scored
+	CanonicalPages int // Pages the canonical phase crawled
 
 	// Broken links
 	BrokenLinks   int
 	BrokenURLs    []string
+	ExternalRedirects []string // "link → final URL" for internal links that redirect off-site
+	InsecureForms []string // "page (reason)" for forms sending their data over plain HTTP
+	MissingHeaders []string // "header (n% of pages lack it)" for security headers some pages don't send
+	EnforcesHTTPS string // How the site enforces HTTPS, e.g. "301 redirects from http://, HSTS"; empty if it does not
 
 	// Non-analyzable links
 	ExternalLinks int
 	FileLinks     int
 	MailtoLinks   int
 	JSLinks       int
+	TrailingSlashPairs int // Pages linked both with and without a trailing slash
+	MalformedLinks int     // <a> elements with an empty, blank or missing href
+	InternalURLs  int      // Distinct internal link URLs
+	ParamURLs     int      // Distinct internal link URLs with a query string
+	ParamHeavyPaths []string // "path (n variants)" for paths with many query variants
+	LongURLs      []string // "URL (reasons)" for internal URLs past analyzer.DefaultURLLimits
 
 	// Indexability
 	NoFollowLinks int
+	InternalNoFollowLinks int // Nofollow links pointing to the audited site
 	NoIndexPages  int
 	RobotBlocked  int
+	InternalRobotsBlocked int // Internal pages linked from the site but blocked by robots.txt
+	RobotsConflicts int // Pages whose meta robots and X-Robots-Tag disagree
 
 	// Canonicals
 	MissingCanonical   int
 	MismatchCanonical  int
 	RedirectToCanonical int
+	MultipleCanonical  int
+	BodyCanonical      int // Canonical tags placed in <body>, ignored by search engines
+	CrossDomainCanonical int
+	CrossDomainHosts   []string // "page host → canonical host" pairs
+	BrokenCanonicals   []BrokenCanonical // Canonical targets the link checker saw fail
+	DuplicateNoCanonical int // Missing canonicals on pages reached through several URLs
+	StrippedParamsCanonical int // Canonicals dropping some of the page's query parameters
+	RedirectedURLs     int      // Crawled URLs that redirected
+	RedirectChains     int      // Crawled URLs needing 2+ redirects
+	RedirectChainURLs  []string // "URL (n hops)" for redirect chains
+	TemporaryRedirects int      // Internal links answering a 302 or 307 that looks like a permanent move
+	HreflangCanonical  int      // Hreflang cluster members canonicalizing to another URL
+	CanonicalLoops     []string // "A → B → A" for canonicals pointing to each other in a loop
+	MassCanonicals     []string // "URL (n pages)" for canonical targets shared by most of the site
 
 	// Performance
 	SlowPages      int   // > 1s
 	VerySlowPages  int   // > 3s
+	RenderBlockingPages int // Many blocking scripts/stylesheets in <head>
 	AvgLatency     time.Duration
 	MaxLatency     time.Duration
+	AvgPageSize    int64
+	LargePages     int      // HTML over LargePageBytes
+	LargePageURLs  []string
+	EmptyPages     []string // "URL (n bytes)" for HTML pages answering 200 under EmptyPageBytes
+	EmptyPageBytes int64    // Config.MinSize
+	NoCacheControl []string // HTML pages answering without Cache-Control
 
 	// SEO (from start page)
 	HasTitle           bool
@@ -122,11 +168,21 @@ type AuditResult struct {
 	HasCanonical       bool
 	HasH1              bool
 	SchemaTypes        []string
+	IneligibleRichResults []string // "Type (missing properties)" for FAQPage, HowTo and Article objects of the start page
+	H1PagesChecked     int      // Pages whose H1 tags were counted, site-wide
+	NoH1Pages          []string // Pages without an H1
+	MultipleH1Pages    []string // "URL (n H1)" for pages with several H1 tags
+	ClientRendered     []string // "URL (evidence)" for pages that appear rendered by JavaScript
+	LegacyMarkup       []string // Meta keywords and deprecated tags of the start page
 
 	// PageRank
 	OrphanPages    int
 	DeadEndPages   int
 	TopPages       []PageRankInfo
+	LinkedNonCanonicals []LinkedNonCanonical // Canonicalized pages still linked from many pages
+
+	// Crawl budget, synthesized from the above
+	CrawlBudget CrawlBudget
 
 	// All issues
 	Issues []Issue
@@ -137,6 +193,9 @@ type AuditResult struct {
 	SEOScore         int
 	PerformanceScore int
 	ArchitectureScore int
+
+	ASCII  bool    // Draw score bars with # and -
+	Phases []Phase // Phases run, nil for all of them
 }
 
 // PageRankInfo holds basic PageRank info
@@ -146,6 +205,93 @@ type PageRankInfo struct {
 	InLinks int
 }
 
+// LinkedNonCanonical is a page declaring another URL as canonical that
+// still receives many internal links
+type LinkedNonCanonical struct {
+	URL          string
+	CanonicalURL string
+	InLinks      int     // Pages linking to URL
+	Score        float64 // PageRank of URL
+}
+
+// BrokenCanonical is a page whose canonical URL returns an error status
+type BrokenCanonical struct {
+	PageURL      string
+	CanonicalURL string
+	StatusCode   int // 0 when the request itself failed
+}
+
+// Thresholds sets when issues escalate to a higher severity. Negative
+// fields fall back to DefaultThresholds; 0 escalates on the first one.
+type Thresholds struct {
+	BrokenHigh     int     // Broken links above which the issue is high
+	BrokenCritical int     // Broken links above which the issue is critical
+	SlowRatio      float64 // Share of slow pages above which the issue is medium
+	VerySlowHigh   int     // Very slow pages above which the issue is high
+}
+
+// DefaultThresholds returns the default severity thresholds
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		BrokenHigh:     10,
+		BrokenCritical: 50,
+		SlowRatio:      0.25,
+		VerySlowHigh:   0,
+	}
+}
+
+// withDefaults fills the unset thresholds with the defaults
+func (t Thresholds) withDefaults() Thresholds {
+	d := DefaultThresholds()
+	if t.BrokenHigh < 0 {
+		t.BrokenHigh = d.BrokenHigh
+	}
+	if t.BrokenCritical < 0 {
+		t.BrokenCritical = d.BrokenCritical
+	}
+	if t.SlowRatio < 0 {
+		t.SlowRatio = d.SlowRatio
+	}
+	if t.VerySlowHigh < 0 {
+		t.VerySlowHigh = d.VerySlowHigh
+	}
+	return t
+}
+
+// Validate rejects thresholds escalating in the wrong order, once the
+// unset ones are filled in
+func (t Thresholds) Validate() error {
+	t = t.withDefaults()
+	if t.BrokenHigh > t.BrokenCritical {
+		return fmt.Errorf("broken links high threshold (%d) is above the critical one (%d)", t.BrokenHigh, t.BrokenCritical)
+	}
+	return nil
+}
+
+func statusText(code int) string {
+	if code == 0 {
+		return "an error"
+	}
+	return fmt.Sprintf("%d", code)
+}
+
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *AuditResult) SummaryLine() string {
+	overall := "skipped"
+	if r.HasScore() {
+		overall = fmt.Sprint(r.OverallScore)
+	}
+	return fmt.Sprintf("SUMMARY tool=siteaudit pages=%d links=%d broken=%d issues=%d score=%s broken_score=%s seo_score=%s performance_score=%s architecture_score=%s indexable=%d budget_wasters=%d no_h1=%d multiple_h1=%d linked_non_canonical=%d client_rendered=%d",
+		r.TotalPages, r.TotalLinks, r.BrokenLinks, len(r.Issues),
+		overall,
+		r.ScoreValue(CategoryBrokenLinks, r.BrokenLinksScore),
+		r.ScoreValue(CategorySEO, r.SEOScore),
+		r.ScoreValue(CategoryPerformance, r.PerformanceScore),
+		r.ScoreValue(CategoryArchitecture, r.ArchitectureScore),
+		r.CrawlBudget.IndexablePages, len(r.CrawlBudget.Wasters),
+		len(r.NoH1Pages), len(r.MultipleH1Pages), len(r.LinkedNonCanonicals), len(r.ClientRendered))
+}
+
 // ANSI colors
 const (
 	colorReset  = "\033[0m"
@@ -163,8 +309,8 @@ const (
 // CalculateScores calculates audit scores
 func (r *AuditResult) CalculateScores() {
 	// Broken Links Score (0-100)
-	if r.TotalLinks > 0 {
-		brokenRatio := float64(r.BrokenLinks) / float64(r.TotalLinks)
+	if r.LinksChecked > 0 {
+		brokenRatio := float64(r.BrokenLinks) / float64(r.LinksChecked)
 		r.BrokenLinksScore = 100 - int(brokenRatio*500) // -5 points per 1% broken
 		if r.BrokenLinksScore < 0 {
 			r.BrokenLinksScore = 0
@@ -190,7 +336,11 @@ func (r *AuditResult) CalculateScores() {
 	if r.HasCanonical {
 		seoPoints += 15
 	}
-	if r.HasH1 {
+	if r.H1PagesChecked > 0 {
+		// Share of the site's pages with exactly one H1
+		good := r.H1PagesChecked - len(r.NoH1Pages) - len(r.MultipleH1Pages)
+		seoPoints += 10 * good / r.H1PagesChecked
+	} else if r.HasH1 {
 		seoPoints += 10
 	}
 	if r.HasOGTags {
@@ -205,9 +355,9 @@ func (r *AuditResult) CalculateScores() {
 	r.SEOScore = seoPoints
 
 	// Performance Score (0-100)
-	if r.TotalPages > 0 {
-		slowRatio := float64(r.SlowPages) / float64(r.TotalPages)
-		verySlowRatio := float64(r.VerySlowPages) / float64(r.TotalPages)
+	if r.MeasuredPages > 0 {
+		slowRatio := float64(r.SlowPages) / float64(r.MeasuredPages)
+		verySlowRatio := float64(r.VerySlowPages) / float64(r.MeasuredPages)
 		r.PerformanceScore = 100 - int(slowRatio*50) - int(verySlowRatio*100)
 		if r.PerformanceScore < 0 {
 			r.PerformanceScore = 0
@@ -216,15 +366,17 @@ func (r *AuditResult) CalculateScores() {
 		r.PerformanceScore = 100
 	}
 
-	// Architecture Score (0-100)
+	// Architecture Score (0-100), from the link graph of the SEO phase and
+	// the canonical phase, whichever ran
 	archPoints := 100
-	if r.TotalPages > 0 {
-		orphanRatio := float64(r.OrphanPages) / float64(r.TotalPages)
-		deadEndRatio := float64(r.DeadEndPages) / float64(r.TotalPages)
-		canonicalIssues := float64(r.MissingCanonical+r.MismatchCanonical) / float64(r.TotalPages)
-
+	if r.RankedPages > 0 {
+		orphanRatio := float64(r.OrphanPages) / float64(r.RankedPages)
+		deadEndRatio := float64(r.DeadEndPages) / float64(r.RankedPages)
 		archPoints -= int(orphanRatio * 200)
 		archPoints -= int(deadEndRatio * 100)
+	}
+	if r.CanonicalPages > 0 {
+		canonicalIssues := float64(r.MissingCanonical+r.MismatchCanonical) / float64(r.CanonicalPages)
 		archPoints -= int(canonicalIssues * 100)
 	}
 	if archPoints < 0 {
@@ -232,21 +384,43 @@ func (r *AuditResult) CalculateScores() {
 	}
 	r.ArchitectureScore = archPoints
 
-	// Overall Score (weighted average)
-	r.OverallScore = (r.BrokenLinksScore*25 + r.SEOScore*25 + r.PerformanceScore*25 + r.ArchitectureScore*25) / 100
+	// Overall Score (weighted average of the categories measured; skipped
+	// ones would count as perfect, so they are left out, and an audit
+	// measuring none of them has no score, see HasScore)
+	total, weights := 0, 0
+	for _, s := range []struct {
+		category Category
+		score    int
+	}{
+		{CategoryBrokenLinks, r.BrokenLinksScore},
+		{CategorySEO, r.SEOScore},
+		{CategoryPerformance, r.PerformanceScore},
+		{CategoryArchitecture, r.ArchitectureScore},
+	} {
+		if r.Scored(s.category) {
+			total += s.score * 25
+			weights += 25
+		}
+	}
+	r.OverallScore = 100
+	if weights > 0 {
+		r.OverallScore = total / weights
+	}
 }
 
-// BuildIssues generates the issues list from results
-func (r *AuditResult) BuildIssues() {
+// BuildIssues generates the issues list from results, escalating severities
+// past the given thresholds
+func (r *AuditResult) BuildIssues(t Thresholds) {
 	r.Issues = nil
+	t = t.withDefaults()
 
 	// Broken links
 	if r.BrokenLinks > 0 {
 		severity := SeverityMedium
-		if r.BrokenLinks > 10 {
+		if r.BrokenLinks > t.BrokenHigh {
 			severity = SeverityHigh
 		}
-		if r.BrokenLinks > 50 {
+		if r.BrokenLinks > t.BrokenCritical {
 			severity = SeverityCritical
 		}
 		r.Issues = append(r.Issues, Issue{
@@ -260,8 +434,48 @@ func (r *AuditResult) BuildIssues() {
 		})
 	}
 
+	// Internal links leaving the site through a redirect
+	if len(r.ExternalRedirects) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryBrokenLinks,
+			Severity:    SeverityHigh,
+			Title:       "Internal links redirect externally",
+			Description: fmt.Sprintf("%d internal link(s) end on another site after redirects", len(r.ExternalRedirects)),
+			Count:       len(r.ExternalRedirects),
+			Examples:    r.ExternalRedirects,
+			Suggestion:  "Link to the destination directly if intended; otherwise fix the redirect, and check it cannot be abused as an open redirect.",
+		})
+	}
+
+	if len(r.InsecureForms) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategorySecurity,
+			Severity:    SeverityHigh,
+			Title:       "Insecure forms",
+			Description: fmt.Sprintf("%d form(s) send what users type over plain HTTP", len(r.InsecureForms)),
+			Count:       len(r.InsecureForms),
+			Examples:    r.InsecureForms,
+			Suggestion:  "Serve these pages over HTTPS and point form actions to https:// URLs; browsers warn users filling them in.",
+		})
+	}
+
+	if len(r.MissingHeaders) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategorySecurity,
+			Severity:    SeverityMedium,
+			Title:       "Missing security headers",
+			Description: fmt.Sprintf("%d security header(s) missing from some or all pages", len(r.MissingHeaders)),
+			Count:       len(r.MissingHeaders),
+			Examples:    r.MissingHeaders,
+			Suggestion:  "Send these headers from the server or CDN for every page, so no template or route is left out.",
+		})
+	}
+
+	// Start page SEO, unknown when the SEO phase was skipped
+	seo := r.Ran(PhaseSEO)
+
 	// Missing title
-	if !r.HasTitle {
+	if seo && !r.HasTitle {
 		r.Issues = append(r.Issues, Issue{
 			Category:    CategorySEO,
 			Severity:    SeverityCritical,
@@ -269,7 +483,7 @@ func (r *AuditResult) BuildIssues() {
 			Description: "The homepage has no <title> tag",
 			Suggestion:  "Add a unique and descriptive <title> tag (30-60 characters).",
 		})
-	} else if r.TitleLength < 30 || r.TitleLength > 60 {
+	} else if seo && (r.TitleLength < 30 || r.TitleLength > 60) {
 		r.Issues = append(r.Issues, Issue{
 			Category:    CategorySEO,
 			Severity:    SeverityMedium,
@@ -280,7 +494,7 @@ func (r *AuditResult) BuildIssues() {
 	}
 
 	// Missing meta description
-	if !r.HasMetaDescription {
+	if seo && !r.HasMetaDescription {
 		r.Issues = append(r.Issues, Issue{
 			Category:    CategorySEO,
 			Severity:    SeverityHigh,
@@ -288,7 +502,7 @@ func (r *AuditResult) BuildIssues() {
 			Description: "The homepage has no meta description",
 			Suggestion:  "Add a unique and engaging meta description (70-155 characters).",
 		})
-	} else if r.DescriptionLength < 70 || r.DescriptionLength > 155 {
+	} else if seo && (r.DescriptionLength < 70 || r.DescriptionLength > 155) {
 		r.Issues = append(r.Issues, Issue{
 			Category:    CategorySEO,
 			Severity:    SeverityLow,
@@ -298,8 +512,87 @@ func (r *AuditResult) BuildIssues() {
 		})
 	}
 
+	// H1 across the crawl
+	if len(r.NoH1Pages) > 0 {
+		severity := SeverityLow
+		if len(r.NoH1Pages)*2 > r.H1PagesChecked {
+			severity = SeverityMedium
+		}
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategorySEO,
+			Severity:    severity,
+			Title:       "Pages without H1",
+			Description: fmt.Sprintf("%d of %d page(s) (%.0f%%) have no H1", len(r.NoH1Pages), r.H1PagesChecked, float64(len(r.NoH1Pages))/float64(r.H1PagesChecked)*100),
+			Count:       len(r.NoH1Pages),
+			Examples:    r.NoH1Pages,
+			Suggestion:  "Give every page one H1 stating its main topic.",
+		})
+	}
+	if len(r.MultipleH1Pages) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategorySEO,
+			Severity:    SeverityLow,
+			Title:       "Pages with multiple H1",
+			Description: fmt.Sprintf("%d of %d page(s) (%.0f%%) have more than one H1", len(r.MultipleH1Pages), r.H1PagesChecked, float64(len(r.MultipleH1Pages))/float64(r.H1PagesChecked)*100),
+			Count:       len(r.MultipleH1Pages),
+			Examples:    r.MultipleH1Pages,
+			Suggestion:  "Keep a single H1 per page and use H2-H6 for sections.",
+		})
+	}
+
+	// Structured data that exists but won't produce a rich result
+	if len(r.IneligibleRichResults) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategorySEO,
+			Severity:    SeverityLow,
+			Title:       "Structured data not eligible for rich results",
+			Description: fmt.Sprintf("%d FAQPage, HowTo or Article object(s) on the start page miss properties Google requires", len(r.IneligibleRichResults)),
+			Count:       len(r.IneligibleRichResults),
+			Examples:    r.IneligibleRichResults,
+			Suggestion:  "Add the missing properties, then confirm with Google's Rich Results Test.",
+		})
+	}
+
+	// Leftovers of old templates, harmless unless keywords are stuffed
+	if len(r.LegacyMarkup) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategorySEO,
+			Severity:    SeverityInfo,
+			Title:       "Legacy markup on the start page",
+			Description: fmt.Sprintf("%d deprecated construct(s), such as meta keywords, which search engines ignore", len(r.LegacyMarkup)),
+			Count:       len(r.LegacyMarkup),
+			Examples:    r.LegacyMarkup,
+			Suggestion:  "Remove them from the templates; stuffed meta keywords can look spammy.",
+		})
+	}
+
+	// Pages the audit only saw as an empty JavaScript shell
+	if len(r.ClientRendered) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategorySEO,
+			Severity:    SeverityMedium,
+			Title:       "Pages appear client-rendered",
+			Description: fmt.Sprintf("%d page(s) have a nearly empty body but load JavaScript bundles; static analysis may be incomplete", len(r.ClientRendered)),
+			Count:       len(r.ClientRendered),
+			Examples:    r.ClientRendered,
+			Suggestion:  "Check these pages in a browser: their title, canonical and links may only exist after rendering. Serve them pre-rendered or server-side rendered.",
+		})
+	}
+
+	// Missing canonical on pages served at several URLs
+	if r.DuplicateNoCanonical > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityHigh,
+			Title:       "Duplicated pages without canonical",
+			Description: fmt.Sprintf("%d page(s) have no canonical tag and were reached through several URLs", r.DuplicateNoCanonical),
+			Count:       r.DuplicateNoCanonical,
+			Suggestion:  "Declare the preferred URL as canonical on these pages first; search engines may index every variant.",
+		})
+	}
+
 	// Missing canonical
-	if r.MissingCanonical > 0 {
+	if missing := r.MissingCanonical - r.DuplicateNoCanonical; missing > 0 {
 		severity := SeverityMedium
 		if r.MissingCanonical > r.TotalPages/2 {
 			severity = SeverityHigh
@@ -308,8 +601,8 @@ func (r *AuditResult) BuildIssues() {
 			Category:    CategoryCanonical,
 			Severity:    severity,
 			Title:       "Missing canonicals",
-			Description: fmt.Sprintf("%d page(s) have no canonical tag", r.MissingCanonical),
-			Count:       r.MissingCanonical,
+			Description: fmt.Sprintf("%d page(s) have no canonical tag", missing),
+			Count:       missing,
 			Suggestion:  "Add <link rel=\"canonical\"> on each page to avoid duplicate content.",
 		})
 	}
@@ -326,6 +619,141 @@ func (r *AuditResult) BuildIssues() {
 		})
 	}
 
+	// The whole site declared as one page
+	if len(r.MassCanonicals) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityCritical,
+			Title:       "Mass canonicalization",
+			Description: fmt.Sprintf("%d URL(s) are the canonical of most pages of the site", len(r.MassCanonicals)),
+			Count:       len(r.MassCanonicals),
+			Examples:    r.MassCanonicals,
+			Suggestion:  "Fix the template declaring the same canonical on every page; pages should canonicalize to themselves.",
+		})
+	}
+
+	// Canonicals pointing to each other, leaving no page to index
+	if len(r.CanonicalLoops) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityCritical,
+			Title:       "Canonical loops",
+			Description: fmt.Sprintf("%d loop(s) of pages canonicalizing to each other", len(r.CanonicalLoops)),
+			Count:       len(r.CanonicalLoops),
+			Examples:    r.CanonicalLoops,
+			Suggestion:  "Make the page to index canonicalize to itself and the rest of the loop point to it.",
+		})
+	}
+
+	// Language versions collapsed into another by their canonical
+	if r.HreflangCanonical > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityHigh,
+			Title:       "Hreflang members canonicalizing away",
+			Description: fmt.Sprintf("%d language version(s) listed in hreflang canonicalize to another URL", r.HreflangCanonical),
+			Count:       r.HreflangCanonical,
+			Suggestion:  "Make each language version canonicalize to itself; only the canonical target stays in search.",
+		})
+	}
+
+	// Temporary redirects standing in for permanent ones
+	if r.TemporaryRedirects > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityMedium,
+			Title:       "Temporary redirects",
+			Description: fmt.Sprintf("%d internal link(s) answer a 302 or 307 that looks like a permanent move", r.TemporaryRedirects),
+			Count:       r.TemporaryRedirects,
+			Suggestion:  "Answer moved pages with a 301 or 308 so their ranking signals pass to the new URL.",
+		})
+	}
+
+	// Canonicals dropping query parameters, often intended
+	if r.StrippedParamsCanonical > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityLow,
+			Title:       "Canonicals strip query parameters",
+			Description: fmt.Sprintf("%d page(s) canonicalize to their URL without some query parameters", r.StrippedParamsCanonical),
+			Count:       r.StrippedParamsCanonical,
+			Suggestion:  "Fine for tracking or sort parameters; paginated pages should canonicalize to themselves instead.",
+		})
+	}
+
+	// Canonical tags outside <head>
+	if r.BodyCanonical > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityHigh,
+			Title:       "Canonicals in body",
+			Description: fmt.Sprintf("%d page(s) place their canonical tag in <body>, where search engines ignore it", r.BodyCanonical),
+			Count:       r.BodyCanonical,
+			Suggestion:  "Move <link rel=\"canonical\"> into <head>, before any element that would end it early.",
+		})
+	}
+
+	// Multiple canonical tags
+	if r.MultipleCanonical > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityHigh,
+			Title:       "Conflicting canonical tags",
+			Description: fmt.Sprintf("%d page(s) declare canonical tags naming different URLs", r.MultipleCanonical),
+			Count:       r.MultipleCanonical,
+			Suggestion:  "Keep a single canonical tag per page; conflicting tags are ignored by Google.",
+		})
+	}
+
+	// Canonicals pointing to another domain
+	if r.CrossDomainCanonical > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityCritical,
+			Title:       "Cross-domain canonicals",
+			Description: fmt.Sprintf("%d page(s) canonicalize to another domain (%s)", r.CrossDomainCanonical, strings.Join(r.CrossDomainHosts, ", ")),
+			Count:       r.CrossDomainCanonical,
+			Suggestion:  "Unless the content is syndicated on purpose, point canonicals to this site. A staging site canonicalizing to production is a misconfiguration.",
+		})
+	}
+
+	// Canonicals pointing to error pages
+	if len(r.BrokenCanonicals) > 0 {
+		var examples []string
+		for i, bc := range r.BrokenCanonicals {
+			if i >= 3 {
+				examples = append(examples, fmt.Sprintf("and %d more", len(r.BrokenCanonicals)-3))
+				break
+			}
+			examples = append(examples, fmt.Sprintf("%s returns %s", bc.CanonicalURL, statusText(bc.StatusCode)))
+		}
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityCritical,
+			Title:       "Canonicals to error pages",
+			Description: fmt.Sprintf("%d page(s) canonicalize to a URL that fails: %s", len(r.BrokenCanonicals), strings.Join(examples, ", ")),
+			Count:       len(r.BrokenCanonicals),
+			Suggestion:  "Point canonicals to live 200 pages. A canonical to an error page sends search engines to a dead end.",
+		})
+	}
+
+	// Canonicalized pages kept in the navigation
+	if len(r.LinkedNonCanonicals) > 0 {
+		var examples []string
+		for _, lc := range r.LinkedNonCanonicals {
+			examples = append(examples, fmt.Sprintf("%s → %s (%d linking pages)", lc.URL, lc.CanonicalURL, lc.InLinks))
+		}
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryCanonical,
+			Severity:    SeverityMedium,
+			Title:       "Canonicalized pages still linked internally",
+			Description: fmt.Sprintf("%d page(s) canonicalize to another URL but are linked from %d or more pages", len(r.LinkedNonCanonicals), linkedNonCanonicalMinInLinks),
+			Count:       len(r.LinkedNonCanonicals),
+			Examples:    examples,
+			Suggestion:  "Link to the canonical URL instead, so internal link equity goes to the page you want indexed.",
+		})
+	}
+
 	// Noindex pages
 	if r.NoIndexPages > 0 {
 		r.Issues = append(r.Issues, Issue{
@@ -350,13 +778,49 @@ func (r *AuditResult) BuildIssues() {
 		})
 	}
 
+	// Nofollow on internal links
+	if r.InternalNoFollowLinks > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryIndexability,
+			Severity:    SeverityMedium,
+			Title:       "Nofollow on internal links",
+			Description: fmt.Sprintf("%d internal link(s) have the rel=\"nofollow\" attribute", r.InternalNoFollowLinks),
+			Count:       r.InternalNoFollowLinks,
+			Suggestion:  "Remove nofollow from links to your own pages; it wastes internal PageRank.",
+		})
+	}
+
+	// Internal links to robots-blocked pages
+	if r.InternalRobotsBlocked > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryIndexability,
+			Severity:    SeverityMedium,
+			Title:       "Internally linked pages blocked by robots.txt",
+			Description: fmt.Sprintf("%d page(s) are linked from the site but blocked by robots.txt", r.InternalRobotsBlocked),
+			Count:       r.InternalRobotsBlocked,
+			Suggestion:  "Remove the internal links to these pages or allow them in robots.txt; run linkindexer for the linking pages.",
+		})
+	}
+
+	// Meta robots and X-Robots-Tag disagreeing
+	if r.RobotsConflicts > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryIndexability,
+			Severity:    SeverityHigh,
+			Title:       "Conflicting robots directives",
+			Description: fmt.Sprintf("%d page(s) have a robots meta tag and X-Robots-Tag header that disagree", r.RobotsConflicts),
+			Count:       r.RobotsConflicts,
+			Suggestion:  "Make the meta tag and header agree; crawlers apply the most restrictive directive. Run linkindexer for both values.",
+		})
+	}
+
 	// Slow pages
 	if r.SlowPages > 0 {
 		severity := SeverityLow
-		if r.SlowPages > r.TotalPages/4 {
+		if float64(r.SlowPages) > t.SlowRatio*float64(r.TotalPages) {
 			severity = SeverityMedium
 		}
-		if r.VerySlowPages > 0 {
+		if r.VerySlowPages > t.VerySlowHigh {
 			severity = SeverityHigh
 		}
 		r.Issues = append(r.Issues, Issue{
@@ -369,6 +833,81 @@ func (r *AuditResult) BuildIssues() {
 		})
 	}
 
+	// Render-blocking resources
+	if r.RenderBlockingPages > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryPerformance,
+			Severity:    SeverityLow,
+			Title:       "Many render-blocking resources",
+			Description: fmt.Sprintf("%d page(s) load many synchronous scripts or stylesheets in <head>", r.RenderBlockingPages),
+			Count:       r.RenderBlockingPages,
+			Suggestion:  "Add async/defer to scripts, inline critical CSS and load the rest asynchronously.",
+		})
+	}
+
+	// Browsers and CDNs fall back to heuristics without Cache-Control
+	if len(r.NoCacheControl) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryPerformance,
+			Severity:    SeverityLow,
+			Title:       "Pages without Cache-Control",
+			Description: fmt.Sprintf("%d HTML page(s) answer without a Cache-Control header", len(r.NoCacheControl)),
+			Count:       len(r.NoCacheControl),
+			Examples:    r.NoCacheControl,
+			Suggestion:  "Declare how long pages may be cached, even if only no-cache, rather than leave it to browser heuristics.",
+		})
+	}
+
+	// Pages answering 200 with next to nothing: soft 404s, broken renders
+	if len(r.EmptyPages) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryBrokenLinks,
+			Severity:    SeverityMedium,
+			Title:       "Suspiciously empty pages",
+			Description: fmt.Sprintf("%d page(s) answer 200 with a body under %d bytes", len(r.EmptyPages), r.EmptyPageBytes),
+			Count:       len(r.EmptyPages),
+			Examples:    r.EmptyPages,
+			Suggestion:  "Check these pages: an error page should answer 404 or 410, and a placeholder or broken template shouldn't be served as content.",
+		})
+	}
+
+	// Anchors without a usable href
+	if r.MalformedLinks > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryBrokenLinks,
+			Severity:    SeverityLow,
+			Title:       "Malformed links",
+			Description: fmt.Sprintf("%d <a> element(s) have an empty, blank or missing href", r.MalformedLinks),
+			Count:       r.MalformedLinks,
+			Suggestion:  "Fix the templates producing them: give each link a real URL, or use a <button> for actions. Run linkanalyzer for the pages.",
+		})
+	}
+
+	// Trailing-slash inconsistencies
+	if r.TrailingSlashPairs > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryArchitecture,
+			Severity:    SeverityLow,
+			Title:       "Inconsistent trailing slashes",
+			Description: fmt.Sprintf("%d page(s) are linked both with and without a trailing slash", r.TrailingSlashPairs),
+			Count:       r.TrailingSlashPairs,
+			Suggestion:  "Pick one form, link to it everywhere and redirect the other to avoid duplicate URLs.",
+		})
+	}
+
+	// Long, deep or parameter-heavy URLs
+	if len(r.LongURLs) > 0 {
+		r.Issues = append(r.Issues, Issue{
+			Category:    CategoryArchitecture,
+			Severity:    SeverityLow,
+			Title:       "Long or complex URLs",
+			Description: fmt.Sprintf("%d internal URL(s) are very long, deeply nested or carry many query parameters", len(r.LongURLs)),
+			Count:       len(r.LongURLs),
+			Examples:    r.LongURLs,
+			Suggestion:  "Prefer short, readable URLs; long ones often come from encoded junk or faceted navigation. Run linkanalyzer for the linking pages.",
+		})
+	}
+
 	// Orphan pages
 	if r.OrphanPages > 1 { // Start page is always orphan
 		r.Issues = append(r.Issues, Issue{
@@ -398,7 +937,7 @@ func (r *AuditResult) BuildIssues() {
 	}
 
 	// Missing Open Graph
-	if !r.HasOGTags {
+	if seo && !r.HasOGTags {
 		r.Issues = append(r.Issues, Issue{
 			Category:    CategorySEO,
 			Severity:    SeverityLow,
@@ -409,7 +948,7 @@ func (r *AuditResult) BuildIssues() {
 	}
 
 	// Missing Twitter Cards
-	if !r.HasTwitterCards {
+	if seo && !r.HasTwitterCards {
 		r.Issues = append(r.Issues, Issue{
 			Category:    CategorySEO,
 			Severity:    SeverityInfo,
@@ -420,7 +959,7 @@ func (r *AuditResult) BuildIssues() {
 	}
 
 	// Missing structured data
-	if len(r.SchemaTypes) == 0 {
+	if seo && len(r.SchemaTypes) == 0 {
 		r.Issues = append(r.Issues, Issue{
 			Category:    CategorySEO,
 			Severity:    SeverityLow,
@@ -441,8 +980,10 @@ func (r *AuditResult) PrintReport() {
 	r.printHeader()
 	r.printScores()
 	r.printSummary()
+	r.printCrawlBudget()
 	r.printIssues()
 	r.printRecommendations()
+	r.CrawlStats.Print()
 	r.printFooter()
 }
 
@@ -481,18 +1022,35 @@ func (r *AuditResult) printScores() {
 		grade = "D"
 	}
 
-	fmt.Printf("  %s%sOverall Score: %d/100 (%s)%s\n\n", colorBold, scoreColor, r.OverallScore, grade, colorReset)
+	if r.HasScore() {
+		fmt.Printf("  %s%sOverall Score: %d/100 (%s)%s\n\n", colorBold, scoreColor, r.OverallScore, grade, colorReset)
+	} else {
+		fmt.Printf("  %s%sOverall Score: not scored, no phase run measures a category%s\n\n", colorBold, colorGray, colorReset)
+	}
 
 	// Individual scores with bars
-	printScoreBar("Broken Links", r.BrokenLinksScore, 20)
-	printScoreBar("SEO", r.SEOScore, 20)
-	printScoreBar("Performance", r.PerformanceScore, 20)
-	printScoreBar("Architecture", r.ArchitectureScore, 20)
+	r.printScoreBar(CategoryBrokenLinks, r.BrokenLinksScore, 20)
+	r.printScoreBar(CategorySEO, r.SEOScore, 20)
+	r.printScoreBar(CategoryPerformance, r.PerformanceScore, 20)
+	r.printScoreBar(CategoryArchitecture, r.ArchitectureScore, 20)
+	if skipped := r.SkippedPhases(); len(skipped) > 0 {
+		names := make([]string, len(skipped))
+		for i, p := range skipped {
+			names[i] = string(p)
+		}
+		fmt.Printf("\n  %sSkipped phases: %s; their categories are left out of the overall score%s\n", colorGray, strings.Join(names, ", "), colorReset)
+	}
 
 	fmt.Println()
 }
 
-func printScoreBar(label string, score int, width int) {
+func (r *AuditResult) printScoreBar(category Category, score int, width int) {
+	label := string(category)
+	if !r.Scored(category) {
+		fmt.Printf("  %-15s %sskipped%s\n", label, colorGray, colorReset)
+		return
+	}
+
 	filled := score * width / 100
 	if filled < 0 {
 		filled = 0
@@ -508,8 +1066,9 @@ func printScoreBar(label string, score int, width int) {
 		color = colorYellow
 	}
 
-	bar := strings.Repeat("█", filled)
-	empty := strings.Repeat("░", width-filled)
+	fill, blank := barchart.Chars(r.ASCII)
+	bar := strings.Repeat(fill, filled)
+	empty := strings.Repeat(blank, width-filled)
 
 	fmt.Printf("  %-15s %s%s%s%s %3d%%\n", label, color, bar, colorGray, empty, score)
 }
@@ -524,6 +1083,9 @@ func (r *AuditResult) printSummary() {
 	fmt.Printf("  %sInternal links:%s        %d\n", colorGray, colorReset, r.TotalLinks)
 	fmt.Printf("  %sExternal links:%s        %d\n", colorGray, colorReset, r.ExternalLinks)
 	fmt.Printf("  %sBroken links:%s          %s%d%s\n", colorGray, colorReset, getCountColor(r.BrokenLinks, 0, 5), r.BrokenLinks, colorReset)
+	if r.EnforcesHTTPS != "" {
+		fmt.Printf("  %sEnforces HTTPS:%s        %s%s%s\n", colorGray, colorReset, colorGreen, r.EnforcesHTTPS, colorReset)
+	}
 	fmt.Printf("  %sAverage latency:%s       %v\n", colorGray, colorReset, r.AvgLatency.Round(time.Millisecond))
 	fmt.Printf("  %sMax latency:%s           %v\n", colorGray, colorReset, r.MaxLatency.Round(time.Millisecond))
 	fmt.Println()
@@ -0,0 +1,10 @@
+package barchart
+
+// Chars returns the characters drawing the filled and empty parts of a bar:
+// block characters, or # and - for terminals and screen readers without them
+func Chars(ascii bool) (fill, empty string) {
+	if ascii {
+		return "#", "-"
+	}
+	return "█", "░"
+}
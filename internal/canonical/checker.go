@@ -2,31 +2,59 @@ package canonical
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/serp"
+	"github.com/ngonzalez/web-tools/internal/urlnorm"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 // Config holds checker configuration
 type Config struct {
-	Concurrency   int
-	Timeout       time.Duration
-	MaxDepth      int
-	Verbose       bool
-	FollowRedirects bool
+	Concurrency         int
+	Timeout             time.Duration
+	MaxDepth            int
+	Verbose             bool
+	Verbosity           int // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Info
+	FollowRedirects     bool
+	IgnoreWWW           bool              // Don't flag www vs non-www canonicals as mismatches
+	IgnoreScheme        bool              // Don't flag http vs https canonicals as mismatches
+	IgnoreParams        []string          // Query parameters ignored when comparing a URL to its canonical, "*" for all
+	KeepParams          []string          // Query parameters always compared, even when IgnoreParams matches them
+	Transport           http.RoundTripper // Optional custom transport, e.g. a shared response cache
+	TreatSchemesAsSame  bool              // Collapse http:// and https:// URLs of the same page
+	KeepHTTP            bool              // Crawl http:// URLs as is even on a site enforcing HTTPS
+	Delay               time.Duration     // Minimum pause between two requests
+	MaxIdleConnsPerHost int               // Idle connections kept per host, 0 matches Concurrency
+	MaxRedirects        int               // Redirects followed per request, 0 = none (reported as redirects), -1 = up to httppool.MaxRedirectsCap
+	HTMLContentTypes    []string          // Extra media types parsed as HTML, besides contenttype.HTML
+	MaxStoredExamples   int               // Issues kept per type, 0 keeps them all; counts stay exact
+	FollowCanonicals    bool              // Crawl the same-site canonical targets pages declare, as if linked
 }
 
 // DefaultConfig returns default configuration
 func DefaultConfig() Config {
 	return Config{
-		Concurrency:   10,
-		Timeout:       10 * time.Second,
-		MaxDepth:      0,
-		Verbose:       false,
+		Concurrency:     10,
+		Timeout:         10 * time.Second,
+		MaxDepth:        0,
+		Verbose:         false,
 		FollowRedirects: true,
+		MaxRedirects:    httppool.DefaultMaxRedirects,
 	}
 }
 
@@ -34,23 +62,38 @@ func DefaultConfig() Config {
 type Checker struct {
 	config       Config
 	baseURL      *url.URL
-	visited      map[string]bool
-	visitedMu    sync.RWMutex
-	canonicals   map[string]string // URL -> canonical URL
-	canonicalsMu sync.RWMutex
+	canonicals   map[string]string          // URL -> canonical URL
+	reached      map[string]bool            // Normalized URLs fetched without error
+	hreflangs    map[string][]serp.Hreflang // Crawled page -> its hreflang alternates
+	followed     map[string]bool            // Normalized canonical targets queued with Config.FollowCanonicals
+	canonicalsMu sync.RWMutex               // Also guards reached, hreflangs and followed
 	result       *CanonicalResult
-	resultMu     sync.Mutex
+	resultMu     sync.Mutex      // Also guards temporary
+	temporary    map[string]bool // URLs already reported as temporary redirects
 	client       *http.Client
-	semaphore    chan struct{}
+	rewrite      func(string) string // HTTPS upgrade of the crawl, nil when none
 	checkedLinks map[string]bool
 	checkedMu    sync.Mutex
+	stats        crawlstats.Counter
+
+	// Missing canonicals are classified once every URL variant is known
+	missing   []missingCanonical
+	variants  map[string]map[string]bool // Variant key + content hash -> URLs serving it
+	variantMu sync.Mutex
+}
+
+// missingCanonical is a page without canonical waiting for classification
+type missingCanonical struct {
+	issue    CanonicalIssue
+	finalURL string
+	group    string
 }
 
 // New creates a new Checker
 func New(config Config) *Checker {
-	transport := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
+	transport := config.Transport
+	if transport == nil {
+		transport = httppool.New(config.Concurrency, config.MaxIdleConnsPerHost)
 	}
 
 	client := &http.Client{
@@ -63,22 +106,20 @@ func New(config Config) *Checker {
 		return http.ErrUseLastResponse
 	}
 
+	config.Verbosity = verbosity.Effective(config.Verbosity, config.Verbose)
 	return &Checker{
 		config:       config,
-		visited:      make(map[string]bool),
 		canonicals:   make(map[string]string),
+		reached:      make(map[string]bool),
 		checkedLinks: make(map[string]bool),
-		semaphore:    make(chan struct{}, config.Concurrency),
+		variants:     make(map[string]map[string]bool),
+		temporary:    make(map[string]bool),
+		hreflangs:    make(map[string][]serp.Hreflang),
+		followed:     make(map[string]bool),
 		client:       client,
 	}
 }
 
-type urlTask struct {
-	url       string
-	sourceURL string
-	depth     int
-}
-
 // Check starts the canonical verification
 func (c *Checker) Check(startURL string) (*CanonicalResult, error) {
 	parsed, err := url.Parse(startURL)
@@ -91,137 +132,208 @@ func (c *Checker) Check(startURL string) (*CanonicalResult, error) {
 	}
 
 	c.baseURL = parsed
+	c.stats.Start()
 	c.result = NewCanonicalResult(startURL)
-
-	tasks := make(chan urlTask, 1000)
-
-	c.markVisited(startURL)
-	tasks <- urlTask{url: startURL, sourceURL: "", depth: 0}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	for i := 0; i < c.config.Concurrency; i++ {
-		go c.worker(ctx, tasks)
-	}
-
-	done := make(chan struct{})
-	go func() {
-		for {
-			time.Sleep(100 * time.Millisecond)
-			c.visitedMu.RLock()
-			visitedCount := len(c.visited)
-			c.visitedMu.RUnlock()
-
-			if len(tasks) == 0 && len(c.semaphore) == 0 {
-				time.Sleep(500 * time.Millisecond)
-				if len(tasks) == 0 && len(c.semaphore) == 0 {
-					close(done)
-					return
-				}
-			}
-
-			if visitedCount > 10000 {
-				close(done)
-				return
-			}
-		}
-	}()
-
-	<-done
-	cancel()
-	close(tasks)
-
-	c.visitedMu.RLock()
-	c.result.TotalPages = len(c.visited)
-	c.visitedMu.RUnlock()
+	c.result.MaxStoredExamples = c.config.MaxStoredExamples
+
+	c.rewrite = httppool.HTTPSRewrite(c.config.KeepHTTP, c.client.Transport, c.config.Timeout, startURL)
+	engine := crawl.New(crawl.Config{
+		Concurrency:        c.config.Concurrency,
+		MaxDepth:           c.config.MaxDepth,
+		Delay:              c.config.Delay,
+		TreatSchemesAsSame: c.config.TreatSchemesAsSame,
+		Rewrite:            c.rewrite,
+	}, c.processURL)
+	c.result.TotalPages = engine.Run(context.Background(), crawl.Task{URL: startURL})
+
+	c.classifyMissing()
+	c.checkCanonicalTargets()
+	c.checkHreflangClusters()
+	c.checkCanonicalLoops()
+	c.checkMassCanonicals()
+	c.result.Conflicts = StrategyConflicts(c.result.Canonicals, c.result.PagesWithout, c.equivalence())
 
 	c.checkedMu.Lock()
 	c.result.TotalLinks = len(c.checkedLinks)
 	c.checkedMu.Unlock()
+	c.result.FollowedCanonicals = len(c.followed)
+	c.result.Equivalence = c.equivalence()
+
+	c.result.CrawlStats = c.stats.Snapshot()
 
 	return c.result, nil
 }
 
-func (c *Checker) worker(ctx context.Context, tasks chan urlTask) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case task, ok := <-tasks:
-			if !ok {
-				return
-			}
-			c.processURL(ctx, task, tasks)
+func (c *Checker) processURL(ctx context.Context, task crawl.Task) []crawl.Task {
+	// Fetch the page, following redirects manually
+	start := time.Now()
+	finalURL, canonical, pageInfo, err := c.fetchPage(ctx, task.URL)
+	if err != nil {
+		if c.config.Verbosity >= verbosity.Warn {
+			printError(task.URL, err.Error(), task.Depth)
 		}
+		return nil
 	}
-}
+	c.checkTemporaryRedirects(task.SourceURL, task.URL)
 
-func (c *Checker) processURL(ctx context.Context, task urlTask, tasks chan urlTask) {
-	select {
-	case c.semaphore <- struct{}{}:
-		defer func() { <-c.semaphore }()
-	case <-ctx.Done():
-		return
+	if c.config.Verbosity >= verbosity.Info {
+		printProgress(task.URL, finalURL, canonical, task.Depth, c.equivalence())
 	}
-
-	if c.config.MaxDepth > 0 && task.depth > c.config.MaxDepth {
-		return
+	if c.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(task.URL, time.Since(start))
 	}
 
-	// Fetch the page, following redirects manually
-	finalURL, canonical, pageInfo, err := c.fetchPage(ctx, task.url)
-	if err != nil {
-		if c.config.Verbose {
-			printError(task.url, err.Error(), task.depth)
+	// Not following redirects, the redirect is reported and its target,
+	// not fetched yet, crawled as a page of its own
+	if c.config.MaxRedirects == 0 && finalURL != task.URL {
+		c.canonicalsMu.Lock()
+		c.reached[urlnorm.Normalize(task.URL)] = true
+		c.canonicalsMu.Unlock()
+
+		if task.SourceURL != "" {
+			c.resultMu.Lock()
+			c.result.AddIssue(CanonicalIssue{
+				Type:      IssueRedirectToCanonical,
+				SourceURL: task.SourceURL,
+				LinkedURL: task.URL,
+				FinalURL:  finalURL,
+			})
+			c.resultMu.Unlock()
 		}
-		return
+		if isSameDomain(finalURL, c.baseURL) {
+			return []crawl.Task{{URL: finalURL, SourceURL: task.URL}}
+		}
+		return nil
 	}
 
-	if c.config.Verbose {
-		printProgress(task.url, finalURL, canonical, task.depth)
+	// Remember which URLs serve this content
+	group := ""
+	if pageInfo != nil && pageInfo.ContentHash != "" {
+		group = VariantKey(finalURL) + " " + pageInfo.ContentHash
+		c.variantMu.Lock()
+		if c.variants[group] == nil {
+			c.variants[group] = make(map[string]bool)
+		}
+		c.variants[group][finalURL] = true
+		c.variantMu.Unlock()
 	}
 
 	// Store canonical for this URL
 	c.canonicalsMu.Lock()
+	c.reached[urlnorm.Normalize(task.URL)] = true
+	c.reached[urlnorm.Normalize(finalURL)] = true
 	if canonical != "" {
-		c.canonicals[task.url] = canonical
+		c.canonicals[task.URL] = canonical
 		c.canonicals[finalURL] = canonical
 	}
+	if pageInfo != nil && len(pageInfo.Hreflangs) > 0 {
+		c.hreflangs[finalURL] = pageInfo.Hreflangs
+	}
 	c.canonicalsMu.Unlock()
 
+	if canonical != "" {
+		c.resultMu.Lock()
+		c.result.Canonicals[finalURL] = canonical
+		c.resultMu.Unlock()
+	}
+
 	// Check if accessed URL matches canonical
 	if canonical != "" {
-		if !URLsEquivalent(finalURL, canonical) {
-			c.resultMu.Lock()
-			c.result.AddIssue(CanonicalIssue{
+		if !c.equivalent(finalURL, canonical) {
+			issue := CanonicalIssue{
 				Type:         IssueCanonicalMismatch,
-				SourceURL:    task.sourceURL,
-				LinkedURL:    task.url,
+				SourceURL:    task.SourceURL,
+				LinkedURL:    task.URL,
 				CanonicalURL: canonical,
 				FinalURL:     finalURL,
-			})
+			}
+			// Parameter-stripping canonicals are often intended, review them apart
+			if stripped := StrippedParams(finalURL, canonical, c.equivalence()); len(stripped) > 0 {
+				issue.Type = IssueCanonicalStripsParams
+				issue.Stripped = stripped
+			}
+			c.resultMu.Lock()
+			c.result.AddIssue(issue)
 			c.resultMu.Unlock()
 		}
 	} else {
-		// No canonical defined
+		// No canonical defined, classified once the crawl is over
 		c.resultMu.Lock()
 		c.result.PagesWithout = append(c.result.PagesWithout, finalURL)
+		c.missing = append(c.missing, missingCanonical{
+			issue: CanonicalIssue{
+				Type:      IssueMissingCanonical,
+				SourceURL: task.SourceURL,
+				LinkedURL: task.URL,
+			},
+			finalURL: finalURL,
+			group:    group,
+		})
+		c.resultMu.Unlock()
+	}
+
+	// Check if the canonical points to another domain
+	if canonical != "" {
+		pageHost, canonicalHost, crossDomain := CanonicalHosts(finalURL, canonical, c.equivalence())
+		if crossDomain {
+			c.resultMu.Lock()
+			c.result.AddIssue(CanonicalIssue{
+				Type:          IssueCrossDomainCanonical,
+				SourceURL:     task.SourceURL,
+				LinkedURL:     finalURL,
+				CanonicalURL:  canonical,
+				PageHost:      pageHost,
+				CanonicalHost: canonicalHost,
+			})
+			c.resultMu.Unlock()
+		}
+	}
+
+	// Check for canonical tags on the same page naming different URLs; a
+	// template printing the same one twice is harmless
+	if pageInfo != nil && c.conflicting(pageInfo.Canonicals) {
+		c.resultMu.Lock()
+		c.result.AddIssue(CanonicalIssue{
+			Type:       IssueMultipleCanonicals,
+			SourceURL:  task.SourceURL,
+			LinkedURL:  finalURL,
+			Canonicals: pageInfo.Canonicals,
+		})
+		c.resultMu.Unlock()
+	}
+
+	// Check for a Link header canonical contradicting the tag
+	if pageInfo != nil && pageInfo.LinkHeader != "" && pageInfo.CanonicalURL != "" && !c.equivalent(pageInfo.LinkHeader, pageInfo.CanonicalURL) {
+		c.resultMu.Lock()
+		c.result.AddIssue(CanonicalIssue{
+			Type:         IssueHeaderConflict,
+			SourceURL:    task.SourceURL,
+			LinkedURL:    finalURL,
+			CanonicalURL: pageInfo.CanonicalURL,
+			LinkHeader:   pageInfo.LinkHeader,
+		})
+		c.resultMu.Unlock()
+	}
+
+	// Check for canonical tags outside <head>
+	if pageInfo != nil && len(pageInfo.BodyCanonicals) > 0 {
+		c.resultMu.Lock()
 		c.result.AddIssue(CanonicalIssue{
-			Type:      IssueMissingCanonical,
-			SourceURL: task.sourceURL,
-			LinkedURL: task.url,
+			Type:       IssueCanonicalInBody,
+			SourceURL:  task.SourceURL,
+			LinkedURL:  finalURL,
+			Canonicals: pageInfo.BodyCanonicals,
 		})
 		c.resultMu.Unlock()
 	}
 
 	// Check if there was a redirect
-	if task.url != finalURL && task.sourceURL != "" {
+	if task.URL != finalURL && task.SourceURL != "" {
 		c.resultMu.Lock()
 		c.result.AddIssue(CanonicalIssue{
 			Type:         IssueRedirectToCanonical,
-			SourceURL:    task.sourceURL,
-			LinkedURL:    task.url,
+			SourceURL:    task.SourceURL,
+			LinkedURL:    task.URL,
 			CanonicalURL: canonical,
 			FinalURL:     finalURL,
 		})
@@ -229,14 +341,156 @@ func (c *Checker) processURL(ctx context.Context, task urlTask, tasks chan urlTa
 	}
 
 	// Process links on this page
+	var next []crawl.Task
 	if pageInfo != nil {
 		for _, link := range pageInfo.Links {
-			c.checkLink(ctx, finalURL, link, tasks, task.depth)
+			c.checkLink(finalURL, link)
+			if isSameDomain(link, c.baseURL) {
+				next = append(next, crawl.Task{URL: link, SourceURL: finalURL})
+			}
+		}
+	}
+	if task, ok := c.followCanonical(finalURL, canonical); ok {
+		next = append(next, task)
+	}
+	return next
+}
+
+// followCanonical returns the crawl task of a page's canonical target with
+// Config.FollowCanonicals, so the URL the page consolidates onto is audited
+// even when nothing links to it. The engine skips targets already crawled.
+func (c *Checker) followCanonical(pageURL, canonical string) (crawl.Task, bool) {
+	if !c.config.FollowCanonicals || canonical == "" || c.equivalent(pageURL, canonical) || !isSameDomain(canonical, c.baseURL) {
+		return crawl.Task{}, false
+	}
+	c.canonicalsMu.Lock()
+	c.followed[urlnorm.Normalize(canonical)] = true
+	c.canonicalsMu.Unlock()
+	return crawl.Task{URL: canonical, SourceURL: pageURL, Element: "canonical"}, true
+}
+
+// classifyMissing reports pages without canonical. Those whose content the
+// crawl reached through several URLs are escalated: they are the ones search
+// engines may index as duplicates.
+func (c *Checker) classifyMissing() {
+	for _, m := range c.missing {
+		issue := m.issue
+		for u := range c.variants[m.group] {
+			if u != m.finalURL {
+				issue.Variants = append(issue.Variants, u)
+			}
+		}
+		if len(issue.Variants) > 0 {
+			sort.Strings(issue.Variants)
+			issue.Type = IssueDuplicateNoCanonical
+		}
+		c.result.AddIssue(issue)
+	}
+}
+
+// checkCanonicalTargets requests the canonical targets the crawl never
+// reached and reports, on every page declaring it, each one that fails. A
+// canonical pointing to a missing page leaves search engines nothing to
+// index.
+func (c *Checker) checkCanonicalTargets() {
+	pagesByTarget := make(map[string][]string)
+	for page, canonical := range c.result.Canonicals {
+		if !c.reached[urlnorm.Normalize(canonical)] {
+			pagesByTarget[canonical] = append(pagesByTarget[canonical], page)
+		}
+	}
+	targets := make([]string, 0, len(pagesByTarget))
+	for target := range pagesByTarget {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	// The targets are only requested, never crawled further
+	problems := make(map[string]string)
+	var problemsMu sync.Mutex
+	seeds := make([]crawl.Task, 0, len(targets))
+	for _, target := range targets {
+		seeds = append(seeds, crawl.Task{URL: target})
+	}
+	engine := crawl.New(crawl.Config{
+		Concurrency: c.config.Concurrency,
+		MaxPages:    len(seeds),
+		Delay:       c.config.Delay,
+	}, func(ctx context.Context, task crawl.Task) []crawl.Task {
+		problem := c.targetProblem(ctx, task.URL)
+		problemsMu.Lock()
+		problems[task.URL] = problem
+		problemsMu.Unlock()
+		return nil
+	})
+	engine.Run(context.Background(), seeds...)
+
+	for _, target := range targets {
+		if problems[target] == "" {
+			continue
+		}
+		pages := pagesByTarget[target]
+		sort.Strings(pages)
+		for _, page := range pages {
+			c.result.AddIssue(CanonicalIssue{
+				Type:         IssueUnreachableCanonical,
+				SourceURL:    page,
+				LinkedURL:    page,
+				CanonicalURL: target,
+				TargetError:  problems[target],
+			})
 		}
 	}
 }
 
-func (c *Checker) checkLink(ctx context.Context, sourceURL, linkedURL string, tasks chan urlTask, depth int) {
+// targetProblem sends a HEAD request to a canonical target, following up to
+// Config.MaxRedirects redirects, and returns why it is unreachable, or ""
+// when it answers. Servers refusing HEAD are asked again with GET.
+func (c *Checker) targetProblem(ctx context.Context, targetURL string) string {
+	maxRedirects := httppool.RedirectLimit(c.config.MaxRedirects)
+	currentURL := targetURL
+
+	for i := 0; ; i++ {
+		var resp *http.Response
+		for _, method := range []string{"HEAD", "GET"} {
+			req, err := http.NewRequestWithContext(ctx, method, currentURL, nil)
+			if err != nil {
+				return err.Error()
+			}
+			req.Header.Set("User-Agent", "CanonicalChecker/1.0")
+
+			resp, err = c.client.Do(req)
+			c.stats.AddRequest()
+			if err != nil {
+				return err.Error()
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+				break
+			}
+		}
+
+		// A redirect is followed, or with MaxRedirects 0 is the answer
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 && maxRedirects > 0 {
+			location, err := resp.Location()
+			if err != nil {
+				return "" // Nowhere to follow, but the target answered
+			}
+			if i == maxRedirects {
+				return httppool.ErrTooManyRedirects.Error()
+			}
+			currentURL = location.String()
+			continue
+		}
+
+		if resp.StatusCode >= 400 {
+			return fmt.Sprintf("HTTP %d", resp.StatusCode)
+		}
+		return ""
+	}
+}
+
+func (c *Checker) checkLink(sourceURL, linkedURL string) {
 	// Track checked links
 	linkKey := sourceURL + " -> " + linkedURL
 	c.checkedMu.Lock()
@@ -247,12 +501,17 @@ func (c *Checker) checkLink(ctx context.Context, sourceURL, linkedURL string, ta
 	c.checkedLinks[linkKey] = true
 	c.checkedMu.Unlock()
 
-	// Check if we know the canonical for this URL
+	// Check if we know the canonical for this URL, crawled as its https://
+	// form on a site enforcing HTTPS
+	crawledURL := linkedURL
+	if c.rewrite != nil {
+		crawledURL = c.rewrite(linkedURL)
+	}
 	c.canonicalsMu.RLock()
-	knownCanonical, hasCanonical := c.canonicals[linkedURL]
+	knownCanonical, hasCanonical := c.canonicals[crawledURL]
 	c.canonicalsMu.RUnlock()
 
-	if hasCanonical && !URLsEquivalent(linkedURL, knownCanonical) {
+	if hasCanonical && !c.equivalent(crawledURL, knownCanonical) {
 		// Link points to non-canonical URL
 		c.resultMu.Lock()
 		c.result.AddIssue(CanonicalIssue{
@@ -263,22 +522,23 @@ func (c *Checker) checkLink(ctx context.Context, sourceURL, linkedURL string, ta
 		})
 		c.resultMu.Unlock()
 	}
-
-	// Queue for crawling if not visited
-	if c.shouldVisit(linkedURL) {
-		c.markVisited(linkedURL)
-		select {
-		case tasks <- urlTask{url: linkedURL, sourceURL: sourceURL, depth: depth + 1}:
-		default:
-		}
-	}
 }
 
 func (c *Checker) fetchPage(ctx context.Context, targetURL string) (finalURL, canonical string, pageInfo *PageInfo, err error) {
 	currentURL := targetURL
-	maxRedirects := 10
+	maxRedirects := httppool.RedirectLimit(c.config.MaxRedirects)
 
-	for i := 0; i < maxRedirects; i++ {
+	// Every redirect answered is kept with its status, whatever the outcome
+	var hops []RedirectHop
+	defer func() {
+		if len(hops) > 0 {
+			c.resultMu.Lock()
+			c.result.Redirects[targetURL] = hops
+			c.resultMu.Unlock()
+		}
+	}()
+
+	for i := 0; i <= maxRedirects; i++ {
 		req, err := http.NewRequestWithContext(ctx, "GET", currentURL, nil)
 		if err != nil {
 			return "", "", nil, err
@@ -287,6 +547,7 @@ func (c *Checker) fetchPage(ctx context.Context, targetURL string) (finalURL, ca
 		req.Header.Set("User-Agent", "CanonicalChecker/1.0")
 
 		resp, err := c.client.Do(req)
+		c.stats.AddRequest()
 		if err != nil {
 			return "", "", nil, err
 		}
@@ -307,7 +568,17 @@ func (c *Checker) fetchPage(ctx context.Context, targetURL string) (finalURL, ca
 				return currentURL, "", nil, nil
 			}
 
+			hops = append(hops, RedirectHop{
+				URL:        currentURL,
+				StatusCode: resp.StatusCode,
+				Location:   base.ResolveReference(redirectURL).String(),
+			})
 			currentURL = base.ResolveReference(redirectURL).String()
+
+			// Not following redirects, the target is reported as the final URL
+			if maxRedirects == 0 {
+				return currentURL, "", nil, nil
+			}
 			continue
 		}
 
@@ -316,48 +587,69 @@ func (c *Checker) fetchPage(ctx context.Context, targetURL string) (finalURL, ca
 			return currentURL, "", nil, fmt.Errorf("HTTP %d", resp.StatusCode)
 		}
 
+		// i redirects were followed to reach this page
+		if i > 0 {
+			c.resultMu.Lock()
+			c.result.RedirectHops[targetURL] = i
+			c.resultMu.Unlock()
+		}
+
+		// A Link header canonical is the only one a non-HTML file can declare
+		baseURL, _ := url.Parse(currentURL)
+		headerCanonical := HeaderCanonical(resp.Header, baseURL)
+
 		// Check content type
 		contentType := resp.Header.Get("Content-Type")
-		if !strings.Contains(contentType, "text/html") {
+		if !contenttype.IsHTML(contentType, c.config.HTMLContentTypes) {
 			resp.Body.Close()
-			return currentURL, "", nil, nil
+			return currentURL, headerCanonical, nil, nil
 		}
 
 		// Parse page
-		baseURL, _ := url.Parse(currentURL)
-		pageInfo = ParsePage(resp.Body, baseURL, currentURL)
+		hash := sha256.New()
+		pageInfo = ParsePage(io.TeeReader(c.stats.Body(resp.Body), hash), baseURL, currentURL)
 		resp.Body.Close()
+		pageInfo.ContentHash = hex.EncodeToString(hash.Sum(nil))
+		pageInfo.LinkHeader = headerCanonical
 
-		return currentURL, pageInfo.CanonicalURL, pageInfo, nil
+		// The tag wins when both are declared, conflicts are reported apart
+		canonical = pageInfo.CanonicalURL
+		if canonical == "" {
+			canonical = headerCanonical
+		}
+		return currentURL, canonical, pageInfo, nil
 	}
 
 	return currentURL, "", nil, fmt.Errorf("too many redirects")
 }
 
-func (c *Checker) markVisited(url string) {
-	c.visitedMu.Lock()
-	c.visited[url] = true
-	c.visitedMu.Unlock()
-}
-
-func (c *Checker) shouldVisit(targetURL string) bool {
-	parsed, err := url.Parse(targetURL)
-	if err != nil {
-		return false
+// equivalence returns the URL comparison options from the config
+func (c *Checker) equivalence() EquivalenceOptions {
+	return EquivalenceOptions{
+		IgnoreWWW:    c.config.IgnoreWWW,
+		IgnoreScheme: c.config.IgnoreScheme || c.config.TreatSchemesAsSame,
+		IgnoreParams: c.config.IgnoreParams,
+		KeepParams:   c.config.KeepParams,
 	}
+}
 
-	if parsed.Host != c.baseURL.Host {
-		return false
+// conflicting reports whether canonical tags name at least two different
+// URLs under the configured equivalence options
+func (c *Checker) conflicting(canonicals []string) bool {
+	for _, other := range canonicals {
+		if !c.equivalent(canonicals[0], other) {
+			return true
+		}
 	}
+	return false
+}
 
-	c.visitedMu.RLock()
-	visited := c.visited[targetURL]
-	c.visitedMu.RUnlock()
-
-	return !visited
+// equivalent compares two URLs using the configured equivalence options
+func (c *Checker) equivalent(url1, url2 string) bool {
+	return URLsEquivalentWith(url1, url2, c.equivalence())
 }
 
-func printProgress(url, finalURL, canonical string, depth int) {
+func printProgress(url, finalURL, canonical string, depth int, opts EquivalenceOptions) {
 	indent := strings.Repeat("  ", depth)
 	status := colorGreen + "✓" + colorReset
 	extra := ""
@@ -365,15 +657,15 @@ func printProgress(url, finalURL, canonical string, depth int) {
 	if canonical == "" {
 		status = colorYellow + "!" + colorReset
 		extra = " (no canonical)"
-	} else if !URLsEquivalent(url, canonical) {
+	} else if !URLsEquivalentWith(url, canonical, opts) {
 		status = colorYellow + "→" + colorReset
 		extra = fmt.Sprintf(" → %s", canonical)
 	}
 
-	fmt.Printf("%s%s %s%s\n", indent, status, url, extra)
+	fmt.Fprintf(os.Stderr, "%s%s %s%s\n", indent, status, url, extra)
 }
 
 func printError(url, errMsg string, depth int) {
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s✗%s %s - %s\n", indent, colorRed, colorReset, url, errMsg)
+	fmt.Fprintf(os.Stderr, "%s%s✗%s %s - %s\n", indent, colorRed, colorReset, url, errMsg)
 }
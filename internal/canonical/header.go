@@ -0,0 +1,66 @@
+package canonical
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HeaderCanonical returns the canonical declared in the HTTP Link headers
+// of a response, Link: <https://example.com/page>; rel="canonical",
+// resolved against the page URL. It returns "" without one. Servers use it
+// for non-HTML files, and CDNs sometimes add it to HTML pages.
+func HeaderCanonical(header http.Header, baseURL *url.URL) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range splitLinks(value) {
+			target, params, ok := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			if hasRel(params, "canonical") {
+				return resolveURL(target[1:len(target)-1], baseURL)
+			}
+		}
+	}
+	return ""
+}
+
+// splitLinks splits a Link header value into its links. Commas separate
+// links, except inside the <URL> or a quoted parameter.
+func splitLinks(value string) []string {
+	var links []string
+	inURL, inQuotes := false, false
+	start := 0
+	for i, r := range value {
+		switch {
+		case r == '<' && !inQuotes:
+			inURL = true
+		case r == '>' && !inQuotes:
+			inURL = false
+		case r == '"' && !inURL:
+			inQuotes = !inQuotes
+		case r == ',' && !inURL && !inQuotes:
+			links = append(links, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(links, value[start:])
+}
+
+// hasRel reports whether the parameters of a link include rel, which may
+// list several space-separated relations
+func hasRel(params, rel string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+			continue
+		}
+		for _, r := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+			if strings.EqualFold(r, rel) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package canonical
+
+import (
+	"sort"
+
+	"github.com/ngonzalez/web-tools/internal/urlnorm"
+)
+
+// checkHreflangClusters reports the members of hreflang clusters that
+// canonicalize to another URL. Each language version should canonicalize
+// to itself; one pointing to the main language collapses the cluster,
+// which neither the hreflang tags nor the canonical show alone. Only
+// members the crawl reached are checked, each once, on the first page
+// listing it.
+func (c *Checker) checkHreflangClusters() {
+	c.canonicalsMu.RLock()
+	defer c.canonicalsMu.RUnlock()
+
+	pages := make([]string, 0, len(c.hreflangs))
+	for page := range c.hreflangs {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+
+	// Members are looked up by normalized URL: an alternate written with
+	// another host case or parameter order is still the crawled page
+	crawled := make(map[string]string, len(c.canonicals))
+	for page, canonical := range c.canonicals {
+		crawled[urlnorm.Normalize(page)] = canonical
+	}
+
+	checked := make(map[string]bool)
+	for _, page := range pages {
+		for _, member := range c.hreflangs[page] {
+			key := urlnorm.Normalize(member.URL)
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+
+			canonical, ok := crawled[key]
+			if !ok || c.equivalent(member.URL, canonical) {
+				continue
+			}
+			c.resultMu.Lock()
+			c.result.AddIssue(CanonicalIssue{
+				Type:         IssueHreflangCanonical,
+				SourceURL:    page,
+				LinkedURL:    member.URL,
+				CanonicalURL: canonical,
+				Lang:         member.Lang,
+			})
+			c.resultMu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package canonical
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/urlnorm"
+)
+
+// checkCanonicalLoops reports the canonical tags forming a loop: A's
+// canonical is B and B's canonical is A, possibly through more pages. No
+// page of a loop is the final version, so search engines pick one
+// arbitrarily. Each loop is reported once, on its first page in URL order,
+// with every page of the loop in canonical order.
+func (c *Checker) checkCanonicalLoops() {
+	c.canonicalsMu.RLock()
+	loops := CanonicalLoops(c.canonicals, c.equivalence())
+	c.canonicalsMu.RUnlock()
+
+	c.resultMu.Lock()
+	defer c.resultMu.Unlock()
+	for _, loop := range loops {
+		c.result.AddIssue(CanonicalIssue{
+			Type:         IssueCanonicalLoop,
+			SourceURL:    loop[0],
+			LinkedURL:    loop[0],
+			CanonicalURL: loop[1],
+			Loop:         loop,
+		})
+	}
+}
+
+// CanonicalLoops finds the cycles of a page -> canonical map, comparing URLs
+// with opts. Self-canonical pages are not loops. Each loop starts with its
+// smallest URL, and loops are sorted by that URL.
+func CanonicalLoops(canonicals map[string]string, opts EquivalenceOptions) [][]string {
+	key := func(u string) string {
+		return strings.TrimSuffix(applyEquivalence(urlnorm.Normalize(u), opts), "/")
+	}
+
+	pages := make([]string, 0, len(canonicals))
+	for page := range canonicals {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+
+	// Several crawled URLs may be the same page: the first in URL order
+	// names it in the report
+	next := make(map[string]string)
+	names := make(map[string]string)
+	for _, page := range pages {
+		from, to := key(page), key(canonicals[page])
+		if from == to {
+			continue
+		}
+		if _, ok := next[from]; !ok {
+			next[from] = to
+			names[from] = page
+		}
+	}
+
+	// Every page has at most one canonical, so walking from each page either
+	// ends, joins a walk already done or comes back on itself: a loop
+	const (
+		walking = 1
+		done    = 2
+	)
+	state := make(map[string]int)
+	var loops [][]string
+	for _, page := range pages {
+		var path []string
+		node := key(page)
+		for state[node] == 0 {
+			if _, ok := next[node]; !ok {
+				break
+			}
+			state[node] = walking
+			path = append(path, node)
+			node = next[node]
+		}
+		if state[node] == walking {
+			start := 0
+			for path[start] != node {
+				start++
+			}
+			loops = append(loops, loopNames(path[start:], names))
+		}
+		for _, n := range path {
+			state[n] = done
+		}
+	}
+
+	sort.Slice(loops, func(i, j int) bool {
+		return loops[i][0] < loops[j][0]
+	})
+	return loops
+}
+
+// loopNames returns the crawled URLs of a loop's pages, rotated to start
+// with the smallest
+func loopNames(loop []string, names map[string]string) []string {
+	first := 0
+	for i := range loop {
+		if names[loop[i]] < names[loop[first]] {
+			first = i
+		}
+	}
+	urls := make([]string, 0, len(loop))
+	for i := range loop {
+		urls = append(urls, names[loop[(first+i)%len(loop)]])
+	}
+	return urls
+}
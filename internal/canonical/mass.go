@@ -0,0 +1,118 @@
+package canonical
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/urlnorm"
+)
+
+// Mass canonicalization thresholds: a target is reported when at least
+// MassCanonicalMinPages other pages, and at least MassCanonicalShare of the
+// distinct pages declaring a canonical, point to it
+const (
+	MassCanonicalMinPages = 5
+	MassCanonicalShare    = 0.5
+)
+
+// MassCanonical is a canonical target shared by many distinct pages
+type MassCanonical struct {
+	Target string
+	Pages  []string // Pages canonicalizing to Target, sorted
+}
+
+// checkMassCanonicals reports the canonical targets most of the site points
+// to. A CMS template with a hard-coded canonical, often the homepage, tells
+// search engines the whole site is one page; each target is reported once,
+// as critical, with the pages pointing to it.
+func (c *Checker) checkMassCanonicals() {
+	c.canonicalsMu.RLock()
+	masses := MassCanonicals(c.canonicals, c.equivalence())
+	c.canonicalsMu.RUnlock()
+
+	c.resultMu.Lock()
+	defer c.resultMu.Unlock()
+	for _, mass := range masses {
+		c.result.AddIssue(CanonicalIssue{
+			Type:         IssueMassCanonical,
+			SourceURL:    mass.Target,
+			LinkedURL:    mass.Target,
+			CanonicalURL: mass.Target,
+			Pages:        mass.Pages,
+		})
+	}
+}
+
+// MassCanonicals finds the targets of a page -> canonical map shared by
+// enough distinct pages, comparing URLs with opts. Pages differing from
+// their canonical only by the query string are variants, not distinct
+// pages, and count neither way: canonicalizing sorting or tracking variants
+// to the clean URL is the intended use. Targets are sorted by the number of
+// pages, most first.
+func MassCanonicals(canonicals map[string]string, opts EquivalenceOptions) []MassCanonical {
+	key := func(u string) string {
+		return strings.TrimSuffix(applyEquivalence(urlnorm.Normalize(u), opts), "/")
+	}
+
+	pages := make([]string, 0, len(canonicals))
+	for page := range canonicals {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+
+	// Several crawled URLs may be the same page: the first in URL order
+	// names it, and the first declaration names the target
+	declaring := make(map[string]bool)
+	pointing := make(map[string]map[string]string) // Target key -> page key -> page
+	targets := make(map[string]string)
+	for _, page := range pages {
+		from, to := key(page), key(canonicals[page])
+		if from == to {
+			declaring[from] = true
+			continue
+		}
+		if withoutQuery(from) == withoutQuery(to) {
+			continue
+		}
+		declaring[from] = true
+		if pointing[to] == nil {
+			pointing[to] = make(map[string]string)
+			targets[to] = canonicals[page]
+		}
+		if _, ok := pointing[to][from]; !ok {
+			pointing[to][from] = page
+		}
+	}
+
+	var masses []MassCanonical
+	for to, from := range pointing {
+		if len(from) < MassCanonicalMinPages || float64(len(from)) < MassCanonicalShare*float64(len(declaring)) {
+			continue
+		}
+		mass := MassCanonical{Target: targets[to]}
+		for _, page := range from {
+			mass.Pages = append(mass.Pages, page)
+		}
+		sort.Strings(mass.Pages)
+		masses = append(masses, mass)
+	}
+
+	sort.Slice(masses, func(i, j int) bool {
+		if len(masses[i].Pages) != len(masses[j].Pages) {
+			return len(masses[i].Pages) > len(masses[j].Pages)
+		}
+		return masses[i].Target < masses[j].Target
+	})
+	return masses
+}
+
+// withoutQuery returns a URL without its query string
+func withoutQuery(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	parsed.RawQuery = ""
+	return strings.TrimSuffix(parsed.String(), "/")
+}
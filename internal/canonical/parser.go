@@ -3,44 +3,83 @@ package canonical
 import (
 	"io"
 	"net/url"
+	"slices"
+	"sort"
 	"strings"
 
 	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
+	"github.com/ngonzalez/web-tools/internal/htmlhead"
+	"github.com/ngonzalez/web-tools/internal/serp"
+	"github.com/ngonzalez/web-tools/internal/urlnorm"
 )
 
 // PageInfo contains parsed page information
 type PageInfo struct {
-	URL          string
-	CanonicalURL string
-	Links        []string
+	URL            string
+	CanonicalURL   string
+	Canonicals     []string // Every canonical tag found in <head>, in document order
+	BodyCanonicals []string // Canonical tags placed after <head>, which search engines ignore
+	Links          []string
+	ContentHash    string          // Hash of the raw body, to spot the same page served at several URLs
+	Hreflangs      []serp.Hreflang // Language alternates declared in <head>, the page itself included
+	LinkHeader     string          // Canonical of the HTTP Link header, set by the caller
 }
 
-// ParsePage extracts canonical and links from HTML
+// ParsePage extracts canonical and links from HTML. Only canonicals in
+// <head> count; those placed in the body are kept apart.
 func ParsePage(body io.Reader, baseURL *url.URL, pageURL string) *PageInfo {
 	info := &PageInfo{
 		URL: pageURL,
 	}
 
-	doc, err := html.Parse(body)
-	if err != nil {
-		return info
-	}
+	tokenizer := html.NewTokenizer(body)
+	inHead := true
+
+	for {
+		tokenType := tokenizer.Next()
 
-	var parseNode func(*html.Node)
-	parseNode = func(n *html.Node) {
-		if n.Type == html.ElementNode {
-			switch n.Data {
+		switch tokenType {
+		case html.ErrorToken:
+			return info
+
+		case html.EndTagToken:
+			if inHead && htmlhead.IsEnd(tokenType, tokenizer.Token().Data) {
+				inHead = false
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if inHead && htmlhead.IsEnd(tokenType, token.Data) {
+				inHead = false
+			}
+
+			switch token.Data {
 			case "link":
-				rel := strings.ToLower(getAttr(n, "rel"))
+				rel := strings.ToLower(htmlattr.Get(token, "rel"))
+				if rel == "alternate" && inHead {
+					lang := strings.TrimSpace(htmlattr.Get(token, "hreflang"))
+					if resolved := resolveURL(htmlattr.Get(token, "href"), baseURL); lang != "" && resolved != "" {
+						info.Hreflangs = append(info.Hreflangs, serp.Hreflang{Lang: lang, URL: resolved})
+					}
+				}
 				if rel == "canonical" {
-					href := getAttr(n, "href")
-					if href != "" {
-						info.CanonicalURL = resolveURL(href, baseURL)
+					href := htmlattr.Get(token, "href")
+					if href == "" {
+						break
+					}
+					resolved := resolveURL(href, baseURL)
+					if inHead {
+						info.CanonicalURL = resolved
+						info.Canonicals = append(info.Canonicals, resolved)
+					} else {
+						info.BodyCanonicals = append(info.BodyCanonicals, resolved)
 					}
 				}
 
 			case "a":
-				href := getAttr(n, "href")
+				href := htmlattr.Get(token, "href")
 				if href != "" {
 					resolved := resolveURL(href, baseURL)
 					if resolved != "" && isSameDomain(resolved, baseURL) {
@@ -49,23 +88,7 @@ func ParsePage(body io.Reader, baseURL *url.URL, pageURL string) *PageInfo {
 				}
 			}
 		}
-
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			parseNode(c)
-		}
 	}
-
-	parseNode(doc)
-	return info
-}
-
-func getAttr(n *html.Node, key string) string {
-	for _, attr := range n.Attr {
-		if strings.ToLower(attr.Key) == key {
-			return attr.Val
-		}
-	}
-	return ""
 }
 
 func resolveURL(href string, baseURL *url.URL) string {
@@ -107,43 +130,46 @@ func isSameDomain(targetURL string, baseURL *url.URL) bool {
 	return parsed.Host == baseURL.Host
 }
 
-// NormalizeURL normalizes URL for comparison
-func NormalizeURL(rawURL string) string {
-	parsed, err := url.Parse(rawURL)
+// VariantKey reduces a URL to the parts that identify a page regardless of
+// scheme, www prefix, trailing slash and query string. URLs sharing a key
+// and serving the same content are variants of one page.
+func VariantKey(rawURL string) string {
+	parsed, err := url.Parse(urlnorm.Normalize(rawURL))
 	if err != nil {
 		return rawURL
 	}
 
-	// Lowercase scheme and host
-	parsed.Scheme = strings.ToLower(parsed.Scheme)
-	parsed.Host = strings.ToLower(parsed.Host)
-
-	// Remove default ports
-	host := parsed.Host
-	if strings.HasSuffix(host, ":80") && parsed.Scheme == "http" {
-		parsed.Host = strings.TrimSuffix(host, ":80")
-	}
-	if strings.HasSuffix(host, ":443") && parsed.Scheme == "https" {
-		parsed.Host = strings.TrimSuffix(host, ":443")
-	}
+	host := strings.TrimPrefix(parsed.Host, "www.")
+	path := strings.TrimSuffix(parsed.EscapedPath(), "/")
 
-	// Remove fragment
-	parsed.Fragment = ""
-
-	// Sort query parameters for consistent comparison
-	if parsed.RawQuery != "" {
-		values := parsed.Query()
-		parsed.RawQuery = values.Encode()
-	}
+	return host + path
+}
 
-	return parsed.String()
+// EquivalenceOptions controls which URL differences are ignored when
+// comparing a page URL with its canonical
+type EquivalenceOptions struct {
+	IgnoreWWW    bool     // Treat www.example.com and example.com as the same host
+	IgnoreScheme bool     // Treat http and https as the same scheme
+	IgnoreParams []string // Query parameters dropped before comparing; "utm_*" matches a prefix, "*" every parameter
+	KeepParams   []string // Query parameters never dropped, even when IgnoreParams matches them
 }
 
 // URLsEquivalent checks if two URLs are equivalent
 func URLsEquivalent(url1, url2 string) bool {
+	return URLsEquivalentWith(url1, url2, EquivalenceOptions{})
+}
+
+// EquivalenceKey returns the normalized form of a URL under opts: equivalent
+// URLs share a key, except when they differ only by a trailing slash
+func EquivalenceKey(rawURL string, opts EquivalenceOptions) string {
+	return applyEquivalence(urlnorm.Normalize(rawURL), opts)
+}
+
+// URLsEquivalentWith checks if two URLs are equivalent using the given options
+func URLsEquivalentWith(url1, url2 string, opts EquivalenceOptions) bool {
 	// Normalize both
-	n1 := NormalizeURL(url1)
-	n2 := NormalizeURL(url2)
+	n1 := applyEquivalence(urlnorm.Normalize(url1), opts)
+	n2 := applyEquivalence(urlnorm.Normalize(url2), opts)
 
 	if n1 == n2 {
 		return true
@@ -155,3 +181,256 @@ func URLsEquivalent(url1, url2 string) bool {
 
 	return n1Slash == n2Slash
 }
+
+// applyEquivalence strips the parts of a normalized URL that the options ignore
+func applyEquivalence(normalized string, opts EquivalenceOptions) string {
+	if !opts.IgnoreWWW && !opts.IgnoreScheme && len(opts.IgnoreParams) == 0 {
+		return normalized
+	}
+
+	parsed, err := url.Parse(normalized)
+	if err != nil {
+		return normalized
+	}
+
+	if opts.IgnoreScheme && parsed.Scheme == "http" {
+		parsed.Scheme = "https"
+	}
+	if opts.IgnoreWWW {
+		parsed.Host = strings.TrimPrefix(parsed.Host, "www.")
+	}
+	if len(opts.IgnoreParams) > 0 && parsed.RawQuery != "" {
+		values := parsed.Query()
+		for name := range values {
+			if matchParam(name, opts.IgnoreParams) && !matchParam(name, opts.KeepParams) {
+				values.Del(name)
+			}
+		}
+		parsed.RawQuery = values.Encode()
+	}
+
+	return parsed.String()
+}
+
+// matchParam reports whether a query parameter name is in a list, where an
+// entry ending in "*" matches every name with that prefix
+func matchParam(name string, list []string) bool {
+	for _, p := range list {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// StrippedParams returns the query parameters a canonical drops from a page
+// URL, when the canonical is otherwise the same page: equivalent once both
+// queries are removed, and keeping every parameter it does declare with the
+// page's value. It returns nil in any other case.
+func StrippedParams(pageURL, canonicalURL string, opts EquivalenceOptions) []string {
+	page, err := url.Parse(urlnorm.Normalize(pageURL))
+	if err != nil || page.RawQuery == "" {
+		return nil
+	}
+	canonical, err := url.Parse(urlnorm.Normalize(canonicalURL))
+	if err != nil {
+		return nil
+	}
+
+	pageQuery := page.Query()
+	canonicalQuery := canonical.Query()
+	page.RawQuery = ""
+	canonical.RawQuery = ""
+	if !URLsEquivalentWith(page.String(), canonical.String(), opts) {
+		return nil
+	}
+
+	// A canonical that changes or adds a parameter is a plain mismatch
+	for name, values := range canonicalQuery {
+		if !slices.Equal(pageQuery[name], values) {
+			return nil
+		}
+	}
+
+	var stripped []string
+	for name := range pageQuery {
+		if _, ok := canonicalQuery[name]; !ok {
+			stripped = append(stripped, name)
+		}
+	}
+	sort.Strings(stripped)
+	return stripped
+}
+
+// Canonical strategies of a page, see CanonicalStrategy
+const (
+	StrategySelf      = "self-canonical"
+	StrategyMissing   = "no canonical"
+	StrategyElsewhere = "canonical to another page"
+)
+
+// CanonicalStrategy describes how a page chooses its canonical: itself, the
+// same page without some parameters ("drops page, sort"), another page, or
+// none. It also returns the query parameters the canonical keeps and drops.
+func CanonicalStrategy(pageURL, canonicalURL string, opts EquivalenceOptions) (strategy string, kept, dropped []string) {
+	if canonicalURL == "" {
+		return StrategyMissing, nil, nil
+	}
+
+	var params []string
+	if parsed, err := url.Parse(pageURL); err == nil {
+		for name := range parsed.Query() {
+			// Parameters the options ignore take no part in the strategy
+			if !matchParam(name, opts.IgnoreParams) || matchParam(name, opts.KeepParams) {
+				params = append(params, name)
+			}
+		}
+		sort.Strings(params)
+	}
+
+	if URLsEquivalentWith(pageURL, canonicalURL, opts) {
+		return StrategySelf, params, nil
+	}
+	stripped := StrippedParams(pageURL, canonicalURL, opts)
+	if len(stripped) == 0 {
+		return StrategyElsewhere, nil, nil
+	}
+	for _, name := range params {
+		if slices.Contains(stripped, name) {
+			dropped = append(dropped, name)
+		} else {
+			kept = append(kept, name)
+		}
+	}
+	return "drops " + strings.Join(dropped, ", "), kept, dropped
+}
+
+// StrategyConflicts groups crawled pages by section, their URL without the
+// query string, and returns the sections with query parameters whose pages
+// canonicalize in different ways: some pages declare a canonical and others
+// don't, some point to another page, or a parameter is kept on some pages
+// and dropped on others. canonicals maps each page to its canonical, pages
+// without canonical are listed in without. Sections are sorted by URL.
+func StrategyConflicts(canonicals map[string]string, without []string, opts EquivalenceOptions) []StrategyConflict {
+	pages := make(map[string]string, len(canonicals)+len(without))
+	for page, canonical := range canonicals {
+		pages[page] = canonical
+	}
+	for _, page := range without {
+		if _, ok := pages[page]; !ok {
+			pages[page] = ""
+		}
+	}
+
+	type section struct {
+		url       string
+		pages     []SectionPage
+		hasParams bool
+		kinds     map[string]bool
+		kept      map[string]bool
+		dropped   map[string]bool
+	}
+	sections := make(map[string]*section)
+
+	for page, canonical := range pages {
+		key := VariantKey(page)
+		sec, ok := sections[key]
+		if !ok {
+			sec = &section{kinds: make(map[string]bool), kept: make(map[string]bool), dropped: make(map[string]bool)}
+			sections[key] = sec
+		}
+
+		parsed, err := url.Parse(page)
+		if err != nil {
+			continue
+		}
+		if parsed.RawQuery != "" {
+			sec.hasParams = true
+		}
+		bare := *parsed
+		bare.RawQuery = ""
+		if sec.url == "" || bare.String() < sec.url {
+			sec.url = bare.String()
+		}
+
+		strategy, kept, dropped := CanonicalStrategy(page, canonical, opts)
+		switch strategy {
+		case StrategyMissing, StrategyElsewhere:
+			sec.kinds[strategy] = true
+		default:
+			sec.kinds["declared"] = true
+		}
+		for _, name := range kept {
+			sec.kept[name] = true
+		}
+		for _, name := range dropped {
+			sec.dropped[name] = true
+		}
+		sec.pages = append(sec.pages, SectionPage{URL: page, Canonical: canonical, Strategy: strategy})
+	}
+
+	var conflicts []StrategyConflict
+	for _, sec := range sections {
+		if !sec.hasParams || len(sec.pages) < 2 {
+			continue
+		}
+		var mixed []string
+		for name := range sec.dropped {
+			if sec.kept[name] {
+				mixed = append(mixed, name)
+			}
+		}
+		if len(sec.kinds) < 2 && len(mixed) == 0 {
+			continue
+		}
+
+		sort.Strings(mixed)
+		sort.Slice(sec.pages, func(i, j int) bool {
+			return sec.pages[i].URL < sec.pages[j].URL
+		})
+		conflicts = append(conflicts, StrategyConflict{Section: sec.url, Pages: sec.pages, MixedParams: mixed})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Section < conflicts[j].Section
+	})
+	return conflicts
+}
+
+// ParseParamList splits a comma-separated list of query parameter names
+func ParseParamList(s string) []string {
+	var params []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// CanonicalHosts returns the hosts of a page URL and its canonical, and
+// whether they belong to different domains under the given options
+func CanonicalHosts(pageURL, canonicalURL string, opts EquivalenceOptions) (pageHost, canonicalHost string, crossDomain bool) {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return "", "", false
+	}
+	canonical, err := url.Parse(canonicalURL)
+	if err != nil || canonical.Host == "" {
+		return "", "", false
+	}
+
+	pageHost = strings.ToLower(page.Hostname())
+	canonicalHost = strings.ToLower(canonical.Hostname())
+
+	a, b := pageHost, canonicalHost
+	if opts.IgnoreWWW {
+		a = strings.TrimPrefix(a, "www.")
+		b = strings.TrimPrefix(b, "www.")
+	}
+
+	return pageHost, canonicalHost, a != b
+}
@@ -0,0 +1,120 @@
+package canonical
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RedirectHop is one redirect answered while fetching a crawled URL
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+	Location   string // Resolved redirect target
+}
+
+// IsPermanentRedirect reports whether a status moves a URL for good, 301 or
+// 308, passing its ranking signals to the target
+func IsPermanentRedirect(status int) bool {
+	return status == 301 || status == 308
+}
+
+// IsTemporaryRedirect reports whether a status is a temporary move, 302 or
+// 307, which leaves search engines indexing the redirecting URL
+func IsTemporaryRedirect(status int) bool {
+	return status == 302 || status == 307
+}
+
+// RedirectKind names the kind of a redirect status
+func RedirectKind(status int) string {
+	switch {
+	case IsPermanentRedirect(status):
+		return "permanent"
+	case IsTemporaryRedirect(status):
+		return "temporary"
+	default:
+		return "other"
+	}
+}
+
+// StatusCount is the number of redirects answered with a status
+type StatusCount struct {
+	StatusCode int
+	Count      int
+}
+
+// RedirectStatuses counts the redirect hops by status code, in code order
+func (r *CanonicalResult) RedirectStatuses() []StatusCount {
+	counts := make(map[int]int)
+	for _, hops := range r.Redirects {
+		for _, hop := range hops {
+			counts[hop.StatusCode]++
+		}
+	}
+	var statuses []StatusCount
+	for status, count := range counts {
+		statuses = append(statuses, StatusCount{StatusCode: status, Count: count})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].StatusCode < statuses[j].StatusCode
+	})
+	return statuses
+}
+
+// authPathWords mark a redirect target that is a login step, a redirect
+// meant to stay temporary
+var authPathWords = []string{"login", "signin", "sign-in", "logon", "auth", "sso"}
+
+// looksPermanent reports whether a temporary redirect reads as a page that
+// moved: it stays on the site, doesn't lead to a login step and doesn't
+// carry the original URL back in a parameter, as a return-to redirect does
+func (c *Checker) looksPermanent(hop RedirectHop) bool {
+	if !isSameDomain(hop.Location, c.baseURL) {
+		return false
+	}
+	target, err := url.Parse(hop.Location)
+	if err != nil {
+		return false
+	}
+	path := strings.ToLower(target.Path)
+	for _, word := range authPathWords {
+		if strings.Contains(path, word) {
+			return false
+		}
+	}
+	source, err := url.Parse(hop.URL)
+	if err != nil {
+		return false
+	}
+	for _, values := range target.Query() {
+		for _, v := range values {
+			if v == hop.URL || (source.Path != "" && source.Path != "/" && strings.Contains(v, source.Path)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkTemporaryRedirects reports the 302 and 307 redirects answered for an
+// internal link that look like permanent moves, each redirecting URL once
+// even when several links or chains reach it
+func (c *Checker) checkTemporaryRedirects(sourceURL, targetURL string) {
+	if sourceURL == "" {
+		return
+	}
+	c.resultMu.Lock()
+	defer c.resultMu.Unlock()
+	for _, hop := range c.result.Redirects[targetURL] {
+		if IsTemporaryRedirect(hop.StatusCode) && !c.temporary[hop.URL] && c.looksPermanent(hop) {
+			c.temporary[hop.URL] = true
+			c.result.AddIssue(CanonicalIssue{
+				Type:       IssueTemporaryRedirect,
+				SourceURL:  sourceURL,
+				LinkedURL:  hop.URL,
+				FinalURL:   hop.Location,
+				StatusCode: hop.StatusCode,
+			})
+		}
+	}
+}
@@ -3,18 +3,32 @@ package canonical
 import (
 	"fmt"
 	"sort"
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
 )
 
 // IssueType categorizes canonical issues
 type IssueType int
 
 const (
-	IssueNonCanonicalLink IssueType = iota // Link points to non-canonical URL
-	IssueMissingCanonical                  // Page has no canonical tag
-	IssueSelfCanonical                     // OK: page canonical points to itself
-	IssueRedirectToCanonical               // Link causes redirect to canonical
-	IssueCanonicalMismatch                 // Canonical differs from accessed URL
-	IssueCanonicalChain                    // Canonical points to another page with different canonical
+	IssueNonCanonicalLink      IssueType = iota // Link points to non-canonical URL
+	IssueMissingCanonical                       // Page has no canonical tag
+	IssueSelfCanonical                          // OK: page canonical points to itself
+	IssueRedirectToCanonical                    // Link causes redirect to canonical
+	IssueCanonicalMismatch                      // Canonical differs from accessed URL
+	IssueCanonicalChain                         // Canonical points to another page with different canonical
+	IssueMultipleCanonicals                     // Page declares more than one canonical tag
+	IssueCrossDomainCanonical                   // Canonical points to a different host
+	IssueDuplicateNoCanonical                   // Page has no canonical and was reached through several URLs
+	IssueCanonicalStripsParams                  // Canonical is the page URL without some query parameters
+	IssueCanonicalInBody                        // Canonical tag placed in <body>, ignored by search engines
+	IssueUnreachableCanonical                   // Canonical points to a URL answering an error or not at all
+	IssueTemporaryRedirect                      // Internal link answers a 302 or 307 that looks like a permanent move
+	IssueHreflangCanonical                      // Hreflang cluster member canonicalizes to another URL
+	IssueCanonicalLoop                          // Canonicals form a loop: A's canonical is B, B's is A
+	IssueHeaderConflict                         // Link header and <link> tag declare different canonicals
+	IssueMassCanonical                          // Many distinct pages canonicalize to a single URL
 )
 
 func (t IssueType) String() string {
@@ -29,6 +43,28 @@ func (t IssueType) String() string {
 		return "Canonical mismatch"
 	case IssueCanonicalChain:
 		return "Canonical chain"
+	case IssueMultipleCanonicals:
+		return "Multiple canonicals"
+	case IssueCrossDomainCanonical:
+		return "Cross-domain canonical"
+	case IssueDuplicateNoCanonical:
+		return "Duplicate, no canonical"
+	case IssueCanonicalStripsParams:
+		return "Canonical strips params"
+	case IssueCanonicalInBody:
+		return "Canonical in body"
+	case IssueUnreachableCanonical:
+		return "Unreachable canonical"
+	case IssueTemporaryRedirect:
+		return "Temporary redirect"
+	case IssueHreflangCanonical:
+		return "Hreflang canonical away"
+	case IssueCanonicalLoop:
+		return "Canonical loop"
+	case IssueHeaderConflict:
+		return "Header/tag conflict"
+	case IssueMassCanonical:
+		return "Mass canonicalization"
 	default:
 		return "Unknown"
 	}
@@ -46,6 +82,28 @@ func (t IssueType) Description() string {
 		return "Canonical URL differs from the accessed URL"
 	case IssueCanonicalChain:
 		return "Canonical points to a page that has a different canonical"
+	case IssueMultipleCanonicals:
+		return "Page has canonical tags naming different URLs - search engines may ignore all of them"
+	case IssueCrossDomainCanonical:
+		return "Canonical points to another domain - fine for syndication, critical if staging points to production"
+	case IssueDuplicateNoCanonical:
+		return "Page has no canonical tag and the same content was reached through several URLs"
+	case IssueCanonicalStripsParams:
+		return "Canonical is the page URL without some query parameters - right for tracking or sorting, wrong for pagination"
+	case IssueCanonicalInBody:
+		return "Canonical tag placed in <body> - search engines ignore it"
+	case IssueUnreachableCanonical:
+		return "Canonical points to a URL that answers an error or can't be reached - there is nothing to index"
+	case IssueTemporaryRedirect:
+		return "Link answers a 302 or 307 that looks like a permanent move - search engines keep the old URL and its signals don't pass"
+	case IssueHreflangCanonical:
+		return "Language version listed in an hreflang cluster canonicalizes to another URL - the cluster collapses and the version drops out of search"
+	case IssueCanonicalLoop:
+		return "Canonicals point to each other in a loop - there is no final version and search engines pick one arbitrarily"
+	case IssueHeaderConflict:
+		return "The Link header and the <link> tag declare different canonicals - search engines get conflicting signals and may ignore both"
+	case IssueMassCanonical:
+		return "Most pages canonicalize to this single URL - search engines are told the whole site is one page and drop the others"
 	default:
 		return ""
 	}
@@ -53,11 +111,22 @@ func (t IssueType) Description() string {
 
 // CanonicalIssue represents a canonical URL issue
 type CanonicalIssue struct {
-	Type         IssueType
-	SourceURL    string // Page where the link was found
-	LinkedURL    string // URL that was linked
-	CanonicalURL string // The canonical URL (if different)
-	FinalURL     string // URL after redirects (if applicable)
+	Type          IssueType
+	SourceURL     string   // Page where the link was found
+	LinkedURL     string   // URL that was linked
+	CanonicalURL  string   // The canonical URL (if different)
+	FinalURL      string   // URL after redirects (if applicable)
+	Canonicals    []string // All canonical values found (for multiple canonicals)
+	PageHost      string   // Host of the page (for cross-domain canonicals)
+	CanonicalHost string   // Host the canonical points to (for cross-domain canonicals)
+	Variants      []string // Other URLs serving the same content (for duplicates without canonical)
+	Stripped      []string // Query parameters the canonical drops (for param-stripping canonicals)
+	TargetError   string   // Why the canonical can't be reached (for unreachable canonicals)
+	StatusCode    int      // Redirect status (for temporary redirects)
+	Lang          string   // hreflang value of the member (for hreflang canonicals)
+	Loop          []string // Pages of the loop in canonical order, SourceURL first (for canonical loops)
+	LinkHeader    string   // Canonical of the Link header, CanonicalURL being the tag's (for header/tag conflicts)
+	Pages         []string // Distinct pages canonicalizing to LinkedURL (for mass canonicalization)
 }
 
 // PageCanonical stores canonical info for a page
@@ -68,15 +137,49 @@ type PageCanonical struct {
 	IsSelfRef    bool
 }
 
+// SectionPage is a page of a section with the way it chooses its canonical
+type SectionPage struct {
+	URL       string
+	Canonical string // Empty without canonical
+	Strategy  string // Self-canonical, "drops page, sort", no canonical or canonical to another page
+}
+
+// StrategyConflict is a section, pages sharing a URL up to the query string,
+// whose paginated or filtered pages use different canonical strategies
+type StrategyConflict struct {
+	Section     string
+	Pages       []SectionPage // Sorted by URL
+	MixedParams []string      // Parameters kept by some canonicals and dropped by others
+}
+
 // CanonicalResult holds analysis results
 type CanonicalResult struct {
-	StartURL       string
-	TotalPages     int
-	TotalLinks     int
-	Issues         []CanonicalIssue
-	ByType         map[IssueType][]CanonicalIssue
-	PagesWithout   []string // Pages without canonical
-	NonCanonicals  map[string]string // URL -> canonical mapping
+	StartURL      string
+	TotalPages    int
+	TotalLinks    int
+	Issues        []CanonicalIssue
+	ByType        map[IssueType][]CanonicalIssue
+	PagesWithout  []string                 // Pages without canonical
+	NonCanonicals map[string]string        // URL -> canonical mapping
+	Canonicals    map[string]string        // Crawled page (after redirects) -> declared canonical
+	Conflicts     []StrategyConflict       // Sections whose pages use different canonical strategies
+	RedirectHops  map[string]int           // Crawled URL that redirected -> redirects followed to reach the page
+	Redirects     map[string][]RedirectHop // Crawled URL that redirected -> every redirect answered, in order
+	CrawlStats    crawlstats.Stats
+
+	// Distinct canonical targets followed with Config.FollowCanonicals, each
+	// crawled once even when links reach it too
+	FollowedCanonicals int
+
+	// How the checker compared URLs, for exports judging page and canonical
+	// the same way the issues do
+	Equivalence EquivalenceOptions
+
+	// Issues and ByType keep at most MaxStoredExamples issues of each type,
+	// 0 keeps them all; the counts below include every issue found
+	MaxStoredExamples int
+	TotalIssues       int
+	CountByType       map[IssueType]int
 }
 
 // NewCanonicalResult creates a new result
@@ -84,16 +187,49 @@ func NewCanonicalResult(startURL string) *CanonicalResult {
 	return &CanonicalResult{
 		StartURL:      startURL,
 		ByType:        make(map[IssueType][]CanonicalIssue),
+		CountByType:   make(map[IssueType]int),
 		NonCanonicals: make(map[string]string),
+		Canonicals:    make(map[string]string),
+		RedirectHops:  make(map[string]int),
+		Redirects:     make(map[string][]RedirectHop),
 	}
 }
 
-// AddIssue adds an issue
+// AddIssue counts an issue and stores it while its type is below
+// MaxStoredExamples
 func (r *CanonicalResult) AddIssue(issue CanonicalIssue) {
+	r.TotalIssues++
+	r.CountByType[issue.Type]++
+	if r.MaxStoredExamples > 0 && len(r.ByType[issue.Type]) >= r.MaxStoredExamples {
+		return
+	}
 	r.Issues = append(r.Issues, issue)
 	r.ByType[issue.Type] = append(r.ByType[issue.Type], issue)
 }
 
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *CanonicalResult) SummaryLine() string {
+	return fmt.Sprintf("SUMMARY tool=linkcanonical pages=%d links=%d issues=%d non_canonical=%d redirects=%d mismatches=%d missing=%d chains=%d multiple=%d cross_domain=%d duplicate_no_canonical=%d strips_params=%d in_body=%d unreachable=%d inconsistent_sections=%d temporary_redirects=%d hreflang_canonical=%d canonical_loops=%d header_conflicts=%d mass_canonicals=%d",
+		r.TotalPages, r.TotalLinks, r.TotalIssues,
+		r.CountByType[IssueNonCanonicalLink],
+		r.CountByType[IssueRedirectToCanonical],
+		r.CountByType[IssueCanonicalMismatch],
+		r.CountByType[IssueMissingCanonical],
+		r.CountByType[IssueCanonicalChain],
+		r.CountByType[IssueMultipleCanonicals],
+		r.CountByType[IssueCrossDomainCanonical],
+		r.CountByType[IssueDuplicateNoCanonical],
+		r.CountByType[IssueCanonicalStripsParams],
+		r.CountByType[IssueCanonicalInBody],
+		r.CountByType[IssueUnreachableCanonical],
+		len(r.Conflicts),
+		r.CountByType[IssueTemporaryRedirect],
+		r.CountByType[IssueHreflangCanonical],
+		r.CountByType[IssueCanonicalLoop],
+		r.CountByType[IssueHeaderConflict],
+		r.CountByType[IssueMassCanonical])
+}
+
 // ANSI colors
 const (
 	colorReset  = "\033[0m"
@@ -109,15 +245,22 @@ const (
 
 // PrintSummary displays the results
 func (r *CanonicalResult) PrintSummary(showDetails bool) {
+	// Crawl stats always close the summary, whichever branch returns
+	defer r.CrawlStats.Print()
+
 	fmt.Println()
 	fmt.Printf("%s%s=== Canonical Analysis ===%s\n", colorBold, colorCyan, colorReset)
 	fmt.Printf("Start URL: %s%s%s\n", colorBlue, r.StartURL, colorReset)
 	fmt.Printf("Pages analyzed: %s%d%s\n", colorGreen, r.TotalPages, colorReset)
 	fmt.Printf("Links checked: %s%d%s\n", colorGreen, r.TotalLinks, colorReset)
+	if r.FollowedCanonicals > 0 {
+		fmt.Printf("Canonical targets followed: %s%d%s\n", colorGreen, r.FollowedCanonicals, colorReset)
+	}
+	r.printRedirectStatuses()
 	fmt.Println()
 
 	// Count issues
-	totalIssues := len(r.Issues)
+	totalIssues := r.TotalIssues
 	if totalIssues == 0 {
 		fmt.Printf("%s%s✓ No canonical issues detected!%s\n", colorBold, colorGreen, colorReset)
 		fmt.Println()
@@ -131,26 +274,42 @@ func (r *CanonicalResult) PrintSummary(showDetails bool) {
 	fmt.Printf("%s%sSummary by type:%s\n", colorBold, colorYellow, colorReset)
 
 	issueTypes := []IssueType{
+		IssueMassCanonical,
 		IssueNonCanonicalLink,
 		IssueRedirectToCanonical,
 		IssueCanonicalMismatch,
+		IssueCanonicalStripsParams,
 		IssueMissingCanonical,
+		IssueDuplicateNoCanonical,
 		IssueCanonicalChain,
+		IssueCanonicalLoop,
+		IssueMultipleCanonicals,
+		IssueHeaderConflict,
+		IssueCanonicalInBody,
+		IssueUnreachableCanonical,
+		IssueCrossDomainCanonical,
+		IssueTemporaryRedirect,
+		IssueHreflangCanonical,
 	}
 
 	for _, t := range issueTypes {
-		issues := r.ByType[t]
-		if len(issues) == 0 {
+		count := r.CountByType[t]
+		if count == 0 {
 			continue
 		}
 
 		color := colorYellow
-		if t == IssueNonCanonicalLink || t == IssueCanonicalChain {
+		if t == IssueMassCanonical || t == IssueNonCanonicalLink || t == IssueCanonicalChain || t == IssueCanonicalLoop || t == IssueMultipleCanonicals || t == IssueHeaderConflict || t == IssueCrossDomainCanonical || t == IssueDuplicateNoCanonical || t == IssueCanonicalInBody || t == IssueUnreachableCanonical || t == IssueHreflangCanonical {
 			color = colorRed
 		}
 
-		fmt.Printf("  %s%-25s%s %d\n", color, t.String()+":", colorReset, len(issues))
+		fmt.Printf("  %s%-25s%s %d\n", color, t.String()+":", colorReset, count)
 	}
+	if len(r.Conflicts) > 0 {
+		fmt.Printf("  %s%-25s%s %d\n", colorRed, "Inconsistent sections:", colorReset, len(r.Conflicts))
+	}
+
+	r.printConflicts()
 
 	if showDetails {
 		r.printDetails()
@@ -162,17 +321,59 @@ func (r *CanonicalResult) PrintSummary(showDetails bool) {
 	fmt.Println()
 }
 
+// printConflicts lists the sections whose paginated or filtered pages
+// canonicalize in different ways, with the strategy of each page
+func (r *CanonicalResult) printConflicts() {
+	if len(r.Conflicts) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sInconsistent canonical strategies (%d sections):%s\n", colorBold, colorRed, len(r.Conflicts), colorReset)
+	fmt.Printf("%sPages of the same section should all be self-canonical, or all drop the same parameters%s\n", colorGray, colorReset)
+
+	for i, conflict := range r.Conflicts {
+		if i >= 10 {
+			fmt.Printf("\n  %s... and %d more sections%s\n", colorGray, len(r.Conflicts)-10, colorReset)
+			break
+		}
+
+		fmt.Printf("\n  %sSection:%s %s\n", colorCyan, colorReset, truncateURL(conflict.Section, 70))
+		if len(conflict.MixedParams) > 0 {
+			fmt.Printf("    %sKept on some pages, dropped on others:%s %s\n", colorRed, colorReset, strings.Join(conflict.MixedParams, ", "))
+		}
+		for j, page := range conflict.Pages {
+			if j >= 5 {
+				fmt.Printf("    %s... and %d more pages%s\n", colorGray, len(conflict.Pages)-5, colorReset)
+				break
+			}
+			fmt.Printf("    %s→%s %s %s(%s)%s\n", colorYellow, colorReset, truncateURL(page.URL, 60), colorGray, page.Strategy, colorReset)
+		}
+	}
+}
+
 func (r *CanonicalResult) printDetails() {
 	fmt.Println()
 	fmt.Printf("%s%s=== Issue Details ===%s\n", colorBold, colorPurple, colorReset)
 
 	// Group by issue type
 	issueTypes := []IssueType{
+		IssueMassCanonical,
 		IssueNonCanonicalLink,
 		IssueRedirectToCanonical,
 		IssueCanonicalMismatch,
+		IssueCanonicalStripsParams,
 		IssueMissingCanonical,
+		IssueDuplicateNoCanonical,
 		IssueCanonicalChain,
+		IssueCanonicalLoop,
+		IssueMultipleCanonicals,
+		IssueHeaderConflict,
+		IssueCanonicalInBody,
+		IssueUnreachableCanonical,
+		IssueCrossDomainCanonical,
+		IssueTemporaryRedirect,
+		IssueHreflangCanonical,
 	}
 
 	for _, t := range issueTypes {
@@ -183,12 +384,15 @@ func (r *CanonicalResult) printDetails() {
 
 		fmt.Println()
 		color := colorYellow
-		if t == IssueNonCanonicalLink || t == IssueCanonicalChain {
+		if t == IssueMassCanonical || t == IssueNonCanonicalLink || t == IssueCanonicalChain || t == IssueCanonicalLoop || t == IssueMultipleCanonicals || t == IssueHeaderConflict || t == IssueCrossDomainCanonical || t == IssueDuplicateNoCanonical || t == IssueCanonicalInBody || t == IssueUnreachableCanonical || t == IssueHreflangCanonical {
 			color = colorRed
 		}
 
-		fmt.Printf("%s%s%s (%d)%s\n", colorBold, color, t.String(), len(issues), colorReset)
+		fmt.Printf("%s%s%s (%d)%s\n", colorBold, color, t.String(), r.CountByType[t], colorReset)
 		fmt.Printf("%s%s%s\n", colorGray, t.Description(), colorReset)
+		if len(issues) < r.CountByType[t] {
+			fmt.Printf("%sOnly the first %d were kept%s\n", colorGray, len(issues), colorReset)
+		}
 
 		// Group by source URL for cleaner output
 		bySource := make(map[string][]CanonicalIssue)
@@ -223,12 +427,50 @@ func (r *CanonicalResult) printDetails() {
 				}
 
 				fmt.Printf("    %s→%s %s\n", colorYellow, colorReset, truncateURL(issue.LinkedURL, 65))
-				if issue.CanonicalURL != "" && issue.CanonicalURL != issue.LinkedURL {
+				if issue.CanonicalURL != "" && issue.CanonicalURL != issue.LinkedURL && issue.LinkHeader == "" {
 					fmt.Printf("      %sCanonical:%s %s\n", colorGreen, colorReset, truncateURL(issue.CanonicalURL, 60))
 				}
 				if issue.FinalURL != "" && issue.FinalURL != issue.LinkedURL {
 					fmt.Printf("      %sRedirects to:%s %s\n", colorGray, colorReset, truncateURL(issue.FinalURL, 55))
 				}
+				if issue.PageHost != "" && issue.CanonicalHost != "" {
+					fmt.Printf("      %sHosts:%s %s → %s\n", colorRed, colorReset, issue.PageHost, issue.CanonicalHost)
+				}
+				for _, c := range issue.Canonicals {
+					fmt.Printf("      %sDeclared:%s %s\n", colorRed, colorReset, truncateURL(c, 60))
+				}
+				for _, v := range issue.Variants {
+					fmt.Printf("      %sAlso served at:%s %s\n", colorRed, colorReset, truncateURL(v, 53))
+				}
+				if issue.StatusCode != 0 {
+					fmt.Printf("      %sStatus:%s %d (%s)\n", colorYellow, colorReset, issue.StatusCode, RedirectKind(issue.StatusCode))
+				}
+				if issue.LinkHeader != "" {
+					fmt.Printf("      %sTag:%s %s\n", colorRed, colorReset, truncateURL(issue.CanonicalURL, 66))
+					fmt.Printf("      %sLink header:%s %s\n", colorRed, colorReset, truncateURL(issue.LinkHeader, 58))
+				}
+				if issue.Lang != "" {
+					fmt.Printf("      %sHreflang:%s %s\n", colorRed, colorReset, issue.Lang)
+				}
+				if len(issue.Pages) > 0 {
+					fmt.Printf("      %sCanonical of %d pages:%s\n", colorRed, len(issue.Pages), colorReset)
+					for k, page := range issue.Pages {
+						if k >= 5 {
+							fmt.Printf("        %s... and %d more%s\n", colorGray, len(issue.Pages)-5, colorReset)
+							break
+						}
+						fmt.Printf("        %s\n", truncateURL(page, 64))
+					}
+				}
+				if len(issue.Loop) > 0 {
+					fmt.Printf("      %sLoop:%s %s → %s\n", colorRed, colorReset, strings.Join(issue.Loop, " → "), issue.Loop[0])
+				}
+				if issue.TargetError != "" {
+					fmt.Printf("      %sTarget:%s %s\n", colorRed, colorReset, issue.TargetError)
+				}
+				if len(issue.Stripped) > 0 {
+					fmt.Printf("      %sStripped:%s %s\n", colorYellow, colorReset, strings.Join(issue.Stripped, ", "))
+				}
 			}
 
 			displayed++
@@ -261,6 +503,94 @@ func (r *CanonicalResult) printRecommendations() {
 		fmt.Printf("   Canonicals should point to the final version, not an\n")
 		fmt.Printf("   intermediate page. Fix chains A→B→C to A→C.\n")
 	}
+
+	if len(r.ByType[IssueMultipleCanonicals]) > 0 {
+		fmt.Printf("\n%s5. Multiple canonicals:%s\n", colorRed, colorReset)
+		fmt.Printf("   Keep a single <link rel=\"canonical\"> per page. When several\n")
+		fmt.Printf("   conflicting tags are present, Google ignores all of them.\n")
+	}
+
+	if len(r.ByType[IssueCrossDomainCanonical]) > 0 {
+		fmt.Printf("\n%s6. Cross-domain canonicals:%s\n", colorRed, colorReset)
+		fmt.Printf("   Check that each foreign canonical is intended (syndicated content).\n")
+		fmt.Printf("   A staging site pointing to production, or the reverse, must be fixed.\n")
+	}
+
+	if len(r.ByType[IssueDuplicateNoCanonical]) > 0 {
+		fmt.Printf("\n%s7. Duplicates without canonical:%s\n", colorRed, colorReset)
+		fmt.Printf("   These pages answer at several URLs (parameters, trailing slash,\n")
+		fmt.Printf("   http/https). Fix them first: declare the preferred URL as canonical.\n")
+	}
+
+	if len(r.ByType[IssueCanonicalStripsParams]) > 0 {
+		fmt.Printf("\n%s8. Canonicals stripping parameters:%s\n", colorYellow, colorReset)
+		fmt.Printf("   Review each one. Dropping tracking, session or sort parameters is the\n")
+		fmt.Printf("   intended consolidation; dropping pagination (page=2) hides the content\n")
+		fmt.Printf("   of later pages. Paginated pages should canonicalize to themselves.\n")
+	}
+
+	if len(r.ByType[IssueCanonicalInBody]) > 0 {
+		fmt.Printf("\n%s9. Canonicals in body:%s\n", colorRed, colorReset)
+		fmt.Printf("   Move <link rel=\"canonical\"> into <head>. A stray element before it\n")
+		fmt.Printf("   (a <div>, an <img>) ends <head> early and pushes the tag into the body.\n")
+	}
+
+	if len(r.ByType[IssueUnreachableCanonical]) > 0 {
+		fmt.Printf("\n%s10. Unreachable canonicals:%s\n", colorRed, colorReset)
+		fmt.Printf("   Point these canonicals to a live page, usually the page itself.\n")
+		fmt.Printf("   A canonical to a missing URL can drop the page from the index.\n")
+	}
+
+	if len(r.Conflicts) > 0 {
+		fmt.Printf("\n%s11. Inconsistent sections:%s\n", colorRed, colorReset)
+		fmt.Printf("   Pick one strategy per section and apply it to every page: paginated\n")
+		fmt.Printf("   pages self-canonical, filters and sorting dropped from the canonical.\n")
+		fmt.Printf("   Templates that disagree usually come from different code paths.\n")
+	}
+
+	if len(r.ByType[IssueTemporaryRedirect]) > 0 {
+		fmt.Printf("\n%s12. Temporary redirects:%s\n", colorYellow, colorReset)
+		fmt.Printf("   Answer moved pages with a 301 or 308. A 302 or 307 tells search\n")
+		fmt.Printf("   engines to keep the old URL indexed, so the new one gains nothing.\n")
+	}
+
+	if len(r.ByType[IssueHreflangCanonical]) > 0 {
+		fmt.Printf("\n%s13. Hreflang members canonicalizing away:%s\n", colorRed, colorReset)
+		fmt.Printf("   Each language version must canonicalize to itself. Pointing every\n")
+		fmt.Printf("   version to the main language leaves only that one in search.\n")
+	}
+
+	if len(r.ByType[IssueCanonicalLoop]) > 0 {
+		fmt.Printf("\n%s14. Canonical loops:%s\n", colorRed, colorReset)
+		fmt.Printf("   Pick the page that should be indexed and make it canonicalize to\n")
+		fmt.Printf("   itself; the other pages of the loop then point to it.\n")
+	}
+
+	if len(r.ByType[IssueHeaderConflict]) > 0 {
+		fmt.Printf("\n%s15. Link header and tag conflicts:%s\n", colorRed, colorReset)
+		fmt.Printf("   Declare the canonical in one place, or make both agree. A CDN or\n")
+		fmt.Printf("   server rule adding a Link header usually disagrees with the CMS tag.\n")
+	}
+
+	if len(r.ByType[IssueMassCanonical]) > 0 {
+		fmt.Printf("\n%s16. Mass canonicalization:%s\n", colorRed, colorReset)
+		fmt.Printf("   Fix this first. A template printing the same canonical on every page,\n")
+		fmt.Printf("   often the homepage, keeps all other pages out of the index. Each page\n")
+		fmt.Printf("   should canonicalize to itself unless it really duplicates another.\n")
+	}
+}
+
+// printRedirectStatuses breaks down the redirects answered by status code
+func (r *CanonicalResult) printRedirectStatuses() {
+	statuses := r.RedirectStatuses()
+	if len(statuses) == 0 {
+		return
+	}
+	var parts []string
+	for _, s := range statuses {
+		parts = append(parts, fmt.Sprintf("%d %s ×%d", s.StatusCode, RedirectKind(s.StatusCode), s.Count))
+	}
+	fmt.Printf("Redirects answered: %s\n", strings.Join(parts, ", "))
 }
 
 func truncateURL(url string, maxLen int) string {
@@ -269,3 +599,37 @@ func truncateURL(url string, maxLen int) string {
 	}
 	return url[:maxLen-3] + "..."
 }
+
+// ExportMappingCSV returns every crawled page with its declared canonical, as
+// CSV sorted by URL. Pages without a canonical have an empty canonical_url;
+// differs is true when the canonical is not the page itself under the
+// checker's URL equivalence, i.e. a candidate redirect rule.
+func (r *CanonicalResult) ExportMappingCSV() string {
+	mapping := make(map[string]string, len(r.Canonicals)+len(r.PagesWithout))
+	for page, canonical := range r.Canonicals {
+		mapping[page] = canonical
+	}
+	for _, page := range r.PagesWithout {
+		if _, ok := mapping[page]; !ok {
+			mapping[page] = ""
+		}
+	}
+
+	pages := make([]string, 0, len(mapping))
+	for page := range mapping {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+
+	var sb strings.Builder
+	sb.WriteString("source_url,canonical_url,differs\n")
+
+	for _, page := range pages {
+		canonical := mapping[page]
+		differs := canonical != "" && !URLsEquivalentWith(page, canonical, r.Equivalence)
+		sb.WriteString(fmt.Sprintf("\"%s\",\"%s\",%t\n",
+			strings.ReplaceAll(page, "\"", "\"\""), strings.ReplaceAll(canonical, "\"", "\"\""), differs))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,42 @@
+package contenttype
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HTML are the media types always parsed as HTML
+var HTML = []string{"text/html", "application/xhtml+xml"}
+
+// MediaType returns the media type of a Content-Type header, lowercase and
+// without parameters
+func MediaType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// IsHTML reports whether a Content-Type header announces HTML, or one of
+// the extra media types configured to be parsed as HTML, such as
+// application/xml for a misconfigured server
+func IsHTML(contentType string, extra []string) bool {
+	mediaType := MediaType(contentType)
+	return slices.Contains(HTML, mediaType) || slices.Contains(extra, mediaType)
+}
+
+// ParseList parses a comma-separated list of media types, such as
+// "application/xml,text/plain"
+func ParseList(s string) ([]string, error) {
+	var types []string
+	for _, part := range strings.Split(s, ",") {
+		mediaType := MediaType(part)
+		if mediaType == "" {
+			continue
+		}
+		if strings.Contains(part, ";") || strings.Count(mediaType, "/") != 1 || strings.HasPrefix(mediaType, "/") || strings.HasSuffix(mediaType, "/") {
+			return nil, fmt.Errorf("invalid media type %q, expected type/subtype", strings.TrimSpace(part))
+		}
+		types = append(types, mediaType)
+	}
+	return types, nil
+}
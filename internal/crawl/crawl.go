@@ -0,0 +1,316 @@
+package crawl
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxPages caps a crawl when Config.MaxPages is not set
+const DefaultMaxPages = 10000
+
+// Order is the order queued URLs are visited in
+type Order string
+
+const (
+	OrderBFS    Order = "bfs"    // Oldest queued URL first: shallow pages before deep ones
+	OrderDFS    Order = "dfs"    // Newest queued URL first: one branch down before the next
+	OrderRandom Order = "random" // Any queued URL, for an unbiased sample of a large site
+)
+
+// ParseOrder parses an --order value, empty meaning OrderBFS
+func ParseOrder(s string) (Order, error) {
+	switch order := Order(strings.ToLower(strings.TrimSpace(s))); order {
+	case "":
+		return OrderBFS, nil
+	case OrderBFS, OrderDFS, OrderRandom:
+		return order, nil
+	default:
+		return "", fmt.Errorf("invalid order %q, expected bfs, dfs or random", s)
+	}
+}
+
+// Config holds the engine configuration
+type Config struct {
+	Concurrency        int
+	MaxDepth           int           // 0 means unlimited
+	MaxPages           int           // Stop visiting pages past this many, 0 uses DefaultMaxPages
+	Delay              time.Duration // Minimum pause between two visits, 0 disables rate limiting
+	TreatSchemesAsSame bool          // Collapse http:// and https:// URLs of the same page
+	ObeyNoFollow       bool          // Don't follow links marked rel="nofollow", like search engines
+	Order              Order         // Visit order of the queue, empty for OrderBFS
+
+	// Rewrite, when set, maps every queued URL to the one to visit, e.g.
+	// httppool.HTTPSPolicy.Upgrade
+	Rewrite func(string) string
+}
+
+// Task is a URL waiting to be visited
+type Task struct {
+	URL       string
+	SourceURL string // Page the URL was found on, empty for seeds
+	Element   string // Element the URL was found in, if the tool tracks it
+	NoFollow  bool   // The link carries rel="nofollow"
+	Href      string // URL as linked when Config.Rewrite changed it, empty otherwise
+	Depth     int
+}
+
+// LinkedURL returns the URL as it was linked, before Config.Rewrite, for
+// reporting
+func (t Task) LinkedURL() string {
+	if t.Href != "" {
+		return t.Href
+	}
+	return t.URL
+}
+
+// VisitFunc processes one page and returns the links to follow from it.
+// Depth is filled in by the engine and SourceURL defaults to the visited page.
+type VisitFunc func(ctx context.Context, task Task) []Task
+
+// Engine runs a crawl over a pool of workers, breadth-first unless
+// Config.Order says otherwise. URLs are deduplicated, and the crawl ends
+// once every queued URL has been visited or Config.MaxPages pages have
+// been: the cap applies as pages are taken from the queue, so Config.Order
+// decides which queued URLs make it under it. With a single worker the
+// visit order is reproducible, except with OrderRandom.
+type Engine struct {
+	config Config
+	visit  VisitFunc
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    []Task
+	seen     map[string]bool
+	active   int
+	finished bool
+	visited  int
+	started  int // Tasks taken from the queue, against Config.MaxPages
+	skipped  int // Nofollow links not followed
+
+	delayMu sync.Mutex
+	nextAt  time.Time
+}
+
+// New creates an engine calling visit for every page
+func New(config Config, visit VisitFunc) *Engine {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	if config.MaxPages <= 0 {
+		config.MaxPages = DefaultMaxPages
+	}
+	e := &Engine{
+		config: config,
+		visit:  visit,
+		seen:   make(map[string]bool),
+	}
+	e.cond = sync.NewCond(&e.mu)
+	return e
+}
+
+// Key returns the key used to deduplicate URLs. With TreatSchemesAsSame,
+// http:// URLs are folded onto https:// so both schemes count as one page.
+func (e *Engine) Key(u string) string {
+	if e.config.Rewrite != nil {
+		u = e.config.Rewrite(u)
+	}
+	if e.config.TreatSchemesAsSame && strings.HasPrefix(u, "http://") {
+		return "https://" + strings.TrimPrefix(u, "http://")
+	}
+	return u
+}
+
+// Run crawls from the seeds until the queue is empty or ctx is cancelled,
+// and returns the number of pages visited
+func (e *Engine) Run(ctx context.Context, seeds ...Task) int {
+	for _, seed := range seeds {
+		e.Enqueue(ctx, seed)
+	}
+
+	e.mu.Lock()
+	if len(e.queue) == 0 {
+		e.finished = true
+	}
+	e.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.visited
+}
+
+// Enqueue queues a task unless its URL was already seen, it is past the
+// depth limit, the page cap is reached, it is a nofollow link the config
+// obeys or the crawl was cancelled. It reports whether the task was queued.
+func (e *Engine) Enqueue(ctx context.Context, task Task) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if e.config.ObeyNoFollow && task.NoFollow {
+		e.mu.Lock()
+		e.skipped++
+		e.mu.Unlock()
+		return false
+	}
+	if e.config.MaxDepth > 0 && task.Depth > e.config.MaxDepth {
+		return false
+	}
+
+	if e.config.Rewrite != nil {
+		if rewritten := e.config.Rewrite(task.URL); rewritten != task.URL {
+			task.Href, task.URL = task.URL, rewritten
+		}
+	}
+	key := e.Key(task.URL)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.seen[key] || e.started >= e.config.MaxPages {
+		return false
+	}
+	e.seen[key] = true
+	e.queue = append(e.queue, task)
+	e.cond.Signal()
+	return true
+}
+
+// Progress is a snapshot of a running crawl
+type Progress struct {
+	Visited int // Pages visited so far
+	Active  int // Pages being visited
+	Queued  int // URLs waiting to be visited
+}
+
+// Progress returns where the crawl stands; it is safe to call while Run runs
+func (e *Engine) Progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return Progress{
+		Visited: e.visited,
+		Active:  e.active,
+		Queued:  len(e.queue),
+	}
+}
+
+// NoFollowSkipped returns how many nofollow links were not followed
+func (e *Engine) NoFollowSkipped() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.skipped
+}
+
+// Seen reports whether a URL has already been queued
+func (e *Engine) Seen(u string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.seen[e.Key(u)]
+}
+
+// work takes tasks from the queue until the crawl is over
+func (e *Engine) work(ctx context.Context) {
+	for {
+		e.mu.Lock()
+		for len(e.queue) == 0 && !e.finished {
+			e.cond.Wait()
+		}
+		if e.finished {
+			e.mu.Unlock()
+			return
+		}
+		if e.started >= e.config.MaxPages {
+			// The page cap is reached: what is still queued is never visited
+			e.queue = nil
+			if e.active == 0 {
+				e.finished = true
+				e.cond.Broadcast()
+			}
+			e.mu.Unlock()
+			continue
+		}
+		task := e.next()
+		e.started++
+		e.active++
+		e.mu.Unlock()
+
+		if ctx.Err() == nil && e.Wait(ctx) {
+			e.mu.Lock()
+			e.visited++
+			e.mu.Unlock()
+
+			for _, next := range e.visit(ctx, task) {
+				next.Depth = task.Depth + 1
+				if next.SourceURL == "" {
+					next.SourceURL = task.URL
+				}
+				e.Enqueue(ctx, next)
+			}
+		}
+
+		e.mu.Lock()
+		e.active--
+		if e.active == 0 && len(e.queue) == 0 {
+			e.finished = true
+			e.cond.Broadcast()
+		}
+		e.mu.Unlock()
+	}
+}
+
+// next removes the task to visit from the queue, according to Config.Order.
+// The caller holds e.mu and the queue is not empty.
+func (e *Engine) next() Task {
+	i := 0
+	switch e.config.Order {
+	case OrderDFS:
+		i = len(e.queue) - 1
+	case OrderRandom:
+		i = rand.Intn(len(e.queue))
+	}
+	task := e.queue[i]
+	if i == 0 {
+		e.queue = e.queue[1:]
+		return task
+	}
+	e.queue[i] = e.queue[len(e.queue)-1]
+	e.queue = e.queue[:len(e.queue)-1]
+	return task
+}
+
+// Wait enforces Config.Delay between visits. A VisitFunc calls it too before
+// any request besides the page's own, so the delay holds for those as well.
+// It returns false if the crawl was cancelled while waiting.
+func (e *Engine) Wait(ctx context.Context) bool {
+	if e.config.Delay <= 0 {
+		return true
+	}
+
+	e.delayMu.Lock()
+	now := time.Now()
+	at := e.nextAt
+	if at.Before(now) {
+		at = now
+	}
+	e.nextAt = at.Add(e.config.Delay)
+	e.delayMu.Unlock()
+
+	select {
+	case <-time.After(time.Until(at)):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
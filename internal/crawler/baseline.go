@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/crawl"
+)
+
+// LoadBaseline reads a file of known URLs, one per line. Blank lines and
+// lines starting with # are ignored. An empty file gives an empty, non-nil
+// baseline, against which every page is new.
+func LoadBaseline(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	urls := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return urls, nil
+}
+
+// compareBaseline splits the pages crawled into those listed in
+// Config.Baseline and new ones, comparing URLs by engine key so
+// --same-scheme and an HTTPS upgrade apply to the baseline too
+func (c *Crawler) compareBaseline(engine *crawl.Engine) (known int, newURLs []string) {
+	baseline := make(map[string]bool, len(c.config.Baseline))
+	for _, u := range c.config.Baseline {
+		baseline[engine.Key(u)] = true
+	}
+
+	for pageURL := range c.pages {
+		if baseline[engine.Key(pageURL)] {
+			known++
+		} else {
+			newURLs = append(newURLs, pageURL)
+		}
+	}
+	sort.Strings(newURLs)
+	return known, newURLs
+}
+
+// printNewURLs lists the first pages crawled that the baseline doesn't
+// know; --json has them all
+func (r *CrawlResult) printNewURLs() {
+	if len(r.NewURLs) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s⚠ %d new URL(s) not in the baseline:%s\n", colorBold, colorYellow, len(r.NewURLs), colorReset)
+	for i, u := range r.NewURLs {
+		if i >= 10 {
+			fmt.Printf("  %s... and %d more%s\n", colorGray, len(r.NewURLs)-10, colorReset)
+			break
+		}
+		fmt.Printf("  %s+%s %s\n", colorYellow, colorReset, u)
+	}
+}
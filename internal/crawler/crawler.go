@@ -4,26 +4,63 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/login"
+	"github.com/ngonzalez/web-tools/internal/sitemap"
+	"github.com/ngonzalez/web-tools/internal/sitetree"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 // Config holds the crawler configuration
 type Config struct {
-	Concurrency int
-	Timeout     time.Duration
-	MaxDepth    int // 0 means unlimited
-	Verbose     bool
+	Concurrency         int
+	Timeout             time.Duration
+	MaxDepth            int // 0 means unlimited
+	Verbose             bool
+	Verbosity           int               // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Info
+	Transport           http.RoundTripper // Optional custom transport, e.g. a shared response cache
+	TreatSchemesAsSame  bool              // Collapse http:// and https:// URLs of the same page
+	ExtraElements       bool              // Also check <area href>, <form action> and <link href>
+	FailFast            bool              // Stop the crawl at the first broken link
+	Delay               time.Duration     // Minimum pause between two requests
+	ObeyNoFollow        bool              // Don't follow rel="nofollow" links, like search engines
+	ProbeOpenRedirects  bool              // Probe redirect-like parameters for open redirects (heuristic)
+	Sitemaps            []string          // Sitemaps or RSS/Atom feeds whose URLs seed the crawl, relative to the start URL
+	MaxIdleConnsPerHost int               // Idle connections kept per host, 0 matches Concurrency
+	MaxRedirects        int               // Redirects followed per request, 0 = none (reported as redirects), -1 = up to httppool.MaxRedirectsCap
+	HTMLContentTypes    []string          // Extra media types parsed as HTML, besides contenttype.HTML
+	AcceptStatus        []int             // Error statuses that are intentional, e.g. 401 and 403 on a login area
+	Login               *login.Flow       // Login form submitted before the crawl, its URL relative to the start URL
+	Accept              string            // Accept header sent with every request, none when empty
+	KeepHTTP            bool              // Crawl http:// URLs as is even on a site enforcing HTTPS
+	MaxPages            int               // Pages visited at most, 0 uses crawl.DefaultMaxPages
+	Order               crawl.Order       // Visit order, empty for breadth-first
+	Baseline            []string          // Known URLs; pages crawled outside it are reported as new, see LoadBaseline
 }
 
 // DefaultConfig returns a default configuration
 func DefaultConfig() Config {
 	return Config{
-		Concurrency: 10,
-		Timeout:     10 * time.Second,
-		MaxDepth:    0,
-		Verbose:     false,
+		Concurrency:  10,
+		Timeout:      10 * time.Second,
+		MaxDepth:     0,
+		Verbose:      false,
+		MaxRedirects: httppool.DefaultMaxRedirects,
 	}
 }
 
@@ -31,42 +68,68 @@ func DefaultConfig() Config {
 type Crawler struct {
 	config     Config
 	baseURL    *url.URL
-	visited    map[string]bool
-	visitedMu  sync.RWMutex
 	broken     []BrokenLink
-	brokenMu   sync.Mutex
-	client     *http.Client
-	semaphore  chan struct{}
-	wg         sync.WaitGroup
-	totalCount int
-	countMu    sync.Mutex
+	restricted []BrokenLink // Links answering a Config.AcceptStatus code
+	brokenMu   sync.Mutex   // Also guards restricted
+	external   []ExternalRedirect
+	externalMu sync.Mutex // Also guards openRedirects, jsonPages, redirects, forms and headers
+	jsonPages  []JSONPage
+	redirects  []Redirect                 // Redirects not followed, with Config.MaxRedirects 0
+	forms      []InsecureForm             // Forms sending their data over plain HTTP
+	headers    []pageHeaders              // Audited response headers of each internal HTML page
+	sources    map[string]map[string]bool // Engine key of a linked URL -> pages linking to it
+	pages      map[string]bool            // Final URLs of the internal pages answering, for the site tree
+	sourcesMu  sync.Mutex                 // Also guards pages
+
+	// Open redirect probing
+	probeClient   *http.Client // Same transport, never follows redirects
+	openRedirects []OpenRedirect
+	probed        map[string]bool // Host, path and parameter already probed
+	probedMu      sync.Mutex
+	client        *http.Client
+	stats         crawlstats.Counter
+	cancel        context.CancelFunc
+
+	engineMu sync.Mutex
+	engine   *crawl.Engine // Set once the crawl has started, for Progress
+
+	https httppool.HTTPSPolicy // Detected before the crawl unless Config.KeepHTTP
 }
 
 // New creates a new Crawler instance
 func New(config Config) *Crawler {
+	config.Verbosity = verbosity.Effective(config.Verbosity, config.Verbose)
+	transport := config.Transport
+	if transport == nil {
+		transport = httppool.New(config.Concurrency, config.MaxIdleConnsPerHost)
+	}
+	// The login session lives in a cookie jar shared by both clients
+	var jar http.CookieJar
+	if config.Login != nil {
+		jar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	}
 	return &Crawler{
-		config:    config,
-		visited:   make(map[string]bool),
-		semaphore: make(chan struct{}, config.Concurrency),
+		config: config,
 		client: &http.Client{
-			Timeout: config.Timeout,
+			Jar:           jar,
+			Timeout:       config.Timeout,
+			Transport:     transport,
+			CheckRedirect: httppool.CheckRedirect(config.MaxRedirects, httppool.ErrTooManyRedirects),
+		},
+		probeClient: &http.Client{
+			Jar:       jar,
+			Timeout:   config.Timeout,
+			Transport: transport,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
+				return http.ErrUseLastResponse
 			},
 		},
+		probed:  make(map[string]bool),
+		sources: make(map[string]map[string]bool),
+		pages:   make(map[string]bool),
 	}
 }
 
-// urlTask represents a URL to be crawled with its metadata
-type urlTask struct {
-	url       string
-	sourceURL string
-	depth     int
-}
-
 // Crawl starts crawling from the given URL and returns the results
 func (c *Crawler) Crawl(startURL string) (*CrawlResult, error) {
 	parsed, err := url.Parse(startURL)
@@ -79,205 +142,452 @@ func (c *Crawler) Crawl(startURL string) (*CrawlResult, error) {
 	}
 
 	c.baseURL = parsed
+	c.stats.Start()
 
-	// Channel for URLs to process
-	tasks := make(chan urlTask, 1000)
-
-	// Start with the initial URL
-	c.markVisited(startURL)
-	tasks <- urlTask{url: startURL, sourceURL: "", depth: 0}
-
-	// Track active workers
-	var activeWorkers sync.WaitGroup
+	if c.config.Login != nil {
+		if err := c.login(); err != nil {
+			return nil, fmt.Errorf("login failed: %w", err)
+		}
+	}
 
-	// Context for cancellation
+	// Context for cancellation, also cancelled by fail-fast
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-
-	// Worker pool
-	for i := 0; i < c.config.Concurrency; i++ {
-		go c.worker(ctx, tasks, &activeWorkers)
-	}
-
-	// Wait for all work to complete
-	activeWorkers.Add(1)
-	go func() {
-		defer activeWorkers.Done()
-		// Initial task is already sent, wait a bit for it to be picked up
-		time.Sleep(100 * time.Millisecond)
-	}()
-
-	// Monitor completion
-	done := make(chan struct{})
-	go func() {
-		for {
-			time.Sleep(100 * time.Millisecond)
-			c.visitedMu.RLock()
-			visitedCount := len(c.visited)
-			c.visitedMu.RUnlock()
-
-			// Check if there are pending tasks
-			if len(tasks) == 0 && len(c.semaphore) == 0 {
-				// Give a moment for any in-flight work
-				time.Sleep(500 * time.Millisecond)
-				if len(tasks) == 0 && len(c.semaphore) == 0 {
-					close(done)
-					return
-				}
+	c.cancel = cancel
+
+	// On a site enforcing HTTPS, http:// links are crawled as their https://
+	// target instead of each being reported as the same upgrade redirect
+	engineConfig := crawl.Config{
+		Concurrency:        c.config.Concurrency,
+		MaxDepth:           c.config.MaxDepth,
+		Delay:              c.config.Delay,
+		TreatSchemesAsSame: c.config.TreatSchemesAsSame,
+		ObeyNoFollow:       c.config.ObeyNoFollow,
+		MaxPages:           c.config.MaxPages,
+		Order:              c.config.Order,
+	}
+	if !c.config.KeepHTTP {
+		c.https = httppool.DetectHTTPS(c.client.Transport, c.config.Timeout, startURL)
+		if c.https.Enforced() {
+			engineConfig.Rewrite = c.https.Upgrade
+			if c.config.Verbosity >= verbosity.Info {
+				fmt.Fprintf(os.Stderr, "%s✓ Site enforces HTTPS (%s), crawling http:// URLs as https://%s\n", colorGreen, c.https, colorReset)
 			}
+		}
+	}
+	engine := crawl.New(engineConfig, c.processURL)
+	c.engineMu.Lock()
+	c.engine = engine
+	c.engineMu.Unlock()
 
-			// Safety timeout
-			if visitedCount > 10000 {
-				close(done)
-				return
-			}
+	seeds, seeded, sitemapErrors := c.sitemapSeeds(startURL)
+	totalVisited := engine.Run(ctx, seeds...)
+
+	sort.Slice(c.external, func(i, j int) bool {
+		if c.external[i].SourceURL != c.external[j].SourceURL {
+			return c.external[i].SourceURL < c.external[j].SourceURL
 		}
-	}()
+		return c.external[i].LinkURL < c.external[j].LinkURL
+	})
+	sort.Slice(c.restricted, func(i, j int) bool {
+		if c.restricted[i].BrokenURL != c.restricted[j].BrokenURL {
+			return c.restricted[i].BrokenURL < c.restricted[j].BrokenURL
+		}
+		return c.restricted[i].SourceURL < c.restricted[j].SourceURL
+	})
+	sort.Slice(c.openRedirects, func(i, j int) bool {
+		return c.openRedirects[i].URL < c.openRedirects[j].URL
+	})
+	sort.Slice(c.jsonPages, func(i, j int) bool {
+		return c.jsonPages[i].URL < c.jsonPages[j].URL
+	})
+	sort.Slice(c.forms, func(i, j int) bool {
+		if c.forms[i].PageURL != c.forms[j].PageURL {
+			return c.forms[i].PageURL < c.forms[j].PageURL
+		}
+		return c.forms[i].Action < c.forms[j].Action
+	})
+	sort.Slice(c.redirects, func(i, j int) bool {
+		if c.redirects[i].URL != c.redirects[j].URL {
+			return c.redirects[i].URL < c.redirects[j].URL
+		}
+		return c.redirects[i].SourceURL < c.redirects[j].SourceURL
+	})
+
+	result := &CrawlResult{
+		StartURL:          startURL,
+		TotalVisited:      totalVisited,
+		BrokenLinks:       c.broken,
+		BrokenTargets:     c.brokenTargets(),
+		FailedFast:        c.config.FailFast && len(c.broken) > 0,
+		Restricted:        c.restricted,
+		SkippedNoFollow:   engine.NoFollowSkipped(),
+		ExternalRedirects: c.external,
+		OpenRedirects:     c.openRedirects,
+		JSONPages:         c.jsonPages,
+		Redirects:         c.redirects,
+		InsecureForms:     c.forms,
+		Headers:           c.headerCoverage(),
+		SitemapSeeds:      seeded,
+		SitemapErrors:     sitemapErrors,
+		SiteTree:          c.siteTree(),
+		HTTPS:             c.https,
+		CrawlStats:        c.stats.Snapshot(),
+	}
+	if c.config.Baseline != nil {
+		result.BaselineChecked = true
+		result.KnownURLs, result.NewURLs = c.compareBaseline(engine)
+	}
+	return result, nil
+}
 
-	<-done
-	cancel()
-	close(tasks)
+// Status is the live state of a running crawl
+type Status struct {
+	Crawl  crawl.Progress
+	Totals crawlstats.Stats
+	Broken int // Broken links found so far
+}
 
-	c.visitedMu.RLock()
-	totalVisited := len(c.visited)
-	c.visitedMu.RUnlock()
+// Progress returns the live numbers of a running crawl, for a dashboard.
+// It may be called from another goroutine while Crawl runs.
+func (c *Crawler) Progress() Status {
+	c.engineMu.Lock()
+	engine := c.engine
+	c.engineMu.Unlock()
+	if engine == nil {
+		return Status{}
+	}
+
+	c.brokenMu.Lock()
+	broken := len(c.broken)
+	c.brokenMu.Unlock()
 
-	return &CrawlResult{
-		StartURL:     startURL,
-		TotalVisited: totalVisited,
-		BrokenLinks:  c.broken,
-	}, nil
+	return Status{
+		Crawl:  engine.Progress(),
+		Totals: c.stats.Snapshot(),
+		Broken: broken,
+	}
 }
 
-// worker processes URLs from the task channel
-func (c *Crawler) worker(ctx context.Context, tasks chan urlTask, activeWorkers *sync.WaitGroup) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case task, ok := <-tasks:
-			if !ok {
-				return
-			}
-			c.processURL(ctx, task, tasks)
-		}
+// login submits the configured login form, resolved against the start URL
+func (c *Crawler) login() error {
+	loginURL, err := c.baseURL.Parse(c.config.Login.URL)
+	if err != nil {
+		return fmt.Errorf("invalid login URL: %w", err)
+	}
+	flow := *c.config.Login
+	flow.URL = loginURL.String()
+	if err := login.Do(c.client, flow); err != nil {
+		return err
+	}
+	if c.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "%s✓ Logged in at %s%s\n", colorGreen, flow.URL, colorReset)
 	}
+	return nil
 }
 
-// processURL fetches and processes a single URL
-func (c *Crawler) processURL(ctx context.Context, task urlTask, tasks chan urlTask) {
-	// Acquire semaphore
-	select {
-	case c.semaphore <- struct{}{}:
-		defer func() { <-c.semaphore }()
-	case <-ctx.Done():
-		return
+// sitemapSeeds returns the start URL followed by the internal URLs listed in
+// the configured sitemaps and feeds. Each seed's source is the sitemap that
+// listed it, so a broken sitemap entry is reported against that sitemap.
+func (c *Crawler) sitemapSeeds(startURL string) ([]crawl.Task, int, []string) {
+	seeds := []crawl.Task{{URL: startURL}}
+	seeded := 0
+	var errs []string
+
+	for _, ref := range c.config.Sitemaps {
+		sitemapURL, err := c.baseURL.Parse(ref)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", ref, err))
+			continue
+		}
+
+		entries, err := sitemap.Load(c.client, sitemapURL.String())
+		if err != nil {
+			errs = append(errs, strings.Split(err.Error(), "\n")...)
+		}
+		for _, entry := range entries {
+			if IsSameDomain(entry.URL, c.baseURL) {
+				seeds = append(seeds, crawl.Task{URL: entry.URL, SourceURL: entry.Sitemap})
+				seeded++
+			}
+		}
 	}
+	return seeds, seeded, errs
+}
 
-	// Check depth limit
-	if c.config.MaxDepth > 0 && task.depth > c.config.MaxDepth {
-		return
+// processURL fetches a single URL and returns the internal links to follow
+func (c *Crawler) processURL(ctx context.Context, task crawl.Task) []crawl.Task {
+	if c.config.ProbeOpenRedirects {
+		c.probeOpenRedirects(ctx, task.SourceURL, task.URL)
 	}
 
 	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", task.url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", task.URL, nil)
 	if err != nil {
-		c.addBrokenLink(task.sourceURL, task.url, 0, err.Error())
-		return
+		c.addBrokenLink(task.SourceURL, task.LinkedURL(), task.Element, 0, err.Error())
+		return nil
 	}
 
-	req.Header.Set("User-Agent", "LinkChecker/1.0")
+	c.setHeaders(req)
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	c.stats.AddRequest()
 	if err != nil {
 		if ctx.Err() != nil {
-			return
+			return nil
 		}
-		if c.config.Verbose {
-			PrintError(task.url, err.Error(), task.depth)
+		if c.config.Verbosity >= verbosity.Warn {
+			PrintError(task.URL, err.Error(), task.Depth)
 		}
-		if task.sourceURL != "" {
-			c.addBrokenLink(task.sourceURL, task.url, 0, err.Error())
+		if task.SourceURL != "" {
+			c.addBrokenLink(task.SourceURL, task.LinkedURL(), task.Element, 0, err.Error())
 		}
-		return
+		return nil
 	}
 	defer resp.Body.Close()
 
-	if c.config.Verbose {
-		PrintProgress(task.url, resp.StatusCode, task.depth)
+	if verbosity.ShowStatus(c.config.Verbosity, resp.StatusCode) {
+		PrintProgress(task.URL, resp.StatusCode, task.Depth)
+	}
+	if c.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(task.URL, time.Since(start))
+	}
+
+	// An accepted error status is reported apart and the page is not parsed
+	if resp.StatusCode >= 400 && slices.Contains(c.config.AcceptStatus, resp.StatusCode) {
+		sourceURL := task.SourceURL
+		if sourceURL == "" {
+			sourceURL = task.URL
+		}
+		c.brokenMu.Lock()
+		c.restricted = append(c.restricted, BrokenLink{
+			SourceURL:     sourceURL,
+			BrokenURL:     task.LinkedURL(),
+			Element:       task.Element,
+			StatusCode:    resp.StatusCode,
+			RedirectChain: redirectChain(resp),
+		})
+		c.brokenMu.Unlock()
+		return nil
 	}
 
 	// Check for broken link
 	if resp.StatusCode >= 400 {
-		if task.sourceURL != "" {
-			c.addBrokenLink(task.sourceURL, task.url, resp.StatusCode, "")
+		if task.SourceURL != "" {
+			c.addBrokenLinkVia(task.SourceURL, task.LinkedURL(), task.Element, resp.StatusCode, redirectChain(resp))
 		} else {
 			// The start URL itself is broken
-			c.addBrokenLink(task.url, task.url, resp.StatusCode, "start URL returned error")
+			c.addBrokenLink(task.LinkedURL(), task.LinkedURL(), "", resp.StatusCode, "start URL returned error")
 		}
-		return
+		return nil
+	}
+
+	// Without redirects followed, the redirect is reported and its target
+	// crawled as a link of its own
+	if c.config.MaxRedirects == 0 && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		location, err := resp.Location()
+		if err != nil {
+			return nil
+		}
+		c.externalMu.Lock()
+		c.redirects = append(c.redirects, Redirect{
+			SourceURL:  task.SourceURL,
+			URL:        task.URL,
+			StatusCode: resp.StatusCode,
+			Location:   location.String(),
+		})
+		c.externalMu.Unlock()
+		if IsSameDomain(location.String(), c.baseURL) {
+			return []crawl.Task{{URL: location.String(), Element: task.Element}}
+		}
+		return nil
 	}
 
-	// Only parse HTML content for links
+	// A link that ends on another site is reported, and that site's page is
+	// not crawled
+	if finalURL := resp.Request.URL; !sameSite(finalURL.Hostname(), c.baseURL.Hostname()) {
+		if task.SourceURL != "" {
+			c.externalMu.Lock()
+			c.external = append(c.external, ExternalRedirect{
+				SourceURL: task.SourceURL,
+				LinkURL:   task.URL,
+				FinalURL:  finalURL.String(),
+			})
+			c.externalMu.Unlock()
+		}
+		return nil
+	}
+	c.addPage(resp.Request.URL.String())
+
+	// Only parse HTML content for links. A JSON answer is reported, as the
+	// server may have negotiated an API response instead of the page.
 	contentType := resp.Header.Get("Content-Type")
-	if !isHTML(contentType) {
-		return
+	if isJSON(contentType) {
+		c.externalMu.Lock()
+		c.jsonPages = append(c.jsonPages, JSONPage{
+			SourceURL:   task.SourceURL,
+			URL:         task.URL,
+			ContentType: contentType,
+		})
+		c.externalMu.Unlock()
+		return nil
+	}
+	if !contenttype.IsHTML(contentType, c.config.HTMLContentTypes) {
+		return nil
 	}
 
-	// Parse and extract links
-	links := ExtractLinks(resp.Body, c.baseURL)
+	c.checkHeaders(resp.Request.URL, resp.Header)
 
-	// Queue new links
+	// Parse and extract links, following internal ones only
+	links, forms := ExtractPage(c.stats.Body(resp.Body), c.baseURL, resp.Request.URL, c.config.ExtraElements)
+	c.checkForms(resp.Request.URL, forms)
+	var next []crawl.Task
 	for _, link := range links {
-		if c.shouldVisit(link) {
-			c.markVisited(link)
-
-			// Try to send task, skip if channel is full
-			select {
-			case tasks <- urlTask{url: link, sourceURL: task.url, depth: task.depth + 1}:
-			default:
-				// Channel full, skip this link
+		if IsSameDomain(link.URL, c.baseURL) {
+			// A logged-in crawl must not end its own session
+			if c.config.Login != nil && login.IsLogout(link.URL) {
+				continue
 			}
+			next = append(next, crawl.Task{URL: link.URL, Element: link.Element, NoFollow: link.NoFollow})
+			c.addSource(link.URL, task.URL)
 		}
 	}
+	return next
 }
 
-// markVisited marks a URL as visited (thread-safe)
-func (c *Crawler) markVisited(url string) {
-	c.visitedMu.Lock()
-	c.visited[url] = true
-	c.visitedMu.Unlock()
+// addPage records an internal page for the site tree, under its final URL
+// so a redirect and its target count once
+func (c *Crawler) addPage(pageURL string) {
+	c.sourcesMu.Lock()
+	c.pages[pageURL] = true
+	c.sourcesMu.Unlock()
 }
 
-// shouldVisit checks if a URL should be visited
-func (c *Crawler) shouldVisit(targetURL string) bool {
-	// Check if it's an internal link
-	if !IsSameDomain(targetURL, c.baseURL) {
-		return false
+// siteTree builds the site structure from the pages crawled
+func (c *Crawler) siteTree() *sitetree.Node {
+	urls := make([]string, 0, len(c.pages))
+	for pageURL := range c.pages {
+		urls = append(urls, pageURL)
 	}
-
-	// Check if already visited
-	c.visitedMu.RLock()
-	visited := c.visited[targetURL]
-	c.visitedMu.RUnlock()
-
-	return !visited
+	return sitetree.Build(urls)
 }
 
 // addBrokenLink adds a broken link to the results (thread-safe)
-func (c *Crawler) addBrokenLink(sourceURL, brokenURL string, statusCode int, errMsg string) {
-	c.brokenMu.Lock()
-	c.broken = append(c.broken, BrokenLink{
+func (c *Crawler) addBrokenLink(sourceURL, brokenURL, element string, statusCode int, errMsg string) {
+	c.addBrokenLinkWith(BrokenLink{
 		SourceURL:  sourceURL,
 		BrokenURL:  brokenURL,
+		Element:    element,
 		StatusCode: statusCode,
 		Error:      errMsg,
 	})
+}
+
+// addBrokenLinkVia records a link whose error status was reached through
+// redirects, keeping the hops so the report can point at the redirect target
+func (c *Crawler) addBrokenLinkVia(sourceURL, brokenURL, element string, statusCode int, redirects []string) {
+	c.addBrokenLinkWith(BrokenLink{
+		SourceURL:     sourceURL,
+		BrokenURL:     brokenURL,
+		Element:       element,
+		StatusCode:    statusCode,
+		RedirectChain: redirects,
+	})
+}
+
+func (c *Crawler) addBrokenLinkWith(link BrokenLink) {
+	c.brokenMu.Lock()
+	if c.config.FailFast && len(c.broken) > 0 {
+		// Only the first broken link is reported in fail-fast mode
+		c.brokenMu.Unlock()
+		return
+	}
+	c.broken = append(c.broken, link)
 	c.brokenMu.Unlock()
+
+	if c.config.FailFast {
+		c.cancel()
+	}
+}
+
+// ParseStatusList parses comma-separated status codes and ranges, such as
+// "401,403" or "401-403,451"
+func ParseStatusList(s string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		low, high, isRange := strings.Cut(part, "-")
+		first, err := parseStatus(low)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseStatus(high); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid status range %q", part)
+			}
+		}
+		for code := first; code <= last; code++ {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+func parseStatus(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || code < 100 || code > 599 {
+		return 0, fmt.Errorf("invalid status code %q", s)
+	}
+	return code, nil
+}
+
+// redirectChain returns the URLs visited after the original request when
+// the client followed redirects, ending with the final URL. Empty if none.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.URL.String()}, chain...)
+	}
+	return chain
+}
+
+// sameSite reports whether two hosts belong to the same registrable domain,
+// so a redirect from example.com to www.example.com stays on the site
+func sameSite(host, baseHost string) bool {
+	if strings.EqualFold(host, baseHost) {
+		return true
+	}
+	site, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(host))
+	if err != nil {
+		return false
+	}
+	baseSite, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(baseHost))
+	if err != nil {
+		return false
+	}
+	return site == baseSite
+}
+
+// setHeaders sets the headers sent with every crawl request
+func (c *Crawler) setHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", "LinkChecker/1.0")
+	if c.config.Accept != "" {
+		req.Header.Set("Accept", c.config.Accept)
+	}
 }
 
-// isHTML checks if the content type indicates HTML content
-func isHTML(contentType string) bool {
-	return len(contentType) >= 9 && contentType[:9] == "text/html" ||
-		len(contentType) >= 21 && contentType[:21] == "application/xhtml+xml"
+// isJSON checks if the content type is JSON, including types such as
+// application/ld+json or application/vnd.api+json
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
 }
@@ -0,0 +1,70 @@
+package crawler
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Delta is how the broken links changed from one crawl to the next. A link
+// is the same link as long as it is found on the same page, whatever its
+// status.
+type Delta struct {
+	NewlyBroken []BrokenLink // Broken now, not in the previous crawl
+	Fixed       []BrokenLink // Broken in the previous crawl, not any more
+}
+
+// Diff compares the broken links of two crawls of the same site
+func Diff(previous, current *CrawlResult) Delta {
+	before := brokenSet(previous.BrokenLinks)
+	after := brokenSet(current.BrokenLinks)
+
+	var delta Delta
+	for key, link := range after {
+		if _, ok := before[key]; !ok {
+			delta.NewlyBroken = append(delta.NewlyBroken, link)
+		}
+	}
+	for key, link := range before {
+		if _, ok := after[key]; !ok {
+			delta.Fixed = append(delta.Fixed, link)
+		}
+	}
+	sortBroken(delta.NewlyBroken)
+	sortBroken(delta.Fixed)
+	return delta
+}
+
+// Empty reports whether nothing changed
+func (d Delta) Empty() bool {
+	return len(d.NewlyBroken) == 0 && len(d.Fixed) == 0
+}
+
+// Print displays the changes, newly broken links first
+func (d Delta) Print() {
+	for _, link := range d.NewlyBroken {
+		fmt.Printf("  %s+ broken%s %s (%s)\n", colorRed, colorReset, link.BrokenURL, link.StatusKey())
+		fmt.Printf("      Found on: %s\n", link.SourceURL)
+	}
+	for _, link := range d.Fixed {
+		fmt.Printf("  %s- fixed%s  %s\n", colorGreen, colorReset, link.BrokenURL)
+		fmt.Printf("      Found on: %s\n", link.SourceURL)
+	}
+}
+
+// brokenSet indexes broken links by source and target
+func brokenSet(links []BrokenLink) map[string]BrokenLink {
+	set := make(map[string]BrokenLink, len(links))
+	for _, link := range links {
+		set[link.SourceURL+"\x00"+link.BrokenURL] = link
+	}
+	return set
+}
+
+func sortBroken(links []BrokenLink) {
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].BrokenURL != links[j].BrokenURL {
+			return links[i].BrokenURL < links[j].BrokenURL
+		}
+		return links[i].SourceURL < links[j].SourceURL
+	})
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// InsecureForm is a form sending what users type over plain HTTP, which
+// browsers warn about
+type InsecureForm struct {
+	PageURL  string
+	Action   string // Where the form submits
+	Password bool   // The form has a password field
+	Reason   string
+}
+
+// insecureFormReason returns why a form of a page is insecure, or "" when
+// it isn't: it posts to http:// from an HTTPS page, which Chrome flags as
+// users fill it in, or it asks for a password on an http:// page, which
+// browsers mark as not secure
+func insecureFormReason(pageURL *url.URL, form Form) string {
+	switch {
+	case pageURL.Scheme == "https" && strings.HasPrefix(form.Action, "http://"):
+		return "posts to http:// from an HTTPS page"
+	case form.Password && pageURL.Scheme == "http":
+		return "password field on an http:// page"
+	}
+	return ""
+}
+
+// checkForms records the insecure forms of a page
+func (c *Crawler) checkForms(pageURL *url.URL, forms []Form) {
+	for _, form := range forms {
+		reason := insecureFormReason(pageURL, form)
+		if reason == "" {
+			continue
+		}
+		c.externalMu.Lock()
+		c.forms = append(c.forms, InsecureForm{
+			PageURL:  pageURL.String(),
+			Action:   form.Action,
+			Password: form.Password,
+			Reason:   reason,
+		})
+		c.externalMu.Unlock()
+	}
+}
+
+// printInsecureForms lists the forms sending their data unencrypted
+func (r *CrawlResult) printInsecureForms() {
+	if len(r.InsecureForms) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s⚠ %d insecure form(s):%s\n", colorBold, colorRed, len(r.InsecureForms), colorReset)
+	fmt.Printf("  What users type is sent unencrypted; browsers warn them before or while they fill these in\n")
+	fmt.Println()
+
+	for i, form := range r.InsecureForms {
+		fmt.Printf("%s[%d]%s %s\n", colorRed, i+1, colorReset, form.PageURL)
+		fmt.Printf("    Action: %s\n", form.Action)
+		reason := form.Reason
+		if form.Password && !strings.Contains(reason, "password") {
+			reason += ", with a password field"
+		}
+		fmt.Printf("    Problem: %s%s%s\n", colorRed, reason, colorReset)
+		fmt.Println()
+	}
+}
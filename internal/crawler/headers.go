@@ -0,0 +1,134 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// AuditedHeaders are the response headers whose coverage is reported, in
+// report order. All but Cache-Control are security headers.
+var AuditedHeaders = []string{
+	"Strict-Transport-Security",
+	"Content-Security-Policy",
+	"X-Frame-Options",
+	"X-Content-Type-Options",
+	"Cache-Control",
+}
+
+// HeaderCoverage is how many internal HTML pages send a response header
+type HeaderCoverage struct {
+	Header   string
+	Security bool     // A missing security header is flagged, a missing Cache-Control only noted
+	Checked  int      // Pages it applies to: only HTTPS pages for Strict-Transport-Security
+	Missing  []string // Pages not sending it, sorted
+}
+
+// Present returns the number of pages sending the header
+func (h HeaderCoverage) Present() int {
+	return h.Checked - len(h.Missing)
+}
+
+// MissingPercent returns the share of checked pages not sending the header,
+// rounded down
+func (h HeaderCoverage) MissingPercent() int {
+	if h.Checked == 0 {
+		return 0
+	}
+	return len(h.Missing) * 100 / h.Checked
+}
+
+// pageHeaders is an internal HTML page with the audited headers it sends
+type pageHeaders struct {
+	url   *url.URL
+	sends map[string]bool
+}
+
+// checkHeaders records which audited headers a page's response sends. A
+// frame-ancestors directive in Content-Security-Policy counts as
+// X-Frame-Options, which it supersedes.
+func (c *Crawler) checkHeaders(pageURL *url.URL, header http.Header) {
+	sends := make(map[string]bool)
+	for _, name := range AuditedHeaders {
+		if strings.TrimSpace(header.Get(name)) != "" {
+			sends[name] = true
+		}
+	}
+	if strings.Contains(strings.ToLower(header.Get("Content-Security-Policy")), "frame-ancestors") {
+		sends["X-Frame-Options"] = true
+	}
+
+	c.externalMu.Lock()
+	c.headers = append(c.headers, pageHeaders{url: pageURL, sends: sends})
+	c.externalMu.Unlock()
+}
+
+// headerCoverage returns the coverage of every audited header, nil when no
+// HTML page was crawled
+func (c *Crawler) headerCoverage() []HeaderCoverage {
+	if len(c.headers) == 0 {
+		return nil
+	}
+
+	var coverage []HeaderCoverage
+	for _, name := range AuditedHeaders {
+		h := HeaderCoverage{Header: name, Security: name != "Cache-Control"}
+		for _, page := range c.headers {
+			// Browsers ignore HSTS received over plain HTTP
+			if name == "Strict-Transport-Security" && page.url.Scheme != "https" {
+				continue
+			}
+			h.Checked++
+			if !page.sends[name] {
+				h.Missing = append(h.Missing, page.url.String())
+			}
+		}
+		sort.Strings(h.Missing)
+		coverage = append(coverage, h)
+	}
+	return coverage
+}
+
+// MissingSecurityHeaders returns the number of security headers some
+// checked page doesn't send
+func (r *CrawlResult) MissingSecurityHeaders() int {
+	count := 0
+	for _, h := range r.Headers {
+		if h.Security && len(h.Missing) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// printHeaders shows the share of pages sending each audited header
+func (r *CrawlResult) printHeaders() {
+	if len(r.Headers) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%sResponse headers:%s\n", colorBold, colorReset)
+	for _, h := range r.Headers {
+		switch {
+		case h.Checked == 0:
+			fmt.Printf("  %-26s %snot checked, no HTTPS page%s\n", h.Header, colorYellow, colorReset)
+		case len(h.Missing) == 0:
+			fmt.Printf("  %-26s %s✓ all %d pages%s\n", h.Header, colorGreen, h.Checked, colorReset)
+		case h.Present() == 0:
+			fmt.Printf("  %-26s %s✗ not set on any page%s\n", h.Header, headerColor(h), colorReset)
+		default:
+			fmt.Printf("  %-26s %s%d%% of pages lack it (%d/%d), e.g. %s%s\n", h.Header, headerColor(h), h.MissingPercent(), len(h.Missing), h.Checked, h.Missing[0], colorReset)
+		}
+	}
+}
+
+// headerColor returns the color of a header with missing pages
+func headerColor(h HeaderCoverage) string {
+	if h.Security {
+		return colorRed
+	}
+	return colorYellow
+}
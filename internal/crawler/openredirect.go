@@ -0,0 +1,112 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// probeTarget is the off-site URL substituted into redirect-like parameters.
+// example.com is reserved for documentation, so a probe never lands anywhere real.
+const probeTarget = "https://example.com/"
+
+// redirectParams are the query parameters that usually carry a redirect target
+var redirectParams = map[string]bool{
+	"url":          true,
+	"redirect":     true,
+	"redirect_url": true,
+	"redirect_uri": true,
+	"next":         true,
+	"return":       true,
+	"return_url":   true,
+	"returnto":     true,
+}
+
+// OpenRedirect is a URL that redirected to the probe target when one of its
+// parameters was replaced. It is a heuristic finding, to be confirmed by hand.
+type OpenRedirect struct {
+	SourceURL string // Page the probed URL was linked from
+	URL       string // URL as found on the site
+	Param     string // Parameter that was replaced
+	Location  string // Location header returned for the probe
+}
+
+// probeOpenRedirects replaces each redirect-like parameter of an internal URL
+// with probeTarget and records it when the response redirects there. Each
+// path and parameter pair is probed once.
+func (c *Crawler) probeOpenRedirects(ctx context.Context, sourceURL, rawURL string) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.RawQuery == "" {
+		return
+	}
+
+	values := parsed.Query()
+	for name := range values {
+		if !redirectParams[strings.ToLower(name)] {
+			continue
+		}
+
+		key := parsed.Host + parsed.Path + "?" + name
+		c.probedMu.Lock()
+		probed := c.probed[key]
+		c.probed[key] = true
+		c.probedMu.Unlock()
+		if probed {
+			continue
+		}
+
+		probe := *parsed
+		query := parsed.Query()
+		query.Set(name, probeTarget)
+		probe.RawQuery = query.Encode()
+
+		if location, ok := c.redirectsToProbe(ctx, probe.String()); ok {
+			c.externalMu.Lock()
+			c.openRedirects = append(c.openRedirects, OpenRedirect{
+				SourceURL: sourceURL,
+				URL:       rawURL,
+				Param:     name,
+				Location:  location,
+			})
+			c.externalMu.Unlock()
+		}
+	}
+}
+
+// redirectsToProbe requests a probe URL without following redirects and
+// reports whether it answered with a redirect to the probe target's host.
+// Probes are paced by the crawl's Config.Delay like page visits.
+func (c *Crawler) redirectsToProbe(ctx context.Context, probeURL string) (string, bool) {
+	if !c.engine.Wait(ctx) {
+		return "", false
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", probeURL, nil)
+	if err != nil {
+		return "", false
+	}
+	c.setHeaders(req)
+
+	resp, err := c.probeClient.Do(req)
+	c.stats.AddRequest()
+	if err != nil {
+		return "", false
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return "", false
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", false
+	}
+
+	target, _ := url.Parse(probeTarget)
+	if !strings.EqualFold(location.Hostname(), target.Hostname()) {
+		return "", false
+	}
+	return location.String(), true
+}
@@ -6,11 +6,47 @@ import (
 	"strings"
 
 	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
 )
 
+// FoundLink is a URL extracted from a page, tagged with the element it came from
+type FoundLink struct {
+	URL      string
+	Element  string // "a", "area", "form" or "link"
+	NoFollow bool   // The element carries rel="nofollow"
+}
+
 // ExtractLinks parses HTML content and extracts all href links
 func ExtractLinks(body io.Reader, baseURL *url.URL) []string {
-	var links []string
+	found := ExtractLinksWith(body, baseURL, false)
+	links := make([]string, len(found))
+	for i, link := range found {
+		links[i] = link.URL
+	}
+	return links
+}
+
+// ExtractLinksWith parses HTML content and extracts <a href> links. With
+// extraElements it also reads <area href>, <form action> and <link href>.
+func ExtractLinksWith(body io.Reader, baseURL *url.URL, extraElements bool) []FoundLink {
+	links, _ := ExtractPage(body, baseURL, baseURL, extraElements)
+	return links
+}
+
+// Form is a <form> of a page, with what tells where its data goes
+type Form struct {
+	Action   string // Resolved action URL, the page URL without one, empty for javascript: and the like
+	Password bool   // The form has an <input type="password">
+}
+
+// ExtractPage is ExtractLinksWith also returning the page's forms, their
+// actions resolved against the page URL. A password field outside any form
+// counts as a form submitting to the page.
+func ExtractPage(body io.Reader, baseURL, pageURL *url.URL, extraElements bool) ([]FoundLink, []Form) {
+	var links []FoundLink
+	var forms []Form
+	inForm := false
 	tokenizer := html.NewTokenizer(body)
 
 	for {
@@ -18,26 +54,55 @@ func ExtractLinks(body io.Reader, baseURL *url.URL) []string {
 
 		switch tokenType {
 		case html.ErrorToken:
-			return links
+			return links, forms
+
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "form" {
+				inForm = false
+			}
 
 		case html.StartTagToken, html.SelfClosingTagToken:
 			token := tokenizer.Token()
 
-			if token.Data == "a" {
-				for _, attr := range token.Attr {
-					if attr.Key == "href" {
-						link := normalizeURL(attr.Val, baseURL)
-						if link != "" {
-							links = append(links, link)
-						}
-						break
-					}
+			switch token.Data {
+			case "form":
+				forms = append(forms, Form{Action: formAction(token, pageURL)})
+				inForm = tokenType == html.StartTagToken
+			case "input":
+				if !strings.EqualFold(strings.TrimSpace(htmlattr.Get(token, "type")), "password") {
+					break
+				}
+				if inForm {
+					forms[len(forms)-1].Password = true
+				} else {
+					forms = append(forms, Form{Action: pageURL.String(), Password: true})
+				}
+			}
+
+			if token.Data != "a" && !extraElements {
+				continue
+			}
+
+			if href, ok := htmlattr.LinkTarget(token); ok {
+				link := normalizeURL(href, baseURL)
+				if link != "" {
+					links = append(links, FoundLink{URL: link, Element: token.Data, NoFollow: htmlattr.IsNoFollow(token)})
 				}
 			}
 		}
 	}
 }
 
+// formAction returns where a form submits: its action resolved against the
+// page URL, or the page itself without one
+func formAction(token html.Token, pageURL *url.URL) string {
+	action := strings.TrimSpace(htmlattr.Get(token, "action"))
+	if action == "" {
+		return pageURL.String()
+	}
+	return normalizeURL(action, pageURL)
+}
+
 // normalizeURL converts a potentially relative URL to an absolute URL
 // and filters out non-HTTP URLs
 func normalizeURL(href string, baseURL *url.URL) string {
@@ -2,22 +2,166 @@ package crawler
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/sitetree"
 )
 
 // BrokenLink represents a broken link found during crawling
 type BrokenLink struct {
-	SourceURL  string
-	BrokenURL  string
+	SourceURL     string
+	BrokenURL     string
+	Element       string // Element the link was found in: a, area, form or link
+	StatusCode    int
+	Error         string
+	RedirectChain []string // Hops followed before the error status, final URL last
+}
+
+// ExternalRedirect is an internal link whose redirects end on another site
+type ExternalRedirect struct {
+	SourceURL string
+	LinkURL   string
+	FinalURL  string
+}
+
+// JSONPage is an internal URL answering JSON where a page was expected
+type JSONPage struct {
+	SourceURL   string // Empty for the start URL
+	URL         string
+	ContentType string
+}
+
+// Redirect is a link answering a redirect, reported when redirects are not
+// followed
+type Redirect struct {
+	SourceURL  string // Empty for the start URL
+	URL        string
 	StatusCode int
-	Error      string
+	Location   string
 }
 
 // CrawlResult holds the complete results of a crawl session
 type CrawlResult struct {
-	StartURL     string
-	TotalVisited int
-	BrokenLinks  []BrokenLink
+	StartURL          string
+	TotalVisited      int
+	BrokenLinks       []BrokenLink
+	BrokenTargets     []BrokenTarget       // Broken links collated by target, with every page linking to it
+	FailedFast        bool                 // Crawl stopped at the first broken link
+	Restricted        []BrokenLink         // Intentionally restricted links answering a Config.AcceptStatus code, sorted by URL
+	SkippedNoFollow   int                  // Nofollow links not followed with Config.ObeyNoFollow
+	ExternalRedirects []ExternalRedirect   // Internal links that redirect off-site, sorted by source
+	OpenRedirects     []OpenRedirect       // Suspected open redirects, with Config.ProbeOpenRedirects
+	JSONPages         []JSONPage           // Internal URLs answering JSON instead of HTML, sorted by URL
+	Redirects         []Redirect           // Links answering a redirect with Config.MaxRedirects 0, sorted by URL
+	InsecureForms     []InsecureForm       // Forms sending their data over plain HTTP, sorted by page
+	Headers           []HeaderCoverage     // Coverage of the AuditedHeaders across internal HTML pages
+	SitemapSeeds      int                  // Internal URLs from Config.Sitemaps added to the start URL
+	SitemapErrors     []string             // Sitemaps or sitemap index children that could not be read
+	SiteTree          *sitetree.Node       // Path hierarchy of the internal pages answering, with page counts
+	HTTPS             httppool.HTTPSPolicy // How the site enforces HTTPS, if it does; its http:// URLs were then crawled as https://
+	CrawlStats        crawlstats.Stats
+
+	// Pages crawled, split against Config.Baseline
+	BaselineChecked bool
+	KnownURLs       int
+	NewURLs         []string // Not in the baseline, sorted
+}
+
+// ViaRedirect reports whether the link only broke after following redirects
+func (l BrokenLink) ViaRedirect() bool {
+	return len(l.RedirectChain) > 0
+}
+
+// CountViaRedirect returns how many broken links were reached through redirects
+func (r *CrawlResult) CountViaRedirect() int {
+	count := 0
+	for _, link := range r.BrokenLinks {
+		if link.ViaRedirect() {
+			count++
+		}
+	}
+	return count
+}
+
+// StatusConnectionError groups the broken links whose request failed
+// without a status: DNS, refused connection, timeout
+const StatusConnectionError = "connection-error"
+
+// StatusGroup is the broken links answering one status
+type StatusGroup struct {
+	Status string // Status code, or StatusConnectionError
+	Links  []BrokenLink
+}
+
+// StatusKey returns the group of a broken link in BrokenByStatus
+func (l BrokenLink) StatusKey() string {
+	if l.StatusCode == 0 {
+		return StatusConnectionError
+	}
+	return strconv.Itoa(l.StatusCode)
+}
+
+// BrokenByStatus groups the broken links by status, like the migration
+// report: 404 first as the links to fix, other codes in order, then
+// connection errors
+func (r *CrawlResult) BrokenByStatus() []StatusGroup {
+	byStatus := make(map[int][]BrokenLink)
+	var errorLinks []BrokenLink
+
+	for _, link := range r.BrokenLinks {
+		if link.StatusCode == 0 {
+			errorLinks = append(errorLinks, link)
+		} else {
+			byStatus[link.StatusCode] = append(byStatus[link.StatusCode], link)
+		}
+	}
+
+	codes := make([]int, 0, len(byStatus))
+	for code := range byStatus {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if (codes[i] == 404) != (codes[j] == 404) {
+			return codes[i] == 404
+		}
+		return codes[i] < codes[j]
+	})
+
+	groups := make([]StatusGroup, 0, len(codes)+1)
+	for _, code := range codes {
+		groups = append(groups, StatusGroup{Status: strconv.Itoa(code), Links: byStatus[code]})
+	}
+	if len(errorLinks) > 0 {
+		groups = append(groups, StatusGroup{Status: StatusConnectionError, Links: errorLinks})
+	}
+	return groups
+}
+
+// ExportCSVByStatus exports the broken links to CSV format, grouped by
+// status in BrokenByStatus order
+func (r *CrawlResult) ExportCSVByStatus() string {
+	var sb strings.Builder
+	sb.WriteString("status,source_url,broken_url,element,error\n")
+
+	for _, group := range r.BrokenByStatus() {
+		for _, link := range group.Links {
+			errField := strings.ReplaceAll(link.Error, "\"", "'")
+			sb.WriteString(fmt.Sprintf("%s,\"%s\",\"%s\",%s,\"%s\"\n",
+				group.Status, link.SourceURL, link.BrokenURL, link.Element, errField))
+		}
+	}
+
+	return sb.String()
+}
+
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *CrawlResult) SummaryLine() string {
+	return fmt.Sprintf("SUMMARY tool=linkchecker pages=%d broken=%d via_redirect=%d nofollow_skipped=%d external_redirects=%d open_redirects=%d sitemap_seeds=%d restricted=%d json_pages=%d redirects=%d broken_targets=%d new_urls=%d insecure_forms=%d missing_security_headers=%d", r.TotalVisited, len(r.BrokenLinks), r.CountViaRedirect(), r.SkippedNoFollow, len(r.ExternalRedirects), len(r.OpenRedirects), r.SitemapSeeds, len(r.Restricted), len(r.JSONPages), len(r.Redirects), len(r.BrokenTargets), len(r.NewURLs), len(r.InsecureForms), r.MissingSecurityHeaders())
 }
 
 // ANSI color codes
@@ -28,6 +172,7 @@ const (
 	colorYellow = "\033[33m"
 	colorBlue   = "\033[34m"
 	colorCyan   = "\033[36m"
+	colorGray   = "\033[90m"
 	colorBold   = "\033[1m"
 )
 
@@ -37,21 +182,70 @@ func (r *CrawlResult) PrintSummary() {
 	fmt.Printf("%s%s=== Crawl Summary ===%s\n", colorBold, colorCyan, colorReset)
 	fmt.Printf("Start URL: %s%s%s\n", colorBlue, r.StartURL, colorReset)
 	fmt.Printf("Total pages visited: %s%d%s\n", colorGreen, r.TotalVisited, colorReset)
+	if r.SitemapSeeds > 0 {
+		fmt.Printf("Seeded from sitemaps: %s%d%s URLs\n", colorGreen, r.SitemapSeeds, colorReset)
+	}
+	for _, err := range r.SitemapErrors {
+		fmt.Printf("%sSitemap not read: %s%s\n", colorYellow, err, colorReset)
+	}
+	if r.BaselineChecked {
+		fmt.Printf("Baseline: %s%d%s known, %s%d%s new\n", colorGreen, r.KnownURLs, colorReset, colorYellow, len(r.NewURLs), colorReset)
+	}
+	if r.HTTPS.Enforced() {
+		fmt.Printf("Site enforces HTTPS: %s%s%s (http:// URLs crawled as https://)\n", colorGreen, r.HTTPS, colorReset)
+	}
+	if r.SkippedNoFollow > 0 {
+		fmt.Printf("Nofollow links not followed: %s%d%s\n", colorYellow, r.SkippedNoFollow, colorReset)
+	}
 	fmt.Println()
 
 	if len(r.BrokenLinks) == 0 {
 		fmt.Printf("%s%s✓ No broken links found!%s\n", colorBold, colorGreen, colorReset)
-		return
+	} else {
+		r.printBrokenLinks()
 	}
 
-	fmt.Printf("%s%s✗ Found %d broken link(s):%s\n\n", colorBold, colorRed, len(r.BrokenLinks), colorReset)
+	r.printBrokenTargets()
+	r.printRestricted()
+	r.printExternalRedirects()
+	r.printOpenRedirects()
+	r.printHeaders()
+	r.printInsecureForms()
+	r.printJSONPages()
+	r.printNewURLs()
+	r.printRedirects()
+	r.CrawlStats.Print()
+}
+
+// printBrokenLinks lists the broken links with where they were found
+func (r *CrawlResult) printBrokenLinks() {
+	if r.FailedFast {
+		fmt.Printf("%sCrawl stopped at the first broken link (--fail-fast)%s\n", colorYellow, colorReset)
+	}
+	fmt.Printf("%s%s✗ Found %d broken link(s):%s\n", colorBold, colorRed, len(r.BrokenLinks), colorReset)
+	var byStatus []string
+	for _, group := range r.BrokenByStatus() {
+		byStatus = append(byStatus, fmt.Sprintf("%s: %d", group.Status, len(group.Links)))
+	}
+	fmt.Printf("  By status: %s\n", strings.Join(byStatus, ", "))
+	if viaRedirect := r.CountViaRedirect(); viaRedirect > 0 {
+		fmt.Printf("  %d broken directly (update the link), %d after a redirect (fix the redirect target)\n",
+			len(r.BrokenLinks)-viaRedirect, viaRedirect)
+	}
+	fmt.Println()
 
 	for i, link := range r.BrokenLinks {
 		fmt.Printf("%s[%d]%s %s%s%s\n", colorYellow, i+1, colorReset, colorRed, link.BrokenURL, colorReset)
 		fmt.Printf("    Found on: %s\n", link.SourceURL)
+		if link.Element != "" && link.Element != "a" {
+			fmt.Printf("    Element: <%s>\n", link.Element)
+		}
 		if link.StatusCode > 0 {
 			fmt.Printf("    Status: %s%d%s\n", colorRed, link.StatusCode, colorReset)
 		}
+		if link.ViaRedirect() {
+			fmt.Printf("    Via redirect: %s%s → %s%s\n", colorYellow, link.BrokenURL, strings.Join(link.RedirectChain, " → "), colorReset)
+		}
 		if link.Error != "" {
 			fmt.Printf("    Error: %s\n", link.Error)
 		}
@@ -59,6 +253,125 @@ func (r *CrawlResult) PrintSummary() {
 	}
 }
 
+// printRestricted lists links answering an accepted status, kept out of the
+// broken links
+func (r *CrawlResult) printRestricted() {
+	if len(r.Restricted) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s%d intentionally restricted link(s):%s\n", colorBold, colorBlue, len(r.Restricted), colorReset)
+	fmt.Printf("  Answered with an accepted status (--accept), not counted as broken\n")
+	fmt.Println()
+
+	for i, link := range r.Restricted {
+		fmt.Printf("%s[%d]%s %s\n", colorYellow, i+1, colorReset, link.BrokenURL)
+		fmt.Printf("    Found on: %s\n", link.SourceURL)
+		fmt.Printf("    Status: %s%d%s\n", colorBlue, link.StatusCode, colorReset)
+		fmt.Println()
+	}
+}
+
+// printExternalRedirects lists internal links that send users to another site
+func (r *CrawlResult) printExternalRedirects() {
+	if len(r.ExternalRedirects) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s⚠ %d internal link(s) redirect externally:%s\n", colorBold, colorYellow, len(r.ExternalRedirects), colorReset)
+	fmt.Printf("  Either a mistake leaking users off-site or a hijacked or open redirect\n")
+	fmt.Println()
+
+	for i, redirect := range r.ExternalRedirects {
+		fmt.Printf("%s[%d]%s %s\n", colorYellow, i+1, colorReset, redirect.LinkURL)
+		fmt.Printf("    Found on: %s\n", redirect.SourceURL)
+		fmt.Printf("    Redirects to: %s%s%s\n", colorRed, redirect.FinalURL, colorReset)
+		fmt.Println()
+	}
+}
+
+// printOpenRedirects lists the URLs the open redirect probe could bounce off-site
+func (r *CrawlResult) printOpenRedirects() {
+	if len(r.OpenRedirects) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s⚠ %d suspected open redirect(s) (heuristic):%s\n", colorBold, colorRed, len(r.OpenRedirects), colorReset)
+	fmt.Printf("  Replacing the parameter with %s redirected there. Confirm by hand:\n", probeTarget)
+	fmt.Printf("  an attacker can use these URLs to send users anywhere under your domain.\n")
+	fmt.Println()
+
+	for i, redirect := range r.OpenRedirects {
+		fmt.Printf("%s[%d]%s %s\n", colorRed, i+1, colorReset, redirect.URL)
+		if redirect.SourceURL != "" {
+			fmt.Printf("    Found on: %s\n", redirect.SourceURL)
+		}
+		fmt.Printf("    Parameter: %s → Location: %s\n", redirect.Param, redirect.Location)
+		fmt.Println()
+	}
+}
+
+// printRedirects lists the links answering a redirect, when redirects are
+// not followed
+func (r *CrawlResult) printRedirects() {
+	if len(r.Redirects) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s⚠ %d link(s) answered a redirect:%s\n", colorBold, colorYellow, len(r.Redirects), colorReset)
+	fmt.Printf("  Redirects are not followed (--max-redirects 0); link to the target directly\n")
+	fmt.Println()
+
+	for i, redirect := range r.Redirects {
+		fmt.Printf("%s[%d]%s %s\n", colorYellow, i+1, colorReset, redirect.URL)
+		if redirect.SourceURL != "" {
+			fmt.Printf("    Found on: %s\n", redirect.SourceURL)
+		}
+		fmt.Printf("    Status: %s%d%s → %s\n", colorYellow, redirect.StatusCode, colorReset, redirect.Location)
+		fmt.Println()
+	}
+}
+
+// printJSONPages lists internal URLs that answered JSON, whose links were
+// not followed
+func (r *CrawlResult) printJSONPages() {
+	if len(r.JSONPages) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s⚠ %d URL(s) answered JSON instead of HTML:%s\n", colorBold, colorYellow, len(r.JSONPages), colorReset)
+	fmt.Printf("  Their links were not followed. Check the Accept header (--accept-header)\n")
+	fmt.Printf("  if these should be pages, e.g. on a site negotiating between HTML and an API.\n")
+	fmt.Println()
+
+	for i, page := range r.JSONPages {
+		if i >= 10 {
+			fmt.Printf("%s... and %d more%s\n", colorGray, len(r.JSONPages)-10, colorReset)
+			break
+		}
+		fmt.Printf("%s[%d]%s %s\n", colorYellow, i+1, colorReset, page.URL)
+		if page.SourceURL != "" {
+			fmt.Printf("    Found on: %s\n", page.SourceURL)
+		}
+		fmt.Printf("    Content-Type: %s\n", page.ContentType)
+		fmt.Println()
+	}
+}
+
+// PrintTree displays the site structure, largest sections first
+func (r *CrawlResult) PrintTree() {
+	fmt.Println()
+	fmt.Printf("%s%sSite structure (%d page(s)):%s\n", colorBold, colorCyan, r.SiteTree.Total, colorReset)
+	fmt.Printf("  Pages per path section; pages at the section's own path in [ ]\n")
+	fmt.Println()
+	r.SiteTree.Write(os.Stdout)
+}
+
 // PrintProgress displays progress information for a visited URL
 func PrintProgress(url string, statusCode int, depth int) {
 	status := fmt.Sprintf("%d", statusCode)
@@ -76,11 +389,11 @@ func PrintProgress(url string, statusCode int, depth int) {
 	}
 
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[%s]%s %s\n", indent, statusColor, status, colorReset, url)
+	fmt.Fprintf(os.Stderr, "%s%s[%s]%s %s\n", indent, statusColor, status, colorReset, url)
 }
 
 // PrintError displays an error for a URL
 func PrintError(url string, err string, depth int) {
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, err)
+	fmt.Fprintf(os.Stderr, "%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, err)
 }
@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BrokenTarget is a broken URL with every crawled page linking to it, to
+// fix all of them in one pass
+type BrokenTarget struct {
+	TargetURL string
+	Status    string   // Status code, or StatusConnectionError
+	Sources   []string // Sorted
+}
+
+// addSource records that a page links to a URL
+func (c *Crawler) addSource(targetURL, sourceURL string) {
+	key := c.engine.Key(targetURL)
+	c.sourcesMu.Lock()
+	if c.sources[key] == nil {
+		c.sources[key] = make(map[string]bool)
+	}
+	c.sources[key][sourceURL] = true
+	c.sourcesMu.Unlock()
+}
+
+// brokenTargets collates the broken links by target. The crawl reports a
+// URL once, on the first page found linking to it; the sources recorded
+// while crawling complete the list. Targets linked from the most pages
+// come first.
+func (c *Crawler) brokenTargets() []BrokenTarget {
+	byTarget := make(map[string]*BrokenTarget)
+	sources := make(map[string]map[string]bool)
+	var targets []*BrokenTarget
+	for _, link := range c.broken {
+		key := c.engine.Key(link.BrokenURL)
+		if byTarget[key] == nil {
+			byTarget[key] = &BrokenTarget{TargetURL: link.BrokenURL, Status: link.StatusKey()}
+			targets = append(targets, byTarget[key])
+			sources[key] = make(map[string]bool)
+			for source := range c.sources[key] {
+				sources[key][source] = true
+			}
+		}
+		sources[key][link.SourceURL] = true
+	}
+
+	for key, target := range byTarget {
+		for source := range sources[key] {
+			target.Sources = append(target.Sources, source)
+		}
+		sort.Strings(target.Sources)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if len(targets[i].Sources) != len(targets[j].Sources) {
+			return len(targets[i].Sources) > len(targets[j].Sources)
+		}
+		return targets[i].TargetURL < targets[j].TargetURL
+	})
+
+	result := make([]BrokenTarget, 0, len(targets))
+	for _, target := range targets {
+		result = append(result, *target)
+	}
+	return result
+}
+
+// printBrokenTargets lists each broken URL with every page linking to it
+func (r *CrawlResult) printBrokenTargets() {
+	if len(r.BrokenTargets) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s%d broken target(s) by linking pages:%s\n", colorBold, colorRed, len(r.BrokenTargets), colorReset)
+	fmt.Printf("  Every page linking to each broken URL, most linked first\n")
+	fmt.Println()
+
+	for i, target := range r.BrokenTargets {
+		fmt.Printf("%s[%d]%s %s%s%s (%s, %d page(s))\n", colorYellow, i+1, colorReset, colorRed, target.TargetURL, colorReset, target.Status, len(target.Sources))
+		for _, source := range target.Sources {
+			fmt.Printf("    %s\n", source)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,120 @@
+package crawlstats
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds operational numbers about a finished crawl
+type Stats struct {
+	Requests int64
+	Bytes    int64
+	Duration time.Duration
+}
+
+// RequestsPerSecond returns the effective request rate over the whole crawl
+func (s Stats) RequestsPerSecond() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Requests) / s.Duration.Seconds()
+}
+
+// Add returns the sum of two stats, e.g. to total several sub-crawls
+func (s Stats) Add(other Stats) Stats {
+	return Stats{
+		Requests: s.Requests + other.Requests,
+		Bytes:    s.Bytes + other.Bytes,
+		Duration: s.Duration + other.Duration,
+	}
+}
+
+// ANSI color codes
+const (
+	colorReset = "\033[0m"
+	colorGray  = "\033[90m"
+	colorBold  = "\033[1m"
+	colorCyan  = "\033[36m"
+)
+
+// Print displays the stats as a summary footer
+func (s Stats) Print() {
+	fmt.Println()
+	fmt.Printf("%s%s=== Crawl Stats ===%s\n", colorBold, colorCyan, colorReset)
+	fmt.Printf("%sRequests: %d | Transferred: %s | Duration: %v | Rate: %.1f req/s%s\n",
+		colorGray,
+		s.Requests,
+		formatSize(s.Bytes),
+		s.Duration.Round(time.Millisecond),
+		s.RequestsPerSecond(),
+		colorReset)
+}
+
+// Counter gathers stats from concurrent workers
+type Counter struct {
+	requests atomic.Int64
+	bytes    atomic.Int64
+	start    time.Time
+}
+
+// Start records the beginning of the crawl
+func (c *Counter) Start() {
+	c.start = time.Now()
+}
+
+// AddRequest counts one HTTP request
+func (c *Counter) AddRequest() {
+	c.requests.Add(1)
+}
+
+// AddBytes counts n transferred bytes
+func (c *Counter) AddBytes(n int64) {
+	c.bytes.Add(n)
+}
+
+// Body wraps a response body so every byte read from it is counted
+func (c *Counter) Body(r io.Reader) io.Reader {
+	return &countingReader{r: r, c: c}
+}
+
+// Snapshot returns the stats gathered so far
+func (c *Counter) Snapshot() Stats {
+	var duration time.Duration
+	if !c.start.IsZero() {
+		duration = time.Since(c.start)
+	}
+	return Stats{
+		Requests: c.requests.Load(),
+		Bytes:    c.bytes.Load(),
+		Duration: duration,
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	c *Counter
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.c.AddBytes(int64(n))
+	return n, err
+}
+
+func formatSize(bytes int64) string {
+	const (
+		KB = 1024
+		MB = KB * 1024
+	)
+
+	switch {
+	case bytes >= MB:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/MB)
+	case bytes >= KB:
+		return fmt.Sprintf("%.1fKB", float64(bytes)/KB)
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
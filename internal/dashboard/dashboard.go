@@ -0,0 +1,124 @@
+package dashboard
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+)
+
+// Interval is the time between two refreshes
+const Interval = 250 * time.Millisecond
+
+// Stats is what the dashboard shows, read again at every refresh
+type Stats struct {
+	Crawl  crawl.Progress
+	Totals crawlstats.Stats
+	Broken int
+}
+
+// ANSI color and cursor codes
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorGray  = "\033[90m"
+	colorBold  = "\033[1m"
+	colorCyan  = "\033[36m"
+	clearLine  = "\033[2K"
+)
+
+// Dashboard redraws a few status lines in place while a crawl runs
+type Dashboard struct {
+	out  io.Writer
+	read func() Stats
+	stop chan struct{}
+	done chan struct{}
+
+	lines        int // Lines drawn last time, erased before redrawing
+	lastRequests int64
+	lastAt       time.Time
+	rate         float64 // Requests per second over the last refresh
+}
+
+// IsTerminal reports whether f can be redrawn in place. Output redirected
+// to a file or a pipe gets the regular scrolling output instead.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Start draws the dashboard on out and refreshes it with read until Stop
+func Start(out io.Writer, read func() Stats) *Dashboard {
+	d := &Dashboard{
+		out:    out,
+		read:   read,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		lastAt: time.Now(),
+	}
+	go d.run()
+	return d
+}
+
+// Stop draws the final numbers and leaves them on screen
+func (d *Dashboard) Stop() {
+	close(d.stop)
+	<-d.done
+}
+
+func (d *Dashboard) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+
+	for {
+		d.draw(d.read())
+		select {
+		case <-ticker.C:
+		case <-d.stop:
+			d.draw(d.read())
+			return
+		}
+	}
+}
+
+// draw erases the previous frame and prints the current one
+func (d *Dashboard) draw(s Stats) {
+	now := time.Now()
+	if elapsed := now.Sub(d.lastAt); elapsed >= Interval/2 {
+		d.rate = float64(s.Totals.Requests-d.lastRequests) / elapsed.Seconds()
+		d.lastRequests = s.Totals.Requests
+		d.lastAt = now
+	}
+
+	brokenColor := colorGreen
+	if s.Broken > 0 {
+		brokenColor = colorRed
+	}
+
+	// Visited and Active are read together but may be a page apart
+	done := s.Crawl.Visited - s.Crawl.Active
+	if done < 0 {
+		done = 0
+	}
+
+	frame := []string{
+		fmt.Sprintf("%s%sCrawling%s %s%v elapsed%s", colorBold, colorCyan, colorReset, colorGray, s.Totals.Duration.Round(time.Second), colorReset),
+		fmt.Sprintf("  Pages:    %d visited, %d in progress, %d queued", done, s.Crawl.Active, s.Crawl.Queued),
+		fmt.Sprintf("  Requests: %d, %.1f req/s now, %.1f req/s overall", s.Totals.Requests, d.rate, s.Totals.RequestsPerSecond()),
+		fmt.Sprintf("  Broken:   %s%d%s", brokenColor, s.Broken, colorReset),
+	}
+
+	if d.lines > 0 {
+		fmt.Fprintf(d.out, "\033[%dA", d.lines)
+	}
+	for _, line := range frame {
+		fmt.Fprintf(d.out, "\r%s%s\n", clearLine, line)
+	}
+	d.lines = len(frame)
+}
@@ -0,0 +1,161 @@
+package export
+
+import "github.com/ngonzalez/web-tools/internal/audit"
+
+// Audit is the siteaudit document
+type Audit struct {
+	Header
+	DurationMS  int64       `json:"duration_ms"`
+	Scores      AuditScores `json:"scores"`
+	Skipped     []string    `json:"skipped_phases,omitempty"`
+	Pages       int         `json:"pages"`
+	Links       int         `json:"links"`
+	BrokenLinks int         `json:"broken_links"`
+	Issues      []Issue     `json:"issues"` // Most severe first
+	CrawlBudget CrawlBudget `json:"crawl_budget"`
+	CrawlStats  CrawlStats  `json:"crawl_stats"`
+}
+
+// AuditScores are the 0-100 audit scores, null for categories no phase run
+// measured
+type AuditScores struct {
+	Overall      *int `json:"overall"`
+	BrokenLinks  *int `json:"broken_links"`
+	SEO          *int `json:"seo"`
+	Performance  *int `json:"performance"`
+	Architecture *int `json:"architecture"`
+}
+
+// auditScore returns a score, nil when its category was not measured
+func auditScore(r *audit.AuditResult, c audit.Category, score int) *int {
+	if !r.Scored(c) {
+		return nil
+	}
+	return &score
+}
+
+// Issue is a problem found by the audit
+type Issue struct {
+	Category    string   `json:"category"`
+	Severity    string   `json:"severity"` // critical, high, medium, low or info
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Count       int      `json:"count,omitempty"`
+	Examples    []string `json:"examples,omitempty"`
+	Suggestion  string   `json:"suggestion"`
+}
+
+// CrawlBudget estimates how a search engine would spend its crawl on the site
+type CrawlBudget struct {
+	Pages            int            `json:"pages"`
+	IndexablePages   int            `json:"indexable_pages"`
+	AvgPageSize      int64          `json:"avg_page_size"`
+	AvgLatencyMS     int64          `json:"avg_latency_ms"`
+	SequentialTimeMS int64          `json:"sequential_time_ms"`
+	ParallelTimeMS   int64          `json:"parallel_time_ms"`
+	Concurrency      int            `json:"concurrency"`
+	Wasters          []BudgetWaster `json:"wasters"`
+}
+
+// BudgetWaster is a pattern spending crawl budget on URLs that add nothing
+type BudgetWaster struct {
+	Name     string   `json:"name"`
+	Count    int      `json:"count"`
+	Detail   string   `json:"detail"`
+	Signal   string   `json:"signal"`
+	Examples []string `json:"examples,omitempty"`
+}
+
+// NewAudit builds the document of a site audit
+func NewAudit(r *audit.AuditResult) Audit {
+	b := r.CrawlBudget
+	doc := Audit{
+		Header:     newHeader("siteaudit", r.URL),
+		DurationMS: r.Duration.Milliseconds(),
+		Scores: AuditScores{
+			BrokenLinks:  auditScore(r, audit.CategoryBrokenLinks, r.BrokenLinksScore),
+			SEO:          auditScore(r, audit.CategorySEO, r.SEOScore),
+			Performance:  auditScore(r, audit.CategoryPerformance, r.PerformanceScore),
+			Architecture: auditScore(r, audit.CategoryArchitecture, r.ArchitectureScore),
+		},
+		Pages:       r.TotalPages,
+		Links:       r.TotalLinks,
+		BrokenLinks: r.BrokenLinks,
+		Issues:      make([]Issue, 0, len(r.Issues)),
+		CrawlBudget: CrawlBudget{
+			Pages:            b.Pages,
+			IndexablePages:   b.IndexablePages,
+			AvgPageSize:      b.AvgPageSize,
+			AvgLatencyMS:     b.AvgLatency.Milliseconds(),
+			SequentialTimeMS: b.SequentialTime.Milliseconds(),
+			ParallelTimeMS:   b.ParallelTime.Milliseconds(),
+			Concurrency:      b.Concurrency,
+			Wasters:          make([]BudgetWaster, 0, len(b.Wasters)),
+		},
+		CrawlStats: newCrawlStats(r.CrawlStats),
+	}
+	for _, issue := range r.Issues {
+		doc.Issues = append(doc.Issues, Issue{
+			Category:    issueCategory(issue.Category),
+			Severity:    issueSeverity(issue.Severity),
+			Title:       issue.Title,
+			Description: issue.Description,
+			Count:       issue.Count,
+			Examples:    issue.Examples,
+			Suggestion:  issue.Suggestion,
+		})
+	}
+	if r.HasScore() {
+		overall := r.OverallScore
+		doc.Scores.Overall = &overall
+	}
+	for _, p := range r.SkippedPhases() {
+		doc.Skipped = append(doc.Skipped, string(p))
+	}
+	for _, w := range b.Wasters {
+		doc.CrawlBudget.Wasters = append(doc.CrawlBudget.Wasters, BudgetWaster{
+			Name:     w.Name,
+			Count:    w.Count,
+			Detail:   w.Detail,
+			Signal:   w.Signal,
+			Examples: w.Examples,
+		})
+	}
+	return doc
+}
+
+func issueCategory(c audit.Category) string {
+	switch c {
+	case audit.CategoryBrokenLinks:
+		return "broken_links"
+	case audit.CategoryIndexability:
+		return "indexability"
+	case audit.CategoryCanonical:
+		return "canonicals"
+	case audit.CategoryPerformance:
+		return "performance"
+	case audit.CategorySEO:
+		return "seo"
+	case audit.CategoryArchitecture:
+		return "architecture"
+	case audit.CategorySecurity:
+		return "security"
+	default:
+		return "other"
+	}
+}
+
+func issueSeverity(s audit.Severity) string {
+	switch s {
+	case audit.SeverityCritical:
+		return "critical"
+	case audit.SeverityHigh:
+		return "high"
+	case audit.SeverityMedium:
+		return "medium"
+	case audit.SeverityLow:
+		return "low"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,68 @@
+// Package export defines the JSON documents the tools write with --json.
+//
+// The types here are the wire format, kept apart from the internal result
+// types so those can be refactored freely: a document only changes when
+// this package does. Every document starts with the same header, whose
+// schema_version tells consumers which layout they read.
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+)
+
+// SchemaVersion is the layout version of every document. Bump it when a
+// field is removed, renamed or changes meaning; adding a field keeps it.
+const SchemaVersion = 1
+
+// Header opens every document
+type Header struct {
+	SchemaVersion int       `json:"schema_version"`
+	Tool          string    `json:"tool"`
+	StartURL      string    `json:"start_url"`
+	GeneratedAt   time.Time `json:"generated_at"`
+}
+
+func newHeader(tool, startURL string) Header {
+	return Header{
+		SchemaVersion: SchemaVersion,
+		Tool:          tool,
+		StartURL:      startURL,
+		GeneratedAt:   time.Now().UTC(),
+	}
+}
+
+// CrawlStats is the request accounting of a crawl
+type CrawlStats struct {
+	Requests   int64 `json:"requests"`
+	Bytes      int64 `json:"bytes"`
+	DurationMS int64 `json:"duration_ms"`
+}
+
+func newCrawlStats(s crawlstats.Stats) CrawlStats {
+	return CrawlStats{
+		Requests:   s.Requests,
+		Bytes:      s.Bytes,
+		DurationMS: s.Duration.Milliseconds(),
+	}
+}
+
+// Write encodes a document as indented JSON
+func Write(w io.Writer, doc any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false) // URLs keep their & as is
+	return encoder.Encode(doc)
+}
+
+// nonNil turns a nil slice into an empty one, so lists encode as [] rather
+// than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
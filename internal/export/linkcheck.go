@@ -0,0 +1,208 @@
+package export
+
+import (
+	"github.com/ngonzalez/web-tools/internal/crawler"
+	"github.com/ngonzalez/web-tools/internal/sitetree"
+)
+
+// LinkCheck is the linkchecker document
+type LinkCheck struct {
+	Header
+	PagesVisited      int                `json:"pages_visited"`
+	FailedFast        bool               `json:"failed_fast"`
+	BrokenLinks       []Link             `json:"broken_links"`
+	BrokenTargets     []BrokenTarget     `json:"broken_targets"`   // Most linked first
+	BrokenByStatus    map[string]int     `json:"broken_by_status"` // Status code or "connection-error" -> broken links
+	Restricted        []Link             `json:"restricted"`
+	ExternalRedirects []ExternalRedirect `json:"external_redirects"`
+	OpenRedirects     []OpenRedirect     `json:"open_redirects"`
+	JSONPages         []JSONPage         `json:"json_pages"`
+	Redirects         []Redirect         `json:"redirects"` // With --max-redirects 0
+	InsecureForms     []InsecureForm     `json:"insecure_forms"`
+	ResponseHeaders   []HeaderCoverage   `json:"response_headers"`
+	NoFollowSkipped   int                `json:"nofollow_skipped"`
+	SitemapSeeds      int                `json:"sitemap_seeds"`
+	SitemapErrors     []string           `json:"sitemap_errors"`
+	SiteTree          TreeNode           `json:"site_tree"`
+	NewURLs           []string           `json:"new_urls"` // With --baseline, pages crawled outside it
+	CrawlStats        CrawlStats         `json:"crawl_stats"`
+}
+
+// Link is a link answering an error status
+type Link struct {
+	SourceURL     string   `json:"source_url"`
+	URL           string   `json:"url"`
+	Element       string   `json:"element"`
+	StatusCode    int      `json:"status_code,omitempty"` // Absent when the request itself failed
+	Error         string   `json:"error,omitempty"`
+	RedirectChain []string `json:"redirect_chain,omitempty"`
+}
+
+// BrokenTarget is a broken URL with every page linking to it
+type BrokenTarget struct {
+	URL     string   `json:"url"`
+	Status  string   `json:"status"` // Status code or "connection-error"
+	Sources []string `json:"sources"`
+}
+
+// TreeNode is a path section of the site, with its largest subsections
+// first
+type TreeNode struct {
+	Path     string     `json:"path"`
+	Pages    int        `json:"pages"` // Pages at exactly this path
+	Total    int        `json:"total"` // Pages in the section, this node's included
+	Children []TreeNode `json:"children"`
+}
+
+func newTreeNode(n *sitetree.Node) TreeNode {
+	node := TreeNode{Path: n.Path, Pages: n.Pages, Total: n.Total, Children: []TreeNode{}}
+	for _, child := range n.Children {
+		node.Children = append(node.Children, newTreeNode(child))
+	}
+	return node
+}
+
+// ExternalRedirect is an internal link whose redirects end on another site
+type ExternalRedirect struct {
+	SourceURL string `json:"source_url"`
+	URL       string `json:"url"`
+	FinalURL  string `json:"final_url"`
+}
+
+// OpenRedirect is a URL suspected to redirect wherever a parameter says
+type OpenRedirect struct {
+	SourceURL string `json:"source_url"`
+	URL       string `json:"url"`
+	Param     string `json:"param"`
+	Location  string `json:"location"`
+}
+
+// JSONPage is an internal URL answering JSON instead of HTML
+type JSONPage struct {
+	SourceURL   string `json:"source_url"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+}
+
+// Redirect is a link answering a redirect that was not followed
+type Redirect struct {
+	SourceURL  string `json:"source_url"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// InsecureForm is a form sending its data over plain HTTP
+type InsecureForm struct {
+	PageURL  string `json:"page_url"`
+	Action   string `json:"action"`
+	Password bool   `json:"password"`
+	Reason   string `json:"reason"`
+}
+
+// HeaderCoverage is how many internal HTML pages send a response header
+type HeaderCoverage struct {
+	Header   string   `json:"header"`
+	Security bool     `json:"security"`
+	Checked  int      `json:"checked"` // Pages it applies to, HTTPS ones for Strict-Transport-Security
+	Present  int      `json:"present"`
+	Missing  []string `json:"missing"`
+}
+
+// NewLinkCheck builds the document of a link check
+func NewLinkCheck(r *crawler.CrawlResult) LinkCheck {
+	doc := LinkCheck{
+		Header:            newHeader("linkchecker", r.StartURL),
+		PagesVisited:      r.TotalVisited,
+		FailedFast:        r.FailedFast,
+		BrokenLinks:       newLinks(r.BrokenLinks),
+		BrokenTargets:     []BrokenTarget{},
+		BrokenByStatus:    make(map[string]int),
+		Restricted:        newLinks(r.Restricted),
+		ExternalRedirects: []ExternalRedirect{},
+		OpenRedirects:     []OpenRedirect{},
+		JSONPages:         []JSONPage{},
+		Redirects:         []Redirect{},
+		InsecureForms:     []InsecureForm{},
+		ResponseHeaders:   []HeaderCoverage{},
+		NoFollowSkipped:   r.SkippedNoFollow,
+		SitemapSeeds:      r.SitemapSeeds,
+		SitemapErrors:     nonNil(r.SitemapErrors),
+		SiteTree:          newTreeNode(r.SiteTree),
+		NewURLs:           nonNil(r.NewURLs),
+		CrawlStats:        newCrawlStats(r.CrawlStats),
+	}
+	for _, group := range r.BrokenByStatus() {
+		doc.BrokenByStatus[group.Status] = len(group.Links)
+	}
+	for _, target := range r.BrokenTargets {
+		doc.BrokenTargets = append(doc.BrokenTargets, BrokenTarget{
+			URL:     target.TargetURL,
+			Status:  target.Status,
+			Sources: target.Sources,
+		})
+	}
+	for _, redirect := range r.ExternalRedirects {
+		doc.ExternalRedirects = append(doc.ExternalRedirects, ExternalRedirect{
+			SourceURL: redirect.SourceURL,
+			URL:       redirect.LinkURL,
+			FinalURL:  redirect.FinalURL,
+		})
+	}
+	for _, redirect := range r.OpenRedirects {
+		doc.OpenRedirects = append(doc.OpenRedirects, OpenRedirect{
+			SourceURL: redirect.SourceURL,
+			URL:       redirect.URL,
+			Param:     redirect.Param,
+			Location:  redirect.Location,
+		})
+	}
+	for _, page := range r.JSONPages {
+		doc.JSONPages = append(doc.JSONPages, JSONPage{
+			SourceURL:   page.SourceURL,
+			URL:         page.URL,
+			ContentType: page.ContentType,
+		})
+	}
+	for _, redirect := range r.Redirects {
+		doc.Redirects = append(doc.Redirects, Redirect{
+			SourceURL:  redirect.SourceURL,
+			URL:        redirect.URL,
+			StatusCode: redirect.StatusCode,
+			Location:   redirect.Location,
+		})
+	}
+	for _, form := range r.InsecureForms {
+		doc.InsecureForms = append(doc.InsecureForms, InsecureForm{
+			PageURL:  form.PageURL,
+			Action:   form.Action,
+			Password: form.Password,
+			Reason:   form.Reason,
+		})
+	}
+	for _, h := range r.Headers {
+		doc.ResponseHeaders = append(doc.ResponseHeaders, HeaderCoverage{
+			Header:   h.Header,
+			Security: h.Security,
+			Checked:  h.Checked,
+			Present:  h.Present(),
+			Missing:  nonNil(h.Missing),
+		})
+	}
+	return doc
+}
+
+func newLinks(broken []crawler.BrokenLink) []Link {
+	links := make([]Link, 0, len(broken))
+	for _, link := range broken {
+		links = append(links, Link{
+			SourceURL:     link.SourceURL,
+			URL:           link.BrokenURL,
+			Element:       link.Element,
+			StatusCode:    link.StatusCode,
+			Error:         link.Error,
+			RedirectChain: link.RedirectChain,
+		})
+	}
+	return links
+}
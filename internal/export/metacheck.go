@@ -0,0 +1,101 @@
+package export
+
+import (
+	"time"
+
+	"github.com/ngonzalez/web-tools/internal/metacheck"
+)
+
+// Description statuses of MetaPage.Status
+const (
+	StatusOK        = "ok"
+	StatusTooLong   = "too_long"
+	StatusTooShort  = "too_short"
+	StatusMissing   = "missing"
+	StatusDuplicate = "duplicate"
+)
+
+// MetaCheck is the metacheck document
+type MetaCheck struct {
+	Header
+	PagesChecked int        `json:"pages_checked"`
+	Counts       MetaCounts `json:"counts"`
+	Pages        []MetaPage `json:"pages"`
+	NotLinked    []string   `json:"not_linked,omitempty"` // Sitemap URLs no internal link reached, with --sitemap
+	CrawlStats   CrawlStats `json:"crawl_stats"`
+}
+
+// MetaCounts counts pages by description status
+type MetaCounts struct {
+	OK        int `json:"ok"`
+	TooLong   int `json:"too_long"`
+	TooShort  int `json:"too_short"`
+	Missing   int `json:"missing"`
+	Duplicate int `json:"duplicate"`
+}
+
+// MetaPage is the title and description of a page
+type MetaPage struct {
+	URL               string     `json:"url"`
+	Title             string     `json:"title"`
+	TitleLength       int        `json:"title_length"`
+	Description       string     `json:"description"`
+	DescriptionLength int        `json:"description_length"`
+	Status            string     `json:"status"`
+	HTMLIssues        []string   `json:"html_issues,omitempty"`
+	Modified          *time.Time `json:"modified,omitempty"`
+	AutoSnippet       string     `json:"auto_snippet,omitempty"`
+	ClientRendered    string     `json:"client_rendered,omitempty"` // Why the page appears rendered by JavaScript
+}
+
+// NewMetaCheck builds the document of a meta description check
+func NewMetaCheck(r *metacheck.MetaResult) MetaCheck {
+	doc := MetaCheck{
+		Header:       newHeader("metacheck", r.StartURL),
+		PagesChecked: r.TotalPages,
+		Counts: MetaCounts{
+			OK:        r.OKCount,
+			TooLong:   r.TooLongCount,
+			TooShort:  r.TooShortCount,
+			Missing:   r.MissingCount,
+			Duplicate: r.DuplicateCount,
+		},
+		Pages:      make([]MetaPage, 0, len(r.AllPages)),
+		NotLinked:  r.NotLinked,
+		CrawlStats: newCrawlStats(r.CrawlStats),
+	}
+	for _, page := range r.AllPages {
+		p := MetaPage{
+			URL:               page.URL,
+			Title:             page.Title,
+			TitleLength:       page.TitleLength,
+			Description:       page.Description,
+			DescriptionLength: page.DescLength,
+			Status:            metaStatus(page.Status),
+			HTMLIssues:        page.HTMLIssues,
+			AutoSnippet:       page.AutoSnippet,
+			ClientRendered:    page.ClientRendered,
+		}
+		if !page.Modified.IsZero() {
+			modified := page.Modified.UTC()
+			p.Modified = &modified
+		}
+		doc.Pages = append(doc.Pages, p)
+	}
+	return doc
+}
+
+func metaStatus(s metacheck.Status) string {
+	switch s {
+	case metacheck.StatusTooLong:
+		return StatusTooLong
+	case metacheck.StatusTooShort:
+		return StatusTooShort
+	case metacheck.StatusMissing:
+		return StatusMissing
+	case metacheck.StatusDuplicate:
+		return StatusDuplicate
+	default:
+		return StatusOK
+	}
+}
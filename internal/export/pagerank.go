@@ -0,0 +1,80 @@
+package export
+
+import (
+	"sort"
+
+	"github.com/ngonzalez/web-tools/internal/pagerank"
+)
+
+// PageRank is the pagerank document
+type PageRank struct {
+	Header
+	Pages           int         `json:"pages"`
+	Links           int         `json:"links"`
+	Iterations      int         `json:"iterations"`
+	Converged       bool        `json:"converged"`
+	DampingFactor   float64     `json:"damping_factor"`
+	ExcludedNoIndex int         `json:"excluded_noindex"`
+	NoFollowSkipped int         `json:"nofollow_skipped"`
+	Scores          []PageScore `json:"scores"` // Highest score first
+	SitemapURLs     int         `json:"sitemap_urls"`
+	SitemapOrphans  []string    `json:"sitemap_orphans"`
+	UnderLinked     []string    `json:"under_linked"` // Fewer out-links than min_out_links
+	OverLinked      []string    `json:"over_linked"`  // More out-links than max_out_links
+	MinOutLinks     int         `json:"min_out_links"`
+	MaxOutLinks     int         `json:"max_out_links"`
+	CrawlStats      CrawlStats  `json:"crawl_stats"`
+}
+
+// PageScore is the PageRank of a page
+type PageScore struct {
+	URL      string  `json:"url"`
+	Score    float64 `json:"score"`
+	InLinks  int     `json:"in_links"`
+	OutLinks int     `json:"out_links"`
+	Depth    int     `json:"depth"` // Clicks from the start page, -1 if unreachable
+}
+
+// NewPageRank builds the document of a PageRank computation
+func NewPageRank(r *pagerank.PageRankResult) PageRank {
+	doc := PageRank{
+		Header:          newHeader("pagerank", r.StartURL),
+		Pages:           r.TotalPages,
+		Links:           r.TotalLinks,
+		Iterations:      r.Iterations,
+		Converged:       r.Converged,
+		DampingFactor:   r.DampingFactor,
+		ExcludedNoIndex: r.ExcludedNoIndex,
+		NoFollowSkipped: r.SkippedNoFollow,
+		Scores:          make([]PageScore, 0, len(r.Scores)),
+		SitemapURLs:     r.SitemapURLs,
+		SitemapOrphans:  nonNil(r.SitemapOrphans),
+		UnderLinked:     pageURLs(r.UnderLinked()),
+		OverLinked:      pageURLs(r.OverLinked()),
+		MinOutLinks:     r.MinOutLinks,
+		MaxOutLinks:     r.MaxOutLinks,
+		CrawlStats:      newCrawlStats(r.CrawlStats),
+	}
+	for _, page := range r.Scores {
+		doc.Scores = append(doc.Scores, PageScore{
+			URL:      page.URL,
+			Score:    page.Score,
+			InLinks:  page.InLinks,
+			OutLinks: page.OutLinks,
+			Depth:    page.Depth,
+		})
+	}
+	sort.SliceStable(doc.Scores, func(i, j int) bool {
+		return doc.Scores[i].Score > doc.Scores[j].Score
+	})
+	return doc
+}
+
+// pageURLs returns the URLs of pages, in order
+func pageURLs(pages []pagerank.PageScore) []string {
+	urls := make([]string, 0, len(pages))
+	for _, page := range pages {
+		urls = append(urls, page.URL)
+	}
+	return urls
+}
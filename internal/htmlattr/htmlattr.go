@@ -0,0 +1,61 @@
+package htmlattr
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Get returns the value of an attribute, "" when the token doesn't have it
+func Get(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// HasRel reports whether an element's rel attribute, a space-separated
+// list, contains value
+func HasRel(token html.Token, value string) bool {
+	for _, part := range strings.Fields(strings.ToLower(Get(token, "rel"))) {
+		if part == value {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNoFollow reports whether an element's rel attribute contains nofollow
+func IsNoFollow(token html.Token) bool {
+	return HasRel(token, "nofollow")
+}
+
+// LinkTarget returns the URL an element references, if it is a link to
+// a document: <a>, <area>, <form> or a <link> other than a resource hint
+func LinkTarget(token html.Token) (string, bool) {
+	var key string
+	switch token.Data {
+	case "a", "area":
+		key = "href"
+	case "form":
+		key = "action"
+	case "link":
+		// Resource hints point at origins, not documents
+		rel := strings.ToLower(Get(token, "rel"))
+		if rel == "" || strings.Contains(rel, "preconnect") || strings.Contains(rel, "dns-prefetch") {
+			return "", false
+		}
+		key = "href"
+	default:
+		return "", false
+	}
+
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,30 @@
+package htmlhead
+
+import "golang.org/x/net/html"
+
+// headElements are the elements allowed inside <head>; any other start tag
+// means the body has begun, even when </head> and <body> are omitted
+var headElements = map[string]bool{
+	"html":     true,
+	"head":     true,
+	"title":    true,
+	"meta":     true,
+	"link":     true,
+	"base":     true,
+	"style":    true,
+	"script":   true,
+	"noscript": true,
+	"template": true,
+}
+
+// IsEnd reports whether a token marks the end of <head>. Tokenizer loops
+// that only need head metadata stop there instead of reading the whole page.
+func IsEnd(tokenType html.TokenType, name string) bool {
+	switch tokenType {
+	case html.EndTagToken:
+		return name == "head"
+	case html.StartTagToken, html.SelfClosingTagToken:
+		return !headElements[name]
+	}
+	return false
+}
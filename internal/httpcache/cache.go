@@ -0,0 +1,152 @@
+package httpcache
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Transport is an http.RoundTripper that keeps GET responses in memory so
+// several crawlers sharing it don't fetch the same URL twice. Server errors
+// (5xx) are not kept.
+type Transport struct {
+	next     http.RoundTripper
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	order   []string // Insertion order, oldest first, for eviction
+	size    int64
+
+	hits   int
+	misses int
+}
+
+type entry struct {
+	statusCode int
+	status     string
+	proto      string
+	header     http.Header
+	body       []byte
+}
+
+// New creates a caching transport wrapping next (http.DefaultTransport if nil).
+// maxBytes caps the total size of cached bodies; oldest entries are evicted first.
+func New(next http.RoundTripper, maxBytes int64) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{
+		next:     next,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*entry),
+	}
+}
+
+// RoundTrip serves GET requests from the cache when possible
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+
+	t.mu.Lock()
+	cached, ok := t.entries[key]
+	if ok {
+		t.hits++
+	} else {
+		t.misses++
+	}
+	t.mu.Unlock()
+
+	if ok {
+		return cached.response(req), nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Server errors are often transient: the next checker asks again
+	if resp.StatusCode >= 500 {
+		return resp, nil
+	}
+
+	// A body larger than the whole cache is never stored, so reading stops
+	// one byte past the cap and the rest is streamed to the caller
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.maxBytes+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > t.maxBytes {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	e := &entry{
+		statusCode: resp.StatusCode,
+		status:     resp.Status,
+		proto:      resp.Proto,
+		header:     resp.Header.Clone(),
+		body:       body,
+	}
+	t.store(key, e)
+
+	return e.response(req), nil
+}
+
+// Stats returns the number of cache hits and misses so far
+func (t *Transport) Stats() (hits, misses int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.hits, t.misses
+}
+
+// store adds an entry, evicting the oldest ones to stay under the size cap
+func (t *Transport) store(key string, e *entry) {
+	size := int64(len(e.body))
+	if size > t.maxBytes {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.entries[key]; exists {
+		return
+	}
+
+	for t.size+size > t.maxBytes && len(t.order) > 0 {
+		oldest := t.order[0]
+		t.order = t.order[1:]
+		t.size -= int64(len(t.entries[oldest].body))
+		delete(t.entries, oldest)
+	}
+
+	t.entries[key] = e
+	t.order = append(t.order, key)
+	t.size += size
+}
+
+// response builds a fresh *http.Response from a cached entry
+func (e *entry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        e.status,
+		StatusCode:    e.statusCode,
+		Proto:         e.proto,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,27 @@
+package httppool
+
+import "net/http"
+
+// MinIdleConnsPerHost is the floor of the idle pool, the net/http default
+const MinIdleConnsPerHost = 2
+
+// New returns a transport whose idle connection pool fits a crawl running
+// concurrency requests at a time against mostly one host. Without it,
+// net/http keeps 2 idle connections per host and a -c 50 crawl reopens
+// connections constantly. maxIdlePerHost overrides the pool size, 0 sizes it
+// to concurrency.
+func New(concurrency, maxIdlePerHost int) *http.Transport {
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = concurrency
+	}
+	if maxIdlePerHost < MinIdleConnsPerHost {
+		maxIdlePerHost = MinIdleConnsPerHost
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = maxIdlePerHost
+	if transport.MaxIdleConns < maxIdlePerHost {
+		transport.MaxIdleConns = maxIdlePerHost
+	}
+	return transport
+}
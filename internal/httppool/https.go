@@ -0,0 +1,157 @@
+package httppool
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPSPolicy is how a site enforces HTTPS. Once it does, its http:// URLs
+// are the same pages as their https:// form: browsers and search engines
+// are sent there, so the tools crawl them as https:// rather than
+// reporting the same upgrade redirect on every page.
+type HTTPSPolicy struct {
+	HTTPHost  string // Host of the http:// URLs upgraded
+	HTTPSHost string // Host they are upgraded to, usually the same
+	Redirect  int    // Status of the http:// to https:// redirects, 0 when not seen
+	HSTS      bool   // https:// answers carry Strict-Transport-Security
+}
+
+// Enforced reports whether the site enforces HTTPS
+func (p HTTPSPolicy) Enforced() bool {
+	return p.HTTPHost != "" && (p.Redirect != 0 || p.HSTS)
+}
+
+// String describes the policy, e.g. "301 redirects from http://, HSTS"
+func (p HTTPSPolicy) String() string {
+	var parts []string
+	if p.Redirect != 0 {
+		parts = append(parts, fmt.Sprintf("%d redirects from http://", p.Redirect))
+	}
+	if p.HSTS {
+		parts = append(parts, "HSTS")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Upgrade returns the https:// form of an http:// URL of the site, and
+// any other URL as is. It suits crawl.Config.Rewrite.
+func (p HTTPSPolicy) Upgrade(u string) string {
+	if !p.Enforced() {
+		return u
+	}
+	rest, ok := strings.CutPrefix(u, "http://"+p.HTTPHost)
+	if !ok || (rest != "" && !strings.ContainsAny(rest[:1], "/?#")) {
+		return u
+	}
+	return "https://" + p.HTTPSHost + rest
+}
+
+// HTTPSRewrite detects the HTTPS policy of startURL's site like DetectHTTPS
+// and returns its Upgrade, for crawl.Config.Rewrite. It returns nil, leaving
+// URLs as linked, when keepHTTP is set or the site doesn't enforce HTTPS.
+func HTTPSRewrite(keepHTTP bool, transport http.RoundTripper, timeout time.Duration, startURL string) func(string) string {
+	if keepHTTP {
+		return nil
+	}
+	if policy := DetectHTTPS(transport, timeout, startURL); policy.Enforced() {
+		return policy.Upgrade
+	}
+	return nil
+}
+
+// DetectHTTPS finds whether the site of startURL enforces HTTPS: both its
+// http:// start URL and /robots.txt redirect to the same URLs over
+// https://, or its https:// start URL sends Strict-Transport-Security.
+// Redirects are not followed; failed requests leave the policy unenforced.
+func DetectHTTPS(transport http.RoundTripper, timeout time.Duration, startURL string) HTTPSPolicy {
+	start, err := url.Parse(startURL)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") {
+		return HTTPSPolicy{}
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	policy := HTTPSPolicy{HTTPHost: start.Host, HTTPSHost: start.Host}
+	httpStart := *start
+	httpStart.Scheme = "http"
+	robotsURL := httpStart
+	robotsURL.Path, robotsURL.RawQuery, robotsURL.Fragment = "/robots.txt", "", ""
+
+	// An https:// start URL with HSTS is enough: no http:// request is made
+	// when the header already says browsers never use it
+	if start.Scheme == "https" {
+		if resp, err := client.Get(startURL); err == nil {
+			resp.Body.Close()
+			policy.HSTS = hasHSTS(resp)
+		}
+		if policy.HSTS {
+			return policy
+		}
+	}
+
+	for i, probe := range []*url.URL{&httpStart, &robotsURL} {
+		status, target, ok := upgradeRedirect(client, probe)
+		if !ok {
+			return HTTPSPolicy{}
+		}
+		if i == 0 {
+			policy.Redirect = status
+			policy.HTTPSHost = target
+		}
+	}
+
+	if start.Scheme == "http" {
+		if resp, err := client.Get(policy.Upgrade(startURL)); err == nil {
+			resp.Body.Close()
+			policy.HSTS = hasHSTS(resp)
+		}
+	}
+	return policy
+}
+
+// upgradeRedirect requests an http:// URL and reports whether it redirects
+// to the same URL over https://, with the status and the https:// host
+func upgradeRedirect(client *http.Client, probe *url.URL) (status int, httpsHost string, ok bool) {
+	resp, err := client.Get(probe.String())
+	if err != nil {
+		return 0, "", false
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return 0, "", false
+	}
+	location, err := resp.Location()
+	if err != nil || location.Scheme != "https" || location.Hostname() != probe.Hostname() {
+		return 0, "", false
+	}
+	if location.EscapedPath() != probe.EscapedPath() && !(location.Path == "/" && probe.Path == "") {
+		return 0, "", false
+	}
+	if location.RawQuery != probe.RawQuery {
+		return 0, "", false
+	}
+	return resp.StatusCode, location.Host, true
+}
+
+// hasHSTS reports whether a response enables Strict-Transport-Security;
+// max-age=0 disables it
+func hasHSTS(resp *http.Response) bool {
+	if resp.Request == nil || resp.Request.URL.Scheme != "https" {
+		return false
+	}
+	for _, directive := range strings.Split(resp.Header.Get("Strict-Transport-Security"), ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			return strings.Trim(value, `"`) != "0" && value != ""
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package httppool
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Redirect limits for the tools' Config.MaxRedirects
+const (
+	DefaultMaxRedirects = 10  // One more than net/http's policy, which refuses the 10th
+	MaxRedirectsCap     = 100 // Safety cap of -1, so a redirect loop still ends
+)
+
+// RedirectLimit returns how many redirects a Config.MaxRedirects value
+// follows: -1 (or any negative value) means MaxRedirectsCap
+func RedirectLimit(maxRedirects int) int {
+	if maxRedirects < 0 {
+		return MaxRedirectsCap
+	}
+	return maxRedirects
+}
+
+// ErrTooManyRedirects fails a request past its redirect limit
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// CheckRedirect returns an http.Client redirect policy following at most
+// maxRedirects redirects: unlike net/http's default, which stops at the
+// 10th, a limit of 10 follows the 10th and stops at the 11th. With 0 the
+// client returns the redirect response itself, so callers see the 3xx
+// status and can report it as a redirect.
+// Past the limit the policy returns pastLimit: ErrTooManyRedirects to fail
+// the request, or http.ErrUseLastResponse to keep the last redirect.
+func CheckRedirect(maxRedirects int, pastLimit error) func(req *http.Request, via []*http.Request) error {
+	limit := RedirectLimit(maxRedirects)
+	return func(req *http.Request, via []*http.Request) error {
+		if limit == 0 {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > limit {
+			return pastLimit
+		}
+		return nil
+	}
+}
@@ -0,0 +1,75 @@
+package httppool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolve forces hosts to connect to given addresses, like curl --resolve,
+// to audit one origin server or a staging box without changing DNS. spec is
+// a comma-separated list of host:ip or host:port:ip entries; IPv6 addresses
+// go in brackets. Only the dialed address changes: the Host header and the
+// TLS server name still use the host, so virtual hosts and certificates
+// work as usual.
+//
+// The override is installed on http.DefaultTransport, which every transport
+// of this module is cloned from or falls back to, so Resolve must be called
+// before the tools are created. An empty spec does nothing.
+func Resolve(spec string) error {
+	overrides, err := parseResolve(spec)
+	if err != nil || len(overrides) == 0 {
+		return err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport)
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			host = strings.ToLower(host)
+			if ip, ok := overrides[net.JoinHostPort(host, port)]; ok {
+				addr = net.JoinHostPort(ip, port)
+			} else if ip, ok := overrides[host]; ok {
+				addr = net.JoinHostPort(ip, port)
+			}
+		}
+		return dial(ctx, network, addr)
+	}
+	return nil
+}
+
+// parseResolve returns the overrides keyed by host, or host:port when the
+// entry names a port
+func parseResolve(spec string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		host, rest, ok := strings.Cut(entry, ":")
+		if !ok || host == "" || rest == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected host:ip or host:port:ip", entry)
+		}
+		key := strings.ToLower(host)
+
+		// host:port:ip, unless the rest is a bare IPv6 address
+		if port, ip, ok := strings.Cut(rest, ":"); ok && !strings.HasPrefix(rest, "[") && net.ParseIP(rest) == nil {
+			key = net.JoinHostPort(key, port)
+			rest = ip
+		}
+
+		ip := strings.TrimSuffix(strings.TrimPrefix(rest, "["), "]")
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid address %q in %q", rest, entry)
+		}
+		overrides[key] = ip
+	}
+	return overrides, nil
+}
@@ -0,0 +1,85 @@
+package indexer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/robots"
+)
+
+// RobotsConflict is a page whose robots meta tag and X-Robots-Tag header
+// contradict each other. Search engines apply the most restrictive
+// directive, so the page is noindex (or nofollow) whatever the other says.
+type RobotsConflict struct {
+	URL        string
+	MetaRobots string // Raw content of the robots meta tag
+	XRobotsTag string // Raw X-Robots-Tag header, values joined with ", "
+	Conflicts  []string
+}
+
+// robotsConflicts compares the directives of the meta tag and the header,
+// returning each contradiction, e.g. "header noindex, meta index"
+func robotsConflicts(metaRobots, xRobotsTag string) []string {
+	if metaRobots == "" || xRobotsTag == "" {
+		return nil
+	}
+	meta := robots.Directives(metaRobots)
+	header := robots.Directives(xRobotsTag)
+
+	var conflicts []string
+	for _, pair := range [][2]string{{"noindex", "index"}, {"nofollow", "follow"}} {
+		restrictive, permissive := pair[0], pair[1]
+		if header[restrictive] && meta[permissive] && !meta[restrictive] {
+			conflicts = append(conflicts, fmt.Sprintf("header %s, meta %s", restrictive, permissive))
+		}
+		if meta[restrictive] && header[permissive] && !header[restrictive] {
+			conflicts = append(conflicts, fmt.Sprintf("meta %s, header %s", restrictive, permissive))
+		}
+	}
+	return conflicts
+}
+
+// addRobotsConflict records a page whose meta robots and header disagree
+func (idx *Indexer) addRobotsConflict(pageURL, metaRobots, xRobotsTag string) {
+	conflicts := robotsConflicts(metaRobots, xRobotsTag)
+	if len(conflicts) == 0 {
+		return
+	}
+	idx.resultMu.Lock()
+	idx.result.RobotsConflicts = append(idx.result.RobotsConflicts, RobotsConflict{
+		URL:        pageURL,
+		MetaRobots: metaRobots,
+		XRobotsTag: xRobotsTag,
+		Conflicts:  conflicts,
+	})
+	idx.resultMu.Unlock()
+}
+
+// sortRobotsConflicts orders the conflicts by URL, as pages finish in any
+// order
+func (r *IndexerResult) sortRobotsConflicts() {
+	sort.Slice(r.RobotsConflicts, func(i, j int) bool {
+		return r.RobotsConflicts[i].URL < r.RobotsConflicts[j].URL
+	})
+}
+
+// printRobotsConflicts lists the pages with both directive values
+func (r *IndexerResult) printRobotsConflicts() {
+	if len(r.RobotsConflicts) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s⚠ Conflicting robots directives (%d page(s)):%s\n", colorBold, colorRed, len(r.RobotsConflicts), colorReset)
+	fmt.Printf("  %sThe meta tag and X-Robots-Tag header disagree; crawlers apply the most restrictive one.%s\n", colorGray, colorReset)
+	for i, conflict := range r.RobotsConflicts {
+		if i >= 10 {
+			fmt.Printf("  %s... and %d more%s\n", colorGray, len(r.RobotsConflicts)-10, colorReset)
+			break
+		}
+		fmt.Printf("  %s %s(%s)%s\n", conflict.URL, colorYellow, strings.Join(conflict.Conflicts, "; "), colorReset)
+		fmt.Printf("    %smeta robots:  %s%s\n", colorGray, conflict.MetaRobots, colorReset)
+		fmt.Printf("    %sX-Robots-Tag: %s%s\n", colorGray, conflict.XRobotsTag, colorReset)
+	}
+}
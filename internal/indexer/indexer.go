@@ -5,18 +5,37 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/robots"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 // Config holds the indexer configuration
 type Config struct {
-	Concurrency    int
-	Timeout        time.Duration
-	MaxDepth       int
-	Verbose        bool
-	CheckRobotsTxt bool
+	Concurrency         int
+	Timeout             time.Duration
+	MaxDepth            int
+	Verbose             bool
+	Verbosity           int // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Info
+	CheckRobotsTxt      bool
+	Transport           http.RoundTripper  // Optional custom transport, e.g. a shared response cache
+	TreatSchemesAsSame  bool               // Collapse http:// and https:// URLs of the same page
+	KeepHTTP            bool               // Crawl http:// URLs as is even on a site enforcing HTTPS
+	RobotsCache         *robots.Cache      // Optional robots.txt cache shared with other crawlers
+	RobotsRetry         robots.RetryConfig // Retries for the robots.txt fetch; zero value uses robots.DefaultRetryConfig()
+	Delay               time.Duration      // Minimum pause between two requests
+	MaxIdleConnsPerHost int                // Idle connections kept per host, 0 matches Concurrency
+	MaxRedirects        int                // Redirects followed per request, 0 = none (the 3xx is kept as the page, its target not crawled), -1 = up to httppool.MaxRedirectsCap
+	HTMLContentTypes    []string           // Extra media types parsed as HTML, besides contenttype.HTML
+	IgnoreCrawlDelay    bool               // Keep Concurrency and Delay whatever robots.txt's Crawl-delay
 }
 
 // DefaultConfig returns default configuration
@@ -27,6 +46,7 @@ func DefaultConfig() Config {
 		MaxDepth:       0,
 		Verbose:        false,
 		CheckRobotsTxt: true,
+		MaxRedirects:   httppool.DefaultMaxRedirects,
 	}
 }
 
@@ -34,41 +54,42 @@ func DefaultConfig() Config {
 type Indexer struct {
 	config        Config
 	baseURL       *url.URL
-	visited       map[string]bool
-	visitedMu     sync.RWMutex
 	result        *IndexerResult
 	resultMu      sync.Mutex
 	client        *http.Client
-	semaphore     chan struct{}
-	robotsChecker *RobotsChecker
+	robotsChecker *robots.Checker
 	seenLinks     map[string]bool
 	seenLinksMu   sync.Mutex
+	stats         crawlstats.Counter
 }
 
 // New creates a new Indexer
 func New(config Config) *Indexer {
+	config.Verbosity = verbosity.Effective(config.Verbosity, config.Verbose)
+	transport := config.Transport
+	if transport == nil {
+		transport = httppool.New(config.Concurrency, config.MaxIdleConnsPerHost)
+	}
 	return &Indexer{
 		config:        config,
-		visited:       make(map[string]bool),
 		seenLinks:     make(map[string]bool),
-		semaphore:     make(chan struct{}, config.Concurrency),
-		robotsChecker: NewRobotsChecker(),
+		robotsChecker: robots.NewChecker(),
 		client: &http.Client{
-			Timeout: config.Timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
+			Timeout:       config.Timeout,
+			Transport:     transport,
+			CheckRedirect: httppool.CheckRedirect(config.MaxRedirects, httppool.ErrTooManyRedirects),
 		},
 	}
 }
 
-type urlTask struct {
-	url       string
-	sourceURL string
-	depth     int
+// loadRobots fills the robots checker, through the shared cache when there is one
+func (idx *Indexer) loadRobots(baseURL *url.URL) error {
+	if idx.config.RobotsCache == nil {
+		return idx.robotsChecker.Load(baseURL, idx.config.Timeout, idx.config.RobotsRetry)
+	}
+	checker, err := idx.config.RobotsCache.Get(baseURL, idx.config.Timeout, idx.config.RobotsRetry)
+	idx.robotsChecker = checker
+	return err
 }
 
 // Analyze starts the indexability analysis
@@ -83,147 +104,110 @@ func (idx *Indexer) Analyze(startURL string) (*IndexerResult, error) {
 	}
 
 	idx.baseURL = parsed
+	idx.stats.Start()
 	idx.result = NewIndexerResult(startURL)
 
 	// Load robots.txt if enabled
 	if idx.config.CheckRobotsTxt {
-		if idx.config.Verbose {
-			fmt.Printf("%sLoading robots.txt...%s\n", colorGray, colorReset)
+		if idx.config.Verbosity >= verbosity.Info {
+			fmt.Fprintf(os.Stderr, "%sLoading robots.txt...%s\n", colorGray, colorReset)
 		}
-		if err := idx.robotsChecker.Load(parsed, idx.config.Timeout); err != nil {
-			if idx.config.Verbose {
-				fmt.Printf("%sCould not load robots.txt: %v%s\n", colorYellow, err, colorReset)
+		if err := idx.loadRobots(parsed); err != nil {
+			if idx.config.Verbosity >= verbosity.Warn {
+				fmt.Fprintf(os.Stderr, "%sCould not load robots.txt: %v - treating every URL as blocked%s\n", colorYellow, err, colorReset)
 			}
+			idx.result.RobotsTxtUnavailable = true
 		} else {
 			idx.result.RobotsTxtRules = idx.robotsChecker.GetRules()
-			if idx.config.Verbose && len(idx.result.RobotsTxtRules) > 0 {
-				fmt.Printf("%sFound %d robots.txt rules%s\n", colorGray, len(idx.result.RobotsTxtRules), colorReset)
+			if idx.config.Verbosity >= verbosity.Info && len(idx.result.RobotsTxtRules) > 0 {
+				fmt.Fprintf(os.Stderr, "%sFound %d robots.txt rules%s\n", colorGray, len(idx.result.RobotsTxtRules), colorReset)
 			}
 		}
 	}
 
-	tasks := make(chan urlTask, 1000)
-
-	idx.markVisited(startURL)
-	tasks <- urlTask{url: startURL, sourceURL: "", depth: 0}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	for i := 0; i < idx.config.Concurrency; i++ {
-		go idx.worker(ctx, tasks)
+	// Honoring robots.txt includes its pace: a Crawl-delay lowers the
+	// concurrency rather than queueing many workers on the same pause
+	concurrency, delay := idx.config.Concurrency, idx.config.Delay
+	if idx.config.CheckRobotsTxt && !idx.config.IgnoreCrawlDelay {
+		idx.result.CrawlDelay = idx.robotsChecker.CrawlDelay()
+		concurrency, delay = robots.PoliteLimits(concurrency, delay, idx.result.CrawlDelay)
+	}
+	idx.result.Concurrency = concurrency
+	if concurrency < idx.config.Concurrency && idx.config.Verbosity >= verbosity.Info {
+		fmt.Fprintf(os.Stderr, "%srobots.txt Crawl-delay %v: concurrency reduced to %d%s\n", colorGray, idx.result.CrawlDelay, concurrency, colorReset)
 	}
 
-	done := make(chan struct{})
-	go func() {
-		for {
-			time.Sleep(100 * time.Millisecond)
-			idx.visitedMu.RLock()
-			visitedCount := len(idx.visited)
-			idx.visitedMu.RUnlock()
-
-			if len(tasks) == 0 && len(idx.semaphore) == 0 {
-				time.Sleep(500 * time.Millisecond)
-				if len(tasks) == 0 && len(idx.semaphore) == 0 {
-					close(done)
-					return
-				}
-			}
-
-			if visitedCount > 10000 {
-				close(done)
-				return
-			}
-		}
-	}()
-
-	<-done
-	cancel()
-	close(tasks)
-
-	idx.visitedMu.RLock()
-	idx.result.TotalPages = len(idx.visited)
-	idx.visitedMu.RUnlock()
+	engine := crawl.New(crawl.Config{
+		Concurrency:        concurrency,
+		MaxDepth:           idx.config.MaxDepth,
+		Delay:              delay,
+		TreatSchemesAsSame: idx.config.TreatSchemesAsSame,
+		Rewrite:            httppool.HTTPSRewrite(idx.config.KeepHTTP, idx.client.Transport, idx.config.Timeout, startURL),
+	}, idx.processURL)
+	idx.result.TotalPages = engine.Run(context.Background(), crawl.Task{URL: startURL})
 
 	idx.seenLinksMu.Lock()
 	idx.result.TotalLinks = len(idx.seenLinks)
 	idx.seenLinksMu.Unlock()
 
 	idx.result.IndexableLinks = idx.result.TotalLinks - len(idx.result.NonIndexableLinks)
+	idx.result.sortRobotsConflicts()
 
-	return idx.result, nil
-}
+	idx.result.CrawlStats = idx.stats.Snapshot()
 
-func (idx *Indexer) worker(ctx context.Context, tasks chan urlTask) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case task, ok := <-tasks:
-			if !ok {
-				return
-			}
-			idx.processURL(ctx, task, tasks)
-		}
-	}
+	return idx.result, nil
 }
 
-func (idx *Indexer) processURL(ctx context.Context, task urlTask, tasks chan urlTask) {
-	select {
-	case idx.semaphore <- struct{}{}:
-		defer func() { <-idx.semaphore }()
-	case <-ctx.Done():
-		return
-	}
-
-	if idx.config.MaxDepth > 0 && task.depth > idx.config.MaxDepth {
-		return
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", task.url, nil)
+func (idx *Indexer) processURL(ctx context.Context, task crawl.Task) []crawl.Task {
+	req, err := http.NewRequestWithContext(ctx, "GET", task.URL, nil)
 	if err != nil {
-		return
+		return nil
 	}
 
 	req.Header.Set("User-Agent", "LinkIndexer/1.0")
 
+	start := time.Now()
 	resp, err := idx.client.Do(req)
+	idx.stats.AddRequest()
 	if err != nil {
 		if ctx.Err() != nil {
-			return
+			return nil
 		}
-		if idx.config.Verbose {
-			printError(task.url, err.Error(), task.depth)
+		if idx.config.Verbosity >= verbosity.Warn {
+			printError(task.URL, err.Error(), task.Depth)
 		}
-		return
+		return nil
 	}
 	defer resp.Body.Close()
 
-	if idx.config.Verbose {
-		printProgress(task.url, resp.StatusCode, task.depth)
+	if verbosity.ShowStatus(idx.config.Verbosity, resp.StatusCode) {
+		printProgress(task.URL, resp.StatusCode, task.Depth)
+	}
+	if idx.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(task.URL, time.Since(start))
 	}
 
 	// Check X-Robots-Tag header
-	xRobotsTag := strings.ToLower(resp.Header.Get("X-Robots-Tag"))
-	hasNoIndexHeader := strings.Contains(xRobotsTag, "noindex")
+	hasNoIndexHeader := robots.IsNoIndex(resp.Header.Values("X-Robots-Tag")...)
 
 	if hasNoIndexHeader {
 		idx.resultMu.Lock()
-		idx.result.PagesWithNoIndex = append(idx.result.PagesWithNoIndex, task.url)
+		idx.result.PagesWithNoIndex = append(idx.result.PagesWithNoIndex, task.URL)
 		idx.resultMu.Unlock()
 	}
 
 	if resp.StatusCode >= 400 {
-		return
+		return nil
 	}
 
 	contentType := resp.Header.Get("Content-Type")
-	if !isHTML(contentType) {
-		return
+	if !contenttype.IsHTML(contentType, idx.config.HTMLContentTypes) {
+		return nil
 	}
 
 	// Parse page
-	pageInfo := ParsePage(resp.Body, idx.baseURL, task.url)
+	pageInfo := ParsePage(idx.stats.Body(resp.Body), idx.baseURL, task.URL)
+	idx.addRobotsConflict(task.URL, pageInfo.MetaRobots, strings.Join(resp.Header.Values("X-Robots-Tag"), ", "))
 
 	// Track noindex pages
 	if pageInfo.HasNoIndex {
@@ -231,22 +215,23 @@ func (idx *Indexer) processURL(ctx context.Context, task urlTask, tasks chan url
 		// Avoid duplicates
 		found := false
 		for _, p := range idx.result.PagesWithNoIndex {
-			if p == task.url {
+			if p == task.URL {
 				found = true
 				break
 			}
 		}
 		if !found {
-			idx.result.PagesWithNoIndex = append(idx.result.PagesWithNoIndex, task.url)
+			idx.result.PagesWithNoIndex = append(idx.result.PagesWithNoIndex, task.URL)
 		}
 		idx.resultMu.Unlock()
 	}
 
 	// Process each link
+	var next []crawl.Task
 	for _, link := range pageInfo.Links {
 		// Track unique links
 		idx.seenLinksMu.Lock()
-		linkKey := task.url + " -> " + link.URL
+		linkKey := task.URL + " -> " + link.URL
 		if idx.seenLinks[linkKey] {
 			idx.seenLinksMu.Unlock()
 			continue
@@ -254,6 +239,16 @@ func (idx *Indexer) processURL(ctx context.Context, task urlTask, tasks chan url
 		idx.seenLinks[linkKey] = true
 		idx.seenLinksMu.Unlock()
 
+		internal := IsSameDomain(link.URL, idx.baseURL)
+
+		idx.resultMu.Lock()
+		if internal {
+			idx.result.InternalLinks++
+		} else {
+			idx.result.ExternalLinks++
+		}
+		idx.resultMu.Unlock()
+
 		// Check indexability issues
 		var reasons []NoIndexReason
 		var details string
@@ -269,7 +264,7 @@ func (idx *Indexer) processURL(ctx context.Context, task urlTask, tasks chan url
 		}
 
 		// Check if target page has noindex (only for internal links we've seen)
-		if IsSameDomain(link.URL, idx.baseURL) {
+		if internal {
 			if idx.config.CheckRobotsTxt && idx.robotsChecker.IsBlocked(link.URL) {
 				reasons = append(reasons, ReasonRobotsTxt)
 			}
@@ -279,45 +274,20 @@ func (idx *Indexer) processURL(ctx context.Context, task urlTask, tasks chan url
 			idx.resultMu.Lock()
 			idx.result.AddNonIndexable(NonIndexableLink{
 				URL:       link.URL,
-				SourceURL: task.url,
+				SourceURL: task.URL,
 				Reasons:   reasons,
 				Details:   details,
+				Internal:  internal,
 			})
 			idx.resultMu.Unlock()
 		}
 
-		// Queue internal links for crawling
-		if IsSameDomain(link.URL, idx.baseURL) && idx.shouldVisit(link.URL) {
-			idx.markVisited(link.URL)
-			select {
-			case tasks <- urlTask{url: link.URL, sourceURL: task.url, depth: task.depth + 1}:
-			default:
-			}
+		// Follow internal links
+		if internal {
+			next = append(next, crawl.Task{URL: link.URL})
 		}
 	}
-}
-
-func (idx *Indexer) markVisited(url string) {
-	idx.visitedMu.Lock()
-	idx.visited[url] = true
-	idx.visitedMu.Unlock()
-}
-
-func (idx *Indexer) shouldVisit(targetURL string) bool {
-	if !IsSameDomain(targetURL, idx.baseURL) {
-		return false
-	}
-
-	idx.visitedMu.RLock()
-	visited := idx.visited[targetURL]
-	idx.visitedMu.RUnlock()
-
-	return !visited
-}
-
-func isHTML(contentType string) bool {
-	return strings.Contains(contentType, "text/html") ||
-		strings.Contains(contentType, "application/xhtml+xml")
+	return next
 }
 
 func printProgress(url string, statusCode int, depth int) {
@@ -334,10 +304,10 @@ func printProgress(url string, statusCode int, depth int) {
 	}
 
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[%d]%s %s\n", indent, statusColor, statusCode, colorReset, url)
+	fmt.Fprintf(os.Stderr, "%s%s[%d]%s %s\n", indent, statusColor, statusCode, colorReset, url)
 }
 
 func printError(url string, err string, depth int) {
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, err)
+	fmt.Fprintf(os.Stderr, "%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, err)
 }
@@ -6,6 +6,9 @@ import (
 	"strings"
 
 	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
+	"github.com/ngonzalez/web-tools/internal/robots"
 )
 
 // LinkInfo contains information about a link and its indexability
@@ -18,12 +21,13 @@ type LinkInfo struct {
 
 // PageInfo contains indexability information about a page
 type PageInfo struct {
-	URL              string
-	Links            []LinkInfo
-	HasNoIndex       bool
-	HasNoFollow      bool
-	CanonicalURL     string
+	URL               string
+	Links             []LinkInfo
+	HasNoIndex        bool
+	HasNoFollow       bool
+	CanonicalURL      string
 	CanonicalMismatch bool
+	MetaRobots        string // Raw content of the robots meta tag
 }
 
 // ParsePage extracts links and indexability info from HTML
@@ -32,32 +36,37 @@ func ParsePage(body io.Reader, baseURL *url.URL, pageURL string) *PageInfo {
 		URL: pageURL,
 	}
 
-	doc, err := html.Parse(body)
-	if err != nil {
-		return info
-	}
+	tokenizer := html.NewTokenizer(body)
+
+	for {
+		tokenType := tokenizer.Next()
 
-	var parseNode func(*html.Node)
-	parseNode = func(n *html.Node) {
-		if n.Type == html.ElementNode {
-			switch n.Data {
+		switch tokenType {
+		case html.ErrorToken:
+			return info
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+
+			switch token.Data {
 			case "meta":
-				name := getAttr(n, "name")
-				content := strings.ToLower(getAttr(n, "content"))
+				name := htmlattr.Get(token, "name")
 
 				if strings.ToLower(name) == "robots" {
-					if strings.Contains(content, "noindex") {
+					info.MetaRobots = strings.TrimSpace(htmlattr.Get(token, "content"))
+					directives := robots.Directives(info.MetaRobots)
+					if directives["noindex"] {
 						info.HasNoIndex = true
 					}
-					if strings.Contains(content, "nofollow") {
+					if directives["nofollow"] {
 						info.HasNoFollow = true
 					}
 				}
 
 			case "link":
-				rel := strings.ToLower(getAttr(n, "rel"))
+				rel := strings.ToLower(htmlattr.Get(token, "rel"))
 				if rel == "canonical" {
-					href := getAttr(n, "href")
+					href := htmlattr.Get(token, "href")
 					if href != "" {
 						canonical := resolveURL(href, baseURL)
 						info.CanonicalURL = canonical
@@ -69,7 +78,7 @@ func ParsePage(body io.Reader, baseURL *url.URL, pageURL string) *PageInfo {
 				}
 
 			case "a":
-				href := getAttr(n, "href")
+				href := htmlattr.Get(token, "href")
 				if href == "" {
 					break
 				}
@@ -79,7 +88,7 @@ func ParsePage(body io.Reader, baseURL *url.URL, pageURL string) *PageInfo {
 					break
 				}
 
-				rel := strings.ToLower(getAttr(n, "rel"))
+				rel := strings.ToLower(htmlattr.Get(token, "rel"))
 				relParts := strings.Fields(rel)
 
 				linkInfo := LinkInfo{
@@ -100,23 +109,7 @@ func ParsePage(body io.Reader, baseURL *url.URL, pageURL string) *PageInfo {
 				info.Links = append(info.Links, linkInfo)
 			}
 		}
-
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			parseNode(c)
-		}
-	}
-
-	parseNode(doc)
-	return info
-}
-
-func getAttr(n *html.Node, key string) string {
-	for _, attr := range n.Attr {
-		if attr.Key == key {
-			return attr.Val
-		}
 	}
-	return ""
 }
 
 func resolveURL(href string, baseURL *url.URL) string {
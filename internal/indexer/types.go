@@ -2,8 +2,12 @@ package indexer
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
 )
 
 // NoIndexReason indicates why a link is not indexable
@@ -67,18 +71,26 @@ type NonIndexableLink struct {
 	SourceURL string
 	Reasons   []NoIndexReason
 	Details   string // Additional info like canonical URL
+	Internal  bool   // Target is on the crawled site
 }
 
 // IndexerResult holds the analysis results
 type IndexerResult struct {
-	StartURL           string
-	TotalPages         int
-	TotalLinks         int
-	IndexableLinks     int
-	NonIndexableLinks  []NonIndexableLink
-	ByReason           map[NoIndexReason][]NonIndexableLink
-	RobotsTxtRules     []string
-	PagesWithNoIndex   []string
+	StartURL             string
+	TotalPages           int
+	TotalLinks           int
+	InternalLinks        int
+	ExternalLinks        int
+	IndexableLinks       int
+	NonIndexableLinks    []NonIndexableLink
+	ByReason             map[NoIndexReason][]NonIndexableLink
+	RobotsTxtRules       []string
+	RobotsTxtUnavailable bool // robots.txt could not be fetched, so every URL counts as blocked
+	PagesWithNoIndex     []string
+	RobotsConflicts      []RobotsConflict // Pages whose meta robots and X-Robots-Tag disagree
+	CrawlDelay           time.Duration    // robots.txt Crawl-delay honored, 0 without one
+	Concurrency          int              // Concurrency used, lowered by CrawlDelay
+	CrawlStats           crawlstats.Stats
 }
 
 // NewIndexerResult creates a new result
@@ -97,6 +109,63 @@ func (r *IndexerResult) AddNonIndexable(link NonIndexableLink) {
 	}
 }
 
+// InternalNoFollow returns the nofollow links pointing to the crawled site itself,
+// which are usually a mistake (nofollow on external links is expected)
+func (r *IndexerResult) InternalNoFollow() []NonIndexableLink {
+	var links []NonIndexableLink
+	for _, link := range r.ByReason[ReasonNoFollow] {
+		if link.Internal {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// RobotsBlockedPage is an internal page blocked by robots.txt, with the pages
+// linking to it
+type RobotsBlockedPage struct {
+	URL     string
+	Sources []string
+}
+
+// InternalRobotsBlocked returns the internal pages that are linked from the
+// site but blocked by robots.txt: crawlers find the links yet can't fetch the
+// page, so either the links or the rule is wrong. Most linked pages come first.
+func (r *IndexerResult) InternalRobotsBlocked() []RobotsBlockedPage {
+	sources := make(map[string][]string)
+	for _, link := range r.ByReason[ReasonRobotsTxt] {
+		if link.Internal && !slices.Contains(sources[link.URL], link.SourceURL) {
+			sources[link.URL] = append(sources[link.URL], link.SourceURL)
+		}
+	}
+
+	pages := make([]RobotsBlockedPage, 0, len(sources))
+	for url, srcs := range sources {
+		sort.Strings(srcs)
+		pages = append(pages, RobotsBlockedPage{URL: url, Sources: srcs})
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if len(pages[i].Sources) != len(pages[j].Sources) {
+			return len(pages[i].Sources) > len(pages[j].Sources)
+		}
+		return pages[i].URL < pages[j].URL
+	})
+	return pages
+}
+
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *IndexerResult) SummaryLine() string {
+	return fmt.Sprintf("SUMMARY tool=linkindexer pages=%d links=%d internal=%d external=%d indexable=%d non_indexable=%d noindex_pages=%d internal_nofollow=%d internal_robots_blocked=%d robots_conflicts=%d concurrency=%d",
+		r.TotalPages, r.TotalLinks, r.InternalLinks, r.ExternalLinks,
+		r.TotalLinks-len(r.NonIndexableLinks),
+		len(r.NonIndexableLinks),
+		len(r.PagesWithNoIndex),
+		len(r.InternalNoFollow()),
+		len(r.InternalRobotsBlocked()),
+		len(r.RobotsConflicts),
+		r.Concurrency)
+}
+
 // ANSI color codes
 const (
 	colorReset  = "\033[0m"
@@ -117,6 +186,12 @@ func (r *IndexerResult) PrintSummary(showDetails bool) {
 	fmt.Printf("Start URL: %s%s%s\n", colorBlue, r.StartURL, colorReset)
 	fmt.Printf("Pages analyzed: %s%d%s\n", colorGreen, r.TotalPages, colorReset)
 	fmt.Printf("Total links found: %s%d%s\n", colorGreen, r.TotalLinks, colorReset)
+	if r.RobotsTxtUnavailable {
+		fmt.Printf("%srobots.txt could not be fetched: every link counts as blocked%s\n", colorYellow, colorReset)
+	}
+	if r.CrawlDelay > 0 {
+		fmt.Printf("robots.txt Crawl-delay: %s%v%s, concurrency used: %s%d%s\n", colorYellow, r.CrawlDelay, colorReset, colorYellow, r.Concurrency, colorReset)
+	}
 	fmt.Println()
 
 	indexable := r.TotalLinks - len(r.NonIndexableLinks)
@@ -131,6 +206,8 @@ func (r *IndexerResult) PrintSummary(showDetails bool) {
 		fmt.Printf("  %sNon-indexable rate:  %.1f%%%s\n", colorYellow, pct, colorReset)
 	}
 
+	r.printByTarget()
+
 	// Pages with noindex
 	if len(r.PagesWithNoIndex) > 0 {
 		fmt.Println()
@@ -144,6 +221,8 @@ func (r *IndexerResult) PrintSummary(showDetails bool) {
 		}
 	}
 
+	r.printRobotsConflicts()
+
 	// Breakdown by reason
 	if len(r.ByReason) > 0 {
 		fmt.Println()
@@ -174,6 +253,83 @@ func (r *IndexerResult) PrintSummary(showDetails bool) {
 	}
 
 	fmt.Println()
+
+	r.CrawlStats.Print()
+}
+
+// printByTarget splits indexability stats between internal and external links
+func (r *IndexerResult) printByTarget() {
+	internalBlocked, externalBlocked := 0, 0
+	for _, link := range r.NonIndexableLinks {
+		if link.Internal {
+			internalBlocked++
+		} else {
+			externalBlocked++
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sBy Link Target:%s\n", colorBold, colorYellow, colorReset)
+	fmt.Printf("  Internal links: %s%d%s, non-indexable %s%d%s (%.1f%%)\n",
+		colorGreen, r.InternalLinks, colorReset,
+		colorRed, internalBlocked, colorReset,
+		percent(internalBlocked, r.InternalLinks))
+	fmt.Printf("  External links: %s%d%s, non-indexable %s%d%s (%.1f%%)\n",
+		colorGreen, r.ExternalLinks, colorReset,
+		colorYellow, externalBlocked, colorReset,
+		percent(externalBlocked, r.ExternalLinks))
+
+	r.printInternalRobotsBlocked()
+
+	internalNoFollow := r.InternalNoFollow()
+	if len(internalNoFollow) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s⚠ Nofollow on internal links (%d) - likely mistakes:%s\n", colorBold, colorRed, len(internalNoFollow), colorReset)
+	fmt.Printf("  %sNofollow on your own pages wastes internal PageRank; nofollow on external links is expected.%s\n", colorGray, colorReset)
+	for i, link := range internalNoFollow {
+		if i >= 10 {
+			fmt.Printf("  %s... and %d more%s\n", colorGray, len(internalNoFollow)-10, colorReset)
+			break
+		}
+		fmt.Printf("  %s → %s\n", link.SourceURL, link.URL)
+	}
+}
+
+// printInternalRobotsBlocked lists robots-blocked pages with the internal
+// links to fix
+func (r *IndexerResult) printInternalRobotsBlocked() {
+	pages := r.InternalRobotsBlocked()
+	if len(pages) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s⚠ Internally linked but blocked by robots.txt (%d page(s)):%s\n", colorBold, colorRed, len(pages), colorReset)
+	fmt.Printf("  %sCrawlers find these links but may not fetch the page: remove the links or allow the URLs.%s\n", colorGray, colorReset)
+	for i, page := range pages {
+		if i >= 10 {
+			fmt.Printf("  %s... and %d more%s\n", colorGray, len(pages)-10, colorReset)
+			break
+		}
+		fmt.Printf("  %s %s(%d linking page(s))%s\n", page.URL, colorGray, len(page.Sources), colorReset)
+		for j, src := range page.Sources {
+			if j >= 3 {
+				fmt.Printf("    %s... and %d more%s\n", colorGray, len(page.Sources)-3, colorReset)
+				break
+			}
+			fmt.Printf("    %s← %s%s\n", colorGray, src, colorReset)
+		}
+	}
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
 }
 
 func (r *IndexerResult) printDetails() {
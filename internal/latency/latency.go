@@ -6,66 +6,73 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 // Config holds the configuration
 type Config struct {
-	Concurrency int
-	Timeout     time.Duration
-	MaxDepth    int
-	Verbose     bool
+	Concurrency         int
+	Timeout             time.Duration
+	MaxDepth            int
+	Verbose             bool
+	Verbosity           int           // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Info
+	TreatSchemesAsSame  bool          // Collapse http:// and https:// URLs of the same page
+	KeepHTTP            bool          // Crawl http:// URLs as is even on a site enforcing HTTPS
+	Delay               time.Duration // Minimum pause between two requests
+	MaxIdleConnsPerHost int           // Idle connections kept per host, 0 matches Concurrency
+	MaxRedirects        int           // Redirects followed per request, 0 = none (the 3xx is kept as the page, its target not crawled), -1 = up to httppool.MaxRedirectsCap
+	HTMLContentTypes    []string      // Extra media types parsed as HTML, besides contenttype.HTML
+	ASCII               bool          // Draw bar graphs with # and - instead of block characters
+	Budget              time.Duration // Load time above which a page is over budget, 0 for none
+	MinSize             int64         // Body size in bytes under which a 200 HTML page is suspiciously empty, 0 disables the check
 }
 
 // DefaultConfig returns default configuration
 func DefaultConfig() Config {
 	return Config{
-		Concurrency: 10,
-		Timeout:     30 * time.Second,
-		MaxDepth:    0,
-		Verbose:     false,
+		Concurrency:  10,
+		Timeout:      30 * time.Second,
+		MaxDepth:     0,
+		Verbose:      false,
+		MaxRedirects: httppool.DefaultMaxRedirects,
+		MinSize:      DefaultMinSize,
 	}
 }
 
 // Measurer measures page latencies
 type Measurer struct {
-	config    Config
-	baseURL   *url.URL
-	visited   map[string]bool
-	visitedMu sync.RWMutex
-	result    *LatencyResult
-	resultMu  sync.Mutex
-	client    *http.Client
-	semaphore chan struct{}
+	config   Config
+	baseURL  *url.URL
+	result   *LatencyResult
+	resultMu sync.Mutex
+	client   *http.Client
+	stats    crawlstats.Counter
 }
 
 // New creates a new Measurer
 func New(config Config) *Measurer {
+	config.Verbosity = verbosity.Effective(config.Verbosity, config.Verbose)
 	return &Measurer{
-		config:    config,
-		visited:   make(map[string]bool),
-		semaphore: make(chan struct{}, config.Concurrency),
+		config: config,
 		client: &http.Client{
-			Timeout: config.Timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
+			Timeout:       config.Timeout,
+			Transport:     httppool.New(config.Concurrency, config.MaxIdleConnsPerHost),
+			CheckRedirect: httppool.CheckRedirect(config.MaxRedirects, httppool.ErrTooManyRedirects),
 		},
 	}
 }
 
-type urlTask struct {
-	url   string
-	depth int
-}
-
 // Measure starts measuring latencies
 func (m *Measurer) Measure(startURL string) (*LatencyResult, error) {
 	parsed, err := url.Parse(startURL)
@@ -78,81 +85,32 @@ func (m *Measurer) Measure(startURL string) (*LatencyResult, error) {
 	}
 
 	m.baseURL = parsed
+	m.stats.Start()
 	m.result = NewLatencyResult(startURL)
-
-	tasks := make(chan urlTask, 1000)
-
-	m.markVisited(startURL)
-	tasks <- urlTask{url: startURL, depth: 0}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	for i := 0; i < m.config.Concurrency; i++ {
-		go m.worker(ctx, tasks)
-	}
-
-	done := make(chan struct{})
-	go func() {
-		for {
-			time.Sleep(100 * time.Millisecond)
-			m.visitedMu.RLock()
-			visitedCount := len(m.visited)
-			m.visitedMu.RUnlock()
-
-			if len(tasks) == 0 && len(m.semaphore) == 0 {
-				time.Sleep(500 * time.Millisecond)
-				if len(tasks) == 0 && len(m.semaphore) == 0 {
-					close(done)
-					return
-				}
-			}
-
-			if visitedCount > 10000 {
-				close(done)
-				return
-			}
-		}
-	}()
-
-	<-done
-	cancel()
-	close(tasks)
+	m.result.ASCII = m.config.ASCII
+	m.result.Budget = m.config.Budget
+	m.result.MinSize = m.config.MinSize
+
+	engine := crawl.New(crawl.Config{
+		Concurrency:        m.config.Concurrency,
+		MaxDepth:           m.config.MaxDepth,
+		Delay:              m.config.Delay,
+		TreatSchemesAsSame: m.config.TreatSchemesAsSame,
+		Rewrite:            httppool.HTTPSRewrite(m.config.KeepHTTP, m.client.Transport, m.config.Timeout, startURL),
+	}, m.processURL)
+	engine.Run(context.Background(), crawl.Task{URL: startURL})
 
 	m.result.Finalize()
-	return m.result, nil
-}
+	m.result.CrawlStats = m.stats.Snapshot()
 
-func (m *Measurer) worker(ctx context.Context, tasks chan urlTask) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case task, ok := <-tasks:
-			if !ok {
-				return
-			}
-			m.processURL(ctx, task, tasks)
-		}
-	}
+	return m.result, nil
 }
 
-func (m *Measurer) processURL(ctx context.Context, task urlTask, tasks chan urlTask) {
-	select {
-	case m.semaphore <- struct{}{}:
-		defer func() { <-m.semaphore }()
-	case <-ctx.Done():
-		return
-	}
-
-	if m.config.MaxDepth > 0 && task.depth > m.config.MaxDepth {
-		return
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", task.url, nil)
+func (m *Measurer) processURL(ctx context.Context, task crawl.Task) []crawl.Task {
+	req, err := http.NewRequestWithContext(ctx, "GET", task.URL, nil)
 	if err != nil {
-		m.addResult(PageLatency{URL: task.url, Error: err.Error()})
-		return
+		m.addResult(PageLatency{URL: task.URL, Error: err.Error()})
+		return nil
 	}
 
 	req.Header.Set("User-Agent", "LinkLatency/1.0")
@@ -160,62 +118,59 @@ func (m *Measurer) processURL(ctx context.Context, task urlTask, tasks chan urlT
 	// Measure timing
 	start := time.Now()
 	resp, err := m.client.Do(req)
+	m.stats.AddRequest()
 	duration := time.Since(start)
 
 	if err != nil {
 		if ctx.Err() != nil {
-			return
+			return nil
 		}
 		m.addResult(PageLatency{
-			URL:      task.url,
+			URL:      task.URL,
 			Duration: duration,
 			Error:    err.Error(),
 		})
-		if m.config.Verbose {
-			printError(task.url, err.Error(), task.depth)
+		if m.config.Verbosity >= verbosity.Warn {
+			printError(task.URL, err.Error(), task.Depth)
 		}
-		return
+		return nil
 	}
 
 	// Read body to get size and complete timing
-	body, _ := io.ReadAll(resp.Body)
+	body, _ := io.ReadAll(m.stats.Body(resp.Body))
 	resp.Body.Close()
 	duration = time.Since(start)
 
 	pageLatency := PageLatency{
-		URL:        task.url,
+		URL:        task.URL,
 		Duration:   duration,
 		StatusCode: resp.StatusCode,
 		Size:       int64(len(body)),
+		HTML:       contenttype.IsHTML(resp.Header.Get("Content-Type"), m.config.HTMLContentTypes),
 	}
 
-	m.addResult(pageLatency)
-
-	if m.config.Verbose {
-		printProgress(task.url, resp.StatusCode, duration, task.depth)
+	// Extract links and count render-blocking resources from HTML pages
+	var links []string
+	if resp.StatusCode < 400 && pageLatency.HTML {
+		links, pageLatency.RenderBlocking = parsePage(strings.NewReader(string(body)), m.baseURL)
 	}
 
-	if resp.StatusCode >= 400 {
-		return
-	}
+	m.addResult(pageLatency)
 
-	contentType := resp.Header.Get("Content-Type")
-	if !isHTML(contentType) {
-		return
+	if verbosity.ShowStatus(m.config.Verbosity, resp.StatusCode) {
+		printProgress(task.URL, resp.StatusCode, duration, task.Depth)
+	}
+	if m.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(task.URL, duration)
 	}
 
-	// Extract links
-	links := extractLinks(strings.NewReader(string(body)), m.baseURL)
-
+	var next []crawl.Task
 	for _, link := range links {
-		if m.shouldVisit(link) {
-			m.markVisited(link)
-			select {
-			case tasks <- urlTask{url: link, depth: task.depth + 1}:
-			default:
-			}
+		if m.isInternal(link) {
+			next = append(next, crawl.Task{URL: link})
 		}
 	}
+	return next
 }
 
 func (m *Measurer) addResult(page PageLatency) {
@@ -224,43 +179,50 @@ func (m *Measurer) addResult(page PageLatency) {
 	m.resultMu.Unlock()
 }
 
-func (m *Measurer) markVisited(url string) {
-	m.visitedMu.Lock()
-	m.visited[url] = true
-	m.visitedMu.Unlock()
-}
-
-func (m *Measurer) shouldVisit(targetURL string) bool {
+func (m *Measurer) isInternal(targetURL string) bool {
 	parsed, err := url.Parse(targetURL)
 	if err != nil {
 		return false
 	}
-	if parsed.Host != m.baseURL.Host {
-		return false
-	}
-
-	m.visitedMu.RLock()
-	visited := m.visited[targetURL]
-	m.visitedMu.RUnlock()
-
-	return !visited
+	return parsed.Host == m.baseURL.Host
 }
 
-func extractLinks(body io.Reader, baseURL *url.URL) []string {
-	var links []string
+// parsePage extracts links and counts the render-blocking resources in <head>:
+// scripts without async/defer and stylesheets not limited to print
+func parsePage(body io.Reader, baseURL *url.URL) (links []string, renderBlocking int) {
 	tokenizer := html.NewTokenizer(body)
+	inHead := false
 
 	for {
 		tokenType := tokenizer.Next()
 
 		switch tokenType {
 		case html.ErrorToken:
-			return links
+			return links, renderBlocking
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if token.Data == "head" {
+				inHead = false
+			}
 
 		case html.StartTagToken, html.SelfClosingTagToken:
 			token := tokenizer.Token()
 
-			if token.Data == "a" {
+			switch token.Data {
+			case "head":
+				inHead = true
+			case "body":
+				inHead = false
+			case "script":
+				if inHead && isBlockingScript(token) {
+					renderBlocking++
+				}
+			case "link":
+				if inHead && isBlockingStylesheet(token) {
+					renderBlocking++
+				}
+			case "a":
 				for _, attr := range token.Attr {
 					if attr.Key == "href" {
 						link := normalizeURL(attr.Val, baseURL)
@@ -275,6 +237,43 @@ func extractLinks(body io.Reader, baseURL *url.URL) []string {
 	}
 }
 
+// isBlockingScript reports whether a <script> blocks rendering
+func isBlockingScript(token html.Token) bool {
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "async", "defer":
+			return false
+		case "type":
+			t := strings.ToLower(strings.TrimSpace(attr.Val))
+			if t != "" && t != "text/javascript" && t != "application/javascript" {
+				// Modules are deferred, JSON-LD and templates never execute
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isBlockingStylesheet reports whether a <link> is a stylesheet that blocks rendering
+func isBlockingStylesheet(token html.Token) bool {
+	isStylesheet := false
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "rel":
+			for _, rel := range strings.Fields(strings.ToLower(attr.Val)) {
+				if rel == "stylesheet" {
+					isStylesheet = true
+				}
+			}
+		case "media":
+			if strings.ToLower(strings.TrimSpace(attr.Val)) == "print" {
+				return false
+			}
+		}
+	}
+	return isStylesheet
+}
+
 func normalizeURL(href string, baseURL *url.URL) string {
 	href = strings.TrimSpace(href)
 	if href == "" {
@@ -304,11 +303,6 @@ func normalizeURL(href string, baseURL *url.URL) string {
 	return resolved.String()
 }
 
-func isHTML(contentType string) bool {
-	return strings.Contains(contentType, "text/html") ||
-		strings.Contains(contentType, "application/xhtml+xml")
-}
-
 func printProgress(url string, statusCode int, duration time.Duration, depth int) {
 	var statusColor string
 	switch {
@@ -323,10 +317,10 @@ func printProgress(url string, statusCode int, duration time.Duration, depth int
 	}
 
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[%d]%s %v %s\n", indent, statusColor, statusCode, colorReset, duration.Round(time.Millisecond), url)
+	fmt.Fprintf(os.Stderr, "%s%s[%d]%s %v %s\n", indent, statusColor, statusCode, colorReset, duration.Round(time.Millisecond), url)
 }
 
 func printError(url string, err string, depth int) {
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, err)
+	fmt.Fprintf(os.Stderr, "%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, err)
 }
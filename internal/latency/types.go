@@ -5,15 +5,20 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/ngonzalez/web-tools/internal/barchart"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
 )
 
 // PageLatency holds timing info for a page
 type PageLatency struct {
-	URL        string
-	Duration   time.Duration
-	StatusCode int
-	Size       int64
-	Error      string
+	URL            string
+	Duration       time.Duration
+	StatusCode     int
+	Size           int64
+	RenderBlocking int  // Blocking scripts and stylesheets in <head>
+	HTML           bool // Content-Type is HTML, per Config.HTMLContentTypes
+	Error          string
 }
 
 // LatencyResult holds all results
@@ -23,6 +28,10 @@ type LatencyResult struct {
 	TotalTime  time.Duration
 	StartTime  time.Time
 	EndTime    time.Time
+	CrawlStats crawlstats.Stats
+	ASCII      bool          // Draw bars with # and -
+	Budget     time.Duration // Load time budget, 0 when none is set
+	MinSize    int64         // Body size under which a 200 HTML page is suspiciously empty, 0 when disabled
 }
 
 // NewLatencyResult creates a new result
@@ -88,6 +97,77 @@ func (r *LatencyResult) Stats() (min, max, avg time.Duration) {
 	return min, max, avg
 }
 
+// renderBlockingThreshold is the number of blocking scripts and stylesheets
+// in <head> above which a page is reported
+const renderBlockingThreshold = 5
+
+// HeavyRenderBlockingPages returns the pages above renderBlockingThreshold
+func (r *LatencyResult) HeavyRenderBlockingPages() []PageLatency {
+	var pages []PageLatency
+	for _, p := range r.Pages {
+		if p.RenderBlocking > renderBlockingThreshold {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+// IsOverBudget reports whether a page loaded slower than the budget. Pages
+// that failed have no load time and are errors, never over budget.
+func (r *LatencyResult) IsOverBudget(p PageLatency) bool {
+	return r.Budget > 0 && p.Error == "" && p.Duration > r.Budget
+}
+
+// OverBudgetPages returns the pages slower than the budget
+func (r *LatencyResult) OverBudgetPages() []PageLatency {
+	var pages []PageLatency
+	for _, p := range r.Pages {
+		if r.IsOverBudget(p) {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+// DefaultMinSize is the body size, in bytes, under which a page answering
+// 200 is suspiciously empty: an error page served with the wrong status, a
+// placeholder or a template that failed to render
+const DefaultMinSize = 512
+
+// IsSuspiciouslyEmpty reports whether an HTML page answered 200 with a body
+// under MinSize, which its status alone doesn't reveal. Other content types,
+// such as a small JSON or text file, are legitimately short.
+func (r *LatencyResult) IsSuspiciouslyEmpty(p PageLatency) bool {
+	return r.MinSize > 0 && p.Error == "" && p.HTML && p.StatusCode == 200 && p.Size < r.MinSize
+}
+
+// EmptyPages returns the suspiciously empty pages
+func (r *LatencyResult) EmptyPages() []PageLatency {
+	var pages []PageLatency
+	for _, p := range r.Pages {
+		if r.IsSuspiciouslyEmpty(p) {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *LatencyResult) SummaryLine() string {
+	errors := 0
+	for _, p := range r.Pages {
+		if p.Error != "" {
+			errors++
+		}
+	}
+	min, max, avg := r.Stats()
+	return fmt.Sprintf("SUMMARY tool=linklatency pages=%d errors=%d min_ms=%d max_ms=%d avg_ms=%d duration_ms=%d render_blocking_pages=%d budget_ms=%d over_budget=%d empty_pages=%d",
+		len(r.Pages), errors,
+		min.Milliseconds(), max.Milliseconds(), avg.Milliseconds(),
+		r.TotalTime.Milliseconds(), len(r.HeavyRenderBlockingPages()),
+		r.Budget.Milliseconds(), len(r.OverBudgetPages()), len(r.EmptyPages()))
+}
+
 // ANSI color codes
 const (
 	colorReset  = "\033[0m"
@@ -115,6 +195,14 @@ func (r *LatencyResult) PrintSummary(barWidth int, showSize bool) {
 	fmt.Printf("  Fastest: %s%v%s\n", colorGreen, min.Round(time.Millisecond), colorReset)
 	fmt.Printf("  Slowest: %s%v%s\n", colorRed, max.Round(time.Millisecond), colorReset)
 	fmt.Printf("  Average: %s%v%s\n", colorYellow, avg.Round(time.Millisecond), colorReset)
+	if r.Budget > 0 {
+		overBudget := len(r.OverBudgetPages())
+		budgetColor := colorGreen
+		if overBudget > 0 {
+			budgetColor = colorRed
+		}
+		fmt.Printf("  Over budget (%v): %s%d%s\n", r.Budget, budgetColor, overBudget, colorReset)
+	}
 
 	// Sort by latency (slowest first)
 	r.SortByLatency()
@@ -138,10 +226,15 @@ func (r *LatencyResult) PrintSummary(barWidth int, showSize bool) {
 		r.printPageBar(p, maxDuration, barWidth, maxURLWidth, showSize)
 	}
 
-	// Distribution histogram
+	// Distribution histograms
 	r.printDistribution()
+	r.printSizeDistribution()
+	r.printLargestPages(10)
+	r.printRenderBlocking(10)
+	r.printOverBudget()
+	r.printEmptyPages(10)
 
-	fmt.Println()
+	r.CrawlStats.Print()
 }
 
 func (r *LatencyResult) printPageBar(p PageLatency, maxDuration time.Duration, barWidth, maxURLWidth int, showSize bool) {
@@ -177,8 +270,9 @@ func (r *LatencyResult) printPageBar(p PageLatency, maxDuration time.Duration, b
 	}
 
 	// Build bar
-	bar := strings.Repeat("█", barLen)
-	emptyBar := strings.Repeat("░", barWidth-barLen)
+	fill, empty := barchart.Chars(r.ASCII)
+	bar := strings.Repeat(fill, barLen)
+	emptyBar := strings.Repeat(empty, barWidth-barLen)
 
 	// Format duration
 	durationStr := fmt.Sprintf("%7v", p.Duration.Round(time.Millisecond))
@@ -202,12 +296,17 @@ func (r *LatencyResult) printPageBar(p PageLatency, maxDuration time.Duration, b
 		sizeStr = fmt.Sprintf(" %s(%s)%s", colorGray, formatSize(p.Size), colorReset)
 	}
 
-	fmt.Printf("%s %s%s%s%s %s %-*s%s\n",
+	budgetStr := ""
+	if r.IsOverBudget(p) {
+		budgetStr = fmt.Sprintf(" %sover budget%s", colorRed, colorReset)
+	}
+
+	fmt.Printf("%s %s%s%s%s %s %-*s%s%s\n",
 		status,
 		barColor, bar, colorGray, emptyBar,
 		durationStr,
 		maxURLWidth, url,
-		sizeStr,
+		sizeStr, budgetStr,
 	)
 }
 
@@ -255,6 +354,71 @@ func (r *LatencyResult) printDistribution() {
 	}
 
 	barWidth := 30
+	fill, _ := barchart.Chars(r.ASCII)
+	for i, b := range buckets {
+		if counts[i] == 0 {
+			continue
+		}
+		barLen := 1
+		if maxCount > 0 {
+			barLen = int(float64(barWidth) * float64(counts[i]) / float64(maxCount))
+			if barLen < 1 {
+				barLen = 1
+			}
+		}
+
+		bar := strings.Repeat(fill, barLen)
+		fmt.Printf("  %s%-12s%s %s%s%s %d\n",
+			colorGray, b.label, colorReset,
+			b.color, bar, colorReset,
+			counts[i],
+		)
+	}
+}
+
+func (r *LatencyResult) printSizeDistribution() {
+	if len(r.Pages) < 5 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sPage Size Distribution:%s\n", colorBold, colorYellow, colorReset)
+
+	// Define buckets
+	buckets := []struct {
+		label    string
+		maxBytes int64
+		color    string
+	}{
+		{"< 50KB", 50 * 1024, colorGreen},
+		{"50-200KB", 200 * 1024, colorGreen},
+		{"200KB-1MB", 1024 * 1024, colorYellow},
+		{"> 1MB", -1, colorRed},
+	}
+
+	counts := make([]int, len(buckets))
+
+	for _, p := range r.Pages {
+		if p.Error != "" {
+			continue
+		}
+		for i, b := range buckets {
+			if b.maxBytes == -1 || p.Size < b.maxBytes {
+				counts[i]++
+				break
+			}
+		}
+	}
+
+	maxCount := 0
+	for _, c := range counts {
+		if c > maxCount {
+			maxCount = c
+		}
+	}
+
+	barWidth := 30
+	fill, _ := barchart.Chars(r.ASCII)
 	for i, b := range buckets {
 		if counts[i] == 0 {
 			continue
@@ -267,7 +431,7 @@ func (r *LatencyResult) printDistribution() {
 			}
 		}
 
-		bar := strings.Repeat("█", barLen)
+		bar := strings.Repeat(fill, barLen)
 		fmt.Printf("  %s%-12s%s %s%s%s %d\n",
 			colorGray, b.label, colorReset,
 			b.color, bar, colorReset,
@@ -276,6 +440,134 @@ func (r *LatencyResult) printDistribution() {
 	}
 }
 
+// printLargestPages lists the n heaviest pages, largest first
+func (r *LatencyResult) printLargestPages(n int) {
+	var pages []PageLatency
+	for _, p := range r.Pages {
+		if p.Error == "" && p.Size > 0 {
+			pages = append(pages, p)
+		}
+	}
+	if len(pages) == 0 {
+		return
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Size > pages[j].Size
+	})
+	if len(pages) > n {
+		pages = pages[:n]
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sLargest Pages:%s\n", colorBold, colorYellow, colorReset)
+
+	for _, p := range pages {
+		sizeColor := colorGreen
+		switch {
+		case p.Size >= 1024*1024:
+			sizeColor = colorRed
+		case p.Size >= 200*1024:
+			sizeColor = colorYellow
+		}
+
+		url := p.URL
+		if len(url) > 60 {
+			url = url[:57] + "..."
+		}
+
+		fmt.Printf("  %s%9s%s  %s\n", sizeColor, formatSize(p.Size), colorReset, url)
+	}
+}
+
+// printRenderBlocking lists pages whose <head> holds more render-blocking
+// resources than renderBlockingThreshold, worst first
+func (r *LatencyResult) printRenderBlocking(n int) {
+	pages := r.HeavyRenderBlockingPages()
+	if len(pages) == 0 {
+		return
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].RenderBlocking > pages[j].RenderBlocking
+	})
+
+	fmt.Println()
+	fmt.Printf("%s%sRender-Blocking Resources (> %d in <head>):%s\n", colorBold, colorYellow, renderBlockingThreshold, colorReset)
+
+	for i, p := range pages {
+		if i >= n {
+			fmt.Printf("  %s... and %d more pages%s\n", colorGray, len(pages)-n, colorReset)
+			break
+		}
+
+		url := p.URL
+		if len(url) > 60 {
+			url = url[:57] + "..."
+		}
+
+		fmt.Printf("  %s%3d%s  %s\n", colorRed, p.RenderBlocking, colorReset, url)
+	}
+}
+
+// printOverBudget lists the pages slower than the budget, slowest first.
+// Pages that failed are not among them: they count as errors.
+func (r *LatencyResult) printOverBudget() {
+	if r.Budget == 0 {
+		return
+	}
+
+	pages := r.OverBudgetPages()
+	fmt.Println()
+	if len(pages) == 0 {
+		fmt.Printf("%s✓ Every page loaded within the %v budget%s\n", colorGreen, r.Budget, colorReset)
+		return
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Duration > pages[j].Duration
+	})
+
+	fmt.Printf("%s%sOver Budget (> %v): %d page(s)%s\n", colorBold, colorRed, r.Budget, len(pages), colorReset)
+	for _, p := range pages {
+		over := (p.Duration - r.Budget).Round(time.Millisecond)
+		fmt.Printf("  %s%7v%s  %s %s(+%v)%s\n", colorRed, p.Duration.Round(time.Millisecond), colorReset, p.URL, colorGray, over, colorReset)
+	}
+}
+
+// printEmptyPages lists the HTML pages answering 200 with a body under MinSize,
+// smallest first: likely soft 404s or broken renders
+func (r *LatencyResult) printEmptyPages(n int) {
+	pages := r.EmptyPages()
+	if len(pages) == 0 {
+		return
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].Size != pages[j].Size {
+			return pages[i].Size < pages[j].Size
+		}
+		return pages[i].URL < pages[j].URL
+	})
+
+	fmt.Println()
+	fmt.Printf("%s%sSuspiciously Empty Pages (200, < %s): %d page(s)%s\n", colorBold, colorYellow, formatSize(r.MinSize), len(pages), colorReset)
+
+	for i, p := range pages {
+		if i >= n {
+			fmt.Printf("  %s... and %d more pages%s\n", colorGray, len(pages)-n, colorReset)
+			break
+		}
+
+		url := p.URL
+		if len(url) > 60 {
+			url = url[:57] + "..."
+		}
+
+		fmt.Printf("  %s%9s%s  %s\n", colorYellow, formatSize(p.Size), colorReset, url)
+	}
+}
+
 func formatSize(bytes int64) string {
 	const (
 		KB = 1024
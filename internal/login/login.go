@@ -0,0 +1,201 @@
+package login
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
+)
+
+// Flow describes a login form to submit before crawling, so the session
+// cookie it sets opens the protected pages
+type Flow struct {
+	URL    string // Page holding the login form, or the URL receiving the POST
+	Fields Fields // Form fields to submit, e.g. the username and password fields
+}
+
+// Fields are form field values, set from repeated name=value flags
+type Fields map[string]string
+
+// String implements flag.Value, without the values: they hold credentials
+func (f Fields) String() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Set implements flag.Value for name=value
+func (f Fields) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	f[name] = value
+	return nil
+}
+
+// Do submits the login form with client, whose cookie jar keeps the session.
+// The login page is fetched first: when it holds a form, the POST goes to
+// that form's action with its hidden inputs, CSRF tokens included, and
+// flow.Fields on top. Otherwise flow.Fields are posted to flow.URL. An
+// unreachable login page, an error status, a response showing the login form
+// again or a login that sets or changes no cookie is an error.
+func Do(client *http.Client, flow Flow) error {
+	if client.Jar == nil {
+		return fmt.Errorf("login needs a cookie jar")
+	}
+	loginURL, err := url.Parse(flow.URL)
+	if err != nil {
+		return fmt.Errorf("invalid login URL: %w", err)
+	}
+
+	action := loginURL
+	values := url.Values{}
+
+	resp, err := get(client, loginURL.String())
+	if err != nil {
+		return fmt.Errorf("login page: %w", err)
+	}
+	if form := findForm(resp.Body); form != nil {
+		if ref, err := resp.Request.URL.Parse(form.action); err == nil {
+			action = ref
+		}
+		values = form.hidden
+	}
+	resp.Body.Close()
+
+	for name, value := range flow.Fields {
+		values.Set(name, value)
+	}
+
+	req, err := http.NewRequest("POST", action.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "LinkChecker/1.0")
+
+	// The GET may already have set a session cookie: only a cookie the POST
+	// sets or changes shows the login worked
+	before := cookies(client.Jar, loginURL, action)
+
+	resp, err = client.Do(req)
+	if err != nil {
+		return fmt.Errorf("login request: %w", err)
+	}
+	form := findForm(resp.Body)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("login returned %d", resp.StatusCode)
+	}
+	if form != nil && form.password {
+		return fmt.Errorf("login form shown again, check the field names and credentials")
+	}
+	for cookie := range cookies(client.Jar, loginURL, action, resp.Request.URL) {
+		if !before[cookie] {
+			return nil
+		}
+	}
+	return fmt.Errorf("login set no session cookie, check the field names and credentials")
+}
+
+// cookies returns the name=value of every cookie the jar sends to the URLs
+func cookies(jar http.CookieJar, urls ...*url.URL) map[string]bool {
+	set := make(map[string]bool)
+	for _, u := range urls {
+		for _, cookie := range jar.Cookies(u) {
+			set[cookie.Name+"="+cookie.Value] = true
+		}
+	}
+	return set
+}
+
+// IsLogout reports whether a URL looks like a logout link, which would end
+// the session if crawled
+func IsLogout(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	path := strings.ToLower(parsed.Path)
+	for _, word := range []string{"logout", "log-out", "log_out", "signout", "sign-out", "sign_out", "logoff"} {
+		if strings.Contains(path, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func get(client *http.Client, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "LinkChecker/1.0")
+	return client.Do(req)
+}
+
+// form is the login form of a page
+type form struct {
+	action   string
+	hidden   url.Values
+	password bool // The form holds a password input
+}
+
+// findForm returns the first form holding a password input, or the first
+// form when none does, with its action and hidden input values
+func findForm(body io.Reader) *form {
+	var first, current *form
+	hasPassword := false
+	tokenizer := html.NewTokenizer(body)
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return first
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "form":
+				current = &form{action: htmlattr.Get(token, "action"), hidden: url.Values{}}
+				hasPassword = false
+				if first == nil {
+					first = current
+				}
+			case "input":
+				if current == nil {
+					continue
+				}
+				switch strings.ToLower(htmlattr.Get(token, "type")) {
+				case "hidden":
+					if name := htmlattr.Get(token, "name"); name != "" {
+						current.hidden.Set(name, htmlattr.Get(token, "value"))
+					}
+				case "password":
+					hasPassword = true
+				}
+			}
+
+		case html.EndTagToken:
+			if tokenizer.Token().Data == "form" && current != nil {
+				if hasPassword {
+					current.password = true
+					return current
+				}
+				current = nil
+			}
+		}
+	}
+}
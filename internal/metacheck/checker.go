@@ -6,67 +6,79 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
 
 	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
+	"github.com/ngonzalez/web-tools/internal/htmlhead"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/serp"
+	"github.com/ngonzalez/web-tools/internal/spa"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 )
 
 // Config holds checker configuration
 type Config struct {
-	Concurrency int
-	Timeout     time.Duration
-	MaxDepth    int
-	Verbose     bool
+	Concurrency         int
+	Timeout             time.Duration
+	MaxDepth            int
+	Verbose             bool
+	Verbosity           int           // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Info
+	TreatSchemesAsSame  bool          // Collapse http:// and https:// URLs of the same page
+	KeepHTTP            bool          // Crawl http:// URLs as is even on a site enforcing HTTPS
+	Delay               time.Duration // Minimum pause between two requests
+	CheckHTML           bool          // Also report duplicate ids and malformed HTML
+	Freshness           bool          // Also report page ages from modification dates
+	MaxIdleConnsPerHost int           // Idle connections kept per host, 0 matches Concurrency
+	MaxRedirects        int           // Redirects followed per request, 0 = none (the 3xx is kept as the page, its target not crawled), -1 = up to httppool.MaxRedirectsCap
+	HTMLContentTypes    []string      // Extra media types parsed as HTML, besides contenttype.HTML
+	AutoSnippet         bool          // Preview the body text Google likely shows for pages without description
+	ASCII               bool          // Draw bar graphs with # and - instead of block characters
+	Sitemaps            []string      // Sitemaps whose internal URLs the crawl should reach, relative to the start URL
 }
 
 // DefaultConfig returns default configuration
 func DefaultConfig() Config {
 	return Config{
-		Concurrency: 10,
-		Timeout:     10 * time.Second,
-		MaxDepth:    0,
-		Verbose:     false,
+		Concurrency:  10,
+		Timeout:      10 * time.Second,
+		MaxDepth:     0,
+		Verbose:      false,
+		MaxRedirects: httppool.DefaultMaxRedirects,
 	}
 }
 
 // Checker analyzes meta descriptions
 type Checker struct {
-	config    Config
-	baseURL   *url.URL
-	visited   map[string]bool
-	visitedMu sync.RWMutex
-	result    *MetaResult
-	resultMu  sync.Mutex
-	client    *http.Client
-	semaphore chan struct{}
+	config   Config
+	baseURL  *url.URL
+	result   *MetaResult
+	resultMu sync.Mutex
+	client   *http.Client
+	stats    crawlstats.Counter
 }
 
 // New creates a new Checker
 func New(config Config) *Checker {
+	config.Verbosity = verbosity.Effective(config.Verbosity, config.Verbose)
 	return &Checker{
-		config:    config,
-		visited:   make(map[string]bool),
-		semaphore: make(chan struct{}, config.Concurrency),
+		config: config,
 		client: &http.Client{
-			Timeout: config.Timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return http.ErrUseLastResponse
-				}
-				return nil
-			},
+			Timeout:       config.Timeout,
+			Transport:     httppool.New(config.Concurrency, config.MaxIdleConnsPerHost),
+			CheckRedirect: httppool.CheckRedirect(config.MaxRedirects, http.ErrUseLastResponse),
 		},
 	}
 }
 
-type urlTask struct {
-	url   string
-	depth int
-}
-
 // Check starts the meta description analysis
 func (c *Checker) Check(startURL string) (*MetaResult, error) {
 	parsed, err := url.Parse(startURL)
@@ -79,161 +91,197 @@ func (c *Checker) Check(startURL string) (*MetaResult, error) {
 	}
 
 	c.baseURL = parsed
+	c.stats.Start()
 	c.result = NewMetaResult(startURL)
-
-	tasks := make(chan urlTask, 1000)
-
-	c.markVisited(startURL)
-	tasks <- urlTask{url: startURL, depth: 0}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	for i := 0; i < c.config.Concurrency; i++ {
-		go c.worker(ctx, tasks)
+	c.result.HTMLChecked = c.config.CheckHTML
+	c.result.FreshnessChecked = c.config.Freshness
+	c.result.AutoSnippetChecked = c.config.AutoSnippet
+	c.result.ASCII = c.config.ASCII
+
+	engine := crawl.New(crawl.Config{
+		Concurrency:        c.config.Concurrency,
+		MaxDepth:           c.config.MaxDepth,
+		Delay:              c.config.Delay,
+		TreatSchemesAsSame: c.config.TreatSchemesAsSame,
+		Rewrite:            httppool.HTTPSRewrite(c.config.KeepHTTP, c.client.Transport, c.config.Timeout, startURL),
+	}, c.processURL)
+	engine.Run(context.Background(), crawl.Task{URL: startURL})
+	if len(c.config.Sitemaps) > 0 {
+		c.checkSitemaps(engine)
 	}
 
-	done := make(chan struct{})
-	go func() {
-		for {
-			time.Sleep(100 * time.Millisecond)
-			c.visitedMu.RLock()
-			visitedCount := len(c.visited)
-			c.visitedMu.RUnlock()
-
-			if len(tasks) == 0 && len(c.semaphore) == 0 {
-				time.Sleep(500 * time.Millisecond)
-				if len(tasks) == 0 && len(c.semaphore) == 0 {
-					close(done)
-					return
-				}
-			}
-
-			if visitedCount > 10000 {
-				close(done)
-				return
-			}
-		}
-	}()
-
-	<-done
-	cancel()
-	close(tasks)
-
 	c.result.Finalize()
 
-	return c.result, nil
-}
+	c.result.CrawlStats = c.stats.Snapshot()
 
-func (c *Checker) worker(ctx context.Context, tasks chan urlTask) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case task, ok := <-tasks:
-			if !ok {
-				return
-			}
-			c.processURL(ctx, task, tasks)
-		}
-	}
+	return c.result, nil
 }
 
-func (c *Checker) processURL(ctx context.Context, task urlTask, tasks chan urlTask) {
-	select {
-	case c.semaphore <- struct{}{}:
-		defer func() { <-c.semaphore }()
-	case <-ctx.Done():
-		return
-	}
-
-	if c.config.MaxDepth > 0 && task.depth > c.config.MaxDepth {
-		return
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", task.url, nil)
+func (c *Checker) processURL(ctx context.Context, task crawl.Task) []crawl.Task {
+	req, err := http.NewRequestWithContext(ctx, "GET", task.URL, nil)
 	if err != nil {
-		return
+		return nil
 	}
 
 	req.Header.Set("User-Agent", "MetaChecker/1.0")
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	c.stats.AddRequest()
 	if err != nil {
 		if ctx.Err() != nil {
-			return
+			return nil
 		}
-		if c.config.Verbose {
-			printError(task.url, err.Error(), task.depth)
+		if c.config.Verbosity >= verbosity.Warn {
+			printError(task.URL, err.Error(), task.Depth)
 		}
-		return
+		return nil
 	}
 	defer resp.Body.Close()
 
-	if c.config.Verbose {
-		printProgress(task.url, resp.StatusCode, task.depth)
+	if verbosity.ShowStatus(c.config.Verbosity, resp.StatusCode) {
+		printProgress(task.URL, resp.StatusCode, task.Depth)
+	}
+	if c.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(task.URL, time.Since(start))
 	}
 
 	if resp.StatusCode >= 400 {
-		return
+		return nil
 	}
 
 	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/html") {
-		return
+	if !contenttype.IsHTML(contentType, c.config.HTMLContentTypes) {
+		return nil
 	}
 
+	// Links found at the depth limit are never followed, so the head is enough
+	headOnly := c.config.MaxDepth > 0 && task.Depth >= c.config.MaxDepth && !c.config.CheckHTML && !c.config.AutoSnippet
+
 	// Parse page
-	pageMeta, links := c.parsePage(resp.Body, task.url)
+	pageMeta, links := c.parsePage(c.stats.Body(resp.Body), task.URL, headOnly)
+
+	// Article metadata wins; the header often only reflects the deploy time
+	if pageMeta.Modified.IsZero() {
+		if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+			pageMeta.Modified = t
+		}
+	}
 
 	// Add to results
 	c.resultMu.Lock()
 	c.result.AddPage(pageMeta)
 	c.resultMu.Unlock()
 
-	// Queue new links
+	// Follow every link found
+	var next []crawl.Task
 	for _, link := range links {
-		if c.shouldVisit(link) {
-			c.markVisited(link)
-			select {
-			case tasks <- urlTask{url: link, depth: task.depth + 1}:
-			default:
-			}
-		}
+		next = append(next, crawl.Task{URL: link})
 	}
+	return next
 }
 
-func (c *Checker) parsePage(body io.Reader, pageURL string) (PageMeta, []string) {
+// parsePage extracts the title, description and internal links of a page.
+// With headOnly it stops at the end of <head> and returns no links.
+func (c *Checker) parsePage(body io.Reader, pageURL string, headOnly bool) (PageMeta, []string) {
 	meta := PageMeta{
 		URL: pageURL,
 	}
 	var links []string
 
-	doc, err := html.Parse(body)
-	if err != nil {
-		return meta, links
+	var structure *structureCheck
+	if c.config.CheckHTML {
+		structure = newStructureCheck()
 	}
+	var snippet *serp.SnippetCollector
+	if c.config.AutoSnippet {
+		snippet = &serp.SnippetCollector{}
+	}
+	var rendering spa.Detector
+
+	tokenizer := html.NewTokenizer(body)
+
+	for {
+		tokenType := tokenizer.Next()
+
+		switch tokenType {
+		case html.ErrorToken:
+			if structure != nil {
+				meta.HTMLIssues = structure.issues()
+			}
+			if snippet != nil && meta.Description == "" {
+				meta.AutoSnippet = snippet.Snippet()
+			}
+			if rendering.ClientRendered() {
+				meta.ClientRendered = rendering.Evidence()
+			}
+			return meta, links
+
+		case html.TextToken:
+			token := tokenizer.Token()
+			rendering.Token(tokenType, token)
+			// The description is in <head>, so it is known before any body text
+			if snippet != nil && meta.Description == "" && !snippet.Done() {
+				snippet.Token(tokenType, token)
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			token := tokenizer.Token()
+
+			if headOnly && htmlhead.IsEnd(tokenType, token.Data) {
+				return meta, links
+			}
+			if structure != nil {
+				structure.token(tokenType, token)
+			}
+			if snippet != nil && meta.Description == "" {
+				snippet.Token(tokenType, token)
+			}
+			rendering.Token(tokenType, token)
+			if tokenType == html.EndTagToken {
+				continue
+			}
+
+			switch token.Data {
+			case "html":
+				meta.Lang = strings.TrimSpace(htmlattr.Get(token, "lang"))
 
-	var parseNode func(*html.Node)
-	parseNode = func(n *html.Node) {
-		if n.Type == html.ElementNode {
-			switch n.Data {
 			case "title":
-				if n.FirstChild != nil {
-					meta.Title = strings.TrimSpace(n.FirstChild.Data)
+				if tokenType != html.StartTagToken {
+					break
+				}
+				switch tokenizer.Next() {
+				case html.TextToken:
+					meta.Title = strings.TrimSpace(tokenizer.Token().Data)
 					meta.TitleLength = utf8.RuneCountInString(meta.Title)
+				case html.EndTagToken:
+					// Empty title, its end tag is consumed here
+					if structure != nil {
+						structure.token(html.EndTagToken, tokenizer.Token())
+					}
 				}
 
 			case "meta":
-				name := strings.ToLower(getAttr(n, "name"))
+				name := strings.ToLower(htmlattr.Get(token, "name"))
 				if name == "description" {
-					content := getAttr(n, "content")
+					content := htmlattr.Get(token, "content")
 					meta.Description = strings.TrimSpace(content)
 					meta.DescLength = utf8.RuneCountInString(meta.Description)
 				}
 
+				switch strings.ToLower(htmlattr.Get(token, "property")) {
+				case "article:modified_time":
+					if t, ok := serp.ParseDate(htmlattr.Get(token, "content")); ok {
+						meta.Modified = t
+					}
+				case "og:updated_time":
+					if t, ok := serp.ParseDate(htmlattr.Get(token, "content")); ok && meta.Modified.IsZero() {
+						meta.Modified = t
+					}
+				}
+
 			case "a":
-				href := getAttr(n, "href")
+				href := htmlattr.Get(token, "href")
 				if href != "" {
 					resolved := c.resolveURL(href)
 					if resolved != "" {
@@ -242,23 +290,7 @@ func (c *Checker) parsePage(body io.Reader, pageURL string) (PageMeta, []string)
 				}
 			}
 		}
-
-		for child := n.FirstChild; child != nil; child = child.NextSibling {
-			parseNode(child)
-		}
-	}
-
-	parseNode(doc)
-	return meta, links
-}
-
-func getAttr(n *html.Node, key string) string {
-	for _, attr := range n.Attr {
-		if strings.ToLower(attr.Key) == key {
-			return attr.Val
-		}
 	}
-	return ""
 }
 
 func (c *Checker) resolveURL(href string) string {
@@ -295,19 +327,6 @@ func (c *Checker) resolveURL(href string) string {
 	return resolved.String()
 }
 
-func (c *Checker) markVisited(url string) {
-	c.visitedMu.Lock()
-	c.visited[url] = true
-	c.visitedMu.Unlock()
-}
-
-func (c *Checker) shouldVisit(targetURL string) bool {
-	c.visitedMu.RLock()
-	visited := c.visited[targetURL]
-	c.visitedMu.RUnlock()
-	return !visited
-}
-
 func printProgress(url string, statusCode int, depth int) {
 	var statusColor string
 	switch {
@@ -322,10 +341,10 @@ func printProgress(url string, statusCode int, depth int) {
 	}
 
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[%d]%s %s\n", indent, statusColor, statusCode, colorReset, url)
+	fmt.Fprintf(os.Stderr, "%s%s[%d]%s %s\n", indent, statusColor, statusCode, colorReset, url)
 }
 
 func printError(url string, errMsg string, depth int) {
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, errMsg)
+	fmt.Fprintf(os.Stderr, "%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, errMsg)
 }
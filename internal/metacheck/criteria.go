@@ -0,0 +1,102 @@
+package metacheck
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultFailOn fails on too long or missing descriptions, what metacheck
+// always exited 1 for
+const DefaultFailOn = "too_long>0,missing>0"
+
+// Criterion fails a check when a category count goes past a threshold,
+// e.g. duplicate>5
+type Criterion struct {
+	Category  string // A summary line key: ok, too_long, too_short, missing, duplicate, html_issues, stale, client_rendered or not_linked
+	OrEqual   bool   // >= rather than >
+	Threshold int
+}
+
+func (c Criterion) String() string {
+	op := ">"
+	if c.OrEqual {
+		op = ">="
+	}
+	return c.Category + op + strconv.Itoa(c.Threshold)
+}
+
+// Met reports whether a count fails the criterion
+func (c Criterion) Met(count int) bool {
+	if c.OrEqual {
+		return count >= c.Threshold
+	}
+	return count > c.Threshold
+}
+
+// ParseCriteria parses a comma-separated list of criteria such as
+// "missing>0,duplicate>5". An empty list never fails.
+func ParseCriteria(spec string) ([]Criterion, error) {
+	var criteria []Criterion
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.Index(entry, ">")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid criterion %q, expected category>count", entry)
+		}
+		criterion := Criterion{Category: strings.ToLower(strings.TrimSpace(entry[:i]))}
+		value := entry[i+1:]
+		if strings.HasPrefix(value, "=") {
+			criterion.OrEqual = true
+			value = value[1:]
+		}
+
+		if !slices.Contains(categories, criterion.Category) {
+			return nil, fmt.Errorf("unknown category %q in %q, expected one of %s", criterion.Category, entry, strings.Join(categories, ", "))
+		}
+		threshold, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("invalid count in %q", entry)
+		}
+		criterion.Threshold = threshold
+		criteria = append(criteria, criterion)
+	}
+	return criteria, nil
+}
+
+// categories are the criteria categories, in summary line order
+var categories = []string{"ok", "too_long", "too_short", "missing", "duplicate", "html_issues", "stale", "client_rendered", "not_linked"}
+
+// categoryCounts returns the count of each category, named as in the
+// summary line
+func categoryCounts(r *MetaResult) map[string]int {
+	return map[string]int{
+		"ok":              r.OKCount,
+		"too_long":        r.TooLongCount,
+		"too_short":       r.TooShortCount,
+		"missing":         r.MissingCount,
+		"duplicate":       r.DuplicateCount,
+		"html_issues":     len(r.HTMLIssues),
+		"stale":           r.StaleCount(),
+		"client_rendered": len(r.ClientRendered),
+		"not_linked":      len(r.NotLinked),
+	}
+}
+
+// Failures returns the criteria the result fails, each with its count, as
+// "missing=3 (missing>0)"
+func (r *MetaResult) Failures(criteria []Criterion) []string {
+	counts := categoryCounts(r)
+	var failures []string
+	for _, criterion := range criteria {
+		if count := counts[criterion.Category]; criterion.Met(count) {
+			failures = append(failures, fmt.Sprintf("%s=%d (%s)", criterion.Category, count, criterion))
+		}
+	}
+	return failures
+}
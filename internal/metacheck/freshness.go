@@ -0,0 +1,68 @@
+package metacheck
+
+import (
+	"sort"
+	"time"
+)
+
+// StaleAge is the age past which a page counts as not maintained
+const StaleAge = 365 * 24 * time.Hour
+
+// AgeBucket counts the dated pages within an age range
+type AgeBucket struct {
+	Label  string
+	MaxAge time.Duration // Upper bound, 0 for the last bucket
+	Count  int
+}
+
+// ageBuckets returns the empty freshness distribution
+func ageBuckets() []AgeBucket {
+	const day = 24 * time.Hour
+	return []AgeBucket{
+		{Label: "< 1 month", MaxAge: 30 * day},
+		{Label: "1-6 months", MaxAge: 182 * day},
+		{Label: "6-12 months", MaxAge: StaleAge},
+		{Label: "1-2 years", MaxAge: 2 * StaleAge},
+		{Label: "> 2 years"},
+	}
+}
+
+// computeFreshness fills the age distribution and the stalest pages list
+func (r *MetaResult) computeFreshness(now time.Time) {
+	r.AgeBuckets = ageBuckets()
+	r.Undated = 0
+	r.Stalest = nil
+
+	for _, page := range r.AllPages {
+		if page.Modified.IsZero() {
+			r.Undated++
+			continue
+		}
+
+		// Dates in the future count as fresh
+		age := now.Sub(page.Modified)
+		for i := range r.AgeBuckets {
+			if r.AgeBuckets[i].MaxAge == 0 || age < r.AgeBuckets[i].MaxAge {
+				r.AgeBuckets[i].Count++
+				break
+			}
+		}
+
+		r.Stalest = append(r.Stalest, page)
+	}
+
+	sort.Slice(r.Stalest, func(i, j int) bool {
+		return r.Stalest[i].Modified.Before(r.Stalest[j].Modified)
+	})
+}
+
+// StaleCount returns how many pages were not modified in over a year
+func (r *MetaResult) StaleCount() int {
+	count := 0
+	for _, b := range r.AgeBuckets {
+		if b.MaxAge == 0 || b.MaxAge > StaleAge {
+			count += b.Count
+		}
+	}
+	return count
+}
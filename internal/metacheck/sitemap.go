@@ -0,0 +1,51 @@
+package metacheck
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/sitemap"
+)
+
+// checkSitemaps loads the configured sitemaps and records the internal URLs
+// they list that the crawl never reached through links. Their meta was not
+// analyzed: nothing on the site links to them, or only pages past the depth
+// limit do.
+func (c *Checker) checkSitemaps(engine *crawl.Engine) {
+	listed := make(map[string]bool)
+	for _, ref := range c.config.Sitemaps {
+		sitemapURL, err := c.baseURL.Parse(ref)
+		if err != nil {
+			c.result.SitemapErrors = append(c.result.SitemapErrors, fmt.Sprintf("%s: %v", ref, err))
+			continue
+		}
+
+		entries, err := sitemap.Load(c.client, sitemapURL.String())
+		if err != nil {
+			c.result.SitemapErrors = append(c.result.SitemapErrors, strings.Split(err.Error(), "\n")...)
+		}
+		for _, entry := range entries {
+			if !c.isInternal(entry.URL) || listed[entry.URL] {
+				continue
+			}
+			listed[entry.URL] = true
+			if !engine.Seen(entry.URL) {
+				c.result.NotLinked = append(c.result.NotLinked, entry.URL)
+			}
+		}
+	}
+
+	c.result.SitemapChecked = true
+	c.result.SitemapURLs = len(listed)
+	sort.Strings(c.result.NotLinked)
+}
+
+// isInternal reports whether a URL is on the crawled host, as the links
+// the crawl follows
+func (c *Checker) isInternal(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && parsed.Host == c.baseURL.Host
+}
@@ -0,0 +1,81 @@
+package metacheck
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
+)
+
+// singleElements may appear only once per document
+var singleElements = []string{"html", "head", "body", "title"}
+
+// rawTextElements swallow the rest of the page when their end tag is missing
+var rawTextElements = map[string]bool{
+	"title":    true,
+	"script":   true,
+	"style":    true,
+	"textarea": true,
+}
+
+// structureCheck collects gross HTML problems while a page is tokenized
+type structureCheck struct {
+	ids     map[string]int
+	counts  map[string]int
+	openRaw string // Raw text element whose end tag has not been seen yet
+}
+
+func newStructureCheck() *structureCheck {
+	return &structureCheck{
+		ids:    make(map[string]int),
+		counts: make(map[string]int),
+	}
+}
+
+// token records one tag token
+func (s *structureCheck) token(tokenType html.TokenType, token html.Token) {
+	if tokenType == html.EndTagToken {
+		if token.Data == s.openRaw {
+			s.openRaw = ""
+		}
+		return
+	}
+
+	s.counts[token.Data]++
+	if id := htmlattr.Get(token, "id"); id != "" {
+		s.ids[id]++
+	}
+	if tokenType == html.StartTagToken && rawTextElements[token.Data] {
+		s.openRaw = token.Data
+	}
+}
+
+// issues lists the problems found, once the whole page has been read
+func (s *structureCheck) issues() []string {
+	var issues []string
+
+	for _, name := range singleElements {
+		if n := s.counts[name]; n > 1 {
+			issues = append(issues, fmt.Sprintf("%d <%s> elements", n, name))
+		}
+	}
+
+	if s.openRaw != "" {
+		issues = append(issues, fmt.Sprintf("unclosed <%s>, the rest of the page is swallowed", s.openRaw))
+	}
+
+	var duplicated []string
+	for id, n := range s.ids {
+		if n > 1 {
+			duplicated = append(duplicated, id)
+		}
+	}
+	sort.Strings(duplicated)
+	for _, id := range duplicated {
+		issues = append(issues, fmt.Sprintf("duplicate id %q (%d times)", id, s.ids[id]))
+	}
+
+	return issues
+}
@@ -4,6 +4,11 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/ngonzalez/web-tools/internal/barchart"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/serp"
 )
 
 // Status of a meta description
@@ -67,18 +72,35 @@ type PageMeta struct {
 	Description string
 	DescLength  int
 	Status      Status
+	HTMLIssues  []string  // Structural HTML problems, when Config.CheckHTML is set
+	Modified    time.Time // Last modification date, zero when unknown
+	AutoSnippet string    // Body text Google likely shows instead, for a missing description with Config.AutoSnippet
+	Lang        string    // <html lang>, which sets the recommended length
+
+	// What suggests the page is rendered by JavaScript, empty when it is not
+	ClientRendered string
+}
+
+// DescRange returns the recommended description length of the page: 70-155
+// characters, or the range of its language when its characters are wider,
+// as in Japanese. byLanguage reports whether the language set it.
+func (p PageMeta) DescRange() (r serp.Range, byLanguage bool) {
+	if _, description, ok := serp.LanguageLengths(p.Lang); ok {
+		return description, true
+	}
+	return serp.Range{Min: DescMinLength, Max: DescMaxLength}, false
 }
 
 // MetaResult holds the analysis results
 type MetaResult struct {
-	StartURL    string
-	TotalPages  int
+	StartURL   string
+	TotalPages int
 
 	// Counts
-	OKCount       int
-	TooLongCount  int
-	TooShortCount int
-	MissingCount  int
+	OKCount        int
+	TooLongCount   int
+	TooShortCount  int
+	MissingCount   int
 	DuplicateCount int
 
 	// Pages by status
@@ -91,8 +113,39 @@ type MetaResult struct {
 	// All pages
 	AllPages []PageMeta
 
+	// Pages judged by the recommended length of their language
+	LanguagePages int
+
+	// Pages with structural HTML problems, sorted by URL
+	HTMLChecked bool
+	HTMLIssues  []PageMeta
+
+	// Pages that appear to be client-rendered, sorted by URL: their
+	// metadata may be that of the shell rather than the page
+	ClientRendered []PageMeta
+
+	// Probable auto-snippets were extracted for missing descriptions
+	AutoSnippetChecked bool
+
+	// Page ages, from article:modified_time or the Last-Modified header
+	FreshnessChecked bool
+	AgeBuckets       []AgeBucket
+	Undated          int
+	Stalest          []PageMeta // Dated pages, oldest first
+
+	// Internal URLs listed in Config.Sitemaps that no crawled link reached,
+	// sorted; their meta was never analyzed
+	SitemapChecked bool
+	SitemapURLs    int // Distinct internal URLs the sitemaps list
+	NotLinked      []string
+	SitemapErrors  []string // Sitemaps or sitemap index children that could not be read
+
 	// Duplicate tracking
 	DescriptionMap map[string][]string // description -> URLs
+	CrawlStats     crawlstats.Stats
+
+	// Bars are drawn with # and - instead of block characters
+	ASCII bool
 }
 
 // NewMetaResult creates a new result
@@ -127,6 +180,10 @@ func (r *MetaResult) Finalize() {
 	// Categorize pages
 	for i := range r.AllPages {
 		page := &r.AllPages[i]
+		recommended, byLanguage := page.DescRange()
+		if byLanguage {
+			r.LanguagePages++
+		}
 
 		// Determine status
 		if page.Description == "" {
@@ -137,11 +194,11 @@ func (r *MetaResult) Finalize() {
 			page.Status = StatusDuplicate
 			r.DuplicateCount++
 			r.Duplicate = append(r.Duplicate, *page)
-		} else if page.DescLength > DescMaxLength {
+		} else if page.DescLength > recommended.Max {
 			page.Status = StatusTooLong
 			r.TooLongCount++
 			r.TooLong = append(r.TooLong, *page)
-		} else if page.DescLength < DescMinLength {
+		} else if page.DescLength < recommended.Min {
 			page.Status = StatusTooShort
 			r.TooShortCount++
 			r.TooShort = append(r.TooShort, *page)
@@ -152,6 +209,29 @@ func (r *MetaResult) Finalize() {
 		}
 	}
 
+	// Pages with structural HTML problems
+	for _, page := range r.AllPages {
+		if len(page.HTMLIssues) > 0 {
+			r.HTMLIssues = append(r.HTMLIssues, page)
+		}
+	}
+	sort.Slice(r.HTMLIssues, func(i, j int) bool {
+		return r.HTMLIssues[i].URL < r.HTMLIssues[j].URL
+	})
+
+	for _, page := range r.AllPages {
+		if page.ClientRendered != "" {
+			r.ClientRendered = append(r.ClientRendered, page)
+		}
+	}
+	sort.Slice(r.ClientRendered, func(i, j int) bool {
+		return r.ClientRendered[i].URL < r.ClientRendered[j].URL
+	})
+
+	if r.FreshnessChecked {
+		r.computeFreshness(time.Now())
+	}
+
 	// Sort too long by length descending
 	sort.Slice(r.TooLong, func(i, j int) bool {
 		return r.TooLong[i].DescLength > r.TooLong[j].DescLength
@@ -163,6 +243,12 @@ func (r *MetaResult) Finalize() {
 	})
 }
 
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *MetaResult) SummaryLine() string {
+	return fmt.Sprintf("SUMMARY tool=metacheck pages=%d ok=%d too_long=%d too_short=%d missing=%d duplicate=%d html_issues=%d stale=%d client_rendered=%d not_linked=%d",
+		r.TotalPages, r.OKCount, r.TooLongCount, r.TooShortCount, r.MissingCount, r.DuplicateCount, len(r.HTMLIssues), r.StaleCount(), len(r.ClientRendered), len(r.NotLinked))
+}
+
 // ANSI colors
 const (
 	colorReset  = "\033[0m"
@@ -182,6 +268,10 @@ func (r *MetaResult) PrintSummary(showAll bool, limit int) {
 	fmt.Printf("%s%s=== Meta Description Analysis ===%s\n", colorBold, colorCyan, colorReset)
 	fmt.Printf("URL: %s%s%s\n", colorBlue, r.StartURL, colorReset)
 	fmt.Printf("Pages analyzed: %s%d%s\n", colorGreen, r.TotalPages, colorReset)
+	if r.LanguagePages > 0 {
+		fmt.Printf("Judged by their language's length: %s%d%s %s(%s)%s\n", colorGreen, r.LanguagePages, colorReset, colorGray, languageLengths(), colorReset)
+	}
+	r.printClientRendered(limit)
 	fmt.Println()
 
 	// Summary
@@ -199,7 +289,7 @@ func (r *MetaResult) PrintSummary(showAll bool, limit int) {
 	if len(r.TooLong) > 0 {
 		fmt.Println()
 		fmt.Printf("%s%s=== Too Long Descriptions (%d) ===%s\n", colorBold, colorRed, len(r.TooLong), colorReset)
-		fmt.Printf("%sRecommended limit is %d characters%s\n", colorGray, DescMaxLength, colorReset)
+		fmt.Printf("%sRecommended limit is %d characters%s%s\n", colorGray, DescMaxLength, r.languageNote(), colorReset)
 		fmt.Println()
 
 		displayCount := limit
@@ -235,6 +325,11 @@ func (r *MetaResult) PrintSummary(showAll bool, limit int) {
 				url = url[:67] + "..."
 			}
 			fmt.Printf("  %s✗%s %s\n", colorRed, colorReset, url)
+			if page.AutoSnippet != "" {
+				fmt.Printf("    %sLikely snippet: \"%s\"%s\n", colorGray, page.AutoSnippet, colorReset)
+			} else if r.AutoSnippetChecked {
+				fmt.Printf("    %sNo meaningful paragraph, Google will pick text on its own%s\n", colorGray, colorReset)
+			}
 		}
 
 		if len(r.Missing) > displayCount {
@@ -246,7 +341,7 @@ func (r *MetaResult) PrintSummary(showAll bool, limit int) {
 	if len(r.TooShort) > 0 && showAll {
 		fmt.Println()
 		fmt.Printf("%s%s=== Too Short Descriptions (%d) ===%s\n", colorBold, colorYellow, len(r.TooShort), colorReset)
-		fmt.Printf("%sRecommended minimum is %d characters%s\n", colorGray, DescMinLength, colorReset)
+		fmt.Printf("%sRecommended minimum is %d characters%s%s\n", colorGray, DescMinLength, r.languageNote(), colorReset)
 		fmt.Println()
 
 		displayCount := limit
@@ -316,10 +411,44 @@ func (r *MetaResult) PrintSummary(showAll bool, limit int) {
 		}
 	}
 
+	if r.HTMLChecked {
+		r.printHTMLIssues(limit)
+	}
+
+	if r.FreshnessChecked {
+		r.printFreshness(limit)
+	}
+
+	if r.SitemapChecked {
+		r.printNotLinked(limit)
+	}
+
 	// Recommendations
 	r.printRecommendations()
 
 	fmt.Println()
+
+	r.CrawlStats.Print()
+}
+
+// printClientRendered warns about pages whose description may be missing
+// only because JavaScript would add it
+func (r *MetaResult) printClientRendered(limit int) {
+	if len(r.ClientRendered) == 0 {
+		return
+	}
+
+	fmt.Printf("%s⚠ %d page(s) appear to be client-rendered; static analysis may be incomplete:%s\n", colorYellow, len(r.ClientRendered), colorReset)
+	displayCount := limit
+	if displayCount <= 0 || displayCount > len(r.ClientRendered) {
+		displayCount = len(r.ClientRendered)
+	}
+	for _, page := range r.ClientRendered[:displayCount] {
+		fmt.Printf("  %s %s(%s)%s\n", page.URL, colorGray, page.ClientRendered, colorReset)
+	}
+	if len(r.ClientRendered) > displayCount {
+		fmt.Printf("  %s... and %d more%s\n", colorGray, len(r.ClientRendered)-displayCount, colorReset)
+	}
 }
 
 func (r *MetaResult) printDistributionChart() {
@@ -331,36 +460,159 @@ func (r *MetaResult) printDistributionChart() {
 	fmt.Printf("%s%sDistribution:%s\n", colorBold, colorYellow, colorReset)
 
 	barWidth := 40
+	fill, empty := barchart.Chars(r.ASCII)
 
 	// OK
 	okPct := float64(r.OKCount) / float64(r.TotalPages)
 	okBar := int(okPct * float64(barWidth))
 	fmt.Printf("  OK        %s%s%s%s %d (%.0f%%)\n",
-		colorGreen, strings.Repeat("█", okBar), colorGray, strings.Repeat("░", barWidth-okBar),
+		colorGreen, strings.Repeat(fill, okBar), colorGray, strings.Repeat(empty, barWidth-okBar),
 		r.OKCount, okPct*100)
 
 	// Too long
 	longPct := float64(r.TooLongCount) / float64(r.TotalPages)
 	longBar := int(longPct * float64(barWidth))
 	fmt.Printf("  Long      %s%s%s%s %d (%.0f%%)\n",
-		colorRed, strings.Repeat("█", longBar), colorGray, strings.Repeat("░", barWidth-longBar),
+		colorRed, strings.Repeat(fill, longBar), colorGray, strings.Repeat(empty, barWidth-longBar),
 		r.TooLongCount, longPct*100)
 
 	// Too short
 	shortPct := float64(r.TooShortCount) / float64(r.TotalPages)
 	shortBar := int(shortPct * float64(barWidth))
 	fmt.Printf("  Short     %s%s%s%s %d (%.0f%%)\n",
-		colorYellow, strings.Repeat("█", shortBar), colorGray, strings.Repeat("░", barWidth-shortBar),
+		colorYellow, strings.Repeat(fill, shortBar), colorGray, strings.Repeat(empty, barWidth-shortBar),
 		r.TooShortCount, shortPct*100)
 
 	// Missing
 	missPct := float64(r.MissingCount) / float64(r.TotalPages)
 	missBar := int(missPct * float64(barWidth))
 	fmt.Printf("  Missing   %s%s%s%s %d (%.0f%%)\n",
-		colorRed, strings.Repeat("█", missBar), colorGray, strings.Repeat("░", barWidth-missBar),
+		colorRed, strings.Repeat(fill, missBar), colorGray, strings.Repeat(empty, barWidth-missBar),
 		r.MissingCount, missPct*100)
 }
 
+func (r *MetaResult) printHTMLIssues(limit int) {
+	fmt.Println()
+	if len(r.HTMLIssues) == 0 {
+		fmt.Printf("%s✓ No structural HTML issues found%s\n", colorGreen, colorReset)
+		return
+	}
+
+	fmt.Printf("%s%s=== HTML Structure Issues (%d pages) ===%s\n", colorBold, colorRed, len(r.HTMLIssues), colorReset)
+	fmt.Printf("%sDuplicate ids, repeated <head>/<title> and unclosed tags break rendering and scrapers%s\n", colorGray, colorReset)
+	fmt.Println()
+
+	displayCount := limit
+	if displayCount <= 0 || displayCount > len(r.HTMLIssues) {
+		displayCount = len(r.HTMLIssues)
+	}
+
+	for i := 0; i < displayCount; i++ {
+		page := r.HTMLIssues[i]
+		url := page.URL
+		if len(url) > 70 {
+			url = url[:67] + "..."
+		}
+		fmt.Printf("  %s✗%s %s\n", colorRed, colorReset, url)
+		for j, issue := range page.HTMLIssues {
+			if j >= 5 {
+				fmt.Printf("    %s... and %d more%s\n", colorGray, len(page.HTMLIssues)-5, colorReset)
+				break
+			}
+			fmt.Printf("    • %s\n", issue)
+		}
+	}
+
+	if len(r.HTMLIssues) > displayCount {
+		fmt.Printf("\n%s... and %d more pages%s\n", colorGray, len(r.HTMLIssues)-displayCount, colorReset)
+	}
+}
+
+func (r *MetaResult) printFreshness(limit int) {
+	fmt.Println()
+	fmt.Printf("%s%s=== Content Freshness ===%s\n", colorBold, colorCyan, colorReset)
+	fmt.Printf("%sFrom article:modified_time, og:updated_time or the Last-Modified header%s\n", colorGray, colorReset)
+	fmt.Println()
+
+	dated := r.TotalPages - r.Undated
+	if dated == 0 {
+		fmt.Printf("  %sNo page declares a modification date%s\n", colorGray, colorReset)
+		return
+	}
+
+	barWidth := 40
+	fill, empty := barchart.Chars(r.ASCII)
+	for _, b := range r.AgeBuckets {
+		pct := float64(b.Count) / float64(dated)
+		bar := int(pct * float64(barWidth))
+		fmt.Printf("  %-12s %s%s%s%s%s %d (%.0f%%)\n",
+			b.Label, colorCyan, strings.Repeat(fill, bar), colorGray, strings.Repeat(empty, barWidth-bar), colorReset,
+			b.Count, pct*100)
+	}
+	if r.Undated > 0 {
+		fmt.Printf("  %s%d page(s) without a usable date%s\n", colorGray, r.Undated, colorReset)
+	}
+
+	stale := r.StaleCount()
+	if stale == 0 {
+		fmt.Println()
+		fmt.Printf("  %s✓ Every dated page was modified within a year%s\n", colorGreen, colorReset)
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("  %s%d page(s) not modified in over a year%s\n", colorYellow, stale, colorReset)
+	fmt.Println()
+
+	displayCount := limit
+	if displayCount <= 0 || displayCount > stale {
+		displayCount = stale
+	}
+	for _, page := range r.Stalest[:displayCount] {
+		url := page.URL
+		if len(url) > 60 {
+			url = url[:57] + "..."
+		}
+		fmt.Printf("  %s%s%s  %s\n", colorYellow, page.Modified.Format("2006-01-02"), colorReset, url)
+	}
+	if stale > displayCount {
+		fmt.Printf("\n%s... and %d more pages%s\n", colorGray, stale-displayCount, colorReset)
+	}
+}
+
+// printNotLinked lists the sitemap URLs the crawl never reached, the pages
+// a content team believes are published but visitors can't navigate to
+func (r *MetaResult) printNotLinked(limit int) {
+	fmt.Println()
+	fmt.Printf("%s%s=== Sitemap Coverage ===%s\n", colorBold, colorCyan, colorReset)
+	for _, err := range r.SitemapErrors {
+		fmt.Printf("%sSitemap not read: %s%s\n", colorYellow, err, colorReset)
+	}
+	fmt.Printf("Internal URLs in sitemaps: %s%d%s\n", colorGreen, r.SitemapURLs, colorReset)
+	fmt.Println()
+
+	if len(r.NotLinked) == 0 {
+		if r.SitemapURLs > 0 {
+			fmt.Printf("  %s✓ Every sitemap URL is reached by internal links%s\n", colorGreen, colorReset)
+		}
+		return
+	}
+
+	fmt.Printf("  %s%d URL(s) in sitemaps but not internally linked; their meta was not analyzed%s\n", colorYellow, len(r.NotLinked), colorReset)
+	fmt.Println()
+
+	displayCount := limit
+	if displayCount <= 0 || displayCount > len(r.NotLinked) {
+		displayCount = len(r.NotLinked)
+	}
+	for _, u := range r.NotLinked[:displayCount] {
+		fmt.Printf("  %s\n", u)
+	}
+	if len(r.NotLinked) > displayCount {
+		fmt.Printf("\n%s... and %d more pages%s\n", colorGray, len(r.NotLinked)-displayCount, colorReset)
+	}
+}
+
 func (r *MetaResult) printPageDetail(page PageMeta, showExcess bool) {
 	url := page.URL
 	if len(url) > 70 {
@@ -368,26 +620,32 @@ func (r *MetaResult) printPageDetail(page PageMeta, showExcess bool) {
 	}
 
 	// Length indicator
+	recommended, byLanguage := page.DescRange()
 	lengthColor := colorGreen
-	if page.DescLength > DescMaxLength {
+	if page.DescLength > recommended.Max {
 		lengthColor = colorRed
-	} else if page.DescLength < DescMinLength {
+	} else if page.DescLength < recommended.Min {
 		lengthColor = colorYellow
 	}
 
-	fmt.Printf("  %s[%d chars]%s %s\n", lengthColor, page.DescLength, colorReset, url)
+	lang := ""
+	if byLanguage {
+		lang = fmt.Sprintf(" %slang=%s, %s recommended%s", colorGray, page.Lang, recommended, colorReset)
+	}
+	fmt.Printf("  %s[%d chars]%s %s%s\n", lengthColor, page.DescLength, colorReset, url, lang)
 
 	// Show description with truncation point
 	if page.Description != "" {
 		desc := page.Description
-		if showExcess && len(desc) > DescMaxLength {
-			// Show where it gets cut
-			visible := desc[:DescMaxLength]
-			excess := desc[DescMaxLength:]
+		if showExcess && page.DescLength > recommended.Max {
+			// Show where it gets cut, in characters rather than bytes
+			runes := []rune(desc)
+			visible := string(runes[:recommended.Max])
+			excess := string(runes[recommended.Max:])
 			fmt.Printf("    %s\"%s%s%s%s\"%s\n",
 				colorGray, visible, colorRed, excess, colorGray, colorReset)
 			fmt.Printf("    %s↑ Cut at %d characters (+%d excess)%s\n",
-				colorRed, DescMaxLength, len(excess), colorReset)
+				colorRed, recommended.Max, len(runes)-recommended.Max, colorReset)
 		} else {
 			if len(desc) > 80 {
 				desc = desc[:77] + "..."
@@ -398,6 +656,35 @@ func (r *MetaResult) printPageDetail(page PageMeta, showExcess bool) {
 	fmt.Println()
 }
 
+// languageLengths names the languages with their own recommended
+// description length, such as "ja, ko, zh: 35-80 characters"
+func languageLengths() string {
+	var ranges []serp.Range
+	langsOf := make(map[serp.Range][]string)
+	for _, lang := range serp.Languages() {
+		_, description, _ := serp.LanguageLengths(lang)
+		if _, ok := langsOf[description]; !ok {
+			ranges = append(ranges, description)
+		}
+		langsOf[description] = append(langsOf[description], lang)
+	}
+
+	parts := make([]string, 0, len(ranges))
+	for _, rng := range ranges {
+		parts = append(parts, fmt.Sprintf("%s: %s characters", strings.Join(langsOf[rng], ", "), rng))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// languageNote qualifies the default limits when some pages were judged
+// by their language's
+func (r *MetaResult) languageNote() string {
+	if r.LanguagePages == 0 {
+		return ""
+	}
+	return " (" + languageLengths() + ")"
+}
+
 func (r *MetaResult) printRecommendations() {
 	issues := r.TooLongCount + r.MissingCount
 	if issues == 0 && r.DuplicateCount == 0 {
@@ -411,7 +698,7 @@ func (r *MetaResult) printRecommendations() {
 
 	if r.TooLongCount > 0 {
 		fmt.Printf("\n  %s1. Too long descriptions (%d)%s\n", colorYellow, r.TooLongCount, colorReset)
-		fmt.Printf("     Shorten them to maximum %d characters.\n", DescMaxLength)
+		fmt.Printf("     Shorten them to maximum %d characters%s.\n", DescMaxLength, r.languageNote())
 		fmt.Printf("     Google truncates longer descriptions with \"...\"\n")
 	}
 
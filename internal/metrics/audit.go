@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/audit"
+)
+
+// Audit returns the metrics of a site audit
+func Audit(r *audit.AuditResult) *Set {
+	s := NewSet("siteaudit", r.URL)
+
+	// Categories no phase run measured have no score to export
+	if r.HasScore() {
+		s.Gauge("overall_score", "Overall audit score, 0-100", float64(r.OverallScore))
+	}
+	for _, score := range []struct {
+		name, help string
+		category   audit.Category
+		value      int
+	}{
+		{"broken_links_score", "Broken links score, 0-100", audit.CategoryBrokenLinks, r.BrokenLinksScore},
+		{"seo_score", "SEO score, 0-100", audit.CategorySEO, r.SEOScore},
+		{"performance_score", "Performance score, 0-100", audit.CategoryPerformance, r.PerformanceScore},
+		{"architecture_score", "Architecture score, 0-100", audit.CategoryArchitecture, r.ArchitectureScore},
+	} {
+		if r.Scored(score.category) {
+			s.Gauge(score.name, score.help, float64(score.value))
+		}
+	}
+
+	s.Gauge("pages", "Pages crawled", float64(r.TotalPages))
+	s.Gauge("links", "Links found", float64(r.TotalLinks))
+	s.Gauge("broken_links", "Broken links found", float64(r.BrokenLinks))
+	s.Gauge("indexable_pages", "Crawled pages without noindex", float64(r.CrawlBudget.IndexablePages))
+	s.Gauge("noindex_pages", "Crawled pages with noindex", float64(r.NoIndexPages))
+	s.Gauge("missing_canonicals", "Pages without canonical tag", float64(r.MissingCanonical))
+	s.Gauge("orphan_pages", "Pages no internal link reaches", float64(r.OrphanPages))
+	s.Gauge("slow_pages", "Pages answering in over 1s", float64(r.SlowPages))
+	s.Gauge("avg_latency_seconds", "Average page latency", r.AvgLatency.Seconds())
+	s.Gauge("max_latency_seconds", "Slowest page latency", r.MaxLatency.Seconds())
+
+	// Every severity is present, at 0 when no issue has it, so alerts
+	// don't see the series vanish
+	counts := make(map[audit.Severity]int)
+	for _, issue := range r.Issues {
+		counts[issue.Severity]++
+	}
+	for _, severity := range []audit.Severity{audit.SeverityCritical, audit.SeverityHigh, audit.SeverityMedium, audit.SeverityLow, audit.SeverityInfo} {
+		s.GaugeWith("issues", "Issues found by severity", "severity", strings.ToLower(severity.String()), float64(counts[severity]))
+	}
+
+	s.Gauge("requests", "HTTP requests sent", float64(r.CrawlStats.Requests))
+	s.Gauge("transferred_bytes", "Response bytes received", float64(r.CrawlStats.Bytes))
+	s.Gauge("duration_seconds", "Run duration", r.Duration.Seconds())
+	s.Gauge("last_run_timestamp_seconds", "End of the run, as a Unix time", float64(r.EndTime.Unix()))
+	return s
+}
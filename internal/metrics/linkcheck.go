@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/ngonzalez/web-tools/internal/crawler"
+)
+
+// LinkCheck returns the metrics of a link check
+func LinkCheck(r *crawler.CrawlResult) *Set {
+	s := NewSet("linkchecker", r.StartURL)
+
+	s.Gauge("pages", "Pages crawled", float64(r.TotalVisited))
+	s.Gauge("broken_links", "Broken links found", float64(len(r.BrokenLinks)))
+	for _, group := range r.BrokenByStatus() {
+		s.GaugeWith("broken_links_by_status", "Broken links by status, connection-error for no answer", "status", group.Status, float64(len(group.Links)))
+	}
+	s.Gauge("broken_links_via_redirect", "Broken links reached through redirects", float64(r.CountViaRedirect()))
+	s.Gauge("broken_targets", "Distinct broken URLs", float64(len(r.BrokenTargets)))
+	s.Gauge("failed_fast", "1 when the crawl stopped at the first broken link", boolValue(r.FailedFast))
+	s.Gauge("restricted_links", "Links answering an accepted status", float64(len(r.Restricted)))
+	s.Gauge("external_redirects", "Internal links redirecting to another site", float64(len(r.ExternalRedirects)))
+	s.Gauge("open_redirects", "Suspected open redirects", float64(len(r.OpenRedirects)))
+	s.Gauge("insecure_forms", "Forms sending their data over plain HTTP", float64(len(r.InsecureForms)))
+	for _, h := range r.Headers {
+		s.GaugeWith("pages_missing_header", "HTML pages not sending an audited response header", "header", h.Header, float64(len(h.Missing)))
+	}
+
+	s.Gauge("requests", "HTTP requests sent", float64(r.CrawlStats.Requests))
+	s.Gauge("transferred_bytes", "Response bytes received", float64(r.CrawlStats.Bytes))
+	s.Gauge("duration_seconds", "Run duration", r.CrawlStats.Duration.Seconds())
+	s.Gauge("last_run_timestamp_seconds", "End of the run, as a Unix time", float64(time.Now().Unix()))
+	return s
+}
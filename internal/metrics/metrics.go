@@ -0,0 +1,116 @@
+// Package metrics writes results in the Prometheus text exposition format,
+// for scheduled runs whose output a monitoring stack scrapes, e.g. through
+// the node exporter's textfile collector.
+//
+// Every metric is a gauge named web_audit_*, labelled with the tool and the
+// start URL so several tools and sites can share one collector directory.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Prefix opens every metric name
+const Prefix = "web_audit_"
+
+// sample is one value of a metric, with its extra label if any
+type sample struct {
+	label string // Extra label name, empty for none
+	value string // Extra label value
+	v     float64
+}
+
+type metric struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+// Set is the metrics of one run
+type Set struct {
+	tool     string
+	startURL string
+	metrics  []*metric
+	byName   map[string]*metric
+}
+
+// NewSet creates an empty set for a run of tool from startURL
+func NewSet(tool, startURL string) *Set {
+	return &Set{tool: tool, startURL: startURL, byName: make(map[string]*metric)}
+}
+
+// Gauge adds a metric, named without the prefix
+func (s *Set) Gauge(name, help string, value float64) {
+	s.GaugeWith(name, help, "", "", value)
+}
+
+// GaugeWith adds a sample of a metric carrying one more label, such as
+// status="404"; samples of the same metric share its help line
+func (s *Set) GaugeWith(name, help, label, labelValue string, value float64) {
+	m := s.byName[name]
+	if m == nil {
+		m = &metric{name: Prefix + name, help: help}
+		s.byName[name] = m
+		s.metrics = append(s.metrics, m)
+	}
+	m.samples = append(m.samples, sample{label: label, value: labelValue, v: value})
+}
+
+// Write writes the set in the text exposition format
+func (s *Set) Write(w io.Writer) error {
+	var sb strings.Builder
+	for _, m := range s.metrics {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(&sb, "# TYPE %s gauge\n", m.name)
+		for _, smp := range m.samples {
+			labels := fmt.Sprintf(`tool="%s",start_url="%s"`, escape(s.tool), escape(s.startURL))
+			if smp.label != "" {
+				labels += fmt.Sprintf(`,%s="%s"`, smp.label, escape(smp.value))
+			}
+			fmt.Fprintf(&sb, "%s{%s} %s\n", m.name, labels, strconv.FormatFloat(smp.v, 'f', -1, 64))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteFile writes the set to a file through a temporary file renamed in
+// place, so a scrape never reads a half-written file
+func (s *Set) WriteFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := s.Write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp makes the file private; scrapers run as another user
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// escape escapes a label value: backslash, double quote and newline
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// boolValue turns a flag into 1 or 0
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
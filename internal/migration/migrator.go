@@ -6,30 +6,46 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 	"golang.org/x/net/html"
 )
 
 // Config holds the migration checker configuration
 type Config struct {
-	Concurrency int
-	Timeout     time.Duration
-	MaxDepth    int // 0 means unlimited
-	Verbose     bool
-	UseHEAD     bool // Use HEAD requests instead of GET for checking
+	Concurrency         int
+	Timeout             time.Duration
+	MaxDepth            int // 0 means unlimited
+	Verbose             bool
+	Verbosity           int           // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Info
+	UseHEAD             bool          // Use HEAD requests instead of GET for checking
+	TreatSchemesAsSame  bool          // Collapse http:// and https:// URLs of the same page
+	KeepHTTP            bool          // Crawl http:// URLs as is even on a site enforcing HTTPS
+	Delay               time.Duration // Minimum pause between two requests
+	CrawlConcurrency    int           // Workers crawling the old site, 0 uses Concurrency
+	CheckConcurrency    int           // Workers checking URLs on the new site, 0 uses Concurrency
+	MaxIdleConnsPerHost int           // Idle connections kept per host, 0 matches the busier phase
+	MaxRedirects        int           // Redirects followed per request, 0 = none (the 3xx is kept as the page, its target not crawled), -1 = up to httppool.MaxRedirectsCap
+	HTMLContentTypes    []string      // Extra media types parsed as HTML, besides contenttype.HTML
 }
 
 // DefaultConfig returns a default configuration
 func DefaultConfig() Config {
 	return Config{
-		Concurrency: 10,
-		Timeout:     10 * time.Second,
-		MaxDepth:    0,
-		Verbose:     false,
-		UseHEAD:     true,
+		Concurrency:  10,
+		Timeout:      10 * time.Second,
+		MaxDepth:     0,
+		Verbose:      false,
+		UseHEAD:      true,
+		MaxRedirects: httppool.DefaultMaxRedirects,
 	}
 }
 
@@ -38,9 +54,9 @@ type Migrator struct {
 	config       Config
 	oldBaseURL   *url.URL
 	newBaseURL   *url.URL
-	visited      map[string]bool
-	visitedMu    sync.RWMutex
+	totalCrawled int
 	collectedURLs []string
+	collected    map[string]bool // Keys of collectedURLs, to skip duplicates
 	collectedMu  sync.Mutex
 	lostLinks    []LostLink
 	lostMu       sync.Mutex
@@ -48,34 +64,32 @@ type Migrator struct {
 	validMu      sync.Mutex
 	client       *http.Client
 	semaphore    chan struct{}
+	stats        crawlstats.Counter
+	engine       *crawl.Engine // Keys collected URLs the way the crawl dedupes them
 }
 
 // New creates a new Migrator instance
 func New(config Config) *Migrator {
+	config.Verbosity = verbosity.Effective(config.Verbosity, config.Verbose)
+	if config.CrawlConcurrency <= 0 {
+		config.CrawlConcurrency = config.Concurrency
+	}
+	if config.CheckConcurrency <= 0 {
+		config.CheckConcurrency = config.Concurrency
+	}
 	return &Migrator{
 		config:        config,
-		visited:       make(map[string]bool),
 		collectedURLs: make([]string, 0),
-		semaphore:     make(chan struct{}, config.Concurrency),
+		collected:     make(map[string]bool),
+		semaphore:     make(chan struct{}, config.CheckConcurrency),
 		client: &http.Client{
-			Timeout: config.Timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
+			Timeout:   config.Timeout,
+			Transport: httppool.New(max(config.CrawlConcurrency, config.CheckConcurrency), config.MaxIdleConnsPerHost),
+			CheckRedirect: httppool.CheckRedirect(config.MaxRedirects, httppool.ErrTooManyRedirects),
 		},
 	}
 }
 
-// urlTask represents a URL to be crawled with its metadata
-type urlTask struct {
-	url       string
-	sourceURL string
-	depth     int
-}
-
 // Check performs the migration check between old and new site
 func (m *Migrator) Check(oldSiteURL, newSiteURL string) (*MigrationResult, error) {
 	// Parse old site URL
@@ -97,10 +111,11 @@ func (m *Migrator) Check(oldSiteURL, newSiteURL string) (*MigrationResult, error
 		return nil, fmt.Errorf("new site URL must use http or https scheme")
 	}
 	m.newBaseURL = newParsed
+	m.stats.Start()
 
 	// Phase 1: Crawl old site to collect all URLs
-	if m.config.Verbose {
-		fmt.Printf("\n%sPhase 1: Crawling old site...%s\n\n", colorCyan, colorReset)
+	if m.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "\n%sPhase 1: Crawling old site...%s\n\n", colorCyan, colorReset)
 	}
 	err = m.crawlOldSite()
 	if err != nil {
@@ -108,15 +123,11 @@ func (m *Migrator) Check(oldSiteURL, newSiteURL string) (*MigrationResult, error
 	}
 
 	// Phase 2: Check each URL on new site
-	if m.config.Verbose {
-		fmt.Printf("\n%sPhase 2: Checking URLs on new site...%s\n\n", colorCyan, colorReset)
+	if m.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "\n%sPhase 2: Checking URLs on new site...%s\n\n", colorCyan, colorReset)
 	}
 	m.checkNewSite()
 
-	m.visitedMu.RLock()
-	totalCrawled := len(m.visited)
-	m.visitedMu.RUnlock()
-
 	m.collectedMu.Lock()
 	totalChecked := len(m.collectedURLs)
 	m.collectedMu.Unlock()
@@ -128,10 +139,11 @@ func (m *Migrator) Check(oldSiteURL, newSiteURL string) (*MigrationResult, error
 	return &MigrationResult{
 		OldSiteURL:   oldSiteURL,
 		NewSiteURL:   newSiteURL,
-		TotalCrawled: totalCrawled,
+		TotalCrawled: m.totalCrawled,
 		TotalChecked: totalChecked,
 		LostLinks:    m.lostLinks,
 		ValidLinks:   validLinks,
+		CrawlStats:   m.stats.Snapshot(),
 	}, nil
 }
 
@@ -139,136 +151,70 @@ func (m *Migrator) Check(oldSiteURL, newSiteURL string) (*MigrationResult, error
 func (m *Migrator) crawlOldSite() error {
 	startURL := m.oldBaseURL.String()
 
-	// Channel for URLs to process
-	tasks := make(chan urlTask, 1000)
-
-	// Start with the initial URL
-	m.markVisited(startURL)
+	m.engine = crawl.New(crawl.Config{
+		Concurrency:        m.config.CrawlConcurrency,
+		MaxDepth:           m.config.MaxDepth,
+		Delay:              m.config.Delay,
+		TreatSchemesAsSame: m.config.TreatSchemesAsSame,
+		Rewrite:            httppool.HTTPSRewrite(m.config.KeepHTTP, m.client.Transport, m.config.Timeout, startURL),
+	}, m.processCrawlURL)
 	m.addCollectedURL(startURL)
-	tasks <- urlTask{url: startURL, sourceURL: "", depth: 0}
-
-	// Context for cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Worker pool
-	for i := 0; i < m.config.Concurrency; i++ {
-		go m.crawlWorker(ctx, tasks)
-	}
-
-	// Monitor completion
-	done := make(chan struct{})
-	go func() {
-		for {
-			time.Sleep(100 * time.Millisecond)
-			m.visitedMu.RLock()
-			visitedCount := len(m.visited)
-			m.visitedMu.RUnlock()
-
-			// Check if there are pending tasks
-			if len(tasks) == 0 && len(m.semaphore) == 0 {
-				// Give a moment for any in-flight work
-				time.Sleep(500 * time.Millisecond)
-				if len(tasks) == 0 && len(m.semaphore) == 0 {
-					close(done)
-					return
-				}
-			}
-
-			// Safety timeout
-			if visitedCount > 10000 {
-				close(done)
-				return
-			}
-		}
-	}()
-
-	<-done
-	cancel()
-	close(tasks)
+	m.totalCrawled = m.engine.Run(context.Background(), crawl.Task{URL: startURL})
 
 	return nil
 }
 
-// crawlWorker processes URLs from the task channel for crawling
-func (m *Migrator) crawlWorker(ctx context.Context, tasks chan urlTask) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case task, ok := <-tasks:
-			if !ok {
-				return
-			}
-			m.processCrawlURL(ctx, task, tasks)
-		}
-	}
-}
-
-// processCrawlURL fetches and processes a single URL during crawling
-func (m *Migrator) processCrawlURL(ctx context.Context, task urlTask, tasks chan urlTask) {
-	// Acquire semaphore
-	select {
-	case m.semaphore <- struct{}{}:
-		defer func() { <-m.semaphore }()
-	case <-ctx.Done():
-		return
-	}
-
-	// Check depth limit
-	if m.config.MaxDepth > 0 && task.depth > m.config.MaxDepth {
-		return
-	}
-
+// processCrawlURL fetches a page of the old site and returns its internal links
+func (m *Migrator) processCrawlURL(ctx context.Context, task crawl.Task) []crawl.Task {
 	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", task.url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", task.URL, nil)
 	if err != nil {
-		return
+		return nil
 	}
 
 	req.Header.Set("User-Agent", "LinkMigration/1.0")
 
+	start := time.Now()
 	resp, err := m.client.Do(req)
+	m.stats.AddRequest()
 	if err != nil {
 		if ctx.Err() != nil {
-			return
+			return nil
 		}
-		return
+		return nil
 	}
 	defer resp.Body.Close()
 
-	if m.config.Verbose {
-		fmt.Printf("  [%d] %s\n", resp.StatusCode, truncateURL(task.url, 70))
+	if verbosity.ShowStatus(m.config.Verbosity, resp.StatusCode) {
+		fmt.Fprintf(os.Stderr, "  [%d] %s\n", resp.StatusCode, truncateURL(task.URL, 70))
+	}
+	if m.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(task.URL, time.Since(start))
 	}
 
 	// Skip error pages
 	if resp.StatusCode >= 400 {
-		return
+		return nil
 	}
 
 	// Only parse HTML content for links
 	contentType := resp.Header.Get("Content-Type")
-	if !isHTML(contentType) {
-		return
+	if !contenttype.IsHTML(contentType, m.config.HTMLContentTypes) {
+		return nil
 	}
 
 	// Parse and extract links
-	links := extractLinks(resp.Body, m.oldBaseURL)
+	links := extractLinks(m.stats.Body(resp.Body), m.oldBaseURL)
 
-	// Queue new links
+	// Collect every internal link, even past the depth limit, and follow them
+	var next []crawl.Task
 	for _, link := range links {
-		if m.shouldVisit(link) {
-			m.markVisited(link)
+		if isSameDomain(link, m.oldBaseURL) {
 			m.addCollectedURL(link)
-
-			// Try to send task, skip if channel is full
-			select {
-			case tasks <- urlTask{url: link, sourceURL: task.url, depth: task.depth + 1}:
-			default:
-				// Channel full, skip this link
-			}
+			next = append(next, crawl.Task{URL: link})
 		}
 	}
+	return next
 }
 
 // checkNewSite checks all collected URLs on the new site
@@ -293,7 +239,7 @@ func (m *Migrator) checkNewSite() {
 
 	// Worker pool for checking
 	var wg sync.WaitGroup
-	for i := 0; i < m.config.Concurrency; i++ {
+	for i := 0; i < m.config.CheckConcurrency; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -341,7 +287,7 @@ func (m *Migrator) checkURL(ctx context.Context, oldURL string) {
 	req, err := http.NewRequestWithContext(ctx, method, newURL, nil)
 	if err != nil {
 		m.addLostLink(oldURL, newURL, 0, err.Error())
-		if m.config.Verbose {
+		if m.config.Verbosity >= verbosity.Warn {
 			PrintError(oldURL, newURL, err.Error())
 		}
 		return
@@ -349,13 +295,15 @@ func (m *Migrator) checkURL(ctx context.Context, oldURL string) {
 
 	req.Header.Set("User-Agent", "LinkMigration/1.0")
 
+	start := time.Now()
 	resp, err := m.client.Do(req)
+	m.stats.AddRequest()
 	if err != nil {
 		if ctx.Err() != nil {
 			return
 		}
 		m.addLostLink(oldURL, newURL, 0, err.Error())
-		if m.config.Verbose {
+		if m.config.Verbosity >= verbosity.Warn {
 			PrintError(oldURL, newURL, err.Error())
 		}
 		return
@@ -365,17 +313,20 @@ func (m *Migrator) checkURL(ctx context.Context, oldURL string) {
 	// Check if the URL is valid on new site
 	if resp.StatusCode >= 400 {
 		m.addLostLink(oldURL, newURL, resp.StatusCode, "")
-		if m.config.Verbose {
+		if m.config.Verbosity >= verbosity.Warn {
 			PrintProgress(oldURL, newURL, resp.StatusCode, true)
 		}
 	} else {
 		m.validMu.Lock()
 		m.validCount++
 		m.validMu.Unlock()
-		if m.config.Verbose {
+		if m.config.Verbosity >= verbosity.Info {
 			PrintProgress(oldURL, newURL, resp.StatusCode, false)
 		}
 	}
+	if m.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(newURL, time.Since(start))
+	}
 }
 
 // mapURL maps a URL from the old site to the new site
@@ -393,33 +344,14 @@ func (m *Migrator) mapURL(oldURL string) string {
 	return parsed.String()
 }
 
-// markVisited marks a URL as visited (thread-safe)
-func (m *Migrator) markVisited(u string) {
-	m.visitedMu.Lock()
-	m.visited[u] = true
-	m.visitedMu.Unlock()
-}
-
-// addCollectedURL adds a URL to the collected list (thread-safe)
+// addCollectedURL adds a URL to the collected list once (thread-safe)
 func (m *Migrator) addCollectedURL(u string) {
 	m.collectedMu.Lock()
-	m.collectedURLs = append(m.collectedURLs, u)
-	m.collectedMu.Unlock()
-}
-
-// shouldVisit checks if a URL should be visited
-func (m *Migrator) shouldVisit(targetURL string) bool {
-	// Check if it's an internal link
-	if !isSameDomain(targetURL, m.oldBaseURL) {
-		return false
+	if key := m.engine.Key(u); !m.collected[key] {
+		m.collected[key] = true
+		m.collectedURLs = append(m.collectedURLs, u)
 	}
-
-	// Check if already visited
-	m.visitedMu.RLock()
-	visited := m.visited[targetURL]
-	m.visitedMu.RUnlock()
-
-	return !visited
+	m.collectedMu.Unlock()
 }
 
 // addLostLink adds a lost link to the results (thread-safe)
@@ -511,9 +443,3 @@ func isSameDomain(targetURL string, baseURL *url.URL) bool {
 
 	return parsed.Host == baseURL.Host
 }
-
-// isHTML checks if the content type indicates HTML content
-func isHTML(contentType string) bool {
-	return strings.Contains(contentType, "text/html") ||
-		strings.Contains(contentType, "application/xhtml+xml")
-}
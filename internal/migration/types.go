@@ -2,7 +2,10 @@ package migration
 
 import (
 	"fmt"
+	"os"
 	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
 )
 
 // LostLink represents a URL that exists on the old site but is not available on the new site
@@ -21,6 +24,13 @@ type MigrationResult struct {
 	TotalChecked int
 	LostLinks    []LostLink
 	ValidLinks   int
+	CrawlStats   crawlstats.Stats
+}
+
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *MigrationResult) SummaryLine() string {
+	return fmt.Sprintf("SUMMARY tool=linkmigration crawled=%d checked=%d valid=%d lost=%d",
+		r.TotalCrawled, r.TotalChecked, r.ValidLinks, len(r.LostLinks))
 }
 
 // ANSI color codes
@@ -37,6 +47,9 @@ const (
 
 // PrintSummary displays the migration check results in a formatted way
 func (r *MigrationResult) PrintSummary() {
+	// Crawl stats always close the summary, whichever branch returns
+	defer r.CrawlStats.Print()
+
 	fmt.Println()
 	fmt.Printf("%s%s=== Migration Check Summary ===%s\n", colorBold, colorCyan, colorReset)
 	fmt.Printf("Old site: %s%s%s\n", colorBlue, r.OldSiteURL, colorReset)
@@ -119,13 +132,13 @@ func PrintProgress(oldURL, newURL string, statusCode int, isLost bool) {
 	displayOld := truncateURL(oldURL, 60)
 	displayNew := truncateURL(newURL, 60)
 
-	fmt.Printf("%s[%s]%s %s → %s\n", statusColor, status, colorReset, displayOld, displayNew)
+	fmt.Fprintf(os.Stderr, "%s[%s]%s %s → %s\n", statusColor, status, colorReset, displayOld, displayNew)
 }
 
 // PrintError displays an error during URL check
 func PrintError(oldURL, newURL, errMsg string) {
 	displayOld := truncateURL(oldURL, 60)
-	fmt.Printf("%s[ERR]%s %s - %s\n", colorRed, colorReset, displayOld, errMsg)
+	fmt.Fprintf(os.Stderr, "%s[ERR]%s %s - %s\n", colorRed, colorReset, displayOld, errMsg)
 }
 
 func truncateURL(url string, maxLen int) string {
@@ -109,12 +109,16 @@ func ComputeWithResult(graph *Graph, config ComputeConfig, startURL string) *Pag
 		Scores:        make([]PageScore, graph.Size()),
 	}
 
+	// The crawl adds the start page first
+	depths := graph.Depths(0)
+
 	for i, url := range graph.Indices {
 		result.Scores[i] = PageScore{
 			URL:      url,
 			Score:    scores[i],
 			InLinks:  len(graph.InLinks[i]),
 			OutLinks: len(graph.OutLinks[i]),
+			Depth:    depths[i],
 		}
 	}
 
@@ -6,21 +6,45 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/robots"
+	"github.com/ngonzalez/web-tools/internal/sitemap"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
 	"golang.org/x/net/html"
 )
 
 // Config holds crawler configuration
 type Config struct {
-	Concurrency   int
-	Timeout       time.Duration
-	MaxDepth      int
-	Verbose       bool
-	DampingFactor float64
-	MaxIterations int
+	Concurrency         int
+	Timeout             time.Duration
+	MaxDepth            int
+	Verbose             bool
+	Verbosity           int // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Info
+	DampingFactor       float64
+	MaxIterations       int
+	Transport           http.RoundTripper // Optional custom transport, e.g. a shared response cache
+	TreatSchemesAsSame  bool              // Collapse http:// and https:// URLs of the same page
+	KeepHTTP            bool              // Crawl http:// URLs as is even on a site enforcing HTTPS
+	ExcludeNoIndex      bool              // Leave noindex pages out of the graph (they are still crawled)
+	Delay               time.Duration     // Minimum pause between two requests
+	ObeyNoFollow        bool              // Neither follow nor count rel="nofollow" links, like search engines
+	MaxIdleConnsPerHost int               // Idle connections kept per host, 0 matches Concurrency
+	MaxRedirects        int               // Redirects followed per request, 0 = none (the 3xx is kept as the page, its target not crawled), -1 = up to httppool.MaxRedirectsCap
+	HTMLContentTypes    []string          // Extra media types parsed as HTML, besides contenttype.HTML
+	ASCII               bool              // Draw bar graphs with # and - instead of block characters
+	Sitemaps            []string          // Sitemaps or feeds whose URLs seed the crawl, MaxDepth counting from each, and are checked for orphans
+	MinOutLinks         int               // Pages linking to fewer internal pages are under-linked, 0 disables
+	MaxOutLinks         int               // Pages linking to more internal pages are over-linked, 0 disables
 }
 
 // DefaultConfig returns default configuration
@@ -32,45 +56,43 @@ func DefaultConfig() Config {
 		Verbose:       false,
 		DampingFactor: 0.85,
 		MaxIterations: 100,
+		MinOutLinks:   3,
+		MaxOutLinks:   100,
+		MaxRedirects:  httppool.DefaultMaxRedirects,
 	}
 }
 
 // Crawler builds a link graph and computes PageRank
 type Crawler struct {
-	config    Config
-	baseURL   *url.URL
-	visited   map[string]bool
-	visitedMu sync.RWMutex
-	graph     *Graph
-	graphMu   sync.Mutex
-	client    *http.Client
-	semaphore chan struct{}
+	config  Config
+	baseURL *url.URL
+	graph   *Graph
+	noIndex map[string]bool // Pages left out of the graph with ExcludeNoIndex
+	graphMu sync.Mutex
+	client  *http.Client
+	stats   crawlstats.Counter
+	engine  *crawl.Engine // Keys graph nodes the way the crawl dedupes URLs
 }
 
 // New creates a new Crawler
 func New(config Config) *Crawler {
+	config.Verbosity = verbosity.Effective(config.Verbosity, config.Verbose)
+	transport := config.Transport
+	if transport == nil {
+		transport = httppool.New(config.Concurrency, config.MaxIdleConnsPerHost)
+	}
 	return &Crawler{
-		config:    config,
-		visited:   make(map[string]bool),
-		graph:     NewGraph(),
-		semaphore: make(chan struct{}, config.Concurrency),
+		config:  config,
+		graph:   NewGraph(),
+		noIndex: make(map[string]bool),
 		client: &http.Client{
-			Timeout: config.Timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return http.ErrUseLastResponse
-				}
-				return nil
-			},
+			Timeout:       config.Timeout,
+			Transport:     transport,
+			CheckRedirect: httppool.CheckRedirect(config.MaxRedirects, http.ErrUseLastResponse),
 		},
 	}
 }
 
-type urlTask struct {
-	url   string
-	depth int
-}
-
 // Crawl builds the link graph and computes PageRank
 func (c *Crawler) Crawl(startURL string) (*PageRankResult, error) {
 	parsed, err := url.Parse(startURL)
@@ -83,54 +105,33 @@ func (c *Crawler) Crawl(startURL string) (*PageRankResult, error) {
 	}
 
 	c.baseURL = parsed
-
-	// Add start page to graph
+	c.stats.Start()
+	c.engine = crawl.New(crawl.Config{
+		Concurrency:        c.config.Concurrency,
+		MaxDepth:           c.config.MaxDepth,
+		Delay:              c.config.Delay,
+		TreatSchemesAsSame: c.config.TreatSchemesAsSame,
+		ObeyNoFollow:       c.config.ObeyNoFollow,
+		Rewrite:            httppool.HTTPSRewrite(c.config.KeepHTTP, c.client.Transport, c.config.Timeout, startURL),
+	}, c.processURL)
+
+	// Add start page to graph, then the sitemap pages so they are part of
+	// the graph even when no crawled page links to them
+	sitemapURLs, sitemapErrors := c.loadSitemaps()
+	seeds := []crawl.Task{{URL: startURL}}
 	c.graphMu.Lock()
-	c.graph.AddPage(startURL)
-	c.graphMu.Unlock()
-
-	tasks := make(chan urlTask, 1000)
-
-	c.markVisited(startURL)
-	tasks <- urlTask{url: startURL, depth: 0}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	for i := 0; i < c.config.Concurrency; i++ {
-		go c.worker(ctx, tasks)
+	c.graph.AddPage(c.engine.Key(startURL))
+	for _, u := range sitemapURLs {
+		c.graph.AddPage(c.engine.Key(u))
+		seeds = append(seeds, crawl.Task{URL: u})
 	}
+	c.graphMu.Unlock()
 
-	done := make(chan struct{})
-	go func() {
-		for {
-			time.Sleep(100 * time.Millisecond)
-			c.visitedMu.RLock()
-			visitedCount := len(c.visited)
-			c.visitedMu.RUnlock()
-
-			if len(tasks) == 0 && len(c.semaphore) == 0 {
-				time.Sleep(500 * time.Millisecond)
-				if len(tasks) == 0 && len(c.semaphore) == 0 {
-					close(done)
-					return
-				}
-			}
-
-			if visitedCount > 10000 {
-				close(done)
-				return
-			}
-		}
-	}()
-
-	<-done
-	cancel()
-	close(tasks)
+	c.engine.Run(context.Background(), seeds...)
 
 	// Compute PageRank
-	if c.config.Verbose {
-		fmt.Printf("\n%sComputing PageRank...%s\n", colorGray, colorReset)
+	if c.config.Verbosity >= verbosity.Warn {
+		fmt.Fprintf(os.Stderr, "\n%sComputing PageRank...%s\n", colorGray, colorReset)
 	}
 
 	computeConfig := ComputeConfig{
@@ -139,94 +140,150 @@ func (c *Crawler) Crawl(startURL string) (*PageRankResult, error) {
 		Tolerance:     1e-6,
 	}
 
-	result := ComputeWithResult(c.graph, computeConfig, startURL)
+	graph := c.graph
+	if len(c.noIndex) > 0 {
+		graph = graph.Without(c.noIndex)
+	}
+
+	crawlStats := c.stats.Snapshot()
+	result := ComputeWithResult(graph, computeConfig, startURL)
+	result.CrawlStats = crawlStats
+	result.ExcludedNoIndex = len(c.noIndex)
+	result.SkippedNoFollow = c.engine.NoFollowSkipped()
+	result.ASCII = c.config.ASCII
+	result.SitemapURLs = len(sitemapURLs)
+	result.SitemapOrphans = c.sitemapOrphans(result, startURL, sitemapURLs)
+	result.SitemapErrors = sitemapErrors
+	result.MinOutLinks = c.config.MinOutLinks
+	result.MaxOutLinks = c.config.MaxOutLinks
 
 	return result, nil
 }
 
-func (c *Crawler) worker(ctx context.Context, tasks chan urlTask) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case task, ok := <-tasks:
-			if !ok {
-				return
+// loadSitemaps returns the internal URLs listed in the configured sitemaps,
+// normalized like links and without duplicates
+func (c *Crawler) loadSitemaps() ([]string, []string) {
+	var urls, errs []string
+	seen := make(map[string]bool)
+
+	for _, ref := range c.config.Sitemaps {
+		sitemapURL, err := c.baseURL.Parse(ref)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", ref, err))
+			continue
+		}
+
+		entries, err := sitemap.Load(c.client, sitemapURL.String())
+		if err != nil {
+			errs = append(errs, strings.Split(err.Error(), "\n")...)
+		}
+		for _, entry := range entries {
+			u := c.normalizeURL(entry.URL)
+			if u == "" || seen[c.engine.Key(u)] {
+				continue
 			}
-			c.processURL(ctx, task, tasks)
+			seen[c.engine.Key(u)] = true
+			urls = append(urls, u)
 		}
 	}
+	return urls, errs
 }
 
-func (c *Crawler) processURL(ctx context.Context, task urlTask, tasks chan urlTask) {
-	select {
-	case c.semaphore <- struct{}{}:
-		defer func() { <-c.semaphore }()
-	case <-ctx.Done():
-		return
+// sitemapOrphans returns the sitemap URLs no chain of internal links from
+// the start page reaches, sorted. The sitemap seeds them into the crawl, so
+// they are in the graph with whatever they link to, but being reachable only
+// through the sitemap makes them true orphans. Pages left out of the graph
+// as noindex are not reported.
+func (c *Crawler) sitemapOrphans(result *PageRankResult, startURL string, sitemapURLs []string) []string {
+	depths := make(map[string]int, len(result.Scores))
+	for _, s := range result.Scores {
+		depths[s.URL] = s.Depth
 	}
 
-	if c.config.MaxDepth > 0 && task.depth > c.config.MaxDepth {
-		return
+	var orphans []string
+	for _, u := range sitemapURLs {
+		k := c.engine.Key(u)
+		if depth, ok := depths[k]; ok && depth == -1 && k != c.engine.Key(startURL) {
+			orphans = append(orphans, k)
+		}
 	}
+	sort.Strings(orphans)
+	return orphans
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", task.url, nil)
+func (c *Crawler) processURL(ctx context.Context, task crawl.Task) []crawl.Task {
+	req, err := http.NewRequestWithContext(ctx, "GET", task.URL, nil)
 	if err != nil {
-		return
+		return nil
 	}
 
 	req.Header.Set("User-Agent", "PageRankBot/1.0")
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	c.stats.AddRequest()
 	if err != nil {
 		if ctx.Err() != nil {
-			return
+			return nil
 		}
-		if c.config.Verbose {
-			printError(task.url, err.Error(), task.depth)
+		if c.config.Verbosity >= verbosity.Warn {
+			printError(task.URL, err.Error(), task.Depth)
 		}
-		return
+		return nil
 	}
 	defer resp.Body.Close()
 
-	if c.config.Verbose {
-		printProgress(task.url, resp.StatusCode, task.depth)
+	if verbosity.ShowStatus(c.config.Verbosity, resp.StatusCode) {
+		printProgress(task.URL, resp.StatusCode, task.Depth)
+	}
+	if c.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(task.URL, time.Since(start))
 	}
 
 	if resp.StatusCode >= 400 {
-		return
+		return nil
 	}
 
 	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/html") {
-		return
+	if !contenttype.IsHTML(contentType, c.config.HTMLContentTypes) {
+		return nil
 	}
 
 	// Extract links
-	links := c.extractLinks(resp.Body)
+	links, metaNoIndex := c.extractLinks(c.stats.Body(resp.Body))
+	noIndex := metaNoIndex || robots.IsNoIndex(resp.Header.Values("X-Robots-Tag")...)
 
-	// Add links to graph
+	// Add links to graph. Excluded pages are dropped once the crawl is over,
+	// since links to them may already be in the graph; the start page stays
+	// as the root. Sitemap seeds are at depth 0 too, so the start page is
+	// told apart by URL.
 	c.graphMu.Lock()
+	if c.config.ExcludeNoIndex && noIndex && c.engine.Key(task.URL) != c.engine.Key(c.baseURL.String()) {
+		c.noIndex[c.engine.Key(task.URL)] = true
+	}
 	for _, link := range links {
-		c.graph.AddLink(task.url, link)
+		// Nofollow links pass no PageRank when the config obeys them
+		if c.config.ObeyNoFollow && link.NoFollow {
+			continue
+		}
+		c.graph.AddLink(c.engine.Key(task.URL), c.engine.Key(link.URL))
 	}
 	c.graphMu.Unlock()
 
-	// Queue new pages
+	// Follow every page found
+	var next []crawl.Task
 	for _, link := range links {
-		if c.shouldVisit(link) {
-			c.markVisited(link)
-			select {
-			case tasks <- urlTask{url: link, depth: task.depth + 1}:
-			default:
-			}
-		}
+		next = append(next, crawl.Task{URL: link.URL, NoFollow: link.NoFollow})
 	}
+	return next
 }
 
-func (c *Crawler) extractLinks(body io.Reader) []string {
-	var links []string
-	seen := make(map[string]bool)
+// extractLinks returns the internal links of a page and whether it has a
+// meta robots noindex
+func (c *Crawler) extractLinks(body io.Reader) ([]pageLink, bool) {
+	var links []pageLink
+	seen := make(map[string]int) // URL -> index in links
+	noIndex := false
 
 	tokenizer := html.NewTokenizer(body)
 
@@ -235,18 +292,29 @@ func (c *Crawler) extractLinks(body io.Reader) []string {
 
 		switch tokenType {
 		case html.ErrorToken:
-			return links
+			return links, noIndex
 
 		case html.StartTagToken, html.SelfClosingTagToken:
 			token := tokenizer.Token()
 
+			if token.Data == "meta" && isNoIndexMeta(token) {
+				noIndex = true
+			}
+
 			if token.Data == "a" {
+				noFollow := htmlattr.IsNoFollow(token)
 				for _, attr := range token.Attr {
 					if attr.Key == "href" {
 						link := c.normalizeURL(attr.Val)
-						if link != "" && !seen[link] {
-							seen[link] = true
-							links = append(links, link)
+						if link == "" {
+							break
+						}
+						// A page linking to a URL both ways does follow it
+						if i, ok := seen[link]; ok {
+							links[i].NoFollow = links[i].NoFollow && noFollow
+						} else {
+							seen[link] = len(links)
+							links = append(links, pageLink{URL: link, NoFollow: noFollow})
 						}
 						break
 					}
@@ -256,6 +324,25 @@ func (c *Crawler) extractLinks(body io.Reader) []string {
 	}
 }
 
+// pageLink is an internal link found on a page
+type pageLink struct {
+	URL      string
+	NoFollow bool // Every link to URL on the page carries rel="nofollow"
+}
+
+func isNoIndexMeta(token html.Token) bool {
+	var name, content string
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "name":
+			name = strings.ToLower(attr.Val)
+		case "content":
+			content = attr.Val
+		}
+	}
+	return name == "robots" && robots.IsNoIndex(content)
+}
+
 func (c *Crawler) normalizeURL(href string) string {
 	href = strings.TrimSpace(href)
 	if href == "" {
@@ -290,19 +377,6 @@ func (c *Crawler) normalizeURL(href string) string {
 	return resolved.String()
 }
 
-func (c *Crawler) markVisited(url string) {
-	c.visitedMu.Lock()
-	c.visited[url] = true
-	c.visitedMu.Unlock()
-}
-
-func (c *Crawler) shouldVisit(targetURL string) bool {
-	c.visitedMu.RLock()
-	visited := c.visited[targetURL]
-	c.visitedMu.RUnlock()
-	return !visited
-}
-
 func printProgress(url string, statusCode int, depth int) {
 	var statusColor string
 	switch {
@@ -317,10 +391,10 @@ func printProgress(url string, statusCode int, depth int) {
 	}
 
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[%d]%s %s\n", indent, statusColor, statusCode, colorReset, url)
+	fmt.Fprintf(os.Stderr, "%s%s[%d]%s %s\n", indent, statusColor, statusCode, colorReset, url)
 }
 
 func printError(url string, errMsg string, depth int) {
 	indent := strings.Repeat("  ", depth)
-	fmt.Printf("%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, errMsg)
+	fmt.Fprintf(os.Stderr, "%s%s[ERR]%s %s - %s\n", indent, colorRed, colorReset, url, errMsg)
 }
@@ -5,6 +5,9 @@ import (
 	"math"
 	"sort"
 	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/barchart"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
 )
 
 // PageScore represents a page and its PageRank score
@@ -13,6 +16,7 @@ type PageScore struct {
 	Score       float64
 	InLinks     int // Number of incoming links
 	OutLinks    int // Number of outgoing links
+	Depth       int // Clicks from the start page, -1 if unreachable
 }
 
 // Graph represents the link graph
@@ -69,15 +73,194 @@ func (g *Graph) Size() int {
 	return len(g.Indices)
 }
 
+// Without returns a copy of the graph leaving out the given pages and every
+// link to or from them. Pages keep their relative order.
+func (g *Graph) Without(exclude map[string]bool) *Graph {
+	filtered := NewGraph()
+	for _, url := range g.Indices {
+		if !exclude[url] {
+			filtered.AddPage(url)
+		}
+	}
+	for from, targets := range g.OutLinks {
+		if exclude[g.Indices[from]] {
+			continue
+		}
+		for _, to := range targets {
+			if !exclude[g.Indices[to]] {
+				filtered.AddLink(g.Indices[from], g.Indices[to])
+			}
+		}
+	}
+	return filtered
+}
+
+// Depths returns the click depth of every page from root, -1 for pages
+// root cannot reach
+func (g *Graph) Depths(root int) []int {
+	depths := make([]int, g.Size())
+	for i := range depths {
+		depths[i] = -1
+	}
+	if root < 0 || root >= g.Size() {
+		return depths
+	}
+
+	depths[root] = 0
+	queue := []int{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range g.OutLinks[current] {
+			if depths[next] == -1 {
+				depths[next] = depths[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return depths
+}
+
 // PageRankResult holds the computation results
 type PageRankResult struct {
-	StartURL    string
-	TotalPages  int
-	TotalLinks  int
-	Iterations  int
-	Converged   bool
-	DampingFactor float64
-	Scores      []PageScore
+	StartURL        string
+	TotalPages      int
+	TotalLinks      int
+	Iterations      int
+	Converged       bool
+	DampingFactor   float64
+	Scores          []PageScore
+	ExcludedNoIndex int // Noindex pages left out of the graph
+	SkippedNoFollow int // Nofollow links neither followed nor counted as edges
+	CrawlStats      crawlstats.Stats
+	ASCII           bool     // Draw bars with # and -
+	SitemapURLs     int      // Internal URLs listed in the sitemaps
+	SitemapOrphans  []string // Sitemap URLs no internal link path from the start page reaches
+	SitemapErrors   []string // Sitemaps or sitemap index children that could not be read
+	MinOutLinks     int      // Out-link count below which a page is under-linked, 0 disables
+	MaxOutLinks     int      // Out-link count above which a page is over-linked, 0 disables
+}
+
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *PageRankResult) SummaryLine() string {
+	orphans, deadEnds := 0, 0
+	for _, s := range r.Scores {
+		if s.InLinks == 0 {
+			orphans++
+		}
+		if s.OutLinks == 0 {
+			deadEnds++
+		}
+	}
+	return fmt.Sprintf("SUMMARY tool=pagerank pages=%d links=%d iterations=%d converged=%t orphans=%d dead_ends=%d outsized=%d excluded_noindex=%d nofollow_skipped=%d sitemap_orphans=%d under_linked=%d over_linked=%d",
+		r.TotalPages, r.TotalLinks, r.Iterations, r.Converged, orphans, deadEnds, len(r.OutsizedShare()), r.ExcludedNoIndex, r.SkippedNoFollow, len(r.SitemapOrphans),
+		len(r.UnderLinked()), len(r.OverLinked()))
+}
+
+// outsizedShareThreshold is the share of all internal links above which a
+// page other than the start page is flagged
+const outsizedShareThreshold = 0.10
+
+// maxDistributionDepth groups deeper pages into a single "N+" bucket
+const maxDistributionDepth = 3
+
+// DepthShare counts the internal links pointing at pages of one click depth
+type DepthShare struct {
+	Depth   int // Click depth, maxDistributionDepth means that depth or more, -1 unreachable
+	Pages   int
+	InLinks int
+}
+
+// LinkDistribution groups internal link targets by click depth: the start
+// page, category-level pages, and deeper leaf pages
+func (r *PageRankResult) LinkDistribution() []DepthShare {
+	buckets := make(map[int]*DepthShare)
+	for _, page := range r.Scores {
+		depth := page.Depth
+		if depth > maxDistributionDepth {
+			depth = maxDistributionDepth
+		}
+		share, ok := buckets[depth]
+		if !ok {
+			share = &DepthShare{Depth: depth}
+			buckets[depth] = share
+		}
+		share.Pages++
+		share.InLinks += page.InLinks
+	}
+
+	var shares []DepthShare
+	for depth := 0; depth <= maxDistributionDepth; depth++ {
+		if share, ok := buckets[depth]; ok {
+			shares = append(shares, *share)
+		}
+	}
+	if share, ok := buckets[-1]; ok {
+		shares = append(shares, *share)
+	}
+	return shares
+}
+
+// OutsizedShare returns the pages, other than the start page, receiving more
+// than outsizedShareThreshold of all internal links, most linked first
+func (r *PageRankResult) OutsizedShare() []PageScore {
+	if r.TotalLinks == 0 {
+		return nil
+	}
+	var pages []PageScore
+	for _, page := range r.Scores {
+		if page.Depth == 0 {
+			continue
+		}
+		if float64(page.InLinks)/float64(r.TotalLinks) > outsizedShareThreshold {
+			pages = append(pages, page)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].InLinks > pages[j].InLinks
+	})
+	return pages
+}
+
+// UnderLinked returns the pages linking to fewer than MinOutLinks internal
+// pages, fewest first. They pass their equity to too few pages. Dead ends,
+// with no links at all, are reported on their own.
+func (r *PageRankResult) UnderLinked() []PageScore {
+	var pages []PageScore
+	for _, page := range r.Scores {
+		if page.OutLinks > 0 && page.OutLinks < r.MinOutLinks {
+			pages = append(pages, page)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].OutLinks != pages[j].OutLinks {
+			return pages[i].OutLinks < pages[j].OutLinks
+		}
+		return pages[i].URL < pages[j].URL
+	})
+	return pages
+}
+
+// OverLinked returns the pages linking to more than MaxOutLinks internal
+// pages, most first. Each link passes a thinner share of their equity.
+func (r *PageRankResult) OverLinked() []PageScore {
+	if r.MaxOutLinks <= 0 {
+		return nil
+	}
+	var pages []PageScore
+	for _, page := range r.Scores {
+		if page.OutLinks > r.MaxOutLinks {
+			pages = append(pages, page)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].OutLinks != pages[j].OutLinks {
+			return pages[i].OutLinks > pages[j].OutLinks
+		}
+		return pages[i].URL < pages[j].URL
+	})
+	return pages
 }
 
 // ANSI colors
@@ -94,11 +277,25 @@ const (
 )
 
 // PrintSummary displays the PageRank results
-func (r *PageRankResult) PrintSummary(topN int, barWidth int) {
+// Pages scoring below minScore are left out of the top list; bottomN > 0 also
+// lists the lowest-ranked pages.
+func (r *PageRankResult) PrintSummary(topN int, barWidth int, minScore float64, bottomN int) {
 	fmt.Println()
 	fmt.Printf("%s%s=== PageRank Analysis ===%s\n", colorBold, colorCyan, colorReset)
 	fmt.Printf("Start URL: %s%s%s\n", colorBlue, r.StartURL, colorReset)
 	fmt.Printf("Pages analyzed: %s%d%s\n", colorGreen, r.TotalPages, colorReset)
+	if r.ExcludedNoIndex > 0 {
+		fmt.Printf("Noindex pages excluded: %s%d%s\n", colorYellow, r.ExcludedNoIndex, colorReset)
+	}
+	if r.SkippedNoFollow > 0 {
+		fmt.Printf("Nofollow links ignored: %s%d%s\n", colorYellow, r.SkippedNoFollow, colorReset)
+	}
+	if r.SitemapURLs > 0 {
+		fmt.Printf("Seeded from sitemaps: %s%d%s URLs\n", colorGreen, r.SitemapURLs, colorReset)
+	}
+	for _, err := range r.SitemapErrors {
+		fmt.Printf("%sSitemap not read: %s%s\n", colorYellow, err, colorReset)
+	}
 	fmt.Printf("Internal links: %s%d%s\n", colorGreen, r.TotalLinks, colorReset)
 	fmt.Printf("Damping factor: %s%.2f%s\n", colorYellow, r.DampingFactor, colorReset)
 	fmt.Printf("Iterations: %s%d%s", colorYellow, r.Iterations, colorReset)
@@ -138,14 +335,19 @@ func (r *PageRankResult) PrintSummary(topN int, barWidth int) {
 		return sorted[i].Score > sorted[j].Score
 	})
 
-	// Display top N
+	// Display top N, above the minimum score
+	displayed := filterMinScore(sorted, minScore)
 	displayCount := topN
-	if displayCount <= 0 || displayCount > len(sorted) {
-		displayCount = len(sorted)
+	if displayCount <= 0 || displayCount > len(displayed) {
+		displayCount = len(displayed)
 	}
 
 	fmt.Println()
-	fmt.Printf("%s%sTop %d pages by PageRank:%s\n", colorBold, colorPurple, displayCount, colorReset)
+	if minScore > 0 {
+		fmt.Printf("%s%sTop %d pages by PageRank (score >= %.6f):%s\n", colorBold, colorPurple, displayCount, minScore, colorReset)
+	} else {
+		fmt.Printf("%s%sTop %d pages by PageRank:%s\n", colorBold, colorPurple, displayCount, colorReset)
+	}
 	fmt.Println()
 
 	// Find max score for scaling
@@ -163,22 +365,70 @@ func (r *PageRankResult) PrintSummary(topN int, barWidth int) {
 	fmt.Printf("%s%s%s\n", colorGray, strings.Repeat("─", 80), colorReset)
 
 	for i := 0; i < displayCount; i++ {
-		page := sorted[i]
+		page := displayed[i]
 		r.printPageBar(i+1, page, maxScore, barWidth)
 	}
 
-	if len(sorted) > displayCount {
+	if len(displayed) > displayCount {
 		fmt.Println()
-		fmt.Printf("%s... and %d more pages%s\n", colorGray, len(sorted)-displayCount, colorReset)
+		fmt.Printf("%s... and %d more pages%s\n", colorGray, len(displayed)-displayCount, colorReset)
+	}
+	if hidden := len(sorted) - len(displayed); hidden > 0 {
+		fmt.Printf("%s%d page(s) below the minimum score hidden%s\n", colorGray, hidden, colorReset)
+	}
+
+	// Lowest-ranked pages receive almost no internal link equity
+	if bottomN > 0 {
+		r.printBottom(sorted, bottomN, maxScore, barWidth)
 	}
 
 	// Show pages with highest incoming links
 	r.printTopByInLinks(sorted, 5)
 
+	// Show where internal links point, by click depth
+	r.printLinkDistribution(sorted)
+
+	// Show pages linking to too few or too many pages
+	r.printOutLinkOutliers()
+
 	// Show potential issues
 	r.printIssues(sorted)
 
+	// Sitemap pages the internal links never lead to
+	r.printSitemapOrphans()
+
+	r.CrawlStats.Print()
+}
+
+// printBottom lists the n lowest-ranked pages, lowest first. sorted must be
+// in descending score order.
+func (r *PageRankResult) printBottom(sorted []PageScore, n int, maxScore float64, barWidth int) {
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sBottom %d pages by PageRank (weakest internal linking):%s\n", colorBold, colorPurple, n, colorReset)
 	fmt.Println()
+
+	for i := 0; i < n; i++ {
+		rank := len(sorted) - i
+		r.printPageBar(rank, sorted[rank-1], maxScore, barWidth)
+	}
+}
+
+// filterMinScore keeps the pages scoring at least minScore
+func filterMinScore(pages []PageScore, minScore float64) []PageScore {
+	if minScore <= 0 {
+		return pages
+	}
+	var kept []PageScore
+	for _, page := range pages {
+		if page.Score >= minScore {
+			kept = append(kept, page)
+		}
+	}
+	return kept
 }
 
 func (r *PageRankResult) printPageBar(rank int, page PageScore, maxScore float64, barWidth int) {
@@ -202,8 +452,9 @@ func (r *PageRankResult) printPageBar(rank int, page PageScore, maxScore float64
 		barColor = colorGray
 	}
 
-	bar := strings.Repeat("█", barLen)
-	emptyBar := strings.Repeat("░", barWidth-barLen)
+	fill, empty := barchart.Chars(r.ASCII)
+	bar := strings.Repeat(fill, barLen)
+	emptyBar := strings.Repeat(empty, barWidth-barLen)
 
 	// Truncate URL
 	url := page.URL
@@ -250,6 +501,60 @@ func (r *PageRankResult) printTopByInLinks(sorted []PageScore, topN int) {
 	}
 }
 
+func (r *PageRankResult) printLinkDistribution(sorted []PageScore) {
+	shares := r.LinkDistribution()
+	if len(shares) == 0 || r.TotalLinks == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sInternal link distribution by target depth:%s\n", colorBold, colorYellow, colorReset)
+
+	for _, share := range shares {
+		var label string
+		switch {
+		case share.Depth == 0:
+			label = "Homepage"
+		case share.Depth == -1:
+			label = "Unreachable"
+		case share.Depth == maxDistributionDepth:
+			label = fmt.Sprintf("Depth %d+", share.Depth)
+		default:
+			label = fmt.Sprintf("Depth %d", share.Depth)
+		}
+		fmt.Printf("  %-12s %s%5d links%s  %5.1f%%  %s(%d pages)%s\n",
+			label,
+			colorBlue, share.InLinks, colorReset,
+			float64(share.InLinks)*100/float64(r.TotalLinks),
+			colorGray, share.Pages, colorReset)
+	}
+
+	orphans := 0
+	for _, page := range sorted {
+		if page.InLinks == 0 {
+			orphans++
+		}
+	}
+	fmt.Printf("  %sPages with no internal links: %d%s\n", colorGray, orphans, colorReset)
+
+	outsized := r.OutsizedShare()
+	if len(outsized) > 0 {
+		fmt.Printf("\n  %sOutsized link share%s (more than %.0f%% of internal links): %d\n",
+			colorYellow, colorReset, outsizedShareThreshold*100, len(outsized))
+		for i, page := range outsized {
+			if i >= 5 {
+				fmt.Printf("    %s... and %d more%s\n", colorGray, len(outsized)-5, colorReset)
+				break
+			}
+			url := page.URL
+			if len(url) > 60 {
+				url = url[:57] + "..."
+			}
+			fmt.Printf("    • %s %s(%.1f%%)%s\n", url, colorGray, float64(page.InLinks)*100/float64(r.TotalLinks), colorReset)
+		}
+	}
+}
+
 func (r *PageRankResult) printIssues(sorted []PageScore) {
 	// Find orphan pages (no incoming links)
 	var orphans []string
@@ -300,3 +605,94 @@ func (r *PageRankResult) printIssues(sorted []PageScore) {
 		}
 	}
 }
+
+// printOutLinkOutliers shows the average number of internal links per page
+// and the pages outside MinOutLinks and MaxOutLinks
+func (r *PageRankResult) printOutLinkOutliers() {
+	linking := 0
+	for _, page := range r.Scores {
+		if page.OutLinks > 0 {
+			linking++
+		}
+	}
+	if linking == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sInternal links per page:%s\n", colorBold, colorYellow, colorReset)
+	fmt.Printf("  Average: %s%.1f%s links to other pages, over %d linking pages\n",
+		colorBlue, float64(r.TotalLinks)/float64(linking), colorReset, linking)
+
+	if under := r.UnderLinked(); len(under) > 0 {
+		fmt.Printf("\n  %sUnder-linked%s (fewer than %d internal links): %d\n", colorYellow, colorReset, r.MinOutLinks, len(under))
+		printOutLinkPages(under)
+	}
+	if over := r.OverLinked(); len(over) > 0 {
+		fmt.Printf("\n  %sOver-linked%s (more than %d internal links): %d\n", colorYellow, colorReset, r.MaxOutLinks, len(over))
+		printOutLinkPages(over)
+	}
+}
+
+func printOutLinkPages(pages []PageScore) {
+	for i, page := range pages {
+		if i >= 10 {
+			fmt.Printf("    %s... and %d more%s\n", colorGray, len(pages)-10, colorReset)
+			break
+		}
+		url := page.URL
+		if len(url) > 60 {
+			url = url[:57] + "..."
+		}
+		fmt.Printf("    %s%4d links%s  %s\n", colorBlue, page.OutLinks, colorReset, url)
+	}
+}
+
+// printSitemapOrphans lists the sitemap URLs that only the sitemap leads to.
+// Search engines may still index them, but they get no internal link equity
+// and visitors cannot navigate to them.
+func (r *PageRankResult) printSitemapOrphans() {
+	if r.SitemapURLs == 0 {
+		return
+	}
+
+	fmt.Println()
+	if len(r.SitemapOrphans) == 0 {
+		fmt.Printf("%s✓ Every sitemap URL is reachable through internal links%s\n", colorGreen, colorReset)
+		return
+	}
+
+	fmt.Printf("%s%sSitemap orphans%s (in the sitemap, never reached by internal links): %d of %d\n",
+		colorBold, colorRed, colorReset, len(r.SitemapOrphans), r.SitemapURLs)
+	for i, url := range r.SitemapOrphans {
+		if i >= 10 {
+			fmt.Printf("  %s... and %d more%s\n", colorGray, len(r.SitemapOrphans)-10, colorReset)
+			break
+		}
+		fmt.Printf("  • %s\n", url)
+	}
+}
+
+// ExportCSV exports the scores to CSV format, highest score first. Pages
+// scoring below minScore are skipped; ranks stay those of the full list.
+func (r *PageRankResult) ExportCSV(minScore float64) string {
+	sorted := make([]PageScore, len(r.Scores))
+	copy(sorted, r.Scores)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Score > sorted[j].Score
+	})
+
+	var sb strings.Builder
+	sb.WriteString("url,score,in_links,out_links,rank\n")
+
+	for i, page := range sorted {
+		if page.Score < minScore {
+			break
+		}
+		url := strings.ReplaceAll(page.URL, "\"", "\"\"")
+		sb.WriteString(fmt.Sprintf("\"%s\",%.6f,%d,%d,%d\n",
+			url, page.Score, page.InLinks, page.OutLinks, i+1))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,48 @@
+package robots
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Cache keeps one loaded Checker per host so several crawlers in the same
+// run fetch robots.txt once and agree on the rules
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	once    sync.Once
+	checker *Checker
+	err     error
+}
+
+// NewCache creates an empty robots.txt cache
+func NewCache() *Cache {
+	return &Cache{
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+// Get returns the checker for baseURL's host, loading robots.txt on first use.
+// Concurrent callers for the same host wait for a single fetch.
+func (c *Cache) Get(baseURL *url.URL, timeout time.Duration, retry RetryConfig) (*Checker, error) {
+	key := baseURL.Scheme + "://" + baseURL.Host
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cacheEntry{}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	e.once.Do(func() {
+		e.checker = NewChecker()
+		e.err = e.checker.Load(baseURL, timeout, retry)
+	})
+
+	return e.checker, e.err
+}
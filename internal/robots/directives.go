@@ -0,0 +1,49 @@
+package robots
+
+import "strings"
+
+// Directives returns the directives of a robots meta tag or
+// X-Robots-Tag value, lowercase. A user agent prefix such as
+// "googlebot: noindex" is dropped; "all" and "none" are expanded.
+func Directives(value string) map[string]bool {
+	directives := make(map[string]bool)
+	for _, part := range strings.Split(strings.ToLower(value), ",") {
+		part = strings.TrimSpace(part)
+		if agent, directive, ok := strings.Cut(part, ":"); ok && !strings.Contains(agent, " ") && !isValueDirective(agent) {
+			part = strings.TrimSpace(directive)
+		}
+		switch part {
+		case "all":
+			directives["index"] = true
+			directives["follow"] = true
+		case "none":
+			directives["noindex"] = true
+			directives["nofollow"] = true
+		case "":
+		default:
+			directives[part] = true
+		}
+	}
+	return directives
+}
+
+// IsNoIndex reports whether any of the robots meta tag or X-Robots-Tag
+// values forbids indexing
+func IsNoIndex(values ...string) bool {
+	for _, value := range values {
+		if Directives(value)["noindex"] {
+			return true
+		}
+	}
+	return false
+}
+
+// isValueDirective reports whether a name before a colon is a directive
+// taking a value, such as max-snippet, rather than a user agent
+func isValueDirective(name string) bool {
+	switch name {
+	case "max-snippet", "max-image-preview", "max-video-preview", "unavailable_after":
+		return true
+	}
+	return false
+}
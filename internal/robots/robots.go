@@ -0,0 +1,223 @@
+package robots
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Checker checks URLs against robots.txt rules
+type Checker struct {
+	rules       []disallowRule
+	loaded      bool
+	loadError   error
+	unavailable bool // Fetch failed or the server errored: everything is blocked
+	crawlDelay  time.Duration
+}
+
+// RetryConfig controls how a failed robots.txt fetch is retried
+type RetryConfig struct {
+	Attempts int           // Total tries, including the first
+	Backoff  time.Duration // Wait before the second try, doubled after each failure
+}
+
+// DefaultRetryConfig returns the retry settings used when none are given
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		Attempts: 3,
+		Backoff:  500 * time.Millisecond,
+	}
+}
+
+// GetWithRetry fetches targetURL, retrying network errors and 5xx responses
+// with exponential backoff. The last response or error is returned.
+func GetWithRetry(client *http.Client, targetURL string, retry RetryConfig) (*http.Response, error) {
+	if retry.Attempts < 1 {
+		retry = DefaultRetryConfig()
+	}
+
+	backoff := retry.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Get(targetURL)
+		if err == nil && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		if attempt >= retry.Attempts {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+type disallowRule struct {
+	path string
+}
+
+// NewChecker creates a new robots.txt checker
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Load fetches and parses robots.txt from the given base URL. A 4xx means
+// there is no robots.txt and everything is allowed; when the file cannot be
+// fetched (network error or 5xx after retries) everything is treated as
+// blocked, as search engines do, and an error is returned.
+func (r *Checker) Load(baseURL *url.URL, timeout time.Duration, retry RetryConfig) error {
+	robotsURL := &url.URL{
+		Scheme: baseURL.Scheme,
+		Host:   baseURL.Host,
+		Path:   "/robots.txt",
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := GetWithRetry(client, robotsURL.String(), retry)
+	if err != nil {
+		r.loadError = err
+		r.unavailable = true
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		r.loadError = fmt.Errorf("HTTP %d", resp.StatusCode)
+		r.unavailable = true
+		return r.loadError
+	}
+
+	if resp.StatusCode != 200 {
+		// No robots.txt - everything is allowed
+		r.loaded = true
+		return nil
+	}
+
+	// Parse robots.txt
+	scanner := bufio.NewScanner(resp.Body)
+	inUserAgentAll := false
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip comments and empty lines
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Split directive and value
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		directive := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		switch directive {
+		case "user-agent":
+			// Check if this applies to all bots
+			inUserAgentAll = value == "*"
+		case "disallow":
+			if inUserAgentAll && value != "" {
+				r.rules = append(r.rules, disallowRule{path: value})
+			}
+		case "crawl-delay":
+			// Seconds between two requests, fractions allowed
+			if seconds, err := strconv.ParseFloat(value, 64); inUserAgentAll && err == nil && seconds > 0 {
+				r.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	r.loaded = true
+	return scanner.Err()
+}
+
+// IsBlocked checks if a URL is blocked by robots.txt
+func (r *Checker) IsBlocked(targetURL string) bool {
+	if r.unavailable {
+		return true
+	}
+	if !r.loaded || len(r.rules) == 0 {
+		return false
+	}
+
+	parsed, err := url.Parse(targetURL)
+	if err != nil {
+		return false
+	}
+
+	path := parsed.Path
+	if path == "" {
+		path = "/"
+	}
+
+	for _, rule := range r.rules {
+		if matchesRule(path, rule.path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Unavailable reports whether robots.txt could not be fetched, in which case
+// every URL counts as blocked
+func (r *Checker) Unavailable() bool {
+	return r.unavailable
+}
+
+// CrawlDelay returns the Crawl-delay robots.txt asks of every bot, 0
+// without one
+func (r *Checker) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
+
+// PoliteLimits returns the concurrency and delay between requests that
+// honor a crawl delay, the more conservative of them and the user's. The
+// delay becomes at least the crawl delay, and concurrency is capped to the
+// requests the site accepts per second: one at a time for a delay of a
+// second or more, rather than many requests waiting on the same pause.
+func PoliteLimits(concurrency int, delay, crawlDelay time.Duration) (int, time.Duration) {
+	if crawlDelay <= 0 {
+		return concurrency, delay
+	}
+	if crawlDelay > delay {
+		delay = crawlDelay
+	}
+	perSecond := max(int(time.Second/crawlDelay), 1)
+	return min(concurrency, perSecond), delay
+}
+
+// GetRules returns the parsed disallow rules
+func (r *Checker) GetRules() []string {
+	rules := make([]string, len(r.rules))
+	for i, rule := range r.rules {
+		rules[i] = "Disallow: " + rule.path
+	}
+	return rules
+}
+
+// matchesRule checks if a path matches a robots.txt rule
+func matchesRule(path, rule string) bool {
+	// Handle wildcard at end
+	if strings.HasSuffix(rule, "*") {
+		prefix := strings.TrimSuffix(rule, "*")
+		return strings.HasPrefix(path, prefix)
+	}
+
+	// Handle $ anchor (exact match)
+	if strings.HasSuffix(rule, "$") {
+		exact := strings.TrimSuffix(rule, "$")
+		return path == exact
+	}
+
+	// Simple prefix match
+	return strings.HasPrefix(path, rule)
+}
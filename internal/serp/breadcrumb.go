@@ -0,0 +1,239 @@
+package serp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BreadcrumbItem is one ListItem of a BreadcrumbList
+type BreadcrumbItem struct {
+	Position int // 0 when missing or not a number
+	Name     string
+	URL      string // Resolved against the page URL; may be empty for the last item
+	Status   int    // HTTP status of URL once checked, 0 if not requested
+	Error    string // Why URL could not be requested
+}
+
+// Breadcrumb is a BreadcrumbList from the page's JSON-LD, the structured
+// data behind breadcrumb rich results. Items are in position order.
+type Breadcrumb struct {
+	Items    []BreadcrumbItem
+	Problems []string
+}
+
+// OK reports whether the breadcrumb is eligible for rich results
+func (b *Breadcrumb) OK() bool {
+	return len(b.Problems) == 0
+}
+
+// parseBreadcrumb extracts the items of a BreadcrumbList object and
+// reports what makes it malformed
+func parseBreadcrumb(obj map[string]interface{}, baseURL *url.URL) Breadcrumb {
+	var b Breadcrumb
+
+	elements, ok := obj["itemListElement"].([]interface{})
+	if !ok || len(elements) == 0 {
+		b.Problems = append(b.Problems, "BreadcrumbList has no itemListElement array")
+		return b
+	}
+
+	for i, element := range elements {
+		listItem, ok := element.(map[string]interface{})
+		if !ok {
+			b.Problems = append(b.Problems, fmt.Sprintf("Element %d is not a ListItem object", i+1))
+			continue
+		}
+
+		item := BreadcrumbItem{Name: jsonString(listItem["name"])}
+		switch v := listItem["position"].(type) {
+		case float64:
+			item.Position = int(v)
+		case string:
+			item.Position, _ = strconv.Atoi(strings.TrimSpace(v))
+		}
+
+		// item is either the URL or a Thing with @id and name
+		switch v := listItem["item"].(type) {
+		case string:
+			item.URL = v
+		case map[string]interface{}:
+			item.URL = jsonString(v["@id"])
+			if item.URL == "" {
+				item.URL = jsonString(v["url"])
+			}
+			if item.Name == "" {
+				item.Name = jsonString(v["name"])
+			}
+		}
+
+		if item.URL != "" {
+			parsed, err := url.Parse(strings.TrimSpace(item.URL))
+			if err != nil || (baseURL == nil && !parsed.IsAbs()) {
+				b.Problems = append(b.Problems, fmt.Sprintf("Element %d has an invalid URL: %s", i+1, item.URL))
+			} else {
+				if baseURL != nil {
+					parsed = baseURL.ResolveReference(parsed)
+				}
+				item.URL = parsed.String()
+				if parsed.Scheme != "http" && parsed.Scheme != "https" {
+					b.Problems = append(b.Problems, fmt.Sprintf("Element %d URL is not http or https: %s", i+1, item.URL))
+				}
+			}
+		}
+
+		if item.Position == 0 {
+			b.Problems = append(b.Problems, fmt.Sprintf("Element %d has no valid position", i+1))
+		}
+		if item.Name == "" {
+			b.Problems = append(b.Problems, fmt.Sprintf("Element %d has no name", i+1))
+		}
+		b.Items = append(b.Items, item)
+	}
+	// Every element was malformed and is already reported
+	if len(b.Items) == 0 {
+		return b
+	}
+
+	sort.SliceStable(b.Items, func(i, j int) bool {
+		return b.Items[i].Position < b.Items[j].Position
+	})
+	for i, item := range b.Items {
+		if item.Position != 0 && item.Position != i+1 {
+			b.Problems = append(b.Problems, fmt.Sprintf("Positions are not sequential from 1: expected %d, found %d", i+1, item.Position))
+			break
+		}
+	}
+
+	// Only the last item, the current page, may leave its URL out
+	for i, item := range b.Items[:len(b.Items)-1] {
+		if item.URL == "" {
+			b.Problems = append(b.Problems, fmt.Sprintf("Item at position %d has no URL; only the last item may omit it", i+1))
+		}
+	}
+
+	return b
+}
+
+// checkBreadcrumbTargets reports breadcrumbs whose last item isn't the
+// page itself. Run once the whole page is parsed, as the canonical may
+// come after the JSON-LD.
+func checkBreadcrumbTargets(meta *PageMeta) {
+	for i := range meta.Breadcrumbs {
+		b := &meta.Breadcrumbs[i]
+		if len(b.Items) == 0 {
+			continue
+		}
+		last := b.Items[len(b.Items)-1]
+		if last.URL == "" || sameURL(last.URL, meta.URL) || (meta.Canonical != "" && sameURL(last.URL, meta.Canonical)) {
+			continue
+		}
+		b.Problems = append(b.Problems, fmt.Sprintf("The last item is %s, not the current page", last.URL))
+	}
+}
+
+// jsonString returns a JSON value as a trimmed string, empty when it is
+// not a string
+func jsonString(v interface{}) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+// CheckBreadcrumbs requests the URL of every breadcrumb item but the
+// current page and reports those that don't answer with a success. Each
+// distinct URL is requested once, several at a time.
+func (f *Fetcher) CheckBreadcrumbs(meta *PageMeta) {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, b := range meta.Breadcrumbs {
+		for _, item := range b.Items {
+			if item.URL == "" || sameURL(item.URL, meta.URL) || !strings.HasPrefix(item.URL, "http") || seen[item.URL] {
+				continue
+			}
+			seen[item.URL] = true
+			targets = append(targets, item.URL)
+		}
+	}
+	statuses := f.statuses(targets)
+
+	for i := range meta.Breadcrumbs {
+		b := &meta.Breadcrumbs[i]
+		for j := range b.Items {
+			item := &b.Items[j]
+			checked, ok := statuses[item.URL]
+			if !ok {
+				continue
+			}
+			item.Status, item.Error = checked.Status, checked.Error
+
+			switch {
+			case item.Error != "":
+				b.Problems = append(b.Problems, fmt.Sprintf("Item at position %d is unreachable: %s", item.Position, item.Error))
+			case item.Status >= 400:
+				b.Problems = append(b.Problems, fmt.Sprintf("Item at position %d answers %d: %s", item.Position, item.Status, item.URL))
+			}
+		}
+	}
+}
+
+// status requests a URL, following redirects, and returns its final status
+func (f *Fetcher) status(targetURL string) (int, string) {
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return 0, err.Error()
+	}
+	userAgent := f.config.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	resp.Body.Close()
+	return resp.StatusCode, ""
+}
+
+// BreadcrumbProblems counts the problems of every breadcrumb on the page
+func (m *PageMeta) BreadcrumbProblems() int {
+	problems := 0
+	for _, b := range m.Breadcrumbs {
+		problems += len(b.Problems)
+	}
+	return problems
+}
+
+// printBreadcrumbs displays the breadcrumb trails and their problems
+func (m *PageMeta) printBreadcrumbs() {
+	if len(m.Breadcrumbs) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sBreadcrumbs (BreadcrumbList):%s\n", colorBold, colorYellow, colorReset)
+	for i, b := range m.Breadcrumbs {
+		if i > 0 {
+			fmt.Println()
+		}
+		for _, item := range b.Items {
+			fmt.Printf("  %d. %s", item.Position, orNone(item.Name))
+			if item.URL != "" {
+				fmt.Printf(" %s→ %s%s", colorGray, item.URL, colorReset)
+			}
+			fmt.Println()
+		}
+
+		if b.OK() {
+			fmt.Printf("  %s✓%s Valid: eligible for breadcrumb rich results\n", colorGreen, colorReset)
+			continue
+		}
+		for _, problem := range b.Problems {
+			fmt.Printf("  %s✗%s %s%s%s\n", colorRed, colorReset, colorRed, problem, colorReset)
+		}
+	}
+}
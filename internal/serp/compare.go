@@ -0,0 +1,113 @@
+package serp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Difference is a metadata field whose desktop and mobile values differ
+type Difference struct {
+	Field   string
+	Desktop string
+	Mobile  string
+}
+
+// DeviceComparison holds a page as served to desktop and mobile agents.
+// With mobile-first indexing Google indexes the mobile version, so any
+// difference is metadata search results don't show as the desktop one does.
+type DeviceComparison struct {
+	Desktop     *PageMeta
+	Mobile      *PageMeta
+	Differences []Difference
+}
+
+// CompareDevices fetches a URL with DesktopUserAgent and MobileUserAgent
+// and compares the extracted metadata
+func (f *Fetcher) CompareDevices(targetURL string) (*DeviceComparison, error) {
+	desktop, err := f.AnalyzeAs(targetURL, DesktopUserAgent)
+	if err != nil {
+		return nil, fmt.Errorf("desktop: %w", err)
+	}
+	mobile, err := f.AnalyzeAs(targetURL, MobileUserAgent)
+	if err != nil {
+		return nil, fmt.Errorf("mobile: %w", err)
+	}
+	return &DeviceComparison{
+		Desktop:     desktop,
+		Mobile:      mobile,
+		Differences: Compare(desktop, mobile),
+	}, nil
+}
+
+// Compare lists the indexing-relevant fields that differ between two
+// versions of a page, in display order
+func Compare(desktop, mobile *PageMeta) []Difference {
+	fields := []struct {
+		name            string
+		desktop, mobile string
+	}{
+		{"Final URL", desktop.URL, mobile.URL},
+		{"Title", desktop.Title, mobile.Title},
+		{"Meta description", desktop.MetaDescription, mobile.MetaDescription},
+		{"Canonical", desktop.Canonical, mobile.Canonical},
+		{"Robots", desktop.Robots, mobile.Robots},
+		{"Googlebot", desktop.GoogleBot, mobile.GoogleBot},
+		{"H1", desktop.H1, mobile.H1},
+		{"H1 count", fmt.Sprint(desktop.H1Count), fmt.Sprint(mobile.H1Count)},
+		{"Schema.org types", schemaList(desktop.SchemaTypes), schemaList(mobile.SchemaTypes)},
+		{"og:title", desktop.OGTitle, mobile.OGTitle},
+		{"og:description", desktop.OGDescription, mobile.OGDescription},
+		{"og:image", desktop.OGImage, mobile.OGImage},
+		{"Language", desktop.Lang, mobile.Lang},
+	}
+
+	var diffs []Difference
+	for _, field := range fields {
+		if strings.TrimSpace(field.desktop) != strings.TrimSpace(field.mobile) {
+			diffs = append(diffs, Difference{Field: field.name, Desktop: field.desktop, Mobile: field.mobile})
+		}
+	}
+	return diffs
+}
+
+// schemaList is the set of schema types, so document order doesn't count
+func schemaList(types []string) string {
+	sorted := slices.Clone(types)
+	slices.Sort(sorted)
+	return strings.Join(slices.Compact(sorted), ", ")
+}
+
+// SummaryLine returns a single machine-readable key=value summary line
+func (c *DeviceComparison) SummaryLine() string {
+	return fmt.Sprintf("SUMMARY tool=serpreview mode=mobile-diff differences=%d", len(c.Differences))
+}
+
+// PrintComparison displays the fields that differ between desktop and mobile
+func (c *DeviceComparison) PrintComparison() {
+	fmt.Println()
+	fmt.Printf("%s%s=== Desktop vs Mobile ===%s\n", colorBold, colorCyan, colorReset)
+	fmt.Printf("  %sURL: %s%s\n", colorGray, c.Desktop.URL, colorReset)
+	fmt.Println()
+
+	if len(c.Differences) == 0 {
+		fmt.Printf("  %s✓%s Desktop and mobile agents get the same metadata\n\n", colorGreen, colorReset)
+		return
+	}
+
+	fmt.Printf("  %s✗%s %s%d field(s) differ: Google indexes the mobile version%s\n", colorRed, colorReset, colorRed, len(c.Differences), colorReset)
+	for _, diff := range c.Differences {
+		fmt.Println()
+		fmt.Printf("  %s%s:%s\n", colorYellow, diff.Field, colorReset)
+		fmt.Printf("    Desktop: %s\n", orNone(diff.Desktop))
+		fmt.Printf("    Mobile:  %s\n", orNone(diff.Mobile))
+	}
+	fmt.Println()
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return colorGray + "(none)" + colorReset
+	}
+	return value
+}
@@ -5,14 +5,29 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/ngonzalez/web-tools/internal/contenttype"
 )
 
 // Config holds fetcher configuration
 type Config struct {
-	Timeout time.Duration
-	Verbose bool
+	Timeout          time.Duration
+	Verbose          bool
+	Transport        http.RoundTripper // Optional custom transport, e.g. a shared response cache
+	UserAgent        string            // Sent with every request, DefaultUserAgent when empty
+	Lengths          Lengths           // Recommended title and description lengths, the defaults when zero
+	HTMLContentTypes []string          // Extra media types parsed as HTML, besides contenttype.HTML
+	HeadOnly         bool              // Stop reading pages at the end of <head>, see ExtractHead
 }
 
+// User agents. The default is browser-like to get the real page; the
+// desktop and mobile ones mimic the two crawlers of mobile-first indexing.
+const (
+	DefaultUserAgent = "Mozilla/5.0 (compatible; SERPreview/1.0)"
+	DesktopUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 (compatible; SERPreview/1.0)"
+	MobileUserAgent  = "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36 (compatible; SERPreview/1.0)"
+)
+
 // DefaultConfig returns default configuration
 func DefaultConfig() Config {
 	return Config{
@@ -32,13 +47,23 @@ func New(config Config) *Fetcher {
 	return &Fetcher{
 		config: config,
 		client: &http.Client{
-			Timeout: config.Timeout,
+			Timeout:   config.Timeout,
+			Transport: config.Transport,
 		},
 	}
 }
 
 // Analyze fetches a URL and extracts SEO metadata
 func (f *Fetcher) Analyze(targetURL string) (*PageMeta, error) {
+	userAgent := f.config.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	return f.AnalyzeAs(targetURL, userAgent)
+}
+
+// AnalyzeAs is Analyze with a given User-Agent
+func (f *Fetcher) AnalyzeAs(targetURL, userAgent string) (*PageMeta, error) {
 	// Validate URL
 	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
 		targetURL = "https://" + targetURL
@@ -53,8 +78,7 @@ func (f *Fetcher) Analyze(targetURL string) (*PageMeta, error) {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Use a browser-like user agent to get the real page
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; SERPreview/1.0)")
+	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml")
 	req.Header.Set("Accept-Language", "fr-FR,fr;q=0.9,en;q=0.8")
 
@@ -70,15 +94,24 @@ func (f *Fetcher) Analyze(targetURL string) (*PageMeta, error) {
 
 	// Check content type
 	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/html") && !strings.Contains(contentType, "application/xhtml") {
+	if !contenttype.IsHTML(contentType, f.config.HTMLContentTypes) {
 		return nil, fmt.Errorf("not an HTML page: %s", contentType)
 	}
 
 	// Get the final URL (after redirects)
 	finalURL := resp.Request.URL.String()
 
-	// Parse the page
-	meta := ExtractMeta(resp.Body, finalURL)
+	// Parse the page, or only its head; the deferred Close then drops the
+	// rest of the body unread
+	var meta *PageMeta
+	if f.config.HeadOnly {
+		meta = ExtractHead(resp.Body, finalURL)
+	} else {
+		meta = ExtractMeta(resp.Body, finalURL)
+	}
+	meta.Lengths = f.config.Lengths
+
+	meta.LastModified = resp.Header.Get("Last-Modified")
 
 	// Check X-Robots-Tag header
 	xRobots := resp.Header.Get("X-Robots-Tag")
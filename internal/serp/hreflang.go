@@ -0,0 +1,85 @@
+package serp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hreflang is a language alternate declared with
+// <link rel="alternate" hreflang="...">
+type Hreflang struct {
+	Lang string // Language code, or x-default
+	URL  string
+}
+
+// SelfHreflang returns the hreflang entry pointing to the page itself, or
+// its canonical, which declares the page's own language
+func (m *PageMeta) SelfHreflang() (Hreflang, bool) {
+	for _, alt := range m.Hreflangs {
+		if strings.EqualFold(alt.Lang, "x-default") {
+			continue
+		}
+		if sameURL(alt.URL, m.URL) || (m.Canonical != "" && sameURL(alt.URL, m.Canonical)) {
+			return alt, true
+		}
+	}
+	return Hreflang{}, false
+}
+
+// LangMismatch describes how <html lang> contradicts the page's own hreflang
+// entry, or returns "" when they agree or one is missing. Languages must
+// match; regions only when both declare one, so lang="en" with hreflang
+// "en-GB" is consistent.
+func (m *PageMeta) LangMismatch() string {
+	self, ok := m.SelfHreflang()
+	if m.Lang == "" || !ok {
+		return ""
+	}
+
+	lang, region := splitLang(m.Lang)
+	selfLang, selfRegion := splitLang(self.Lang)
+	if lang != selfLang || (region != "" && selfRegion != "" && region != selfRegion) {
+		return fmt.Sprintf("<html lang=\"%s\"> contradicts the page's own hreflang \"%s\"", m.Lang, self.Lang)
+	}
+	return ""
+}
+
+// splitLang splits a language tag such as en-GB or en_gb into its
+// lowercased language and region
+func splitLang(tag string) (lang, region string) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	lang, rest, _ := strings.Cut(tag, "-")
+	// Skip a script subtag, as in zh-Hant-TW
+	if script, after, ok := strings.Cut(rest, "-"); ok && len(script) == 4 {
+		rest = after
+	} else if len(rest) == 4 {
+		rest = ""
+	}
+	region, _, _ = strings.Cut(rest, "-")
+	return lang, region
+}
+
+// printLanguage displays <html lang>, the hreflang alternates and whether
+// they agree
+func (m *PageMeta) printLanguage() {
+	fmt.Println()
+	fmt.Printf("%s%sLanguage:%s\n", colorBold, colorYellow, colorReset)
+	if m.Lang != "" {
+		fmt.Printf("  %s✓%s html lang: %s\n", colorGreen, colorReset, m.Lang)
+	} else {
+		fmt.Printf("  %s!%s %sNo <html lang> declared%s\n", colorYellow, colorReset, colorYellow, colorReset)
+	}
+
+	if len(m.Hreflangs) > 0 {
+		if self, ok := m.SelfHreflang(); ok {
+			fmt.Printf("  %s✓%s hreflang (self): %s\n", colorGreen, colorReset, self.Lang)
+		} else {
+			fmt.Printf("  %s!%s %sNo hreflang entry for the page itself%s\n", colorYellow, colorReset, colorYellow, colorReset)
+		}
+		fmt.Printf("    %s%d hreflang alternates declared%s\n", colorGray, len(m.Hreflangs), colorReset)
+	}
+
+	if mismatch := m.LangMismatch(); mismatch != "" {
+		fmt.Printf("  %s✗%s %s%s%s\n", colorRed, colorReset, colorRed, mismatch, colorReset)
+	}
+}
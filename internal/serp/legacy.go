@@ -0,0 +1,105 @@
+package serp
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
+)
+
+// KeywordsStuffed is the number of comma-separated meta keywords past which
+// the list looks stuffed
+const KeywordsStuffed = 10
+
+// deprecatedMeta are <meta name> values no search engine reads any more
+var deprecatedMeta = map[string]bool{
+	"revisit-after":  true,
+	"distribution":   true,
+	"resource-type":  true,
+	"classification": true,
+}
+
+// deprecatedHTTPEquiv are <meta http-equiv> values superseded by markup:
+// content-language by <html lang>, imagetoolbar by nothing since IE6
+var deprecatedHTTPEquiv = map[string]bool{
+	"content-language": true,
+	"imagetoolbar":     true,
+}
+
+// obsoleteElements are presentational or frame elements removed from HTML5
+var obsoleteElements = map[string]bool{
+	"font":     true,
+	"center":   true,
+	"marquee":  true,
+	"blink":    true,
+	"big":      true,
+	"strike":   true,
+	"tt":       true,
+	"frameset": true,
+	"frame":    true,
+	"applet":   true,
+}
+
+// deprecatedConstruct returns how to name a start tag in the report when it
+// is deprecated, or ""
+func deprecatedConstruct(token html.Token) string {
+	if obsoleteElements[token.Data] {
+		return "<" + token.Data + ">"
+	}
+	if token.Data != "meta" {
+		return ""
+	}
+	if name := strings.ToLower(htmlattr.Get(token, "name")); deprecatedMeta[name] {
+		return fmt.Sprintf("<meta name=%q>", name)
+	}
+	if equiv := strings.ToLower(htmlattr.Get(token, "http-equiv")); deprecatedHTTPEquiv[equiv] {
+		return fmt.Sprintf("<meta http-equiv=%q>", equiv)
+	}
+	return ""
+}
+
+// addDeprecated records a deprecated construct once
+func (m *PageMeta) addDeprecated(construct string) {
+	for _, d := range m.Deprecated {
+		if d == construct {
+			return
+		}
+	}
+	m.Deprecated = append(m.Deprecated, construct)
+}
+
+// KeywordCount returns the number of comma-separated meta keywords
+func (m *PageMeta) KeywordCount() int {
+	count := 0
+	for _, keyword := range strings.Split(m.Keywords, ",") {
+		if strings.TrimSpace(keyword) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// printLegacy lists the meta keywords and deprecated markup of the page,
+// nothing when it has none
+func (m *PageMeta) printLegacy() {
+	if m.Keywords == "" && len(m.Deprecated) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sLegacy markup:%s\n", colorBold, colorYellow, colorReset)
+	if m.Keywords != "" {
+		count := m.KeywordCount()
+		fmt.Printf("  %s-%s meta keywords present (%d), ignored by search engines\n", colorGray, colorReset, count)
+		if count > KeywordsStuffed {
+			fmt.Printf("    %sLooks keyword-stuffed, remove it%s\n", colorYellow, colorReset)
+		} else {
+			fmt.Printf("    %sSafe to remove%s\n", colorGray, colorReset)
+		}
+	}
+	for _, construct := range m.Deprecated {
+		fmt.Printf("  %s!%s %s is deprecated\n", colorYellow, colorReset, construct)
+	}
+}
@@ -0,0 +1,153 @@
+package serp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Range is a recommended length, in characters
+type Range struct {
+	Min int
+	Max int
+}
+
+// ParseRange parses a "min-max" range such as "30-60"
+func ParseRange(s string) (Range, error) {
+	low, high, ok := strings.Cut(s, "-")
+	if !ok {
+		return Range{}, fmt.Errorf("invalid range %q, expected min-max", s)
+	}
+	first, err := strconv.Atoi(strings.TrimSpace(low))
+	if err != nil || first < 0 {
+		return Range{}, fmt.Errorf("invalid range %q, expected min-max", s)
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(high))
+	if err != nil || last < first || last == 0 {
+		return Range{}, fmt.Errorf("invalid range %q, expected min-max", s)
+	}
+	return Range{Min: first, Max: last}, nil
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// Lengths are the recommended title and description lengths the analysis
+// checks. The usual ranges are for English; other languages, CJK ones
+// above all, fit more or less meaning in a character.
+type Lengths struct {
+	Title       Range // When zero, the range of the page's language, else 30-60 characters
+	Description Range // When zero, the range of the page's language, else 70-155 characters
+	Pixels      bool  // Judge too long by estimated width, as Google truncates, see titleMaxPixels
+}
+
+// Default recommended lengths
+var (
+	DefaultTitleRange = Range{Min: 30, Max: TitleMaxChars}
+	DefaultDescRange  = Range{Min: 70, Max: DescMaxChars}
+)
+
+// languageLengths are the recommended lengths of languages written in
+// characters about twice as wide as Latin ones, so that half as many fit
+var languageLengths = map[string]struct{ title, description Range }{
+	"ja": {Range{Min: 15, Max: 30}, Range{Min: 35, Max: 80}},
+	"zh": {Range{Min: 15, Max: 30}, Range{Min: 35, Max: 80}},
+	"ko": {Range{Min: 15, Max: 30}, Range{Min: 35, Max: 80}},
+}
+
+// LanguageLengths returns the recommended title and description lengths of
+// a language tag such as ja or zh-Hant-TW. ok is false for Latin-script and
+// unknown languages, which use DefaultTitleRange and DefaultDescRange.
+func LanguageLengths(lang string) (title, description Range, ok bool) {
+	primary, _ := splitLang(lang)
+	lengths, ok := languageLengths[primary]
+	if !ok {
+		return DefaultTitleRange, DefaultDescRange, false
+	}
+	return lengths.title, lengths.description, true
+}
+
+// Languages returns, sorted, the language subtags that have their own
+// recommended lengths
+func Languages() []string {
+	langs := make([]string, 0, len(languageLengths))
+	for lang := range languageLengths {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// titleRange and descRange are the recommended lengths of the page: the
+// configured ones, else those of its language. byLanguage reports whether
+// the language set them.
+func (m *PageMeta) titleRange() (r Range, byLanguage bool) {
+	if m.Lengths.Title.Max != 0 {
+		return m.Lengths.Title, false
+	}
+	title, _, ok := LanguageLengths(m.Lang)
+	return title, ok
+}
+
+func (m *PageMeta) descRange() (r Range, byLanguage bool) {
+	if m.Lengths.Description.Max != 0 {
+		return m.Lengths.Description, false
+	}
+	_, description, ok := LanguageLengths(m.Lang)
+	return description, ok
+}
+
+// titleMaxPixels and descMaxPixels are the widths past which a text is too
+// long in pixel mode: TitleMaxPixels and DescMaxPixels, or the configured
+// maximum converted at the same rate. A language's range doesn't apply,
+// since the width already counts wide characters double.
+func (m *PageMeta) titleMaxPixels() int {
+	if m.Lengths.Title.Max != 0 {
+		return m.Lengths.Title.Max * TitleMaxPixels / TitleMaxChars
+	}
+	return TitleMaxPixels
+}
+
+func (m *PageMeta) descMaxPixels() int {
+	if m.Lengths.Description.Max != 0 {
+		return m.Lengths.Description.Max * DescMaxPixels / DescMaxChars
+	}
+	return DescMaxPixels
+}
+
+// titleWidth and descWidth estimate the pixels a text takes in a search
+// result, scaled so an average Latin character is worth the width the
+// pixel limits are usually quoted for (600px for 60 characters)
+func titleWidth(s string) int {
+	return int(textWidth(s) * TitleMaxPixels / TitleMaxChars)
+}
+
+func descWidth(s string) int {
+	return int(textWidth(s) * DescMaxPixels / DescMaxChars)
+}
+
+// textWidth is the width of a text in average Latin characters: narrow
+// letters count less, capitals and wide letters more, and CJK, Hangul and
+// fullwidth characters twice as much
+func textWidth(s string) float64 {
+	width := 0.0
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+			r >= 0x3000 && r <= 0x303F || r >= 0xFF00 && r <= 0xFFEF:
+			width += 2
+		case strings.ContainsRune("iljtfrI.,;:!|'()[] ", r):
+			width += 0.55
+		case strings.ContainsRune("mwMW@", r):
+			width += 1.6
+		case unicode.IsUpper(r):
+			width += 1.3
+		default:
+			width += 1
+		}
+	}
+	return width
+}
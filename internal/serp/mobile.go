@@ -0,0 +1,101 @@
+package serp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/urlnorm"
+)
+
+// MobileAlternateCheck validates the separate mobile URL a desktop page
+// declares with <link rel="alternate" media="...">, the m-dot pattern. The
+// mobile page must answer and canonicalize back to the desktop page, or
+// search engines may index both or drop the wrong one.
+type MobileAlternateCheck struct {
+	DesktopURL string // Canonical of the desktop page, or its URL without one
+	MobileURL  string
+	Media      string
+	Error      string // Why the mobile URL could not be fetched, empty when it answered
+	Canonical  string // Canonical declared by the mobile page
+	Problems   []string
+}
+
+// OK reports whether the mobile alternate is consistent
+func (c *MobileAlternateCheck) OK() bool {
+	return len(c.Problems) == 0
+}
+
+// CheckMobileAlternate fetches the mobile alternate of a page with the
+// mobile user agent and checks both pages point at each other correctly.
+// It returns nil when the page declares no mobile alternate.
+func (f *Fetcher) CheckMobileAlternate(meta *PageMeta) *MobileAlternateCheck {
+	if meta.MobileAlternate == "" {
+		return nil
+	}
+
+	desktopURL := meta.URL
+	if meta.Canonical != "" {
+		desktopURL = meta.Canonical
+	}
+	check := &MobileAlternateCheck{
+		DesktopURL: desktopURL,
+		MobileURL:  meta.MobileAlternate,
+		Media:      meta.MobileMedia,
+	}
+
+	if sameURL(meta.MobileAlternate, desktopURL) {
+		check.Problems = append(check.Problems, "The mobile alternate is the desktop page itself")
+		return check
+	}
+	if meta.Canonical != "" && sameURL(meta.Canonical, meta.MobileAlternate) {
+		check.Problems = append(check.Problems, "The desktop page canonicalizes to its mobile alternate; the desktop URL should be canonical")
+	}
+
+	mobile, err := f.AnalyzeAs(meta.MobileAlternate, MobileUserAgent)
+	if err != nil {
+		check.Error = err.Error()
+		check.Problems = append(check.Problems, "The mobile URL is unreachable: "+check.Error)
+		return check
+	}
+
+	check.Canonical = mobile.Canonical
+	switch {
+	case mobile.Canonical == "":
+		check.Problems = append(check.Problems, "The mobile page declares no canonical; it should point to the desktop URL")
+	case !sameURL(mobile.Canonical, desktopURL):
+		check.Problems = append(check.Problems, fmt.Sprintf("The mobile page canonicalizes to %s, not to the desktop URL", mobile.Canonical))
+	}
+	return check
+}
+
+// sameURL compares normalized URLs, ignoring a trailing slash
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(urlnorm.Normalize(a), "/") == strings.TrimSuffix(urlnorm.Normalize(b), "/")
+}
+
+// isMobileMedia reports whether the media query of an alternate targets
+// small screens, as a separate mobile URL's does: "only screen and
+// (max-width: 640px)". Alternates for print or other media are not mobile
+// versions.
+func isMobileMedia(media string) bool {
+	return strings.Contains(strings.ToLower(media), "max-width")
+}
+
+// Print displays the mobile alternate and its problems
+func (c *MobileAlternateCheck) Print() {
+	fmt.Println()
+	fmt.Printf("%s%sMobile alternate:%s\n", colorBold, colorYellow, colorReset)
+	fmt.Printf("  %s\n", c.MobileURL)
+	fmt.Printf("  %smedia: %s%s\n", colorGray, c.Media, colorReset)
+	if c.Canonical != "" {
+		fmt.Printf("  %scanonical: %s%s\n", colorGray, c.Canonical, colorReset)
+	}
+
+	if c.OK() {
+		fmt.Printf("  %s✓%s Reachable and canonicalizes back to the desktop URL\n", colorGreen, colorReset)
+		return
+	}
+	for _, problem := range c.Problems {
+		fmt.Printf("  %s✗%s %s%s%s\n", colorRed, colorReset, colorRed, problem, colorReset)
+	}
+}
@@ -7,35 +7,71 @@ import (
 	"strings"
 
 	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
+	"github.com/ngonzalez/web-tools/internal/htmlhead"
+	"github.com/ngonzalez/web-tools/internal/spa"
 )
 
-// ExtractMeta parses HTML and extracts all SEO-relevant metadata
+// ExtractMeta parses HTML and extracts all SEO-relevant metadata. The page
+// is streamed through a tokenizer rather than built into a DOM.
 func ExtractMeta(body io.Reader, pageURL string) *PageMeta {
-	meta := &PageMeta{
-		URL: pageURL,
-	}
+	return extractMeta(body, pageURL, false)
+}
 
-	doc, err := html.Parse(body)
-	if err != nil {
-		return meta
+// ExtractHead is ExtractMeta stopping at the end of <head>, without reading
+// the body. The H1, JSON-LD declared in the body and the client-rendering
+// check are left out; PageMeta.HeadOnly records it.
+func ExtractHead(body io.Reader, pageURL string) *PageMeta {
+	return extractMeta(body, pageURL, true)
+}
+
+func extractMeta(body io.Reader, pageURL string, headOnly bool) *PageMeta {
+	meta := &PageMeta{
+		URL:      pageURL,
+		HeadOnly: headOnly,
 	}
 
 	baseURL, _ := url.Parse(pageURL)
+	tokenizer := html.NewTokenizer(body)
+	var rendering spa.Detector
+
+parse:
+	for {
+		tokenType := tokenizer.Next()
 
-	var parseNode func(*html.Node)
-	parseNode = func(n *html.Node) {
-		if n.Type == html.ElementNode {
-			switch n.Data {
+		switch tokenType {
+		case html.ErrorToken:
+			break parse
+
+		case html.TextToken, html.EndTagToken:
+			token := tokenizer.Token()
+			if headOnly && htmlhead.IsEnd(tokenType, token.Data) {
+				break parse
+			}
+			rendering.Token(tokenType, token)
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if headOnly && htmlhead.IsEnd(tokenType, token.Data) {
+				break parse
+			}
+			rendering.Token(tokenType, token)
+			if construct := deprecatedConstruct(token); construct != "" {
+				meta.addDeprecated(construct)
+			}
+
+			switch token.Data {
 			case "title":
-				if n.FirstChild != nil {
-					meta.Title = strings.TrimSpace(n.FirstChild.Data)
+				if tokenizer.Next() == html.TextToken {
+					meta.Title = strings.TrimSpace(tokenizer.Token().Data)
 				}
 
 			case "meta":
-				name := strings.ToLower(getAttr(n, "name"))
-				property := strings.ToLower(getAttr(n, "property"))
-				content := getAttr(n, "content")
-				charset := getAttr(n, "charset")
+				name := strings.ToLower(htmlattr.Get(token, "name"))
+				property := strings.ToLower(htmlattr.Get(token, "property"))
+				content := htmlattr.Get(token, "content")
+				charset := htmlattr.Get(token, "charset")
 
 				if charset != "" {
 					meta.Charset = charset
@@ -49,6 +85,8 @@ func ExtractMeta(body io.Reader, pageURL string) *PageMeta {
 					meta.Robots = content
 				case "googlebot":
 					meta.GoogleBot = content
+				case "keywords":
+					meta.Keywords = content
 				}
 
 				// Open Graph
@@ -57,12 +95,28 @@ func ExtractMeta(body io.Reader, pageURL string) *PageMeta {
 					meta.OGTitle = content
 				case "og:description":
 					meta.OGDescription = content
-				case "og:image":
-					meta.OGImage = resolveURL(content, baseURL)
+				case "og:image", "og:image:url":
+					// Several images are allowed, the first one is primary
+					if image := resolveURL(content, baseURL); image != "" {
+						meta.OGImages = append(meta.OGImages, image)
+						if meta.OGImage == "" {
+							meta.OGImage = image
+						}
+					}
+				case "og:locale":
+					meta.OGLocale = content
+				case "og:locale:alternate":
+					meta.OGLocaleAlternates = append(meta.OGLocaleAlternates, content)
 				case "og:type":
 					meta.OGType = content
 				case "og:site_name":
 					meta.OGSiteName = content
+				case "article:modified_time":
+					meta.ModifiedTime = content
+				case "og:updated_time":
+					if meta.ModifiedTime == "" {
+						meta.ModifiedTime = content
+					}
 				}
 
 				// Twitter Cards
@@ -78,8 +132,8 @@ func ExtractMeta(body io.Reader, pageURL string) *PageMeta {
 				}
 
 			case "link":
-				rel := strings.ToLower(getAttr(n, "rel"))
-				href := getAttr(n, "href")
+				rel := strings.ToLower(htmlattr.Get(token, "rel"))
+				href := htmlattr.Get(token, "href")
 
 				switch rel {
 				case "canonical":
@@ -88,43 +142,47 @@ func ExtractMeta(body io.Reader, pageURL string) *PageMeta {
 					if meta.Favicon == "" {
 						meta.Favicon = resolveURL(href, baseURL)
 					}
+				case "alternate":
+					if hreflang := strings.TrimSpace(htmlattr.Get(token, "hreflang")); hreflang != "" {
+						meta.Hreflangs = append(meta.Hreflangs, Hreflang{Lang: hreflang, URL: resolveURL(href, baseURL)})
+						break
+					}
+					// Separate mobile URL (m-dot site), unlike hreflang alternates
+					if media := htmlattr.Get(token, "media"); isMobileMedia(media) && meta.MobileAlternate == "" {
+						meta.MobileAlternate = resolveURL(href, baseURL)
+						meta.MobileMedia = media
+					}
 				}
 
 			case "h1":
-				if meta.H1 == "" {
-					meta.H1 = extractTextContent(n)
+				if tokenType == html.StartTagToken {
+					meta.H1Count++
+					if meta.H1 == "" {
+						meta.H1 = extractTextContent(tokenizer, "h1")
+					}
 				}
 
 			case "html":
-				lang := getAttr(n, "lang")
+				lang := htmlattr.Get(token, "lang")
 				if lang != "" {
 					meta.Lang = lang
 				}
 
 			case "script":
-				scriptType := getAttr(n, "type")
-				if scriptType == "application/ld+json" && n.FirstChild != nil {
-					parseJSONLD(n.FirstChild.Data, meta)
+				scriptType := htmlattr.Get(token, "type")
+				if scriptType == "application/ld+json" && tokenizer.Next() == html.TextToken {
+					parseJSONLD(tokenizer.Token().Data, meta, baseURL)
 				}
 			}
 		}
-
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			parseNode(c)
-		}
 	}
 
-	parseNode(doc)
-	return meta
-}
-
-func getAttr(n *html.Node, key string) string {
-	for _, attr := range n.Attr {
-		if strings.ToLower(attr.Key) == key {
-			return attr.Val
-		}
+	checkBreadcrumbTargets(meta)
+	// Without the body there is nothing to judge the rendering on
+	if !headOnly && rendering.ClientRendered() {
+		meta.ClientRendered = rendering.Evidence()
 	}
-	return ""
+	return meta
 }
 
 func resolveURL(href string, baseURL *url.URL) string {
@@ -140,28 +198,30 @@ func resolveURL(href string, baseURL *url.URL) string {
 	return baseURL.ResolveReference(parsed).String()
 }
 
-func extractTextContent(n *html.Node) string {
+// extractTextContent collects the text up to the closing tag of the element
+// the tokenizer just entered
+func extractTextContent(tokenizer *html.Tokenizer, tag string) string {
 	var text strings.Builder
 
-	var extract func(*html.Node)
-	extract = func(node *html.Node) {
-		if node.Type == html.TextNode {
-			text.WriteString(node.Data)
-		}
-		for c := node.FirstChild; c != nil; c = c.NextSibling {
-			extract(c)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(text.String())
+		case html.TextToken:
+			text.Write(tokenizer.Text())
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == tag {
+				return strings.TrimSpace(text.String())
+			}
 		}
 	}
-
-	extract(n)
-	return strings.TrimSpace(text.String())
 }
 
-func parseJSONLD(data string, meta *PageMeta) {
+func parseJSONLD(data string, meta *PageMeta, baseURL *url.URL) {
 	// Try to parse as single object
 	var single map[string]interface{}
 	if err := json.Unmarshal([]byte(data), &single); err == nil {
-		extractSchemaType(single, meta)
+		extractSchemaType(single, meta, baseURL)
 		return
 	}
 
@@ -169,30 +229,45 @@ func parseJSONLD(data string, meta *PageMeta) {
 	var arr []map[string]interface{}
 	if err := json.Unmarshal([]byte(data), &arr); err == nil {
 		for _, item := range arr {
-			extractSchemaType(item, meta)
+			extractSchemaType(item, meta, baseURL)
 		}
 	}
 }
 
-func extractSchemaType(obj map[string]interface{}, meta *PageMeta) {
+func extractSchemaType(obj map[string]interface{}, meta *PageMeta, baseURL *url.URL) {
 	if t, ok := obj["@type"]; ok {
+		var types []string
 		switch v := t.(type) {
 		case string:
-			meta.SchemaTypes = append(meta.SchemaTypes, v)
+			types = append(types, v)
 		case []interface{}:
 			for _, item := range v {
 				if s, ok := item.(string); ok {
-					meta.SchemaTypes = append(meta.SchemaTypes, s)
+					types = append(types, s)
 				}
 			}
 		}
+		meta.SchemaTypes = append(meta.SchemaTypes, types...)
+
+		for _, schemaType := range types {
+			if schemaType == "BreadcrumbList" {
+				meta.Breadcrumbs = append(meta.Breadcrumbs, parseBreadcrumb(obj, baseURL))
+				break
+			}
+		}
+		for _, schemaType := range types {
+			if result, ok := checkRichResult(schemaType, obj); ok {
+				meta.RichResults = append(meta.RichResults, result)
+				break
+			}
+		}
 	}
 
 	// Check for @graph
 	if graph, ok := obj["@graph"].([]interface{}); ok {
 		for _, item := range graph {
 			if m, ok := item.(map[string]interface{}); ok {
-				extractSchemaType(m, meta)
+				extractSchemaType(m, meta, baseURL)
 			}
 		}
 	}
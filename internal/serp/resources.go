@@ -0,0 +1,55 @@
+package serp
+
+import "sync"
+
+// resourceConcurrency caps the requests sent at once for the URLs a page
+// refers to, kept small as they all go to the same few hosts
+const resourceConcurrency = 4
+
+// resourceStatus is the answer to a request for a URL the page refers to
+type resourceStatus struct {
+	Status int
+	Error  string
+}
+
+// CheckResources runs the checks requesting the URLs a page refers to, the
+// breadcrumb items and the mobile alternate, at the same time rather than
+// one after the other after the main fetch. It returns the mobile
+// alternate check, nil when the page declares none.
+func (f *Fetcher) CheckResources(meta *PageMeta) *MobileAlternateCheck {
+	var mobile *MobileAlternateCheck
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mobile = f.CheckMobileAlternate(meta)
+	}()
+
+	f.CheckBreadcrumbs(meta)
+	wg.Wait()
+	return mobile
+}
+
+// statuses requests URLs, resourceConcurrency at a time, and returns the
+// answer to each
+func (f *Fetcher) statuses(urls []string) map[string]resourceStatus {
+	results := make([]resourceStatus, len(urls))
+	slots := make(chan struct{}, resourceConcurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, u string) {
+			defer wg.Done()
+			results[i].Status, results[i].Error = f.status(u)
+			<-slots
+		}(i, u)
+	}
+	wg.Wait()
+
+	statuses := make(map[string]resourceStatus, len(urls))
+	for i, u := range urls {
+		statuses[u] = results[i]
+	}
+	return statuses
+}
@@ -0,0 +1,172 @@
+package serp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RichResult is the rich-result eligibility of one JSON-LD object of a
+// type Google shows as a rich result: FAQPage, HowTo or Article. Missing
+// lists the properties Google needs that the object doesn't provide.
+type RichResult struct {
+	Type    string // Schema.org type as declared, e.g. BlogPosting
+	Missing []string
+}
+
+// Eligible reports whether the object has what its rich result needs
+func (r RichResult) Eligible() bool {
+	return len(r.Missing) == 0
+}
+
+// articleTypes are the types of article rich results
+var articleTypes = map[string]bool{
+	"Article":     true,
+	"NewsArticle": true,
+	"BlogPosting": true,
+}
+
+// checkRichResult returns the eligibility of an object of one of the rich
+// result types, or false for other types
+func checkRichResult(schemaType string, obj map[string]interface{}) (RichResult, bool) {
+	result := RichResult{Type: schemaType}
+	switch {
+	case schemaType == "FAQPage":
+		result.Missing = faqMissing(obj)
+	case schemaType == "HowTo":
+		result.Missing = howToMissing(obj)
+	case articleTypes[schemaType]:
+		for _, property := range []string{"headline", "datePublished", "author", "image"} {
+			if !hasValue(obj[property]) {
+				result.Missing = append(result.Missing, property)
+			}
+		}
+	default:
+		return RichResult{}, false
+	}
+	return result, true
+}
+
+// faqMissing checks that an FAQPage lists its questions in mainEntity, each
+// with a name and an acceptedAnswer text
+func faqMissing(obj map[string]interface{}) []string {
+	questions := jsonObjects(obj["mainEntity"])
+	if len(questions) == 0 {
+		return []string{"mainEntity"}
+	}
+
+	var missing []string
+	for i, question := range questions {
+		if jsonString(question["name"]) == "" {
+			missing = append(missing, fmt.Sprintf("mainEntity[%d].name", i+1))
+		}
+		answers := jsonObjects(question["acceptedAnswer"])
+		if len(answers) == 0 || jsonString(answers[0]["text"]) == "" {
+			missing = append(missing, fmt.Sprintf("mainEntity[%d].acceptedAnswer.text", i+1))
+		}
+	}
+	return missing
+}
+
+// howToMissing checks that a HowTo has a name and steps, each step with a
+// text, or a section with steps of its own
+func howToMissing(obj map[string]interface{}) []string {
+	var missing []string
+	if jsonString(obj["name"]) == "" {
+		missing = append(missing, "name")
+	}
+
+	// A step may be plain text
+	var steps []map[string]interface{}
+	switch v := obj["step"].(type) {
+	case string:
+		if strings.TrimSpace(v) != "" {
+			return missing
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				steps = append(steps, map[string]interface{}{"text": s})
+			}
+		}
+	}
+	steps = append(steps, jsonObjects(obj["step"])...)
+	if len(steps) == 0 {
+		return append(missing, "step")
+	}
+
+	for i, step := range steps {
+		if jsonString(step["text"]) != "" {
+			continue
+		}
+		if len(jsonObjects(step["itemListElement"])) > 0 {
+			continue // HowToSection
+		}
+		missing = append(missing, fmt.Sprintf("step[%d].text", i+1))
+	}
+	return missing
+}
+
+// jsonObjects returns a JSON value as a list of objects: the object itself,
+// or the objects of an array
+func jsonObjects(v interface{}) []map[string]interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		var objects []map[string]interface{}
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, m)
+			}
+		}
+		return objects
+	}
+	return nil
+}
+
+// hasValue reports whether a JSON property holds something: a non-blank
+// string, an object or a non-empty array
+func hasValue(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v) != ""
+	case map[string]interface{}:
+		return len(v) > 0
+	case []interface{}:
+		for _, item := range v {
+			if hasValue(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RichResultProblems counts the rich-result objects missing a property
+func (m *PageMeta) RichResultProblems() int {
+	problems := 0
+	for _, r := range m.RichResults {
+		if !r.Eligible() {
+			problems++
+		}
+	}
+	return problems
+}
+
+// printRichResults displays the rich-result eligibility of each FAQPage,
+// HowTo and Article object
+func (m *PageMeta) printRichResults() {
+	if len(m.RichResults) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%sRich Results:%s\n", colorBold, colorYellow, colorReset)
+	for _, r := range m.RichResults {
+		if r.Eligible() {
+			fmt.Printf("  %s✓%s %s: eligible\n", colorGreen, colorReset, r.Type)
+			continue
+		}
+		fmt.Printf("  %s✗%s %s: %smissing %s%s\n", colorRed, colorReset, r.Type, colorRed, strings.Join(r.Missing, ", "), colorReset)
+	}
+}
@@ -0,0 +1,81 @@
+package serp
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// MinSnippetChars is the shortest paragraph taken as meaningful body text
+const MinSnippetChars = 50
+
+// snippetSkip are the elements whose paragraphs are boilerplate, not content
+var snippetSkip = map[string]bool{
+	"nav":      true,
+	"header":   true,
+	"footer":   true,
+	"aside":    true,
+	"form":     true,
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+}
+
+// SnippetCollector finds the first meaningful paragraph of a page, the text
+// Google likely shows when the page has no meta description. It is fed the
+// tokens of a parse that is already running and never advances the
+// tokenizer itself, so the caller still sees every link.
+type SnippetCollector struct {
+	inParagraph bool
+	skip        int // Depth inside boilerplate elements
+	text        strings.Builder
+	snippet     string
+}
+
+// Token feeds the next token of the page
+func (s *SnippetCollector) Token(tokenType html.TokenType, token html.Token) {
+	if s.snippet != "" {
+		return
+	}
+
+	switch tokenType {
+	case html.StartTagToken:
+		if snippetSkip[token.Data] {
+			s.skip++
+		} else if token.Data == "p" && s.skip == 0 {
+			s.inParagraph = true
+			s.text.Reset()
+		}
+
+	case html.EndTagToken:
+		if snippetSkip[token.Data] && s.skip > 0 {
+			s.skip--
+		} else if token.Data == "p" && s.inParagraph {
+			s.inParagraph = false
+			text := strings.Join(strings.Fields(s.text.String()), " ")
+			if utf8.RuneCountInString(text) >= MinSnippetChars {
+				s.snippet = text
+			}
+		}
+
+	case html.TextToken:
+		if s.inParagraph && s.skip == 0 {
+			s.text.WriteString(token.Data)
+		}
+	}
+}
+
+// Done reports whether a snippet was found, so the caller may stop feeding
+func (s *SnippetCollector) Done() bool {
+	return s.snippet != ""
+}
+
+// Snippet returns the probable auto-snippet, cut to DescMaxChars like a
+// description, or "" when the page has no meaningful paragraph
+func (s *SnippetCollector) Snippet() string {
+	if utf8.RuneCountInString(s.snippet) > DescMaxChars {
+		return truncateString(s.snippet, DescMaxChars-3) + "..."
+	}
+	return s.snippet
+}
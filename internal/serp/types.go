@@ -2,25 +2,40 @@ package serp
 
 import (
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
+
+	"github.com/ngonzalez/web-tools/internal/robots"
+	"github.com/ngonzalez/web-tools/internal/spa"
 )
 
 // PageMeta holds extracted SEO metadata
 type PageMeta struct {
-	URL             string
-	Title           string
-	MetaDescription string
-	OGTitle         string
-	OGDescription   string
-	OGImage         string
-	OGType          string
-	OGSiteName      string
-	Canonical       string
-	H1              string
-	Favicon         string
-	Lang            string
-	Charset         string
+	URL                string
+	Title              string
+	MetaDescription    string
+	OGTitle            string
+	OGDescription      string
+	OGImage            string   // Primary image, the first og:image declared
+	OGImages           []string // Every og:image, in document order
+	OGLocale           string
+	OGLocaleAlternates []string
+	OGType             string
+	OGSiteName         string
+	Canonical          string
+	MobileAlternate    string // Separate mobile URL from <link rel="alternate" media="..."> with a max-width query
+	MobileMedia        string // Media query of the mobile alternate
+	H1                 string // Text of the first H1
+	H1Count            int    // Number of H1 tags; one is expected
+	Favicon            string
+	Lang               string
+	Hreflangs          []Hreflang // Language alternates, in document order
+	Charset            string
+	ClientRendered     string // What suggests the page is rendered by JavaScript, empty when it is not
+	HeadOnly           bool   // Only <head> was parsed, the H1 and body JSON-LD are unknown
 
 	// Twitter cards
 	TwitterCard        string
@@ -30,10 +45,23 @@ type PageMeta struct {
 
 	// Schema.org
 	SchemaTypes []string
+	Breadcrumbs []Breadcrumb // BreadcrumbList items, see CheckBreadcrumbs
+	RichResults []RichResult // FAQPage, HowTo and Article objects, in document order
 
 	// Robots
-	Robots      string
-	GoogleBot   string
+	Robots    string
+	GoogleBot string
+
+	// Legacy markup, see printLegacy
+	Keywords   string   // <meta name="keywords">, ignored by search engines
+	Deprecated []string // Deprecated meta tags and obsolete elements, each once, in document order
+
+	// Freshness
+	ModifiedTime string // article:modified_time or og:updated_time
+	LastModified string // Last-Modified response header
+
+	// Recommended lengths the analysis checks, set from Config.Lengths
+	Lengths Lengths
 }
 
 // SERPPreview represents how the page will appear in Google
@@ -186,21 +214,39 @@ func (m *PageMeta) PrintMetaAnalysis() {
 	fmt.Printf("%s%s=== SEO Analysis ===%s\n", colorBold, colorCyan, colorReset)
 	fmt.Println()
 
+	if m.ClientRendered != "" {
+		fmt.Printf("%s⚠ The %s (%s)%s\n", colorYellow, spa.Warning, m.ClientRendered, colorReset)
+		fmt.Println()
+	}
+
 	// Title analysis
 	fmt.Printf("%s%sTitle:%s\n", colorBold, colorYellow, colorReset)
 	if m.Title != "" {
 		titleLen := utf8.RuneCountInString(m.Title)
+		width := titleWidth(m.Title)
+		recommended, byLanguage := m.titleRange()
 		status := colorGreen + "✓" + colorReset
 		warning := ""
-		if titleLen > TitleMaxChars {
+		switch {
+		case m.Lengths.Pixels && width > m.titleMaxPixels():
+			status = colorRed + "✗" + colorReset
+			warning = fmt.Sprintf(" %s(too long: ~%d/%d px)%s", colorRed, width, m.titleMaxPixels(), colorReset)
+		case !m.Lengths.Pixels && titleLen > recommended.Max:
 			status = colorRed + "✗" + colorReset
-			warning = fmt.Sprintf(" %s(too long: %d/%d chars)%s", colorRed, titleLen, TitleMaxChars, colorReset)
-		} else if titleLen < 30 {
+			warning = fmt.Sprintf(" %s(too long: %d/%d chars)%s", colorRed, titleLen, recommended.Max, colorReset)
+		case titleLen < recommended.Min:
 			status = colorYellow + "!" + colorReset
-			warning = fmt.Sprintf(" %s(too short: %d chars, recommended: 30-60)%s", colorYellow, titleLen, colorReset)
+			warning = fmt.Sprintf(" %s(too short: %d chars, recommended: %s)%s", colorYellow, titleLen, recommended, colorReset)
 		}
 		fmt.Printf("  %s %s%s\n", status, m.Title, warning)
-		fmt.Printf("    %sLength: %d characters%s\n", colorGray, titleLen, colorReset)
+		if m.Lengths.Pixels {
+			fmt.Printf("    %sLength: %d characters, ~%d px%s\n", colorGray, titleLen, width, colorReset)
+		} else {
+			fmt.Printf("    %sLength: %d characters%s\n", colorGray, titleLen, colorReset)
+		}
+		if byLanguage {
+			fmt.Printf("    %sRecommended for lang=%s: %s characters%s\n", colorGray, m.Lang, recommended, colorReset)
+		}
 	} else {
 		fmt.Printf("  %s✗%s %sMissing!%s\n", colorRed, colorReset, colorRed, colorReset)
 	}
@@ -210,14 +256,20 @@ func (m *PageMeta) PrintMetaAnalysis() {
 	fmt.Printf("%s%sMeta Description:%s\n", colorBold, colorYellow, colorReset)
 	if m.MetaDescription != "" {
 		descLen := utf8.RuneCountInString(m.MetaDescription)
+		width := descWidth(m.MetaDescription)
+		recommended, byLanguage := m.descRange()
 		status := colorGreen + "✓" + colorReset
 		warning := ""
-		if descLen > DescMaxChars {
+		switch {
+		case m.Lengths.Pixels && width > m.descMaxPixels():
+			status = colorRed + "✗" + colorReset
+			warning = fmt.Sprintf(" %s(too long: ~%d/%d px)%s", colorRed, width, m.descMaxPixels(), colorReset)
+		case !m.Lengths.Pixels && descLen > recommended.Max:
 			status = colorRed + "✗" + colorReset
-			warning = fmt.Sprintf(" %s(too long: %d/%d chars)%s", colorRed, descLen, DescMaxChars, colorReset)
-		} else if descLen < 70 {
+			warning = fmt.Sprintf(" %s(too long: %d/%d chars)%s", colorRed, descLen, recommended.Max, colorReset)
+		case descLen < recommended.Min:
 			status = colorYellow + "!" + colorReset
-			warning = fmt.Sprintf(" %s(too short: %d chars, recommended: 70-155)%s", colorYellow, descLen, colorReset)
+			warning = fmt.Sprintf(" %s(too short: %d chars, recommended: %s)%s", colorYellow, descLen, recommended, colorReset)
 		}
 		wrapped := wrapText(m.MetaDescription, 65)
 		lines := strings.Split(wrapped, "\n")
@@ -225,7 +277,14 @@ func (m *PageMeta) PrintMetaAnalysis() {
 		for _, line := range lines[1:] {
 			fmt.Printf("    %s\n", line)
 		}
-		fmt.Printf("    %sLength: %d characters%s\n", colorGray, descLen, colorReset)
+		if m.Lengths.Pixels {
+			fmt.Printf("    %sLength: %d characters, ~%d px%s\n", colorGray, descLen, width, colorReset)
+		} else {
+			fmt.Printf("    %sLength: %d characters%s\n", colorGray, descLen, colorReset)
+		}
+		if byLanguage {
+			fmt.Printf("    %sRecommended for lang=%s: %s characters%s\n", colorGray, m.Lang, recommended, colorReset)
+		}
 	} else {
 		fmt.Printf("  %s✗%s %sMissing! Google will use a page excerpt.%s\n", colorRed, colorReset, colorRed, colorReset)
 	}
@@ -247,8 +306,13 @@ func (m *PageMeta) PrintMetaAnalysis() {
 	// H1
 	fmt.Println()
 	fmt.Printf("%s%sH1:%s\n", colorBold, colorYellow, colorReset)
-	if m.H1 != "" {
+	if m.HeadOnly {
+		fmt.Printf("  %s-%s %sNot checked, only <head> was parsed%s\n", colorGray, colorReset, colorGray, colorReset)
+	} else if m.H1 != "" {
 		fmt.Printf("  %s✓%s %s\n", colorGreen, colorReset, m.H1)
+		if m.H1Count > 1 {
+			fmt.Printf("  %s!%s %s%d H1 tags, keep a single one%s\n", colorYellow, colorReset, colorYellow, m.H1Count, colorReset)
+		}
 	} else {
 		fmt.Printf("  %s!%s %sNo H1 found%s\n", colorYellow, colorReset, colorYellow, colorReset)
 	}
@@ -265,6 +329,8 @@ func (m *PageMeta) PrintMetaAnalysis() {
 		{"og:image", m.OGImage},
 		{"og:type", m.OGType},
 		{"og:site_name", m.OGSiteName},
+		{"og:locale", m.OGLocale},
+		{"og:locale:alternate", strings.Join(m.OGLocaleAlternates, ", ")},
 	}
 
 	hasOG := false
@@ -276,6 +342,15 @@ func (m *PageMeta) PrintMetaAnalysis() {
 				value = value[:57] + "..."
 			}
 			fmt.Printf("  %s✓%s %s: %s\n", colorGreen, colorReset, item.name, value)
+			if item.name == "og:image" && len(m.OGImages) > 1 {
+				fmt.Printf("    %s%d images declared, the first is primary. Others:%s\n", colorGray, len(m.OGImages), colorReset)
+				for _, img := range m.OGImages[1:] {
+					if len(img) > 60 {
+						img = img[:57] + "..."
+					}
+					fmt.Printf("    %s- %s%s\n", colorGray, img, colorReset)
+				}
+			}
 		}
 	}
 	if !hasOG {
@@ -315,14 +390,14 @@ func (m *PageMeta) PrintMetaAnalysis() {
 	fmt.Printf("%s%sRobots:%s\n", colorBold, colorYellow, colorReset)
 	if m.Robots != "" {
 		status := colorGreen + "✓" + colorReset
-		if strings.Contains(strings.ToLower(m.Robots), "noindex") {
+		if robots.IsNoIndex(m.Robots) {
 			status = colorRed + "✗" + colorReset
 		}
 		fmt.Printf("  %s meta robots: %s\n", status, m.Robots)
 	}
 	if m.GoogleBot != "" {
 		status := colorGreen + "✓" + colorReset
-		if strings.Contains(strings.ToLower(m.GoogleBot), "noindex") {
+		if robots.IsNoIndex(m.GoogleBot) {
 			status = colorRed + "✗" + colorReset
 		}
 		fmt.Printf("  %s googlebot: %s\n", status, m.GoogleBot)
@@ -339,8 +414,93 @@ func (m *PageMeta) PrintMetaAnalysis() {
 			fmt.Printf("  %s✓%s %s\n", colorGreen, colorReset, t)
 		}
 	}
+	m.printBreadcrumbs()
+	m.printRichResults()
+
+	m.printLanguage()
+	m.printLegacy()
 
+	// Freshness
 	fmt.Println()
+	fmt.Printf("%s%sLast Modified:%s\n", colorBold, colorYellow, colorReset)
+	if modified, source, ok := m.Modified(); ok {
+		days := int(time.Since(modified).Hours() / 24)
+		status := colorGreen + "✓" + colorReset
+		if days > 365 {
+			status = colorYellow + "!" + colorReset
+		}
+		fmt.Printf("  %s %s (%d days ago)\n", status, modified.Format("2006-01-02"), days)
+		fmt.Printf("    %sFrom %s%s\n", colorGray, source, colorReset)
+	} else {
+		fmt.Printf("  %s-%s %sNo modification date declared%s\n", colorGray, colorReset, colorGray, colorReset)
+	}
+
+	fmt.Println()
+}
+
+// SummaryLine returns a single machine-readable key=value summary line.
+// The H1 keys read unknown when only <head> was parsed.
+func (m *PageMeta) SummaryLine() string {
+	noindex := robots.IsNoIndex(m.Robots, m.GoogleBot)
+	h1, h1Count := strconv.FormatBool(m.H1 != ""), strconv.Itoa(m.H1Count)
+	if m.HeadOnly {
+		h1, h1Count = "unknown", "unknown"
+	}
+	return fmt.Sprintf("SUMMARY tool=serpreview title_len=%d desc_len=%d canonical=%t h1=%s h1_count=%s og=%t twitter=%t schema=%d breadcrumbs=%d breadcrumb_problems=%d rich_results=%d rich_result_problems=%d noindex=%t lang_mismatch=%t client_rendered=%t head_only=%t meta_keywords=%d deprecated=%d",
+		utf8.RuneCountInString(m.Title),
+		utf8.RuneCountInString(m.MetaDescription),
+		m.Canonical != "",
+		h1,
+		h1Count,
+		m.OGTitle != "" || m.OGDescription != "",
+		m.TwitterCard != "",
+		len(m.SchemaTypes),
+		len(m.Breadcrumbs),
+		m.BreadcrumbProblems(),
+		len(m.RichResults),
+		m.RichResultProblems(),
+		noindex,
+		m.LangMismatch() != "",
+		m.ClientRendered != "",
+		m.HeadOnly,
+		m.KeywordCount(),
+		len(m.Deprecated))
+}
+
+// Modified returns the page's last modification date and where it came
+// from. Article metadata wins over the Last-Modified header, which often
+// only reflects the deploy time. Missing or invalid dates return ok=false.
+func (m *PageMeta) Modified() (modified time.Time, source string, ok bool) {
+	if t, ok := ParseDate(m.ModifiedTime); ok {
+		return t, "page metadata", true
+	}
+	if t, err := http.ParseTime(m.LastModified); err == nil {
+		return t, "Last-Modified header", true
+	}
+	return time.Time{}, "", false
+}
+
+// dateLayouts are the formats found in article:modified_time and similar tags
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// ParseDate reads a metadata date such as article:modified_time, ignoring
+// values in unknown formats
+func ParseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
 }
 
 // Helper functions
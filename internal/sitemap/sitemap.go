@@ -0,0 +1,321 @@
+package sitemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ngonzalez/web-tools/internal/robots"
+)
+
+// MaxSitemaps caps how many documents one Load fetches, sitemap index children included
+const MaxSitemaps = 100
+
+// Limits of a single sitemap set by the protocol
+const (
+	MaxEntries = 50000    // URLs in a sitemap, or sitemaps in a sitemap index
+	MaxSize    = 50 << 20 // Uncompressed bytes
+)
+
+// formats are the root elements Load understands
+var formats = map[string]bool{
+	"urlset":       true, // Sitemap
+	"sitemapindex": true, // Sitemap index, listing other sitemaps
+	"rss":          true, // RSS 2.0 feed
+	"feed":         true, // Atom feed
+}
+
+// Entry is a page URL listed in a sitemap or feed
+type Entry struct {
+	URL     string
+	Sitemap string // Document that listed the URL, a child when loading a sitemap index
+}
+
+// Document is a sitemap, sitemap index or feed fetched by Inspect
+type Document struct {
+	URL      string
+	Format   string   // Root element: urlset, sitemapindex, rss or feed
+	Gzip     bool     // The body was gzip-compressed
+	Size     int64    // Uncompressed bytes read
+	Entries  int      // Page URLs listed, duplicates included
+	Children int      // Sitemaps listed by a sitemap index
+	Invalid  []string // Listed URLs that are not absolute http(s) URLs, as a sitemap requires
+	Err      error    // Why the document could not be fetched or parsed
+}
+
+// Load fetches a sitemap, a sitemap index or an RSS/Atom feed and returns the
+// page URLs it lists, in document order and without duplicates. The format is
+// detected from the root element and gzip bodies are decompressed, so
+// sitemap.xml.gz works like sitemap.xml. Children of a sitemap index are
+// followed; when some of them fail, the URLs found elsewhere are returned
+// along with the error.
+func Load(client *http.Client, rawURL string) ([]Entry, error) {
+	l := newLoader(client)
+	if err := l.load(rawURL); err != nil {
+		return nil, err
+	}
+	return l.entries, errors.Join(l.errs...)
+}
+
+// Inspect is Load for validating sitemaps. It also returns every document
+// it fetched, in order, with its size and counts; the errors are those of
+// the documents rather than one joined error.
+func Inspect(client *http.Client, rawURL string) ([]Entry, []Document) {
+	l := newLoader(client)
+	l.load(rawURL)
+	return l.entries, l.docs
+}
+
+type loader struct {
+	client  *http.Client
+	seen    map[string]bool // Documents already fetched
+	fetched int
+	pages   map[string]bool
+	entries []Entry
+	errs    []error // Failed sitemap index children
+	docs    []Document
+}
+
+func newLoader(client *http.Client) *loader {
+	return &loader{
+		client: client,
+		seen:   make(map[string]bool),
+		pages:  make(map[string]bool),
+	}
+}
+
+// load fetches one document and follows the sitemaps it lists
+func (l *loader) load(rawURL string) error {
+	if l.seen[rawURL] {
+		return nil
+	}
+	l.seen[rawURL] = true
+
+	// The slot is taken first so an index comes before its children
+	i := len(l.docs)
+	l.docs = append(l.docs, Document{})
+	doc := Document{URL: rawURL}
+	err := l.loadDocument(&doc)
+	doc.Err = err
+	l.docs[i] = doc
+	return err
+}
+
+// loadDocument fetches one document, filling in doc, and follows the
+// sitemaps it lists
+func (l *loader) loadDocument(doc *Document) error {
+	if l.fetched >= MaxSitemaps {
+		return fmt.Errorf("%s: more than %d sitemaps, skipped", doc.URL, MaxSitemaps)
+	}
+	l.fetched++
+
+	base, err := url.Parse(doc.URL)
+	if err != nil {
+		return fmt.Errorf("invalid sitemap URL: %w", err)
+	}
+
+	pages, children, err := l.fetch(doc)
+	if err != nil {
+		return err
+	}
+	doc.Entries = len(pages)
+	doc.Children = len(children)
+
+	// Feeds may use relative links, sitemaps may not
+	strict := doc.Format == "urlset" || doc.Format == "sitemapindex"
+
+	for _, ref := range pages {
+		u := resolve(base, ref)
+		if u == "" || (strict && !isAbsolute(ref)) {
+			doc.Invalid = append(doc.Invalid, ref)
+		}
+		if u != "" && !l.pages[u] {
+			l.pages[u] = true
+			l.entries = append(l.entries, Entry{URL: u, Sitemap: doc.URL})
+		}
+	}
+	for _, ref := range children {
+		u := resolve(base, ref)
+		if u == "" || !isAbsolute(ref) {
+			doc.Invalid = append(doc.Invalid, ref)
+		}
+		if u != "" {
+			if err := l.load(u); err != nil {
+				l.errs = append(l.errs, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fetch downloads and parses one document, recording its format and size
+func (l *loader) fetch(doc *Document) (pages, children []string, err error) {
+	rawURL := doc.URL
+	resp, err := robots.GetWithRetry(l.client, rawURL, robots.DefaultRetryConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s: status %d", rawURL, resp.StatusCode)
+	}
+
+	// Sniff gzip from the magic bytes rather than the .gz extension or the
+	// content type: the transport may already have decompressed the body
+	// One byte over the limit is read to tell an oversized sitemap apart
+	body := bufio.NewReader(io.LimitReader(resp.Body, MaxSize+1))
+	var r io.Reader = body
+	if magic, _ := body.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", rawURL, err)
+		}
+		defer gz.Close()
+		r = io.LimitReader(gz, MaxSize+1)
+		doc.Gzip = true
+	}
+	counted := &countingReader{r: r}
+
+	doc.Format, pages, children, err = parse(counted)
+	doc.Size = counted.n
+	if doc.Size > MaxSize {
+		return nil, nil, fmt.Errorf("%s: larger than the %dMB limit", rawURL, MaxSize>>20)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	return pages, children, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// parse reads a UTF-8 document, as the protocol requires, and returns the
+// root element, the page URLs and the child sitemaps it lists. Only elements
+// in the root's namespace count, so <image:loc> in a sitemap or <atom:link>
+// in an RSS feed are ignored.
+func parse(r io.Reader) (format string, pages, children []string, err error) {
+	decoder := xml.NewDecoder(r)
+
+	var root xml.Name
+	var inEntry bool     // Inside an RSS <item> or an Atom <entry>
+	var target *[]string // Where the text of the current element goes
+	var text strings.Builder
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return root.Local, nil, nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if root.Local == "" {
+				root = t.Name
+				if !formats[root.Local] {
+					return root.Local, nil, nil, fmt.Errorf("unknown format <%s>, expected a sitemap or an RSS/Atom feed", root.Local)
+				}
+				continue
+			}
+			if t.Name.Space != root.Space {
+				continue
+			}
+
+			switch name := t.Name.Local; {
+			case name == "item" || name == "entry":
+				inEntry = true
+			case name == "loc" && root.Local == "urlset":
+				target = &pages
+			case name == "loc" && root.Local == "sitemapindex":
+				target = &children
+			case name == "link" && inEntry && root.Local == "rss":
+				target = &pages
+			case name == "link" && inEntry && root.Local == "feed":
+				// Atom links carry the URL in href; rel defaults to alternate
+				if rel := attr(t, "rel"); rel == "" || rel == "alternate" {
+					if href := attr(t, "href"); href != "" {
+						pages = append(pages, href)
+					}
+				}
+			}
+			text.Reset()
+
+		case xml.CharData:
+			if target != nil {
+				text.Write(t)
+			}
+
+		case xml.EndElement:
+			if target != nil {
+				if u := strings.TrimSpace(text.String()); u != "" {
+					*target = append(*target, u)
+				}
+				target = nil
+			}
+			if t.Name.Space == root.Space && (t.Name.Local == "item" || t.Name.Local == "entry") {
+				inEntry = false
+			}
+		}
+	}
+
+	if root.Local == "" {
+		return "", nil, nil, fmt.Errorf("empty document")
+	}
+	return root.Local, pages, children, nil
+}
+
+// attr returns the value of an unqualified attribute
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// resolve makes a listed URL absolute, dropping anything that is not http(s)
+func resolve(base *url.URL, ref string) string {
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
+
+// isAbsolute reports whether a listed URL is absolute, with a scheme and host
+func isAbsolute(ref string) bool {
+	u, err := url.Parse(ref)
+	return err == nil && u.IsAbs() && u.Host != ""
+}
+
+// ParseList splits a comma-separated list of sitemap URLs
+func ParseList(s string) []string {
+	var urls []string
+	for _, u := range strings.Split(s, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
@@ -0,0 +1,270 @@
+package sitemapcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/canonical"
+	"github.com/ngonzalez/web-tools/internal/contenttype"
+	"github.com/ngonzalez/web-tools/internal/crawl"
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/htmlattr"
+	"github.com/ngonzalez/web-tools/internal/htmlhead"
+	"github.com/ngonzalez/web-tools/internal/httppool"
+	"github.com/ngonzalez/web-tools/internal/robots"
+	"github.com/ngonzalez/web-tools/internal/sitemap"
+	"github.com/ngonzalez/web-tools/internal/verbosity"
+)
+
+// Config holds checker configuration
+type Config struct {
+	Concurrency         int
+	Timeout             time.Duration
+	Verbose             bool
+	Verbosity           int               // verbosity.Quiet to verbosity.Debug; Verbose alone maps to verbosity.Info
+	Transport           http.RoundTripper // Optional custom transport, e.g. a shared response cache
+	Delay               time.Duration     // Minimum pause between two requests
+	MaxIdleConnsPerHost int               // Idle connections kept per host, 0 matches Concurrency
+	HTMLContentTypes    []string          // Extra media types parsed as HTML, besides contenttype.HTML
+}
+
+// DefaultConfig returns default configuration
+func DefaultConfig() Config {
+	return Config{
+		Concurrency: 10,
+		Timeout:     10 * time.Second,
+		Verbose:     false,
+	}
+}
+
+// Checker validates sitemaps and the URLs they list
+type Checker struct {
+	config   Config
+	result   *Result
+	resultMu sync.Mutex
+	client   *http.Client // Follows redirects, for the sitemaps themselves
+	noFollow *http.Client // Listed URLs must answer 200 themselves
+	stats    crawlstats.Counter
+}
+
+// New creates a new Checker
+func New(config Config) *Checker {
+	config.Verbosity = verbosity.Effective(config.Verbosity, config.Verbose)
+	transport := config.Transport
+	if transport == nil {
+		transport = httppool.New(config.Concurrency, config.MaxIdleConnsPerHost)
+	}
+	return &Checker{
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+		},
+		noFollow: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Check fetches a sitemap or sitemap index, validates every document it
+// leads to and requests every URL they list
+func (c *Checker) Check(sitemapURL string) (*Result, error) {
+	parsed, err := url.Parse(sitemapURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("URL must use http or https scheme")
+	}
+
+	c.stats.Start()
+	c.result = &Result{SitemapURL: sitemapURL}
+
+	entries, docs := sitemap.Inspect(c.client, sitemapURL)
+	c.result.Documents = docs
+	c.result.TotalURLs = len(entries)
+	for _, doc := range docs {
+		c.result.Duplicates += doc.Entries
+		c.stats.AddRequest()
+	}
+	c.result.Duplicates -= len(entries)
+
+	seeds := make([]crawl.Task, 0, len(entries))
+	for _, entry := range entries {
+		seeds = append(seeds, crawl.Task{URL: entry.URL, SourceURL: entry.Sitemap})
+	}
+
+	// Listed URLs are only checked, never crawled further
+	engine := crawl.New(crawl.Config{
+		Concurrency: c.config.Concurrency,
+		MaxPages:    len(seeds),
+		Delay:       c.config.Delay,
+	}, c.processURL)
+	c.result.Checked = engine.Run(context.Background(), seeds...)
+
+	c.result.sort()
+	c.result.CrawlStats = c.stats.Snapshot()
+
+	return c.result, nil
+}
+
+// processURL requests a listed URL and records how it falls short of a
+// sitemap entry: anything but a 200, a noindex or a canonical elsewhere
+func (c *Checker) processURL(ctx context.Context, task crawl.Task) []crawl.Task {
+	check := URLCheck{URL: task.URL, Sitemap: task.SourceURL}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", task.URL, nil)
+	if err != nil {
+		check.Error = err.Error()
+		c.add(check)
+		return nil
+	}
+
+	req.Header.Set("User-Agent", "SitemapCheck/1.0")
+
+	start := time.Now()
+	resp, err := c.noFollow.Do(req)
+	c.stats.AddRequest()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if c.config.Verbosity >= verbosity.Warn {
+			printError(task.URL, err.Error())
+		}
+		check.Error = err.Error()
+		c.add(check)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if verbosity.ShowStatus(c.config.Verbosity, resp.StatusCode) {
+		printProgress(task.URL, resp.StatusCode)
+	}
+	if c.config.Verbosity >= verbosity.Debug {
+		verbosity.Timing(task.URL, time.Since(start))
+	}
+
+	check.Status = resp.StatusCode
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if location, err := resp.Location(); err == nil {
+			check.Location = location.String()
+		}
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		check.NoIndex = robots.IsNoIndex(resp.Header.Values("X-Robots-Tag")...)
+		if contenttype.IsHTML(resp.Header.Get("Content-Type"), c.config.HTMLContentTypes) {
+			head := parseHead(c.stats.Body(resp.Body), task.URL)
+			check.NoIndex = check.NoIndex || robots.IsNoIndex(head.robots...)
+			check.Canonical = head.canonical
+		}
+	}
+
+	c.add(check)
+	return nil
+}
+
+// pageHead is what the <head> of a listed page says about indexing
+type pageHead struct {
+	robots    []string // Content of the robots and googlebot meta tags
+	canonical string   // Canonical URL, empty when it is the page itself
+}
+
+// parseHead reads the <head> of a page for robots meta tags and a canonical
+// pointing elsewhere
+func parseHead(body io.Reader, pageURL string) pageHead {
+	var head pageHead
+	tokenizer := html.NewTokenizer(body)
+
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			return head
+
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			token := tokenizer.Token()
+			if htmlhead.IsEnd(tokenType, token.Data) {
+				return head
+			}
+			if tokenType == html.EndTagToken {
+				continue
+			}
+
+			switch token.Data {
+			case "meta":
+				switch strings.ToLower(htmlattr.Get(token, "name")) {
+				case "robots", "googlebot":
+					head.robots = append(head.robots, htmlattr.Get(token, "content"))
+				}
+			case "link":
+				if !htmlattr.HasRel(token, "canonical") || head.canonical != "" {
+					continue
+				}
+				if target := resolveURL(pageURL, htmlattr.Get(token, "href")); target != "" && !canonical.URLsEquivalent(target, pageURL) {
+					head.canonical = target
+				}
+			}
+		}
+	}
+}
+
+// resolveURL makes a canonical href absolute against the page URL
+func resolveURL(pageURL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	resolved, err := base.Parse(href)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+		return ""
+	}
+	resolved.Fragment = ""
+	return resolved.String()
+}
+
+// add records the result of a URL
+func (c *Checker) add(check URLCheck) {
+	c.resultMu.Lock()
+	c.result.add(check)
+	c.resultMu.Unlock()
+}
+
+func printProgress(url string, statusCode int) {
+	var statusColor string
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		statusColor = colorGreen
+	case statusCode >= 300 && statusCode < 400:
+		statusColor = colorYellow
+	case statusCode >= 400:
+		statusColor = colorRed
+	default:
+		statusColor = colorReset
+	}
+
+	fmt.Fprintf(os.Stderr, "%s[%d]%s %s\n", statusColor, statusCode, colorReset, url)
+}
+
+func printError(url string, errMsg string) {
+	fmt.Fprintf(os.Stderr, "%s[ERR]%s %s - %s\n", colorRed, colorReset, url, errMsg)
+}
@@ -0,0 +1,259 @@
+package sitemapcheck
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ngonzalez/web-tools/internal/crawlstats"
+	"github.com/ngonzalez/web-tools/internal/sitemap"
+)
+
+// URLCheck is the answer to a URL listed in a sitemap
+type URLCheck struct {
+	URL       string
+	Sitemap   string // Document that listed the URL
+	Status    int    // 0 on a connection error
+	Location  string // Redirect target
+	Error     string
+	NoIndex   bool   // Forbidden from the index by a robots meta tag or X-Robots-Tag
+	Canonical string // Canonical URL when it names another page
+}
+
+// Result holds the validation results
+type Result struct {
+	SitemapURL string
+	Documents  []sitemap.Document // Every sitemap fetched, in order
+	TotalURLs  int                // Distinct page URLs listed
+	Duplicates int                // Entries listing a URL already listed
+	Checked    int
+
+	OK            int
+	Redirects     []URLCheck // Sorted by URL
+	Broken        []URLCheck // Errors and statuses of 400 and above, sorted by URL
+	Other         []URLCheck // Any other status than 200 or a redirect, sorted by URL
+	NoIndex       []URLCheck // Sorted by URL
+	Canonicalized []URLCheck // Sorted by URL
+
+	CrawlStats crawlstats.Stats
+}
+
+// add files the answer to a URL
+func (r *Result) add(check URLCheck) {
+	switch {
+	case check.Error != "" || check.Status >= 400:
+		r.Broken = append(r.Broken, check)
+	case check.Status >= 300:
+		r.Redirects = append(r.Redirects, check)
+	case check.Status != 200:
+		r.Other = append(r.Other, check)
+	default:
+		r.OK++
+		if check.NoIndex {
+			r.NoIndex = append(r.NoIndex, check)
+		}
+		if check.Canonical != "" {
+			r.Canonicalized = append(r.Canonicalized, check)
+		}
+	}
+}
+
+func (r *Result) sort() {
+	for _, checks := range [][]URLCheck{r.Redirects, r.Broken, r.Other, r.NoIndex, r.Canonicalized} {
+		sort.Slice(checks, func(i, j int) bool {
+			return checks[i].URL < checks[j].URL
+		})
+	}
+}
+
+// InvalidDocuments returns the sitemaps that could not be fetched or parsed
+func (r *Result) InvalidDocuments() []sitemap.Document {
+	var docs []sitemap.Document
+	for _, doc := range r.Documents {
+		if doc.Err != nil {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// Oversized returns the sitemaps over the protocol limits: more than
+// sitemap.MaxEntries URLs or sitemaps, or more than sitemap.MaxSize bytes
+func (r *Result) Oversized() []sitemap.Document {
+	var docs []sitemap.Document
+	for _, doc := range r.Documents {
+		if doc.Entries > sitemap.MaxEntries || doc.Children > sitemap.MaxEntries || doc.Size > sitemap.MaxSize {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// InvalidEntries counts the listed URLs that are not absolute http(s) URLs
+func (r *Result) InvalidEntries() int {
+	count := 0
+	for _, doc := range r.Documents {
+		count += len(doc.Invalid)
+	}
+	return count
+}
+
+// HasProblems reports whether anything in the sitemaps needs fixing
+func (r *Result) HasProblems() bool {
+	return len(r.InvalidDocuments()) > 0 || len(r.Oversized()) > 0 || r.InvalidEntries() > 0 ||
+		r.Duplicates > 0 || len(r.Redirects) > 0 || len(r.Broken) > 0 || len(r.Other) > 0 ||
+		len(r.NoIndex) > 0 || len(r.Canonicalized) > 0
+}
+
+// SummaryLine returns a single machine-readable key=value summary line
+func (r *Result) SummaryLine() string {
+	return fmt.Sprintf("SUMMARY tool=sitemapcheck sitemaps=%d invalid_sitemaps=%d oversized=%d urls=%d invalid_urls=%d duplicates=%d ok=%d redirects=%d broken=%d other=%d noindex=%d canonicalized=%d",
+		len(r.Documents), len(r.InvalidDocuments()), len(r.Oversized()), r.TotalURLs, r.InvalidEntries(), r.Duplicates,
+		r.OK, len(r.Redirects), len(r.Broken), len(r.Other), len(r.NoIndex), len(r.Canonicalized))
+}
+
+// ANSI colors
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorPurple = "\033[35m"
+	colorCyan   = "\033[36m"
+	colorGray   = "\033[90m"
+	colorBold   = "\033[1m"
+)
+
+// PrintSummary prints a formatted summary of the results
+func (r *Result) PrintSummary(limit int) {
+	fmt.Println()
+	fmt.Printf("%s%s=== Sitemap Validation ===%s\n", colorBold, colorCyan, colorReset)
+	fmt.Printf("Sitemap: %s%s%s\n", colorBlue, r.SitemapURL, colorReset)
+	fmt.Printf("Documents fetched: %s%d%s\n", colorGreen, len(r.Documents), colorReset)
+	fmt.Printf("URLs listed: %s%d%s\n", colorGreen, r.TotalURLs, colorReset)
+	fmt.Println()
+
+	fmt.Printf("%s%sSummary:%s\n", colorBold, colorYellow, colorReset)
+	fmt.Printf("  %s✓ OK (200):%s             %s%d%s\n", colorGreen, colorReset, colorBold, r.OK, colorReset)
+	fmt.Printf("  %s→ Redirects:%s            %s%d%s\n", colorYellow, colorReset, colorBold, len(r.Redirects), colorReset)
+	fmt.Printf("  %s✗ Broken:%s               %s%d%s\n", colorRed, colorReset, colorBold, len(r.Broken), colorReset)
+	if len(r.Other) > 0 {
+		fmt.Printf("  %s? Other status:%s         %s%d%s\n", colorGray, colorReset, colorBold, len(r.Other), colorReset)
+	}
+	fmt.Printf("  %s⚠ Noindex:%s              %s%d%s\n", colorPurple, colorReset, colorBold, len(r.NoIndex), colorReset)
+	fmt.Printf("  %s⚠ Canonicalized:%s        %s%d%s\n", colorPurple, colorReset, colorBold, len(r.Canonicalized), colorReset)
+	fmt.Printf("  %s⚠ Duplicate entries:%s    %s%d%s\n", colorYellow, colorReset, colorBold, r.Duplicates, colorReset)
+
+	r.printDocuments()
+
+	r.printChecks("Redirects", colorYellow, "Listed URLs must answer 200 themselves; list the target instead", r.Redirects, limit)
+	r.printChecks("Broken URLs", colorRed, "", r.Broken, limit)
+	r.printChecks("Other Statuses", colorGray, "", r.Other, limit)
+	r.printChecks("Noindex URLs", colorPurple, "Sitemaps should only list pages meant for the index", r.NoIndex, limit)
+	r.printChecks("Canonicalized URLs", colorPurple, "The page names another URL as canonical; list that one instead", r.Canonicalized, limit)
+
+	if !r.HasProblems() {
+		fmt.Println()
+		fmt.Printf("%s✓ No problems found%s\n", colorGreen, colorReset)
+	}
+
+	fmt.Println()
+
+	r.CrawlStats.Print()
+}
+
+// printDocuments lists every sitemap fetched with its problems
+func (r *Result) printDocuments() {
+	fmt.Println()
+	fmt.Printf("%s%s=== Sitemaps (%d) ===%s\n", colorBold, colorCyan, len(r.Documents), colorReset)
+	fmt.Println()
+
+	for _, doc := range r.Documents {
+		if doc.Err != nil {
+			fmt.Printf("  %s✗%s %s\n", colorRed, colorReset, doc.URL)
+			fmt.Printf("    %s%v%s\n", colorRed, doc.Err, colorReset)
+			continue
+		}
+
+		gz := ""
+		if doc.Gzip {
+			gz = ", gzip"
+		}
+		count := fmt.Sprintf("%d URLs", doc.Entries)
+		if doc.Format == "sitemapindex" {
+			count = fmt.Sprintf("%d sitemaps", doc.Children)
+		}
+		fmt.Printf("  %s✓%s %s %s(%s, %s, %s%s)%s\n", colorGreen, colorReset, doc.URL, colorGray, doc.Format, count, formatSize(doc.Size), gz, colorReset)
+
+		if doc.Entries > sitemap.MaxEntries || doc.Children > sitemap.MaxEntries {
+			fmt.Printf("    %sOver the limit of %d entries, split it and list the parts in a sitemap index%s\n", colorRed, sitemap.MaxEntries, colorReset)
+		}
+		if doc.Format == "rss" || doc.Format == "feed" {
+			fmt.Printf("    %sA feed rather than a sitemap%s\n", colorGray, colorReset)
+		}
+		for i, ref := range doc.Invalid {
+			if i == 5 {
+				fmt.Printf("    %s... and %d more invalid URLs%s\n", colorGray, len(doc.Invalid)-i, colorReset)
+				break
+			}
+			fmt.Printf("    %sNot an absolute http(s) URL:%s %s\n", colorYellow, colorReset, ref)
+		}
+	}
+}
+
+// printChecks lists the URLs of one kind of problem, up to limit (0 = all)
+func (r *Result) printChecks(title, color, hint string, checks []URLCheck, limit int) {
+	if len(checks) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s%s=== %s (%d) ===%s\n", colorBold, color, title, len(checks), colorReset)
+	if hint != "" {
+		fmt.Printf("%s%s%s\n", colorGray, hint, colorReset)
+	}
+	fmt.Println()
+
+	displayCount := limit
+	if displayCount <= 0 || displayCount > len(checks) {
+		displayCount = len(checks)
+	}
+
+	multiple := len(r.Documents) > 1
+	for _, check := range checks[:displayCount] {
+		switch {
+		case check.Error != "":
+			fmt.Printf("  %s[ERR]%s %s\n", color, colorReset, check.URL)
+			fmt.Printf("    %s%s%s\n", colorGray, check.Error, colorReset)
+		case check.Status != 200:
+			fmt.Printf("  %s[%d]%s %s\n", color, check.Status, colorReset, check.URL)
+		default:
+			fmt.Printf("  %s⚠%s %s\n", color, colorReset, check.URL)
+		}
+		if check.Location != "" {
+			fmt.Printf("    → %s\n", check.Location)
+		}
+		if check.Canonical != "" {
+			fmt.Printf("    Canonical: %s\n", check.Canonical)
+		}
+		if multiple {
+			fmt.Printf("    %sListed in: %s%s\n", colorGray, check.Sitemap, colorReset)
+		}
+	}
+
+	if len(checks) > displayCount {
+		fmt.Printf("\n%s... and %d more URLs%s\n", colorGray, len(checks)-displayCount, colorReset)
+	}
+}
+
+// formatSize formats a byte count for display
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,102 @@
+// Package sitetree builds the structure of a site from the URLs of its
+// crawled pages: a tree of path segments with page counts per section, to
+// see how a site is organized and which sections are largest.
+package sitetree
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Node is a path section of the site
+type Node struct {
+	Segment  string // Last path segment, empty for the root
+	Path     string // Path up to this node, e.g. /blog/2024
+	Pages    int    // Pages at exactly this path, query variants included
+	Total    int    // Pages in the section, this node's included
+	Children []*Node
+
+	bySegment map[string]*Node
+}
+
+// Build returns the tree of the given page URLs. Query strings and
+// fragments are ignored, and /blog and /blog/ are the same node. Children
+// are sorted largest section first.
+func Build(urls []string) *Node {
+	root := &Node{Path: "/"}
+	for _, raw := range urls {
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		node := root
+		node.Total++
+		for _, segment := range strings.Split(parsed.Path, "/") {
+			if segment == "" {
+				continue
+			}
+			node = node.child(segment)
+			node.Total++
+		}
+		node.Pages++
+	}
+	root.sort()
+	return root
+}
+
+// child returns the child for a segment, creating it if needed
+func (n *Node) child(segment string) *Node {
+	if c := n.bySegment[segment]; c != nil {
+		return c
+	}
+	if n.bySegment == nil {
+		n.bySegment = make(map[string]*Node)
+	}
+	c := &Node{Segment: segment, Path: strings.TrimSuffix(n.Path, "/") + "/" + segment}
+	n.bySegment[segment] = c
+	n.Children = append(n.Children, c)
+	return c
+}
+
+func (n *Node) sort() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		if n.Children[i].Total != n.Children[j].Total {
+			return n.Children[i].Total > n.Children[j].Total
+		}
+		return n.Children[i].Segment < n.Children[j].Segment
+	})
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
+
+// Write writes the tree as indented text, two spaces per level, each
+// section with its page count
+func (n *Node) Write(w io.Writer) error {
+	return n.write(w, 0)
+}
+
+func (n *Node) write(w io.Writer, depth int) error {
+	name := n.Segment
+	if depth == 0 {
+		name = "/"
+	} else if len(n.Children) > 0 {
+		name += "/"
+	}
+	line := fmt.Sprintf("%s%s (%d)", strings.Repeat("  ", depth), name, n.Total)
+	if len(n.Children) > 0 && n.Pages > 0 {
+		line += fmt.Sprintf(" [%d here]", n.Pages)
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+	for _, c := range n.Children {
+		if err := c.write(w, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
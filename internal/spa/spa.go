@@ -0,0 +1,155 @@
+// Package spa detects pages rendered in the browser. The tools read the
+// HTML as served and never run JavaScript, so on a client-rendered page the
+// title, canonical and links they see are those of the empty shell.
+package spa
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"github.com/ngonzalez/web-tools/internal/htmlhead"
+)
+
+// Thresholds of a body nearly empty of content
+const (
+	MaxTextChars = 200 // Visible body text, in characters
+	MaxLinks     = 3   // <a href> links in the body
+
+	// Inline script that counts as a bundle on its own
+	InlineBundleBytes = 20 << 10
+)
+
+// Warning is shown for a page that appears to be client-rendered
+const Warning = "page appears to be client-rendered; static analysis may be incomplete"
+
+// mountIDs are the ids frameworks render the application into
+var mountIDs = map[string]bool{
+	"root":      true,
+	"app":       true,
+	"__next":    true,
+	"__nuxt":    true,
+	"___gatsby": true,
+	"svelte":    true,
+}
+
+// mountAttrs mark the root element of a framework application
+var mountAttrs = []string{"ng-version", "ng-app", "data-reactroot", "data-v-app"}
+
+// hidden are the elements whose text is not page content
+var hidden = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+}
+
+// Detector looks for a body nearly empty of content and links that loads
+// JavaScript bundles. Like serp.SnippetCollector it is fed the tokens of a
+// parse that is already running.
+type Detector struct {
+	inBody       bool
+	skip         string // Hidden element being read
+	inlineScript bool
+	text         int // Visible body text, in characters
+	links        int
+	scripts      int // External scripts
+	inlineBytes  int
+	mount        string // Mount point found, e.g. #root
+}
+
+// Token feeds the next token of the page
+func (d *Detector) Token(tokenType html.TokenType, token html.Token) {
+	// The body starts at <body>, or at its first element when the tag is omitted
+	if htmlhead.IsEnd(tokenType, token.Data) {
+		d.inBody = true
+	}
+
+	switch tokenType {
+	case html.StartTagToken, html.SelfClosingTagToken:
+		switch token.Data {
+		case "script":
+			if attr(token, "src") != "" {
+				d.scripts++
+			} else if tokenType == html.StartTagToken {
+				d.inlineScript = true
+			}
+		case "a":
+			if d.inBody && strings.TrimSpace(attr(token, "href")) != "" {
+				d.links++
+			}
+		}
+		if d.mount == "" && d.inBody {
+			d.mount = mountPoint(token)
+		}
+		if tokenType == html.StartTagToken && hidden[token.Data] && d.skip == "" {
+			d.skip = token.Data
+		}
+
+	case html.EndTagToken:
+		if token.Data == d.skip {
+			d.skip = ""
+		}
+		if token.Data == "script" {
+			d.inlineScript = false
+		}
+
+	case html.TextToken:
+		if d.inlineScript {
+			d.inlineBytes += len(token.Data)
+		}
+		if d.inBody && d.skip == "" {
+			d.text += utf8.RuneCountInString(strings.Join(strings.Fields(token.Data), " "))
+		}
+	}
+}
+
+// ClientRendered reports whether the page appears to be rendered by
+// JavaScript: a nearly empty body, and either a framework mount point with
+// scripts, several external scripts or a large inline bundle
+func (d *Detector) ClientRendered() bool {
+	if d.text > MaxTextChars || d.links > MaxLinks {
+		return false
+	}
+	bundle := d.inlineBytes >= InlineBundleBytes
+	return (d.mount != "" && (d.scripts > 0 || bundle)) || d.scripts >= 2 || bundle
+}
+
+// Evidence describes what the detection is based on, e.g.
+// "12 chars of text, 0 links, 3 scripts, mount #root"
+func (d *Detector) Evidence() string {
+	evidence := fmt.Sprintf("%d chars of text, %d links, %d scripts", d.text, d.links, d.scripts)
+	if d.inlineBytes >= InlineBundleBytes {
+		evidence += fmt.Sprintf(", %d KB inline script", d.inlineBytes>>10)
+	}
+	if d.mount != "" {
+		evidence += ", mount " + d.mount
+	}
+	return evidence
+}
+
+// mountPoint returns how an element marks an application root, or ""
+func mountPoint(token html.Token) string {
+	if id := attr(token, "id"); mountIDs[id] {
+		return "#" + id
+	}
+	for _, name := range mountAttrs {
+		for _, a := range token.Attr {
+			if a.Key == name {
+				return "[" + name + "]"
+			}
+		}
+	}
+	return ""
+}
+
+func attr(token html.Token, key string) string {
+	for _, a := range token.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package urlnorm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Normalize puts a URL in a form fit for comparison: lowercase scheme and
+// host, no default port or fragment, and sorted query parameters
+func Normalize(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	// Lowercase scheme and host
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+
+	// Remove default ports
+	host := parsed.Host
+	if strings.HasSuffix(host, ":80") && parsed.Scheme == "http" {
+		parsed.Host = strings.TrimSuffix(host, ":80")
+	}
+	if strings.HasSuffix(host, ":443") && parsed.Scheme == "https" {
+		parsed.Host = strings.TrimSuffix(host, ":443")
+	}
+
+	// Remove fragment
+	parsed.Fragment = ""
+
+	// Sort query parameters for consistent comparison
+	if parsed.RawQuery != "" {
+		values := parsed.Query()
+		parsed.RawQuery = values.Encode()
+	}
+
+	return parsed.String()
+}
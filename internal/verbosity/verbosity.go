@@ -0,0 +1,54 @@
+package verbosity
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Levels for the Verbosity field of the tool configs
+const (
+	Quiet = iota // Summary only
+	Warn         // Errors and notable events (-v)
+	Info         // Every visited URL (-vv)
+	Debug        // Timing and request internals (-vvv)
+)
+
+// Effective returns the level to use for a config. The legacy Verbose flag
+// maps to Info, which is what it used to print.
+func Effective(level int, verbose bool) int {
+	if level == Quiet && verbose {
+		return Info
+	}
+	return level
+}
+
+// FromFlags turns the -v, -vv and -vvv command-line flags into a level
+func FromFlags(v, vv, vvv bool) int {
+	switch {
+	case vvv:
+		return Debug
+	case vv:
+		return Info
+	case v:
+		return Warn
+	default:
+		return Quiet
+	}
+}
+
+const (
+	colorReset = "\033[0m"
+	colorGray  = "\033[90m"
+)
+
+// ShowStatus reports whether a fetched URL should be printed: every URL at
+// Info, only HTTP errors at Warn
+func ShowStatus(level, statusCode int) bool {
+	return level >= Info || level >= Warn && statusCode >= 400
+}
+
+// Timing prints a debug line with the time a fetch took
+func Timing(url string, elapsed time.Duration) {
+	fmt.Fprintf(os.Stderr, "%s[DBG] %s fetched in %v%s\n", colorGray, url, elapsed.Round(time.Millisecond), colorReset)
+}